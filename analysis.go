@@ -0,0 +1,224 @@
+package triplestore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DuplicateReport lists data quality issues of literal values. Triples differing
+// only by the notation of their datatype (ex: "xsd:integer" and its full IRI) are
+// the same triple (see TripleKey), held once by graphs, and so are not reported.
+type DuplicateReport struct {
+	// NearDuplicates are groups of triples with the same subject and predicate
+	// whose literals are equal in the value space of their datatypes but
+	// lexically different (ex: "1"^^xsd:integer and "01"^^xsd:integer)
+	NearDuplicates [][]Triple
+	// Conflicts are groups of triples with the same subject and predicate
+	// having several distinct literal values (with the same language tag, if any)
+	Conflicts [][]Triple
+}
+
+// ReportDuplicates analyzes the literal valued triples of the graph
+// for near duplicates and conflicting values.
+func ReportDuplicates(g RDFGraph) *DuplicateReport {
+	groups := make(map[string][]*triple)
+	for _, t := range g.Triples() {
+		tri := t.(*triple)
+		if !tri.obj.isLit {
+			continue
+		}
+		k := nodeString(subjectObject(tri)) + "\x00" + tri.pred + "\x00" + strings.ToLower(tri.obj.lit.langtag)
+		groups[k] = append(groups[k], tri)
+	}
+
+	var keys []string
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	report := &DuplicateReport{}
+	for _, k := range keys {
+		tris := groups[k]
+		sort.Slice(tris, func(i, j int) bool { return tris[i].key() < tris[j].key() })
+
+		// near duplicates: clusters of values equal in value space
+		var clusters [][]*triple
+		for _, t := range tris {
+			placed := false
+			for i, c := range clusters {
+				if sameLiteralValue(c[0], t) {
+					clusters[i] = append(c, t)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				clusters = append(clusters, []*triple{t})
+			}
+		}
+		for _, c := range clusters {
+			if len(c) > 1 && lexicalForms(c) > 1 {
+				report.NearDuplicates = append(report.NearDuplicates, asTriples(c))
+			}
+		}
+
+		if len(clusters) > 1 {
+			report.Conflicts = append(report.Conflicts, asTriples(tris))
+		}
+	}
+	return report
+}
+
+func sameLiteralValue(a, b *triple) bool {
+	la, lb := a.obj.lit, b.obj.lit
+	la.typ, lb.typ = shortXsdType(la.typ), shortXsdType(lb.typ)
+	if la.typ == lb.typ && la.val == lb.val {
+		return true
+	}
+	cmp, err := CompareLiterals(la, lb)
+	return err == nil && cmp == 0
+}
+
+func lexicalForms(tris []*triple) int {
+	forms := make(map[string]bool)
+	for _, t := range tris {
+		forms[string(shortXsdType(t.obj.lit.typ))+"\x00"+t.obj.lit.val] = true
+	}
+	return len(forms)
+}
+
+func asTriples(tris []*triple) []Triple {
+	out := make([]Triple, len(tris))
+	for i, t := range tris {
+		out[i] = t
+	}
+	return out
+}
+
+// SchemaProfile summarizes the observed usage of the predicates of a graph
+type SchemaProfile struct {
+	Predicates []PredicateProfile
+}
+
+// PredicateProfile describes the observed usage of a predicate
+type PredicateProfile struct {
+	Predicate string
+	// Triples is the number of triples using the predicate
+	Triples int
+	// SubjectTypes are the classes (rdf:type) of the subjects
+	SubjectTypes []string
+	// ObjectTypes are the classes (rdf:type) of the resource objects
+	ObjectTypes []string
+	// Datatypes are the datatypes of the literal objects, "rdf:langString" for language tagged strings
+	Datatypes []XsdType
+	// MinCardinality and MaxCardinality are the minimum and maximum number
+	// of values of the predicate per subject using it
+	MinCardinality, MaxCardinality int
+}
+
+// ExtractSchema profiles the predicates of the graph: observed subject and object classes,
+// literal datatypes and cardinalities. Predicates are sorted.
+func ExtractSchema(g RDFGraph) *SchemaProfile {
+	byPred := make(map[string][]*triple)
+	for _, t := range g.Triples() {
+		tri := t.(*triple)
+		byPred[tri.pred] = append(byPred[tri.pred], tri)
+	}
+
+	var preds []string
+	for p := range byPred {
+		preds = append(preds, p)
+	}
+	sort.Strings(preds)
+
+	profile := &SchemaProfile{}
+	for _, pred := range preds {
+		p := PredicateProfile{Predicate: pred, Triples: len(byPred[pred])}
+		subjTypes, objTypes, datatypes := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+		perSubject := make(map[string]int)
+		for _, t := range byPred[pred] {
+			sub := subjectObject(t)
+			perSubject[sub.key()]++
+			for _, c := range nodeTypes(g, sub) {
+				subjTypes[c] = true
+			}
+			switch {
+			case t.obj.isLit && t.obj.lit.langtag != "":
+				datatypes["rdf:langString"] = true
+			case t.obj.isLit:
+				datatypes[string(shortXsdType(t.obj.lit.typ))] = true
+			default:
+				for _, c := range nodeTypes(g, t.obj) {
+					objTypes[c] = true
+				}
+			}
+		}
+		p.SubjectTypes, p.ObjectTypes = sortedKeys(subjTypes), sortedKeys(objTypes)
+		for _, dt := range sortedKeys(datatypes) {
+			p.Datatypes = append(p.Datatypes, XsdType(dt))
+		}
+		for _, n := range perSubject {
+			if p.MinCardinality == 0 || n < p.MinCardinality {
+				p.MinCardinality = n
+			}
+			if n > p.MaxCardinality {
+				p.MaxCardinality = n
+			}
+		}
+		profile.Predicates = append(profile.Predicates, p)
+	}
+	return profile
+}
+
+// Triples returns the profile as a summary graph: each predicate is described
+// by a void:propertyPartition of the "schema" blank node, with rdfs:domain
+// and rdfs:range for observed classes and datatypes, and owl:minCardinality
+// and owl:maxCardinality for observed cardinalities.
+func (s *SchemaProfile) Triples() []Triple {
+	schema := "schema"
+	out := []Triple{BnodePred(schema, "rdf:type").Resource("void:Dataset")}
+	for i, p := range s.Predicates {
+		id := fmt.Sprintf("property%d", i+1)
+		out = append(out,
+			BnodePred(schema, "void:propertyPartition").Bnode(id),
+			BnodePred(id, "void:property").Resource(p.Predicate),
+			BnodePred(id, "void:triples").IntegerLiteral(p.Triples),
+			BnodePred(id, "owl:minCardinality").IntegerLiteral(p.MinCardinality),
+			BnodePred(id, "owl:maxCardinality").IntegerLiteral(p.MaxCardinality),
+		)
+		for _, c := range p.SubjectTypes {
+			out = append(out, BnodePred(id, "rdfs:domain").Resource(c))
+		}
+		for _, c := range p.ObjectTypes {
+			out = append(out, BnodePred(id, "rdfs:range").Resource(c))
+		}
+		for _, dt := range p.Datatypes {
+			out = append(out, BnodePred(id, "rdfs:range").Resource(string(dt)))
+		}
+	}
+	return out
+}
+
+// nodeTypes returns the classes of a node
+func nodeTypes(g RDFGraph, node object) (out []string) {
+	if node.isLit {
+		return
+	}
+	for _, c := range values(g, node, rdfTypePreds...) {
+		if !c.isLit {
+			out = append(out, nodeID(c))
+		}
+	}
+	return
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package triplestore
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReportDuplicates(t *testing.T) {
+	fullInteger := XsdType(XMLSchemaNamespace + "#integer")
+	s := NewSource()
+	s.Add(
+		SubjPred("a", "age").IntegerLiteral(42),
+		SubjPred("a", "age").Object(object{isLit: true, lit: literal{typ: fullInteger, val: "42"}}),
+		SubjPred("b", "age").IntegerLiteral(1),
+		SubjPred("b", "age").Object(object{isLit: true, lit: literal{typ: XsdInteger, val: "01"}}),
+		SubjPred("b", "age").Object(object{isLit: true, lit: literal{typ: XsdDecimal, val: "1.0"}}),
+		SubjPred("c", "name").StringLiteral("John"),
+		SubjPred("c", "name").StringLiteral("Johnny"),
+		SubjPred("d", "label").StringLiteralWithLang("chat", "fr"),
+		SubjPred("d", "label").StringLiteralWithLang("cat", "en"),
+		SubjPred("e", "knows").Resource("a"),
+		SubjPred("e", "knows").Resource("b"),
+	)
+
+	// the notations of a datatype are the same triple, held once
+	if got, want := len(s.Snapshot().WithSubjPred("a", "age")), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	report := ReportDuplicates(s.Snapshot())
+
+	if got, want := len(report.NearDuplicates), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := len(report.NearDuplicates[0]), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	if got, want := len(report.Conflicts), 1; got != want {
+		t.Fatalf("got %d, want %d: %v", got, want, report.Conflicts)
+	}
+	if got, want := Triples(report.Conflicts[0]), (Triples{
+		SubjPred("c", "name").StringLiteral("John"),
+		SubjPred("c", "name").StringLiteral("Johnny"),
+	}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestExtractSchema(t *testing.T) {
+	s := NewSource()
+	s.Add(
+		SubjPred("alice", "rdf:type").Resource("Person"),
+		SubjPred("bob", "rdf:type").Resource("Person"),
+		SubjPred("acme", "rdf:type").Resource("Company"),
+		SubjPred("alice", "name").StringLiteral("Alice"),
+		SubjPred("alice", "name").StringLiteralWithLang("Alicia", "es"),
+		SubjPred("bob", "name").StringLiteral("Bob"),
+		SubjPred("alice", "worksFor").Resource("acme"),
+		SubjPred("bob", "age").IntegerLiteral(42),
+	)
+
+	profile := ExtractSchema(s.Snapshot())
+
+	if got, want := len(profile.Predicates), 4; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	name := profile.Predicates[1]
+	if got, want := name.Predicate, "name"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := name.Triples, 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := fmt.Sprint(name.SubjectTypes), "[Person]"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(name.Datatypes), "[rdf:langString xsd:string]"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(name.MinCardinality, name.MaxCardinality), "1 2"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	works := profile.Predicates[3]
+	if got, want := fmt.Sprint(works.Predicate, works.ObjectTypes, works.Datatypes), "worksFor[Company] []"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	summary := NewSource()
+	summary.Add(profile.Triples()...)
+	snap := summary.Snapshot()
+	if got, want := len(snap.WithPredicate("void:propertyPartition")), 4; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	partition := snap.WithPredObj("void:property", Resource("worksFor"))[0].Subject()
+	if !snap.Contains(BnodePred(partition, "rdfs:range").Resource("Company")) {
+		t.Fatalf("missing range of worksFor in %v", snap.Triples())
+	}
+}
@@ -0,0 +1,348 @@
+package triplestore
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// AvroSchema is the Avro schema of triples written by NewAvroEncoder and
+// MarshalAvro, for instance to register in a schema registry.
+// Datatypes are absolute IRIs, omitted for xsd:string and language tagged literals.
+const AvroSchema = `{
+  "type": "record",
+  "name": "Triple",
+  "namespace": "com.github.wallix.triplestore",
+  "fields": [
+    {"name": "subject", "type": {
+      "type": "record",
+      "name": "Term",
+      "fields": [
+        {"name": "type", "type": {"type": "enum", "name": "TermType", "symbols": ["uri", "bnode", "literal"]}},
+        {"name": "value", "type": "string"},
+        {"name": "datatype", "type": ["null", "string"], "default": null},
+        {"name": "lang", "type": ["null", "string"], "default": null}
+      ]
+    }},
+    {"name": "predicate", "type": "string"},
+    {"name": "object", "type": "Term"}
+  ]
+}`
+
+const (
+	avroMagic       = "Obj\x01"
+	avroSyncSize    = 16
+	avroRecordName  = "com.github.wallix.triplestore.Triple"
+	avroTermTypeURI = 0
+	avroTermBnode   = 1
+	avroTermLiteral = 2
+)
+
+var avroTermTypes = []string{"uri", "bnode", "literal"}
+
+type avroEncoder struct {
+	w    io.Writer
+	sync []byte
+}
+
+// NewAvroEncoder returns an encoder writing triples in an Avro object container file
+// embedding AvroSchema. The header is written on the first call to Encode,
+// then each call writes a block of triples.
+func NewAvroEncoder(w io.Writer) Encoder {
+	return &avroEncoder{w: w}
+}
+
+func (enc *avroEncoder) Encode(tris ...Triple) error {
+	var buf []byte
+	if enc.sync == nil {
+		enc.sync = make([]byte, avroSyncSize)
+		if _, err := rand.Read(enc.sync); err != nil {
+			return err
+		}
+		buf = append(buf, avroMagic...)
+		buf = binary.AppendVarint(buf, 2)
+		buf = appendAvroString(buf, "avro.schema")
+		buf = appendAvroString(buf, AvroSchema)
+		buf = appendAvroString(buf, "avro.codec")
+		buf = appendAvroString(buf, "null")
+		buf = binary.AppendVarint(buf, 0)
+		buf = append(buf, enc.sync...)
+	}
+	if len(tris) > 0 {
+		var block []byte
+		for _, t := range tris {
+			block = appendAvroTriple(block, t.(*triple))
+		}
+		buf = binary.AppendVarint(buf, int64(len(tris)))
+		buf = binary.AppendVarint(buf, int64(len(block)))
+		buf = append(buf, block...)
+		buf = append(buf, enc.sync...)
+	}
+	_, err := enc.w.Write(buf)
+	return err
+}
+
+// MarshalAvro returns the Avro binary encoding of the triple, without schema
+// (ex: the payload of a Kafka message, the schema being registered apart)
+func MarshalAvro(t Triple) []byte {
+	return appendAvroTriple(nil, t.(*triple))
+}
+
+// UnmarshalAvro decodes a triple encoded with MarshalAvro
+func UnmarshalAvro(data []byte) (Triple, error) {
+	r := bytes.NewReader(data)
+	t, err := readAvroTriple(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("avro: %d trailing bytes", r.Len())
+	}
+	return t, nil
+}
+
+func appendAvroTriple(buf []byte, t *triple) []byte {
+	sub := jsonTerm{Type: "uri", Value: t.sub}
+	if t.isSubBnode {
+		sub.Type = "bnode"
+	}
+	buf = appendAvroTerm(buf, sub)
+	buf = appendAvroString(buf, t.pred)
+	return appendAvroTerm(buf, toJSONTerm(t.obj))
+}
+
+func appendAvroTerm(buf []byte, t jsonTerm) []byte {
+	typ := avroTermTypeURI
+	switch t.Type {
+	case "bnode":
+		typ = avroTermBnode
+	case "literal":
+		typ = avroTermLiteral
+	}
+	buf = binary.AppendVarint(buf, int64(typ))
+	buf = appendAvroString(buf, t.Value)
+	buf = appendAvroOptionalString(buf, t.Datatype)
+	return appendAvroOptionalString(buf, t.Lang)
+}
+
+// appendAvroOptionalString writes a ["null", "string"] union, empty strings being null
+func appendAvroOptionalString(buf []byte, s string) []byte {
+	if s == "" {
+		return binary.AppendVarint(buf, 0)
+	}
+	buf = binary.AppendVarint(buf, 1)
+	return appendAvroString(buf, s)
+}
+
+func appendAvroString(buf []byte, s string) []byte {
+	buf = binary.AppendVarint(buf, int64(len(s)))
+	return append(buf, s...)
+}
+
+type avroDecoder struct {
+	r    io.Reader
+	opts decoderOptions
+}
+
+// NewAvroDecoder returns a decoder reading an Avro object container file
+// written with AvroSchema (ex: by NewAvroEncoder). Blocks can be uncompressed or deflated.
+func NewAvroDecoder(r io.Reader, opts ...DecoderOption) Decoder {
+	return &avroDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
+func (dec *avroDecoder) Decode() ([]Triple, error) {
+	tracker := newProgressTracker(dec.opts.progress)
+	r := bufio.NewReader(tracker.reader(dec.r))
+	codec, sync, err := readAvroHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("avro: header: %s", err)
+	}
+
+	var out []Triple
+	for {
+		count, err := binary.ReadVarint(r)
+		if err == io.EOF {
+			tracker.done()
+			return out, nil
+		} else if err != nil {
+			return out, fmt.Errorf("avro: block: %s", err)
+		}
+		size, err := binary.ReadVarint(r)
+		if err != nil {
+			return out, fmt.Errorf("avro: block: %s", err)
+		}
+		if count < 0 || size < 0 {
+			return out, fmt.Errorf("avro: block: invalid count %d or size %d", count, size)
+		}
+		var block io.Reader = io.LimitReader(r, size)
+		if codec == "deflate" {
+			block = flate.NewReader(block)
+		}
+		br := bufio.NewReader(block)
+		for i := int64(0); i < count; i++ {
+			t, err := readAvroTriple(br, dec.opts.maxTermSize)
+			if err != nil {
+				return out, err
+			}
+			out = append(out, t)
+			tracker.addTriple()
+		}
+		if _, err := io.Copy(ioutil.Discard, br); err != nil {
+			return out, fmt.Errorf("avro: block: %s", err)
+		}
+		marker := make([]byte, avroSyncSize)
+		if _, err := io.ReadFull(r, marker); err != nil {
+			return out, fmt.Errorf("avro: block sync marker: %s", err)
+		}
+		if !bytes.Equal(marker, sync) {
+			return out, errors.New("avro: block sync marker mismatch")
+		}
+	}
+}
+
+type avroByteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readAvroHeader checks the schema of the file and returns its codec and sync marker
+func readAvroHeader(r avroByteReader) (string, []byte, error) {
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return "", nil, err
+	}
+	if string(magic) != avroMagic {
+		return "", nil, errors.New("not an avro object container file")
+	}
+
+	meta := make(map[string]string)
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return "", nil, err
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			count = -count
+			if _, err := binary.ReadVarint(r); err != nil {
+				return "", nil, err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			k, err := readAvroString(r, 0)
+			if err != nil {
+				return "", nil, err
+			}
+			v, err := readAvroString(r, 0)
+			if err != nil {
+				return "", nil, err
+			}
+			meta[k] = v
+		}
+	}
+
+	var schema struct {
+		Name, Namespace string
+	}
+	if err := json.Unmarshal([]byte(meta["avro.schema"]), &schema); err != nil {
+		return "", nil, fmt.Errorf("invalid schema: %s", err)
+	}
+	if name := schema.Namespace + "." + schema.Name; name != avroRecordName {
+		return "", nil, fmt.Errorf("expected %s records, got %s", avroRecordName, name)
+	}
+	codec := meta["avro.codec"]
+	switch codec {
+	case "":
+		codec = "null"
+	case "null", "deflate":
+	default:
+		return "", nil, fmt.Errorf("unsupported codec %s", codec)
+	}
+
+	sync := make([]byte, avroSyncSize)
+	if _, err := io.ReadFull(r, sync); err != nil {
+		return "", nil, err
+	}
+	return codec, sync, nil
+}
+
+func readAvroTriple(r avroByteReader, maxTermSize int) (*triple, error) {
+	sub, err := readAvroTerm(r, maxTermSize)
+	if err != nil {
+		return nil, fmt.Errorf("avro: subject: %s", err)
+	}
+	if sub.isLit {
+		return nil, errors.New("avro: subject: literal not allowed")
+	}
+	pred, err := readAvroString(r, maxTermSize)
+	if err != nil {
+		return nil, fmt.Errorf("avro: predicate: %s", err)
+	}
+	obj, err := readAvroTerm(r, maxTermSize)
+	if err != nil {
+		return nil, fmt.Errorf("avro: object: %s", err)
+	}
+	return newTriple(sub, pred, obj), nil
+}
+
+func readAvroTerm(r avroByteReader, maxTermSize int) (object, error) {
+	typ, err := binary.ReadVarint(r)
+	if err != nil {
+		return object{}, err
+	}
+	if typ < 0 || typ >= int64(len(avroTermTypes)) {
+		return object{}, fmt.Errorf("invalid term type %d", typ)
+	}
+	t := jsonTerm{Type: avroTermTypes[typ]}
+	if t.Value, err = readAvroString(r, maxTermSize); err != nil {
+		return object{}, err
+	}
+	if t.Datatype, err = readAvroOptionalString(r, maxTermSize); err != nil {
+		return object{}, fmt.Errorf("datatype: %s", err)
+	}
+	if t.Lang, err = readAvroOptionalString(r, maxTermSize); err != nil {
+		return object{}, fmt.Errorf("lang: %s", err)
+	}
+	return fromJSONTerm(t)
+}
+
+func readAvroOptionalString(r avroByteReader, maxTermSize int) (string, error) {
+	branch, err := binary.ReadVarint(r)
+	if err != nil {
+		return "", err
+	}
+	switch branch {
+	case 0:
+		return "", nil
+	case 1:
+		return readAvroString(r, maxTermSize)
+	}
+	return "", fmt.Errorf("invalid union branch %d", branch)
+}
+
+func readAvroString(r avroByteReader, maxTermSize int) (string, error) {
+	l, err := binary.ReadVarint(r)
+	if err != nil {
+		return "", err
+	}
+	if l < 0 {
+		return "", fmt.Errorf("invalid string length %d", l)
+	}
+	if maxTermSize > 0 && l > int64(maxTermSize) {
+		return "", fmt.Errorf("term of length %d bytes exceeds maximum term size of %d bytes", l, maxTermSize)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, l); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,131 @@
+package triplestore
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAvroCodec(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").Resource("three"),
+		BnodePred("one", "two").Bnode("three"),
+		SubjPred("one", "two").StringLiteral(""),
+		SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+		SubjPred("one", "two").IntegerLiteral(42),
+	}
+
+	var buf bytes.Buffer
+	enc := NewAvroEncoder(&buf)
+	if err := enc.Encode(tris[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(tris[2:]...); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("Obj\x01")) {
+		t.Fatalf("got %q, want avro magic", buf.Bytes()[:4])
+	}
+	decoded, err := NewAvroDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for i, tri := range tris {
+		decoded, err := UnmarshalAvro(MarshalAvro(tri))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(tri) {
+			t.Fatalf("case %d: got %v, want %v", i+1, decoded, tri)
+		}
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(AvroSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAvroDeflateCodec(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").Resource("three"),
+		SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+	}
+	sync := []byte("0123456789abcdef")
+
+	file := []byte("Obj\x01")
+	file = binary.AppendVarint(file, 2)
+	file = appendAvroString(file, "avro.schema")
+	file = appendAvroString(file, AvroSchema)
+	file = appendAvroString(file, "avro.codec")
+	file = appendAvroString(file, "deflate")
+	file = binary.AppendVarint(file, 0)
+	file = append(file, sync...)
+
+	var block bytes.Buffer
+	w, _ := flate.NewWriter(&block, flate.BestCompression)
+	for _, tri := range tris {
+		w.Write(MarshalAvro(tri))
+	}
+	w.Close()
+	file = binary.AppendVarint(file, int64(len(tris)))
+	file = binary.AppendVarint(file, int64(block.Len()))
+	file = append(file, block.Bytes()...)
+	file = append(file, sync...)
+
+	decoded, err := NewAvroDecoder(bytes.NewReader(file)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	file[len(file)-1] = 'z'
+	if _, err := NewAvroDecoder(bytes.NewReader(file)).Decode(); err == nil || !strings.Contains(err.Error(), "sync marker mismatch") {
+		t.Fatalf("got %v, want sync marker error", err)
+	}
+}
+
+func TestAvroDecodeErrors(t *testing.T) {
+	header := func(schema, codec string) []byte {
+		h := []byte("Obj\x01")
+		h = binary.AppendVarint(h, 2)
+		h = appendAvroString(h, "avro.schema")
+		h = appendAvroString(h, schema)
+		h = appendAvroString(h, "avro.codec")
+		h = appendAvroString(h, codec)
+		h = binary.AppendVarint(h, 0)
+		return append(h, "0123456789abcdef"...)
+	}
+	tcases := []struct {
+		in  []byte
+		err string
+	}{
+		{in: []byte("PAR1"), err: "not an avro object container file"},
+		{in: header(`{"type": "record", "name": "User", "fields": []}`, "null"), err: "expected com.github.wallix.triplestore.Triple records, got .User"},
+		{in: header(AvroSchema, "snappy"), err: "unsupported codec snappy"},
+		{in: append(header(AvroSchema, "null"), 0x02, 0x04, 0x0a, 0x00), err: "invalid term type 5"},
+		{in: append(header(AvroSchema, "null"), 0x02, 0x0a, 0x04, 0x02, 's', 0x00, 0x00, 0x02, 'p', 0x00), err: "subject: literal not allowed"},
+	}
+	for i, tc := range tcases {
+		_, err := NewAvroDecoder(bytes.NewReader(tc.in)).Decode()
+		if err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+		if got, want := err.Error(), tc.err; !strings.Contains(got, want) {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	if _, err := UnmarshalAvro(append(MarshalAvro(SubjPred("s", "p").Resource("o")), 0)); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,66 @@
+package triplestore
+
+import "math"
+
+// bloomFilter is a probabilistic set of strings: it never misses a string
+// added to it but may report strings that were not added (false positives)
+type bloomFilter struct {
+	bits   []uint64
+	m      uint64 // number of bits
+	hashes uint64 // number of hash functions
+}
+
+// newBloomFilter sizes a filter for n strings with the given false positive rate
+func newBloomFilter(n int, falsePositiveRate float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, hashes: k}
+}
+
+func (b *bloomFilter) add(s string) {
+	h1, h2 := bloomHashes(s)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloomFilter) mayContain(s string) bool {
+	h1, h2 := bloomHashes(s)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes derives two hashes from the 64-bit FNV-1a hash of s
+// for double hashing, without allocating
+func bloomHashes(s string) (uint64, uint64) {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	// splitmix64 finalizer for the second hash
+	h2 := h + 0x9e3779b97f4a7c15
+	h2 = (h2 ^ (h2 >> 30)) * 0xbf58476d1ce4e5b9
+	h2 = (h2 ^ (h2 >> 27)) * 0x94d049bb133111eb
+	h2 ^= h2 >> 31
+	return h, h2 | 1
+}
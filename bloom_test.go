@@ -0,0 +1,46 @@
+package triplestore
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	n := 10000
+	b := newBloomFilter(n, 0.01)
+	for i := 0; i < n; i++ {
+		b.add(fmt.Sprint("in", i))
+	}
+	for i := 0; i < n; i++ {
+		if !b.mayContain(fmt.Sprint("in", i)) {
+			t.Fatalf("missing in%d", i)
+		}
+	}
+	var falsePositives int
+	for i := 0; i < n; i++ {
+		if b.mayContain(fmt.Sprint("out", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / float64(n); rate > 0.02 {
+		t.Fatalf("got false positive rate %f, want about 0.01", rate)
+	}
+}
+
+func TestSourceWithBloomFilter(t *testing.T) {
+	s := NewSource(WithBloomFilter(0.01))
+	s.Add(
+		SubjPred("one", "two").Resource("three"),
+		BnodePred("four", "two").IntegerLiteral(42),
+	)
+	snap := s.Snapshot()
+	if snap.(*graph).bloom == nil {
+		t.Fatal("expected bloom filter")
+	}
+	if !snap.Contains(SubjPred("one", "two").Resource("three")) || !snap.Contains(BnodePred("four", "two").IntegerLiteral(42)) {
+		t.Fatal("expected triples in snapshot")
+	}
+	if snap.Contains(SubjPred("four", "two").IntegerLiteral(42)) {
+		t.Fatal("unexpected triple in snapshot")
+	}
+}
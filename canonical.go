@@ -0,0 +1,181 @@
+package triplestore
+
+import (
+	"encoding/hex"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CanonicalLiteral returns the literal with its lexical form normalized
+// according to its datatype so that equal values have equal lexical forms.
+// Ex: "+01"^^xsd:integer -> "1", "1"^^xsd:boolean -> "true", dateTime to UTC,
+// "--02-29+00:00"^^xsd:gMonthDay -> "--02-29Z".
+// Language tags are lowercased. Invalid or unknown lexical forms are returned unchanged.
+func CanonicalLiteral(l Literal) Literal {
+	out := literal{typ: l.Type(), val: l.Value(), langtag: strings.ToLower(l.Lang())}
+	if out.langtag != "" {
+		return out
+	}
+	if canonical, ok := canonicalLexicalForm(out.typ, out.val); ok {
+		out.val = canonical
+	}
+	return out
+}
+
+// xsdFloatRegexp matches the lexical space of xsd:double and xsd:float,
+// which unlike strconv.ParseFloat has no hexadecimal or "Inf" forms
+var xsdFloatRegexp = regexp.MustCompile(`^([+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?|[+-]?INF|NaN)$`)
+
+// xsdIntegerBounds are the value spaces of the bounded integer datatypes
+var xsdIntegerBounds = map[XsdType][2]int64{
+	XsdByte:          {math.MinInt8, math.MaxInt8},
+	XsdShort:         {math.MinInt16, math.MaxInt16},
+	XsdUnsignedByte:  {0, math.MaxUint8},
+	XsdUnsignedShort: {0, math.MaxUint16},
+	XsdUinteger:      {0, math.MaxUint32},
+}
+
+// canonicalLexicalForm returns the canonical form of the value, or the value
+// and false when it is not in the lexical space of the datatype. As required
+// for RDF literals, lexical forms with surrounding spaces are invalid.
+func canonicalLexicalForm(typ XsdType, val string) (string, bool) {
+	switch typ {
+	case XsdBoolean:
+		switch val {
+		case "true", "1":
+			return "true", true
+		case "false", "0":
+			return "false", true
+		}
+	case XsdInteger, XsdByte, XsdShort, XsdUinteger, XsdUnsignedByte, XsdUnsignedShort:
+		i, ok := new(big.Int).SetString(val, 10)
+		if !ok {
+			return val, false
+		}
+		if bounds, bounded := xsdIntegerBounds[typ]; bounded && (!i.IsInt64() || i.Int64() < bounds[0] || i.Int64() > bounds[1]) {
+			return val, false
+		}
+		return i.String(), true
+	case XsdDecimal:
+		return canonicalDecimal(val)
+	case XsdDouble, XsdFloat:
+		if !xsdFloatRegexp.MatchString(val) {
+			return val, false
+		}
+		bitSize := 64
+		if typ == XsdFloat {
+			bitSize = 32
+		}
+		if f, err := strconv.ParseFloat(val, bitSize); err == nil {
+			return formatXsdFloat(f, bitSize), true
+		}
+	case XsdDateTime:
+		var t time.Time
+		if err := t.UnmarshalText([]byte(val)); err == nil {
+			text, err := t.UTC().MarshalText()
+			if err == nil {
+				return string(text), true
+			}
+		}
+	case XsdDate:
+		return canonicalTemporal(val, xsdDateLayout)
+	case XsdTime:
+		return canonicalTemporal(val, xsdTimeLayout)
+	case XsdGYear, XsdGYearMonth, XsdGMonthDay, XsdGMonth, XsdGDay:
+		return canonicalGregorian(typ, val)
+	case XsdDuration:
+		if d, err := parseXsdDuration(val); err == nil {
+			return formatXsdDuration(d), true
+		}
+	case XsdHexBinary:
+		if _, err := hex.DecodeString(val); err == nil {
+			return strings.ToUpper(val), true
+		}
+	}
+	return val, false
+}
+
+// canonicalTemporal formats a xsd:date or xsd:time value with the given layout,
+// keeping its timezone, written 'Z' when it is UTC, and removing trailing zeros
+// of fractional seconds (ex: "13:20:00.500+00:00" -> "13:20:00.5Z")
+func canonicalTemporal(val, layout string) (string, bool) {
+	if t, err := time.Parse(layout+xsdTZLayout, val); err == nil {
+		return t.Format(layout + xsdTZLayout), true
+	}
+	if t, err := time.Parse(layout, val); err == nil {
+		return t.Format(layout), true
+	}
+	return val, false
+}
+
+// canonicalDecimal removes sign, leading and trailing zeros
+// keeping at least one digit on each side of the decimal point (ex: "+01.50" -> "1.5")
+func canonicalDecimal(val string) (string, bool) {
+	var neg bool
+	switch {
+	case strings.HasPrefix(val, "-"):
+		neg = true
+		val = val[1:]
+	case strings.HasPrefix(val, "+"):
+		val = val[1:]
+	}
+	intPart, fracPart := val, ""
+	if i := strings.IndexByte(val, '.'); i >= 0 {
+		intPart, fracPart = val[:i], val[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return val, false
+	}
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return val, false
+		}
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	fracPart = strings.TrimRight(fracPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if fracPart == "" {
+		fracPart = "0"
+	}
+	if intPart == "0" && fracPart == "0" {
+		neg = false
+	}
+	canonical := intPart + "." + fracPart
+	if neg {
+		canonical = "-" + canonical
+	}
+	return canonical, true
+}
+
+func canonicalTriple(t Triple) Triple {
+	lit, ok := t.Object().Literal()
+	if !ok {
+		return t
+	}
+	tri := t.(*triple)
+	return makeTriple(tri.sub, tri.isSubBnode, tri.pred, object{isLit: true, lit: CanonicalLiteral(lit).(literal)})
+}
+
+type canonicalEncoder struct {
+	enc Encoder
+}
+
+// NewCanonicalEncoder wraps an encoder so that literals
+// are canonicalized (see CanonicalLiteral) before being encoded
+func NewCanonicalEncoder(enc Encoder) Encoder {
+	return &canonicalEncoder{enc: enc}
+}
+
+func (c *canonicalEncoder) Encode(tris ...Triple) error {
+	canonicals := make([]Triple, len(tris))
+	for i, t := range tris {
+		canonicals[i] = canonicalTriple(t)
+	}
+	return c.enc.Encode(canonicals...)
+}
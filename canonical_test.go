@@ -0,0 +1,89 @@
+package triplestore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalLiteral(t *testing.T) {
+	tcases := []struct {
+		typ      XsdType
+		val, exp string
+	}{
+		{XsdInteger, "+01", "1"},
+		{XsdInteger, "-007", "-7"},
+		{XsdInteger, "123456789012345678901234567890", "123456789012345678901234567890"},
+		{XsdUnsignedShort, "0042", "42"},
+		{XsdBoolean, "1", "true"},
+		{XsdBoolean, "0", "false"},
+		{XsdDecimal, "+01.50", "1.5"},
+		{XsdDecimal, "-.5", "-0.5"},
+		{XsdDecimal, "12", "12.0"},
+		{XsdDecimal, "-0.00", "0.0"},
+		{XsdDouble, "100", "1.0E2"},
+		{XsdDouble, "+INF", "INF"},
+		{XsdFloat, "0.5", "5.0E-1"},
+		{XsdDateTime, "2017-11-21T13:20:00+02:00", "2017-11-21T11:20:00Z"},
+		{XsdDuration, "PT90M", "PT1H30M"},
+		{XsdHexBinary, "cafe", "CAFE"},
+		{XsdDate, "2017-11-21+00:00", "2017-11-21Z"},
+		{XsdDate, "2017-11-21", "2017-11-21"},
+		{XsdTime, "13:20:00.500Z", "13:20:00.5Z"},
+		{XsdTime, "13:20:00.000+02:00", "13:20:00+02:00"},
+		{XsdGYearMonth, "2017-03-00:00", "2017-03Z"},
+		{XsdGDay, "---05+00:00", "---05Z"},
+		{XsdGMonthDay, "--02-29", "--02-29"},
+		{XsdString, " any ", " any "},
+
+		// invalid lexical forms left unchanged
+		{XsdInteger, "abc", "abc"},
+		{XsdBoolean, "yes", "yes"},
+		{XsdBoolean, "TRUE", "TRUE"},
+		{XsdBoolean, " true", " true"},
+		{XsdInteger, " 1", " 1"},
+		{XsdInteger, "+-1", "+-1"},
+		{XsdByte, "300", "300"},
+		{XsdUnsignedShort, "-1", "-1"},
+		{XsdDouble, "0x1p-2", "0x1p-2"},
+		{XsdDouble, "inf", "inf"},
+		{XsdDate, "2017-13-01", "2017-13-01"},
+		{XsdTime, "25:00:00", "25:00:00"},
+		{XsdGMonth, "--13", "--13"},
+		{XsdHexBinary, " 0A", " 0A"},
+		{XsdDecimal, "1.2.3", "1.2.3"},
+		{XsdDateTime, "yesterday", "yesterday"},
+		{XsdHexBinary, "cafez", "cafez"},
+		{XsdHexBinary, "caf", "caf"},
+	}
+
+	for _, tc := range tcases {
+		lit := CanonicalLiteral(literal{typ: tc.typ, val: tc.val})
+		if got, want := lit.Value(), tc.exp; got != want {
+			t.Fatalf("%s %s: got %s, want %s", tc.typ, tc.val, got, want)
+		}
+		if got, want := lit.Type(), tc.typ; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	lit := CanonicalLiteral(literal{typ: XsdString, val: "hello", langtag: "EN-us"})
+	if got, want := lit.Lang(), "en-us"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestCanonicalEncoder(t *testing.T) {
+	tris := []Triple{
+		SubjPred("me", "age").Object(object{isLit: true, lit: literal{typ: XsdInteger, val: "+032"}}),
+		SubjPred("me", "knows").Resource("you"),
+	}
+
+	var buf bytes.Buffer
+	if err := NewCanonicalEncoder(NewLenientNTEncoder(&buf)).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	exp := "<me> <age> \"32\"^^<xsd:integer> .\n<me> <knows> <you> .\n"
+	if got, want := buf.String(), exp; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
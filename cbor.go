@@ -0,0 +1,185 @@
+package triplestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Triples are encoded in CBOR (RFC 8949) as a sequence of maps (RFC 8742)
+// shaped as their JSON marshaling (see appendTermMapTriple).
+
+// CBOR major types
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// cborMaxDepth bounds the nesting of skipped items
+const cborMaxDepth = 32
+
+type cborEncoder struct {
+	w io.Writer
+}
+
+// NewCBOREncoder returns an encoder writing triples as a sequence of CBOR maps
+func NewCBOREncoder(w io.Writer) Encoder {
+	return &cborEncoder{w: w}
+}
+
+func (enc *cborEncoder) Encode(tris ...Triple) error {
+	var buf []byte
+	for _, t := range tris {
+		buf = appendTermMapTriple(buf[:0], t.(*triple), appendCBORMapHeader, appendCBORString)
+		if _, err := enc.w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= 0xff:
+		return append(buf, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major<<5|27), n)
+	}
+}
+
+func appendCBORMapHeader(buf []byte, n int) []byte {
+	return appendCBORHead(buf, cborMap, uint64(n))
+}
+
+func appendCBORString(buf []byte, s string) []byte {
+	buf = appendCBORHead(buf, cborText, uint64(len(s)))
+	return append(buf, s...)
+}
+
+type cborDecoder struct {
+	r    io.Reader
+	opts decoderOptions
+}
+
+// NewCBORDecoder returns a decoder reading a sequence of CBOR maps as written
+// by NewCBOREncoder. Unknown map entries are ignored.
+// Indefinite length items are not supported.
+func NewCBORDecoder(r io.Reader, opts ...DecoderOption) Decoder {
+	return &cborDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
+func (dec *cborDecoder) Decode() ([]Triple, error) {
+	var out []Triple
+	tracker := newProgressTracker(dec.opts.progress)
+	r := &cborReader{r: bufio.NewReader(tracker.reader(dec.r)), maxTermSize: dec.opts.maxTermSize}
+	for {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			tracker.done()
+			return out, nil
+		}
+		tri, err := readTermMapTriple(r)
+		if err != nil {
+			return out, fmt.Errorf("cbor: triple %d: %s", len(out)+1, err)
+		}
+		out = append(out, tri)
+		tracker.addTriple()
+	}
+}
+
+type cborReader struct {
+	r           *bufio.Reader
+	maxTermSize int
+}
+
+// readHead returns the major type and the argument of the next item
+func (c *cborReader) readHead() (byte, uint64, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	major, info := b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		var buf [8]byte
+		size := 1 << (info - 24)
+		if _, err := io.ReadFull(c.r, buf[8-size:]); err != nil {
+			return 0, 0, err
+		}
+		return major, binary.BigEndian.Uint64(buf[:]), nil
+	case info == 31:
+		return 0, 0, errors.New("indefinite length items not supported")
+	}
+	return 0, 0, fmt.Errorf("invalid additional information %d", info)
+}
+
+func (c *cborReader) readMapHeader() (int, error) {
+	major, n, err := c.readHead()
+	if err != nil {
+		return 0, err
+	}
+	if major != cborMap {
+		return 0, fmt.Errorf("expected map, got major type %d", major)
+	}
+	return int(n), nil
+}
+
+func (c *cborReader) readString() (string, error) {
+	major, l, err := c.readHead()
+	if err != nil {
+		return "", err
+	}
+	if major != cborText {
+		return "", fmt.Errorf("expected text string, got major type %d", major)
+	}
+	if c.maxTermSize > 0 && l > uint64(c.maxTermSize) {
+		return "", fmt.Errorf("term of length %d bytes exceeds maximum term size of %d bytes", l, c.maxTermSize)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(c.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (c *cborReader) skip(depth int) error {
+	if depth > cborMaxDepth {
+		return errors.New("items nested too deeply")
+	}
+	major, n, err := c.readHead()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		_, err := c.r.Discard(int(n))
+		return err
+	case cborArray, cborMap:
+		if major == cborMap {
+			n *= 2
+		}
+		for i := uint64(0); i < n; i++ {
+			if err := c.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		return c.skip(depth + 1)
+	}
+	return nil
+}
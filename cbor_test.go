@@ -0,0 +1,89 @@
+package triplestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCBORCodec(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").Resource("three"),
+		BnodePred("one", "two").Bnode("three"),
+		SubjPred("one", "two").StringLiteral(""),
+		SubjPred("one", "two").StringLiteral(strings.Repeat("long ", 100)),
+		SubjPred("one", "two").StringLiteral(strings.Repeat("longer ", 10000)),
+		SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+		SubjPred("one", "two").IntegerLiteral(42),
+	}
+
+	var buf bytes.Buffer
+	if err := NewCBOREncoder(&buf).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewCBORDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCBORWireFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewCBOREncoder(&buf).Encode(SubjPred("s", "p").Resource("o")); err != nil {
+		t.Fatal(err)
+	}
+	term := func(value string) string {
+		return "\xa2\x64type\x63uri\x65value\x61" + value
+	}
+	expected := "\xa3\x67subject" + term("s") + "\x69predicate" + term("p") + "\x66object" + term("o")
+	if got, want := buf.String(), expected; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// unknown entries of any type are skipped
+	withUnknown := "\xa5" +
+		"\x65extra\x84\xf6\xf5\x19\x01\x02\x3a\x00\x01\x00\x00" +
+		"\x67subject\xa3\x64type\x65bnode\x65value\x61s\x62id\xfb\x00\x00\x00\x00\x00\x00\x00\x00" +
+		"\x69predicate" + term("p") +
+		"\x66object\xa3\x64type\x67literal\x65value\x6242\x68xml:lang\x62en" +
+		"\x64meta\xa1\x63bin\xc2\x42\x00\x01"
+	decoded, err := NewCBORDecoder(strings.NewReader(withUnknown)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := TripleKey(decoded[0]), `_:s <p> "42"@en`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestCBORDecodeErrors(t *testing.T) {
+	tcases := []struct {
+		in, err string
+	}{
+		{in: "\x63one", err: "expected map, got major type 3"},
+		{in: "\xa1\x01", err: "expected text string, got major type 0"},
+		{in: "\xbf\xff", err: "indefinite length items not supported"},
+		{in: "\xa1\x67subject\xa2\x64type", err: "EOF"},
+		{in: "\xa1\x67subject\xa2\x64type\x63uri\x65value\x61s", err: "requires a subject, a predicate and an object"},
+		{in: "\xa1\x65extra\x1c", err: "invalid additional information 28"},
+		{in: "\xa3\x67subject\xa2\x64type\x67literal\x65value\x61s\x69predicate\xa2\x64type\x63uri\x65value\x61p\x66object\xa2\x64type\x63uri\x65value\x61o", err: "subject: literal not allowed"},
+	}
+	for i, tc := range tcases {
+		_, err := NewCBORDecoder(strings.NewReader(tc.in)).Decode()
+		if err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+		if got, want := err.Error(), tc.err; !strings.Contains(got, want) {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	NewCBOREncoder(&buf).Encode(SubjPred("one", "two").Resource(strings.Repeat("a", 1000)))
+	if _, err := NewCBORDecoder(&buf, WithMaxTermSize(10)).Decode(); err == nil {
+		t.Fatal("expected error")
+	}
+}
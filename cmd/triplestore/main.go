@@ -1,9 +1,12 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
@@ -18,28 +21,42 @@ var (
 	filesFlag                   arrayFlags
 	prefixesFlag                arrayFlags
 	useRdfPrefixesFlag          bool
+	gzipFlag                    bool
+	sortFlag                    bool
 )
 
 func init() {
-	flag.StringVar(&outFormatFlag, "out", "ntriples", "output format (ntriples, bin)")
-	flag.StringVar(&inFormatFlag, "in", "bin", "input format (ntriples, bin)")
-	flag.Var(&filesFlag, "files", "input file paths")
+	flag.StringVar(&outFormatFlag, "out", "ntriples", "output format (ntriples, bin, turtle, jsonld, dot or a media type)")
+	flag.StringVar(&inFormatFlag, "in", "bin", "input format (ntriples, bin, turtle, jsonld, auto or a media type)")
+	flag.Var(&filesFlag, "files", "input file paths (gzipped or not). Read from stdin when omitted")
 	flag.BoolVar(&useRdfPrefixesFlag, "rdf-prefixes", false, "use default RDF prefixes (rdf, rdfs, xsd)")
 	flag.Var(&prefixesFlag, "prefix", "RDF custom prefixes (format: \"prefix:http://my.uri\"")
 	flag.StringVar(&baseFlag, "base", "", "RDF custom base prefix")
 	flag.StringVar(&dotPredicateFlag, "predicate", "", "Predicate on which to build a dot graph file")
+	flag.BoolVar(&gzipFlag, "gzip", false, "gzip output")
+	flag.BoolVar(&sortFlag, "sort", false, "sort output triples by subject, predicate and object")
 }
 
 func main() {
 	flag.Parse()
-	if len(filesFlag) == 0 {
-		log.Fatal("need at list an argument `-files INPUT_FILE`")
-	}
 	context, err := buildContext(useRdfPrefixesFlag, prefixesFlag, baseFlag)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := convert(filesFlag, outFormatFlag, context); err != nil {
+
+	var out io.Writer = os.Stdout
+	if gzipFlag {
+		gz := gzip.NewWriter(os.Stdout)
+		defer gz.Close()
+		out = gz
+	}
+
+	if len(filesFlag) == 0 {
+		err = streamConvert(os.Stdin, out, context)
+	} else {
+		err = convert(filesFlag, out, context)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -62,51 +79,204 @@ func buildContext(useRdfPrefixes bool, prefixes []string, base string) (*tstore.
 	return context, nil
 }
 
-func convert(inFilePaths []string, outFormatFlag string, context *tstore.Context) error {
+func normalizeFormat(format string) string {
+	switch format {
+	case "nt":
+		return "ntriples"
+	case "binary":
+		return "bin"
+	case "ttl":
+		return "turtle"
+	case "json-ld":
+		return "jsonld"
+	default:
+		return format
+	}
+}
+
+// formatMediaType returns the media type of the format, other formats being media types
+func formatMediaType(format string) string {
+	switch format {
+	case "turtle":
+		return "text/turtle"
+	case "jsonld":
+		return "application/ld+json"
+	default:
+		return format
+	}
+}
+
+func newDecoder(r io.Reader) (tstore.Decoder, error) {
+	switch format := normalizeFormat(inFormatFlag); format {
+	case "auto":
+		return tstore.NewAutoDecoder(r), nil
+	case "bin":
+		return tstore.NewBinaryDecoder(r), nil
+	case "ntriples":
+		return tstore.NewLenientNTDecoder(r), nil
+	default:
+		dec, err := tstore.DecoderForContentType(r, formatMediaType(format))
+		if err != nil {
+			return nil, fmt.Errorf("unknown in flag '%s': expect 'ntriples', 'bin', 'turtle', 'jsonld', 'auto' or a media type", inFormatFlag)
+		}
+		return dec, nil
+	}
+}
+
+// maybeGunzip transparently decompresses gzipped input
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	isGzip, r := tstore.IsGzipFormat(r)
+	if isGzip {
+		return gzip.NewReader(r)
+	}
+	return r, nil
+}
+
+func convert(inFilePaths []string, out io.Writer, context *tstore.Context) error {
 	var inFiles []io.Reader
 	for _, inFilePath := range inFilePaths {
 		in, err := os.Open(inFilePath)
 		if err != nil {
 			return fmt.Errorf("open input file '%s': %s", inFilePath, err)
 		}
+		defer in.Close()
 		inFiles = append(inFiles, in)
 	}
 
-	var inDecoder func(io.Reader) tstore.Decoder
-	switch inFormatFlag {
-	case "bin":
-		inDecoder = tstore.NewBinaryDecoder
-	case "ntriples":
-		inDecoder = tstore.NewLenientNTDecoder
-	default:
-		return fmt.Errorf("unknown in flag '%s': expect 'ntriples' or 'bin'", outFormatFlag)
+	if _, err := newDecoder(nil); err != nil {
+		return err
+	}
+	inDecoder := func(r io.Reader) tstore.Decoder {
+		dec, _ := newDecoder(r)
+		return dec
 	}
 
-	triples, err := tstore.NewDatasetDecoder(inDecoder, inFiles...).Decode()
+	gzipDecoder := func(r io.Reader) tstore.Decoder {
+		return tstore.NewGzipDecoder(inDecoder, r)
+	}
+	triples, err := tstore.NewDatasetDecoder(gzipDecoder, inFiles...).Decode()
 	if err != nil {
 		return err
 	}
 
+	encoder, err := newEncoder(out, context)
+	if err != nil {
+		return err
+	}
+
+	if err := encoder.Encode(triples...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func newEncoder(out io.Writer, context *tstore.Context) (tstore.Encoder, error) {
 	var encoder tstore.Encoder
-	switch outFormatFlag {
+	switch format := normalizeFormat(outFormatFlag); format {
 	case "ntriples":
-		encoder = tstore.NewLenientNTEncoderWithContext(os.Stdout, context)
+		encoder = tstore.NewLenientNTEncoderWithContext(out, context)
 	case "bin":
-		encoder = tstore.NewBinaryEncoder(os.Stdout)
+		encoder = tstore.NewBinaryEncoder(out)
+	case "turtle":
+		encoder = tstore.NewTurtleEncoder(out, tstore.WithPrefixes(context.Prefixes), tstore.WithBase(context.Base))
+	case "jsonld":
+		encoder = tstore.NewJSONLDEncoder(out, tstore.WithPrefixes(context.Prefixes), tstore.WithBase(context.Base))
 	case "dot":
 		if dotPredicateFlag == "" {
-			return fmt.Errorf("missing -predicate param to output to dot format")
+			return nil, fmt.Errorf("missing -predicate param to output to dot format")
 		}
-		encoder = tstore.NewDotGraphEncoder(os.Stdout, dotPredicateFlag)
+		encoder = tstore.NewDotGraphEncoder(out, dotPredicateFlag)
 	default:
-		return fmt.Errorf("unknown out flag '%s': expect 'ntriples, 'dot' or 'bin'", outFormatFlag)
+		var err error
+		if encoder, err = tstore.EncoderForContentType(out, formatMediaType(format)); err != nil {
+			return nil, fmt.Errorf("unknown out flag '%s': expect 'ntriples', 'bin', 'turtle', 'jsonld', 'dot' or a media type", outFormatFlag)
+		}
+	}
+	if sortFlag {
+		encoder = tstore.NewSortedEncoder(encoder)
 	}
+	return encoder, nil
+}
 
-	if err := encoder.Encode(triples...); err != nil {
+// streamConvert converts triples as they are decoded without loading them all in memory.
+// Formats or options needing all the triples (i.e. turtle, dot, sort) fallback on a full decoding
+func streamConvert(in io.Reader, out io.Writer, rdfContext *tstore.Context) error {
+	r, err := maybeGunzip(in)
+	if err != nil {
+		return fmt.Errorf("gunzip input: %s", err)
+	}
+
+	var decoder tstore.StreamDecoder
+	switch normalizeFormat(inFormatFlag) {
+	case "bin":
+		decoder = tstore.NewBinaryStreamDecoder(ioutil.NopCloser(r))
+	case "ntriples":
+		decoder = tstore.NewLenientNTStreamDecoder(r)
+	default:
+		dec, err := newDecoder(r)
+		if err != nil {
+			return err
+		}
+		triples, err := dec.Decode()
+		if err != nil {
+			return err
+		}
+		all, err := newEncoder(out, rdfContext)
+		if err != nil {
+			return err
+		}
+		return all.Encode(triples...)
+	}
+
+	var encoder tstore.StreamEncoder
+	switch normalizeFormat(outFormatFlag) {
+	case "ntriples":
+		encoder = tstore.NewLenientNTStreamEncoderWithContext(out, rdfContext)
+	case "bin":
+		encoder = tstore.NewBinaryStreamEncoder(out)
+	}
+	if encoder == nil || sortFlag {
+		all, err := newEncoder(out, rdfContext)
+		if err != nil {
+			return err
+		}
+		var triples []tstore.Triple
+		for res := range decoder.StreamDecode(context.Background()) {
+			if res.Err != nil {
+				return res.Err
+			}
+			triples = append(triples, res.Tri)
+		}
+		return all.Encode(triples...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	triples := make(chan tstore.Triple)
+	decodeErr := make(chan error, 1)
+	go func() {
+		defer close(triples)
+		for res := range decoder.StreamDecode(ctx) {
+			if res.Err != nil {
+				decodeErr <- res.Err
+				return
+			}
+			triples <- res.Tri
+		}
+	}()
+
+	if err := encoder.StreamEncode(ctx, triples); err != nil {
 		return err
 	}
 
-	return nil
+	select {
+	case err := <-decodeErr:
+		return err
+	default:
+		return nil
+	}
 }
 
 type arrayFlags []string
@@ -2,6 +2,9 @@ package triplestore
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -50,6 +53,86 @@ func TestLooselyDetectNTFormatReturningFullReader(t *testing.T) {
 	}
 }
 
+func TestAutoDecoderDetectsFormat(t *testing.T) {
+	triples := []Triple{
+		SubjPred("http://example.org/one", "http://example.org/two").Resource("http://example.org/three"),
+		BnodePred("four", "http://example.org/five").IntegerLiteral(6),
+	}
+	encoded := func(contentType string) string {
+		var buff bytes.Buffer
+		enc, err := EncoderForContentType(&buff, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(triples...); err != nil {
+			t.Fatal(err)
+		}
+		return buff.String()
+	}
+	gzipped := func(s string) string {
+		var buff bytes.Buffer
+		gz := gzip.NewWriter(&buff)
+		gz.Write([]byte(s))
+		gz.Close()
+		return buff.String()
+	}
+	jsonTriples, err := json.Marshal(triples)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcases := []struct {
+		name, in string
+	}{
+		{name: "binary", in: encoded("application/octet-stream")},
+		{name: "ntriples", in: encoded("application/n-triples")},
+		{name: "protobuf", in: encoded("application/x-protobuf")},
+		{name: "msgpack", in: encoded("application/x-msgpack")},
+		{name: "cbor", in: encoded("application/cbor")},
+		{name: "avro", in: encoded("avro/binary")},
+		{name: "json", in: string(jsonTriples)},
+		{name: "json-ld", in: encoded("application/ld+json")},
+		{name: "encoded turtle", in: encoded("text/turtle")},
+		{name: "gzipped binary", in: gzipped(encoded("application/octet-stream"))},
+		{name: "gzipped ntriples", in: gzipped(encoded("application/n-triples"))},
+		{name: "commented ntriples", in: "# a comment\n\n" + encoded("application/n-triples")},
+		{name: "turtle", in: "@prefix ex: <http://example.org/> .\nex:one ex:two ex:three .\n_:four ex:five 6 ."},
+		{name: "sparql style turtle", in: "PREFIX ex: <http://example.org/>\nex:one ex:two ex:three .\n_:four ex:five 6 ."},
+		{name: "turtle starting with an IRI", in: "<http://example.org/one> <http://example.org/two> <http://example.org/three> .\n_:four <http://example.org/five> 6 ."},
+		{name: "turtle starting with a blank node", in: "_:four <http://example.org/five> 6 .\n<http://example.org/one> <http://example.org/two> <http://example.org/three> ."},
+	}
+	for _, tc := range tcases {
+		tris, err := NewAutoDecoder(strings.NewReader(tc.in)).Decode()
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if got, want := Triples(tris), Triples(triples); !got.Equal(want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, want)
+		}
+	}
+
+	tris, err := NewAutoDecoder(strings.NewReader("")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(tris), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	tris, err = NewAutoDecoder(strings.NewReader("<a> <p> <b> ; <q> \"x\" .\n<a> a <B> .")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(tris), Triples([]Triple{SubjPredRes("a", "p", "b"), SubjPred("a", "q").StringLiteral("x"), SubjPredRes("a", rdfNamespace+"type", "B")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	_, err = NewAutoDecoder(strings.NewReader(`<?xml version="1.0"?><rdf:RDF></rdf:RDF>`)).Decode()
+	if err == nil || !strings.Contains(err.Error(), "RDF/XML is not supported") {
+		t.Fatalf("got %v, want RDF/XML error", err)
+	}
+}
+
 func TestEncodeAndDecodeAllTripleTypes(t *testing.T) {
 	tcases := []struct {
 		in Triple
@@ -213,6 +296,34 @@ func TestDecodeDataset(t *testing.T) {
 		t.Fatalf("decoded dataset should contains %v", two)
 	}
 }
+func TestDecodeDatasetWithCancelledContext(t *testing.T) {
+	hung, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	dec := NewDatasetDecoderContext(ctx, NewLenientNTDecoder, strings.NewReader("<one> <two> <three> ."), hung)
+	if _, err := dec.Decode(); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	var decoded int
+	countingDecoder := func(r io.Reader) Decoder {
+		decoded++
+		return NewLenientNTDecoder(r)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	dec = NewDatasetDecoderContext(cancelled, countingDecoder, strings.NewReader("<one> <two> <three> ."))
+	if _, err := dec.Decode(); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if decoded != 0 {
+		t.Fatalf("got %d decoded readers, want none", decoded)
+	}
+}
+
 func TestEncodeDecodeSomeNTriplesSampleFiles(t *testing.T) {
 	path := filepath.Join("testdata", "*.nt")
 	filenames, _ := filepath.Glob(path)
@@ -313,6 +424,66 @@ third line`),
 			t.Fatalf("got \n%s\nwant \n%s\n", got, want)
 		}
 	})
+
+	t.Run("without allocations", func(t *testing.T) {
+		triples := []Triple{
+			SubjPred("one", "two").StringLiteral("first\nsecond"),
+			SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+			SubjPred("one", "two").IntegerLiteral(42),
+			SubjPred("one", "two").Resource("three"),
+			BnodePred("one", "two").Bnode("three"),
+		}
+		var buff bytes.Buffer
+		buff.Grow(1024)
+		allocs := testing.AllocsPerRun(100, func() {
+			buff.Reset()
+			for _, tri := range triples {
+				encodeNTriple(tri, nil, &buff)
+			}
+		})
+		if allocs != 0 {
+			t.Fatalf("got %v allocations, want none", allocs)
+		}
+	})
+}
+
+func TestEncodeDot(t *testing.T) {
+	tris := []Triple{
+		SubjPredRes("http://xmlns.com/foaf/0.1/me", "http://xmlns.com/foaf/0.1/knows", "you"),
+		SubjPred("you", "name").StringLiteral("a \"quoted\" long name"),
+		SubjPred("you", "owns").Bnode("b1"),
+	}
+
+	tcases := []struct {
+		opts *DotOptions
+		exp  string
+	}{
+		{nil, `digraph {
+"http://xmlns.com/foaf/0.1/me" -> "you" [label="http://xmlns.com/foaf/0.1/knows"];
+lit1 [label="a \"quoted\" long name", shape=box];
+"you" -> lit1 [label="name"];
+"you" -> "_:b1" [label="owns"];
+}
+`},
+		{&DotOptions{MaxLabelLength: 6, Context: &Context{Prefixes: map[string]string{"foaf": "http://xmlns.com/foaf/0.1/"}}}, `digraph {
+"http://xmlns.com/foaf/0.1/me" [label="foaf:m..."];
+"http://xmlns.com/foaf/0.1/me" -> "you" [label="foaf:k..."];
+lit1 [label="a \"quo...", shape=box];
+"you" -> lit1 [label="name"];
+"you" -> "_:b1" [label="owns"];
+}
+`},
+	}
+
+	for i, tc := range tcases {
+		var buff bytes.Buffer
+		if err := NewDotEncoder(&buff, tc.opts).Encode(tris...); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := buff.String(), tc.exp; got != want {
+			t.Fatalf("case %d: got\n%s\nwant\n%s", i, got, want)
+		}
+	}
 }
 
 func TestEncodeDotGraph(t *testing.T) {
@@ -368,3 +539,65 @@ func compareMultiline(t *testing.T, actual, expected []byte) {
 		}
 	}
 }
+
+func TestEncodeTo(t *testing.T) {
+	triples := []Triple{
+		SubjPred("one", "two").StringLiteral("first\nsecond"),
+		SubjPred("one", "two").IntegerLiteral(42),
+		BnodePred("one", "two").Bnode("three"),
+	}
+
+	tcases := []struct {
+		enc    func(io.Writer) Encoder
+		dec    func(io.Reader) Decoder
+		prefix string
+	}{
+		{enc: NewBinaryEncoder, dec: NewBinaryDecoder, prefix: "\x00\x01"},
+		{enc: NewLenientNTEncoder, dec: NewLenientNTDecoder, prefix: "# dump\n"},
+	}
+	for i, tc := range tcases {
+		var expected bytes.Buffer
+		if err := tc.enc(&expected).Encode(triples...); err != nil {
+			t.Fatal(err)
+		}
+
+		buf, err := tc.enc(nil).(BufferEncoder).EncodeTo([]byte(tc.prefix), triples...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(buf), tc.prefix+expected.String(); got != want {
+			t.Fatalf("case %d: got %q, want %q", i+1, got, want)
+		}
+
+		decoded, err := tc.dec(bytes.NewReader(buf[len(tc.prefix):])).Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := Triples(decoded), Triples(triples); !got.Equal(want) {
+			t.Fatalf("case %d: got %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestParallelEncoder(t *testing.T) {
+	var triples []Triple
+	for i := 0; i < 103; i++ {
+		triples = append(triples, SubjPred(fmt.Sprint(i), "digit").IntegerLiteral(i))
+	}
+
+	for _, newEnc := range []func(io.Writer) Encoder{NewBinaryEncoder, NewLenientNTEncoder} {
+		var expected bytes.Buffer
+		if err := newEnc(&expected).Encode(triples...); err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{0, 1, 4, 200} {
+			var buff bytes.Buffer
+			if err := NewParallelEncoder(&buff, workers, newEnc).Encode(triples...); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := buff.String(), expected.String(); got != want {
+				t.Fatalf("%d workers: got %q, want %q", workers, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package triplestore
+
+import (
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	termKindResource = uint8(iota)
+	termKindBnode
+	termKindLiteral
+)
+
+// columnarGraph is a read-only graph storing its terms and triples in column
+// arrays rather than in maps of structs, so that scans go through contiguous memory.
+//
+// Triples are sorted by subject, predicate and object IDs. Two permutations of
+// their positions, sorted by predicate/object/subject and object/subject/predicate,
+// serve the other access patterns. All lookups are binary searches.
+type columnarGraph struct {
+	// terms dictionary, one column per term field
+	ids    map[string]termID
+	kinds  []uint8
+	values []string
+	types  []XsdType
+	langs  []string
+
+	subs, preds, objs []termID
+	pos, osp          []uint32
+
+	subjects, predicates, objects int
+}
+
+// NewColumnarGraph converts the graph (ex: a source snapshot) to a read-only
+// columnar representation. It uses less than half the memory of snapshots for similar
+// scans (ex: all triples with a given predicate), while counts and point lookups
+// are slower as they rely on binary searches rather than hash maps.
+func NewColumnarGraph(g RDFGraph) RDFGraph {
+	tris := g.Triples()
+	c := &columnarGraph{ids: make(map[string]termID)}
+	spo := make([]idTriple, len(tris))
+	for i, t := range tris {
+		tri := t.(*triple)
+		spo[i] = idTriple{s: c.intern(subjectObject(tri)), p: c.intern(object{resource: tri.pred}), o: c.intern(tri.obj)}
+	}
+	sort.Slice(spo, func(i, j int) bool { return spo[i].less(spo[j]) })
+
+	c.subs, c.preds, c.objs = make([]termID, len(spo)), make([]termID, len(spo)), make([]termID, len(spo))
+	c.pos, c.osp = make([]uint32, len(spo)), make([]uint32, len(spo))
+	distinctS, distinctP, distinctO := make(map[termID]bool), make(map[termID]bool), make(map[termID]bool)
+	for i, t := range spo {
+		c.subs[i], c.preds[i], c.objs[i] = t.s, t.p, t.o
+		c.pos[i], c.osp[i] = uint32(i), uint32(i)
+		distinctS[t.s], distinctP[t.p], distinctO[t.o] = true, true, true
+	}
+	c.subjects, c.predicates, c.objects = len(distinctS), len(distinctP), len(distinctO)
+	sort.Slice(c.pos, func(i, j int) bool { return c.posKey(c.pos[i]).less(c.posKey(c.pos[j])) })
+	sort.Slice(c.osp, func(i, j int) bool { return c.ospKey(c.osp[i]).less(c.ospKey(c.osp[j])) })
+	return c
+}
+
+func (t idTriple) less(other idTriple) bool {
+	if t.s != other.s {
+		return t.s < other.s
+	}
+	if t.p != other.p {
+		return t.p < other.p
+	}
+	return t.o < other.o
+}
+
+func (c *columnarGraph) spoKey(i uint32) idTriple {
+	return idTriple{c.subs[i], c.preds[i], c.objs[i]}
+}
+
+func (c *columnarGraph) posKey(i uint32) idTriple {
+	return idTriple{c.preds[i], c.objs[i], c.subs[i]}
+}
+
+func (c *columnarGraph) ospKey(i uint32) idTriple {
+	return idTriple{c.objs[i], c.subs[i], c.preds[i]}
+}
+
+func (c *columnarGraph) intern(o object) termID {
+	k := o.key()
+	if id, ok := c.ids[k]; ok {
+		return id
+	}
+	id := termID(len(c.kinds))
+	c.ids[k] = id
+	kind, val := termKindResource, o.resource
+	switch {
+	case o.isLit:
+		kind, val = termKindLiteral, o.lit.val
+	case o.isBnode:
+		kind, val = termKindBnode, o.bnode
+	}
+	c.kinds = append(c.kinds, kind)
+	c.values = append(c.values, val)
+	c.types = append(c.types, o.lit.typ)
+	c.langs = append(c.langs, o.lit.langtag)
+	return id
+}
+
+func (c *columnarGraph) term(id termID) object {
+	switch c.kinds[id] {
+	case termKindLiteral:
+		return object{isLit: true, lit: literal{typ: c.types[id], val: c.values[id], langtag: c.langs[id]}}
+	case termKindBnode:
+		return object{isBnode: true, bnode: c.values[id]}
+	default:
+		return object{resource: c.values[id]}
+	}
+}
+
+func (c *columnarGraph) lookup(o object) (termID, bool) {
+	id, ok := c.ids[o.key()]
+	return id, ok
+}
+
+// subjectIDs returns the IDs of the resource and of the blank node named s
+func (c *columnarGraph) subjectIDs(s string) (out []termID) {
+	for _, o := range []object{{resource: s}, {isBnode: true, bnode: s}} {
+		if id, ok := c.lookup(o); ok {
+			out = append(out, id)
+		}
+	}
+	return
+}
+
+func (c *columnarGraph) triple(i uint32) *triple {
+	sub := c.term(c.subs[i])
+	return makeTriple(nodeID(sub), sub.isBnode, c.values[c.preds[i]], c.term(c.objs[i]))
+}
+
+// span returns the range [from, to) of the n sorted positions whose keys start with the given IDs
+func span(n int, key func(int) idTriple, ids ...termID) (int, int) {
+	cmp := func(i int) int {
+		k := key(i)
+		fields := [3]termID{k.s, k.p, k.o}
+		for j, id := range ids {
+			switch {
+			case fields[j] < id:
+				return -1
+			case fields[j] > id:
+				return 1
+			}
+		}
+		return 0
+	}
+	from := sort.Search(n, func(i int) bool { return cmp(i) >= 0 })
+	to := sort.Search(n, func(i int) bool { return cmp(i) > 0 })
+	return from, to
+}
+
+func (c *columnarGraph) spoSpan(ids ...termID) (int, int) {
+	return span(len(c.subs), func(i int) idTriple { return c.spoKey(uint32(i)) }, ids...)
+}
+
+func (c *columnarGraph) posSpan(ids ...termID) (int, int) {
+	return span(len(c.pos), func(i int) idTriple { return c.posKey(c.pos[i]) }, ids...)
+}
+
+func (c *columnarGraph) ospSpan(ids ...termID) (int, int) {
+	return span(len(c.osp), func(i int) idTriple { return c.ospKey(c.osp[i]) }, ids...)
+}
+
+func (c *columnarGraph) materialize(perm []uint32, from, to int, out []Triple) []Triple {
+	if out == nil && to > from {
+		out = make([]Triple, 0, to-from)
+	}
+	for i := from; i < to; i++ {
+		if perm == nil {
+			out = append(out, c.triple(uint32(i)))
+		} else {
+			out = append(out, c.triple(perm[i]))
+		}
+	}
+	return out
+}
+
+func (c *columnarGraph) Contains(t Triple) bool {
+	tri := t.(*triple)
+	s, sOk := c.lookup(subjectObject(tri))
+	p, pOk := c.lookup(object{resource: tri.pred})
+	o, oOk := c.lookup(tri.obj)
+	if !sOk || !pOk || !oOk {
+		return false
+	}
+	from, to := c.spoSpan(s, p, o)
+	return to > from
+}
+
+func (c *columnarGraph) Triples() []Triple {
+	return c.materialize(nil, 0, len(c.subs), make([]Triple, 0, len(c.subs)))
+}
+
+func (c *columnarGraph) Count() int {
+	return len(c.subs)
+}
+
+func (c *columnarGraph) WithSubject(s string) (out []Triple) {
+	for _, id := range c.subjectIDs(s) {
+		from, to := c.spoSpan(id)
+		out = c.materialize(nil, from, to, out)
+	}
+	return
+}
+
+func (c *columnarGraph) WithPredicate(p string) []Triple {
+	id, ok := c.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	from, to := c.posSpan(id)
+	return c.materialize(c.pos, from, to, nil)
+}
+
+func (c *columnarGraph) WithObject(o Object) []Triple {
+	id, ok := c.lookup(o.(object))
+	if !ok {
+		return nil
+	}
+	from, to := c.ospSpan(id)
+	return c.materialize(c.osp, from, to, nil)
+}
+
+func (c *columnarGraph) WithSubjObj(s string, o Object) (out []Triple) {
+	oid, ok := c.lookup(o.(object))
+	if !ok {
+		return nil
+	}
+	for _, id := range c.subjectIDs(s) {
+		from, to := c.ospSpan(oid, id)
+		out = c.materialize(c.osp, from, to, out)
+	}
+	return
+}
+
+func (c *columnarGraph) WithSubjPred(s, p string) (out []Triple) {
+	pid, ok := c.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	for _, id := range c.subjectIDs(s) {
+		from, to := c.spoSpan(id, pid)
+		out = c.materialize(nil, from, to, out)
+	}
+	return
+}
+
+func (c *columnarGraph) WithPredObj(p string, o Object) []Triple {
+	pid, pOk := c.lookup(object{resource: p})
+	oid, oOk := c.lookup(o.(object))
+	if !pOk || !oOk {
+		return nil
+	}
+	from, to := c.posSpan(pid, oid)
+	return c.materialize(c.pos, from, to, nil)
+}
+
+// WithPredObjMatching matches each distinct object of the predicate only once,
+// the triples of a predicate being sorted by object
+func (c *columnarGraph) WithPredObjMatching(p string, re *regexp.Regexp) (out []Triple) {
+	pid, ok := c.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	from, to := c.posSpan(pid)
+	for i := from; i < to; {
+		oid := c.objs[c.pos[i]]
+		j := i + 1
+		for j < to && c.objs[c.pos[j]] == oid {
+			j++
+		}
+		if c.kinds[oid] == termKindLiteral && re.MatchString(c.values[oid]) {
+			out = c.materialize(c.pos, i, j, out)
+		}
+		i = j
+	}
+	return
+}
+
+// WithPredRange parses each distinct object of the predicate only once
+func (c *columnarGraph) WithPredRange(p string, min, max Literal) (out []Triple) {
+	pid, ok := c.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	r, ok := newNumericRange(min, max)
+	if !ok {
+		return nil
+	}
+	from, to := c.posSpan(pid)
+	for i := from; i < to; {
+		oid := c.objs[c.pos[i]]
+		j := i + 1
+		for j < to && c.objs[c.pos[j]] == oid {
+			j++
+		}
+		if v, ok := numericValue(c.term(oid)); ok && r.contains(v) {
+			out = c.materialize(c.pos, i, j, out)
+		}
+		i = j
+	}
+	return
+}
+
+// WithPredTimeRange parses each distinct object of the predicate only once
+func (c *columnarGraph) WithPredTimeRange(p string, from, to time.Time) (out []Triple) {
+	pid, ok := c.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	r := timeRange{from: from, to: to}
+	start, end := c.posSpan(pid)
+	for i := start; i < end; {
+		oid := c.objs[c.pos[i]]
+		j := i + 1
+		for j < end && c.objs[c.pos[j]] == oid {
+			j++
+		}
+		if t, ok := timeValue(c.term(oid)); ok && r.contains(t) {
+			out = c.materialize(c.pos, i, j, out)
+		}
+		i = j
+	}
+	return
+}
+
+// CountWith returns the number of triples matching the given subject, predicate
+// and object, nil meaning any. It counts without materializing triples.
+func (c *columnarGraph) CountWith(s, p *string, o Object) int {
+	var pid, oid termID
+	if p != nil {
+		id, ok := c.lookup(object{resource: *p})
+		if !ok {
+			return 0
+		}
+		pid = id
+	}
+	if o != nil {
+		id, ok := c.lookup(o.(object))
+		if !ok {
+			return 0
+		}
+		oid = id
+	}
+
+	count := func(from, to int) int { return to - from }
+	if s == nil {
+		switch {
+		case p != nil && o != nil:
+			return count(c.posSpan(pid, oid))
+		case p != nil:
+			return count(c.posSpan(pid))
+		case o != nil:
+			return count(c.ospSpan(oid))
+		default:
+			return len(c.subs)
+		}
+	}
+
+	var n int
+	for _, sub := range [2]object{{resource: *s}, {isBnode: true, bnode: *s}} {
+		sid, ok := c.lookup(sub)
+		if !ok {
+			continue
+		}
+		switch {
+		case p != nil && o != nil:
+			n += count(c.spoSpan(sid, pid, oid))
+		case p != nil:
+			n += count(c.spoSpan(sid, pid))
+		case o != nil:
+			n += count(c.ospSpan(oid, sid))
+		default:
+			n += count(c.spoSpan(sid))
+		}
+	}
+	return n
+}
+
+// Exists returns true if a triple matches the given subject, predicate
+// and object, nil meaning any
+func (c *columnarGraph) Exists(s, p *string, o Object) bool {
+	return c.CountWith(s, p, o) > 0
+}
+
+func (c *columnarGraph) Stats() Stats {
+	st := Stats{Triples: len(c.subs), Subjects: c.subjects, Predicates: c.predicates, Objects: c.objects}
+
+	mem := 0
+	for k := range c.ids {
+		mem += stringHeaderSize + len(k) + 4 + mapEntryOverhead
+	}
+	for i := range c.kinds {
+		mem += 1 + 3*stringHeaderSize + len(c.values[i]) + len(c.langs[i])
+	}
+	// 3 term ID columns and 2 permutations
+	mem += len(c.subs) * 5 * 4
+	st.MemoryBytes = mem
+	return st
+}
+
+func (c *columnarGraph) Subgraph(root string, depth int) Triples {
+	return subgraph(c, root, depth)
+}
@@ -0,0 +1,170 @@
+package triplestore
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestColumnarGraph(t *testing.T) {
+	src := NewSource()
+	src.Add(
+		SubjPred("one", "two").Resource("three"),
+		SubjPred("one", "two").Resource("four"),
+		SubjPred("one", "five").StringLiteral("three"),
+		SubjPred("one", "five").StringLiteralWithLang("three", "en"),
+		SubjPred("four", "two").IntegerLiteral(42),
+		SubjPred("four", "six").Bnode("one"),
+		BnodePred("one", "two").Resource("three"),
+		BnodePred("one", "seven").Resource("four"),
+	)
+	snap := src.Snapshot()
+	col := NewColumnarGraph(snap)
+
+	if got, want := Triples(col.Triples()), Triples(snap.Triples()); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := col.Count(), snap.Count(); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	for _, tri := range snap.Triples() {
+		if !col.Contains(tri) {
+			t.Fatalf("should contain %v", tri)
+		}
+	}
+	if col.Contains(SubjPred("one", "two").Resource("four2")) || col.Contains(SubjPred("four", "two").IntegerLiteral(43)) {
+		t.Fatal("should not contain triple")
+	}
+
+	subjects := []string{"one", "four", "unknown"}
+	preds := []string{"two", "five", "seven", "unknown"}
+	objs := []Object{Resource("three"), Resource("four"), StringLiteral("three"), StringLiteralWithLang("three", "en"), IntegerLiteral(42), object{isBnode: true, bnode: "one"}, Resource("unknown")}
+	for _, s := range subjects {
+		if got, want := Triples(col.WithSubject(s)), Triples(snap.WithSubject(s)); !got.Equal(want) {
+			t.Fatalf("subject %s: got %v, want %v", s, got, want)
+		}
+		for _, p := range preds {
+			if got, want := Triples(col.WithSubjPred(s, p)), Triples(snap.WithSubjPred(s, p)); !got.Equal(want) {
+				t.Fatalf("subject %s, predicate %s: got %v, want %v", s, p, got, want)
+			}
+		}
+		for _, o := range objs {
+			if got, want := Triples(col.WithSubjObj(s, o)), Triples(snap.WithSubjObj(s, o)); !got.Equal(want) {
+				t.Fatalf("subject %s, object %v: got %v, want %v", s, o, got, want)
+			}
+		}
+		if got, want := Subgraph(col, s, -1), Subgraph(snap, s, -1); !got.Equal(want) {
+			t.Fatalf("subgraph %s: got %v, want %v", s, got, want)
+		}
+	}
+	for _, p := range preds {
+		if got, want := Triples(col.WithPredicate(p)), Triples(snap.WithPredicate(p)); !got.Equal(want) {
+			t.Fatalf("predicate %s: got %v, want %v", p, got, want)
+		}
+		for _, o := range objs {
+			if got, want := Triples(col.WithPredObj(p, o)), Triples(snap.WithPredObj(p, o)); !got.Equal(want) {
+				t.Fatalf("predicate %s, object %v: got %v, want %v", p, o, got, want)
+			}
+		}
+	}
+	for _, p := range preds {
+		for _, re := range []*regexp.Regexp{regexp.MustCompile("^th"), regexp.MustCompile("4"), regexp.MustCompile("")} {
+			if got, want := Triples(WithPredObjMatching(col, p, re)), Triples(WithPredObjMatching(snap, p, re)); !got.Equal(want) {
+				t.Fatalf("predicate %s, regex %s: got %v, want %v", p, re, got, want)
+			}
+		}
+	}
+	for _, p := range preds {
+		forty := object{isLit: true, lit: literal{typ: XsdInteger, val: "40"}}.lit
+		for _, bounds := range [][2]Literal{{nil, nil}, {forty, nil}, {nil, forty}} {
+			if got, want := Triples(WithPredRange(col, p, bounds[0], bounds[1])), Triples(WithPredRange(snap, p, bounds[0], bounds[1])); !got.Equal(want) {
+				t.Fatalf("predicate %s, range %v: got %v, want %v", p, bounds, got, want)
+			}
+		}
+	}
+	for _, o := range objs {
+		if got, want := Triples(col.WithObject(o)), Triples(snap.WithObject(o)); !got.Equal(want) {
+			t.Fatalf("object %v: got %v, want %v", o, got, want)
+		}
+	}
+
+	for _, s := range append(subjects, "") {
+		for _, p := range append(preds, "") {
+			for _, o := range append(objs, nil) {
+				sp, pp := &s, &p
+				if s == "" {
+					sp = nil
+				}
+				if p == "" {
+					pp = nil
+				}
+				if got, want := CountWith(col, sp, pp, o), CountWith(snap, sp, pp, o); got != want {
+					t.Fatalf("count %q %q %v: got %d, want %d", s, p, o, got, want)
+				}
+			}
+		}
+	}
+
+	cst, sst := StatsOf(col), StatsOf(snap)
+	if got, want := [4]int{cst.Triples, cst.Subjects, cst.Predicates, cst.Objects}, [4]int{sst.Triples, sst.Subjects, sst.Predicates, sst.Objects}; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if cst.MemoryBytes >= sst.MemoryBytes {
+		t.Fatalf("expected less memory than snapshot, got %d >= %d", cst.MemoryBytes, sst.MemoryBytes)
+	}
+
+	if got := NewColumnarGraph(NewSource().Snapshot()); got.Count() != 0 || got.WithPredicate("two") != nil {
+		t.Fatal("expected empty graph")
+	}
+}
+
+// BenchmarkColumnarGraph/snapshot_scan_predicate-8           484   2078113 ns/op   1603840 B/op   10001 allocs/op
+// BenchmarkColumnarGraph/snapshot_scan_predicate_object-8    9493    209719 ns/op    160384 B/op    1001 allocs/op
+// BenchmarkColumnarGraph/snapshot_count-8                 7630923       191.0 ns/op        0 B/op       0 allocs/op
+// BenchmarkColumnarGraph/snapshot_subject-8               1502851      1100 ns/op       488 B/op       5 allocs/op
+// BenchmarkColumnarGraph/columnar_scan_predicate-8           560   2349927 ns/op   1603840 B/op   10001 allocs/op
+// BenchmarkColumnarGraph/columnar_scan_predicate_object-8   10000    184762 ns/op    160384 B/op    1001 allocs/op
+// BenchmarkColumnarGraph/columnar_count-8                 3480961       331.2 ns/op        0 B/op       0 allocs/op
+// BenchmarkColumnarGraph/columnar_subject-8               1256672       849.1 ns/op      488 B/op       5 allocs/op
+func BenchmarkColumnarGraph(b *testing.B) {
+	src := NewSource()
+	for i := 0; i < 10000; i++ {
+		src.Add(
+			SubjPred(fmt.Sprint(i), "digit").IntegerLiteral(i%10),
+			SubjPred(fmt.Sprint(i), "parity").BooleanLiteral(i%2 == 0),
+			SubjPred(fmt.Sprint(i), "next").Resource(fmt.Sprint(i+1)),
+		)
+	}
+	snap := src.Snapshot()
+	graphs := []struct {
+		name string
+		g    RDFGraph
+	}{
+		{"snapshot", snap},
+		{"columnar", NewColumnarGraph(snap)},
+	}
+	pred, obj := "digit", IntegerLiteral(5)
+
+	for _, g := range graphs {
+		b.Run(g.name+" scan predicate", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.g.WithPredicate("parity")
+			}
+		})
+		b.Run(g.name+" scan predicate object", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.g.WithPredObj(pred, obj)
+			}
+		})
+		b.Run(g.name+" count", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				CountWith(g.g, nil, &pred, obj)
+			}
+		})
+		b.Run(g.name+" subject", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.g.WithSubject("5000")
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package triplestore
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompareLiterals compares two literals in the value space of their datatypes,
+// returning -1, 0 or +1. Numeric datatypes are comparable with each other (ex: "9"^^xsd:integer < "10.5"^^xsd:decimal),
+// as are booleans (false < true), strings (lexicographically, language tag aside),
+// and the temporal datatypes dateTime, date, time and duration with themselves.
+// An error is returned for incomparable datatypes or invalid lexical forms.
+func CompareLiterals(a, b Literal) (int, error) {
+	ta, tb := shortXsdType(a.Type()), shortXsdType(b.Type())
+	switch {
+	case isNumericType(ta) && isNumericType(tb):
+		return compareNumerics(a, b)
+	case ta != tb:
+		return 0, fmt.Errorf("compare literals: cannot compare %s with %s", ta, tb)
+	}
+
+	switch ta {
+	case XsdString:
+		return strings.Compare(a.Value(), b.Value()), nil
+	case XsdBoolean:
+		ba, err := strconv.ParseBool(a.Value())
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		bb, err := strconv.ParseBool(b.Value())
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		switch {
+		case ba == bb:
+			return 0, nil
+		case !ba:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	case XsdDateTime, XsdDate, XsdTime:
+		tma, err := temporalValue(a)
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		tmb, err := temporalValue(b)
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		switch {
+		case tma.Before(tmb):
+			return -1, nil
+		case tma.After(tmb):
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	case XsdDuration:
+		da, err := parseXsdDuration(a.Value())
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		db, err := parseXsdDuration(b.Value())
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		switch {
+		case da < db:
+			return -1, nil
+		case da > db:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	default:
+		return 0, fmt.Errorf("compare literals: unsupported datatype %s", ta)
+	}
+}
+
+func isNumericType(t XsdType) bool {
+	switch t {
+	case XsdInteger, XsdByte, XsdShort, XsdUinteger, XsdUnsignedByte, XsdUnsignedShort,
+		XsdDecimal, XsdDouble, XsdFloat:
+		return true
+	}
+	return false
+}
+
+func isFloatingType(t XsdType) bool {
+	return t == XsdDouble || t == XsdFloat
+}
+
+func compareNumerics(a, b Literal) (int, error) {
+	if isFloatingType(shortXsdType(a.Type())) || isFloatingType(shortXsdType(b.Type())) {
+		fa, err := strconv.ParseFloat(a.Value(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		fb, err := strconv.ParseFloat(b.Value(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("compare literals: %s", err)
+		}
+		if math.IsNaN(fa) || math.IsNaN(fb) {
+			return 0, fmt.Errorf("compare literals: NaN is not comparable")
+		}
+		switch {
+		case fa < fb:
+			return -1, nil
+		case fa > fb:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	}
+
+	ra, ok := exactNumericValue(shortXsdType(a.Type()), a.Value())
+	if !ok {
+		return 0, fmt.Errorf("compare literals: invalid %s '%s'", a.Type(), a.Value())
+	}
+	rb, ok := exactNumericValue(shortXsdType(b.Type()), b.Value())
+	if !ok {
+		return 0, fmt.Errorf("compare literals: invalid %s '%s'", b.Type(), b.Value())
+	}
+	return ra.Cmp(rb), nil
+}
+
+// exactNumericValue parses the value of an integer or decimal lexical form,
+// rejecting forms the datatype does not allow (ex: "1/2", "1e5", "1.5"^^xsd:integer)
+func exactNumericValue(typ XsdType, val string) (*big.Rat, bool) {
+	if typ != XsdDecimal {
+		i, ok := new(big.Int).SetString(val, 10)
+		if !ok {
+			return nil, false
+		}
+		return new(big.Rat).SetInt(i), true
+	}
+	canonical, ok := canonicalDecimal(val)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(canonical)
+}
+
+// temporalValue parses the instant of a temporal literal, UTC being assumed without timezone
+func temporalValue(l Literal) (time.Time, error) {
+	obj := object{isLit: true, lit: literal{typ: shortXsdType(l.Type()), val: l.Value()}}
+	switch obj.lit.typ {
+	case XsdDate:
+		return ParseDate(obj)
+	case XsdTime:
+		return ParseTime(obj)
+	default:
+		return parseTemporal(obj, XsdDateTime, xsdDateTimeLayout)
+	}
+}
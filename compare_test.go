@@ -0,0 +1,73 @@
+package triplestore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareLiterals(t *testing.T) {
+	lit := func(o Object) Literal {
+		l, _ := o.Literal()
+		return l
+	}
+	typed := func(typ XsdType, val string) Literal {
+		return literal{typ: typ, val: val}
+	}
+	now := time.Now()
+
+	tcases := []struct {
+		a, b Literal
+		exp  int
+	}{
+		{lit(IntegerLiteral(9)), lit(IntegerLiteral(10)), -1},
+		{lit(IntegerLiteral(10)), lit(IntegerLiteral(9)), 1},
+		{lit(IntegerLiteral(10)), typed(XsdInteger, "+010"), 0},
+		{lit(IntegerLiteral(10)), typed(XsdDecimal, "10.5"), -1},
+		{lit(Uint8Literal(255)), lit(Int16Literal(-3)), 1},
+		{typed(XsdDecimal, "0.30000000000000000001"), typed(XsdDecimal, "0.3"), 1},
+		{lit(Float64Literal(1.5)), lit(IntegerLiteral(2)), -1},
+		{lit(Float32Literal(2)), lit(Float64Literal(2)), 0},
+		{typed(XsdDouble, "INF"), lit(IntegerLiteral(1000)), 1},
+		{lit(BooleanLiteral(false)), lit(BooleanLiteral(true)), -1},
+		{lit(BooleanLiteral(true)), lit(BooleanLiteral(true)), 0},
+		{lit(StringLiteral("a")), lit(StringLiteral("b")), -1},
+		{lit(DateTimeLiteral(now)), lit(DateTimeLiteral(now.Add(time.Second))), -1},
+		{typed(XsdDateTime, "2017-11-21T13:00:00+02:00"), typed(XsdDateTime, "2017-11-21T12:00:00Z"), -1},
+		{typed(XsdDateTime, "2017-11-21T13:00:00"), typed(XsdDateTime, "2017-11-21T14:00:00+02:00"), 1},
+		{typed("http://www.w3.org/2001/XMLSchema#dateTime", "2017-11-21T12:00:00Z"), typed(XsdDateTime, "2017-11-21T12:00:00Z"), 0},
+		{typed("http://www.w3.org/2001/XMLSchema#integer", "12"), typed(XsdDouble, "1.2e1"), 0},
+		{lit(DateLiteral(now)), lit(DateLiteral(now.AddDate(0, 0, -1))), 1},
+		{typed(XsdTime, "13:00:00+02:00"), typed(XsdTime, "11:00:00Z"), 0},
+		{lit(DurationLiteral(time.Hour)), lit(DurationLiteral(90 * time.Minute)), -1},
+	}
+
+	for i, tc := range tcases {
+		got, err := CompareLiterals(tc.a, tc.b)
+		if err != nil {
+			t.Fatalf("case %d: %s", i+1, err)
+		}
+		if got != tc.exp {
+			t.Fatalf("case %d: got %d, want %d", i+1, got, tc.exp)
+		}
+	}
+
+	errCases := []struct {
+		a, b Literal
+	}{
+		{lit(IntegerLiteral(1)), lit(StringLiteral("1"))},
+		{lit(DateLiteral(now)), lit(DateTimeLiteral(now))},
+		{typed(XsdInteger, "abc"), lit(IntegerLiteral(1))},
+		{typed(XsdInteger, "1/2"), lit(IntegerLiteral(1))},
+		{typed(XsdInteger, "1e5"), lit(IntegerLiteral(1))},
+		{typed(XsdInteger, "1.5"), lit(IntegerLiteral(1))},
+		{typed(XsdDecimal, "1e5"), lit(IntegerLiteral(1))},
+		{typed(XsdDecimal, "1/2"), lit(IntegerLiteral(1))},
+		{typed(XsdDouble, "NaN"), lit(IntegerLiteral(1))},
+		{typed("custom", "a"), typed("custom", "b")},
+	}
+	for i, tc := range errCases {
+		if _, err := CompareLiterals(tc.a, tc.b); err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+	}
+}
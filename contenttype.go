@@ -0,0 +1,100 @@
+package triplestore
+
+import (
+	"fmt"
+	"io"
+	"mime"
+)
+
+const (
+	ntriplesMediaType = "application/n-triples"
+	binaryMediaType   = "application/octet-stream"
+	protobufMediaType = "application/x-protobuf"
+	msgpackMediaType  = "application/x-msgpack"
+	avroMediaType     = "avro/binary"
+	cborMediaType     = "application/cbor"
+	nquadsMediaType   = "application/n-quads"
+	turtleMediaType   = "text/turtle"
+	jsonldMediaType   = "application/ld+json"
+	// jsonMediaType is the JSON triples format of this package, not JSON-LD
+	jsonMediaType = "application/x-triplestore+json"
+)
+
+// decodedMediaTypes are the media types of DecoderForContentType,
+// from the most to the least preferred when requesting RDF documents
+var decodedMediaTypes = []string{
+	turtleMediaType,
+	ntriplesMediaType,
+	jsonldMediaType,
+	nquadsMediaType,
+	jsonMediaType,
+	protobufMediaType,
+	msgpackMediaType,
+	cborMediaType,
+	avroMediaType,
+	binaryMediaType,
+}
+
+// EncoderForContentType returns the encoder matching the given media type
+// (ex: "application/n-triples"). Media type parameters are ignored.
+func EncoderForContentType(w io.Writer, contentType string) (Encoder, error) {
+	switch mediatype := parseMediaType(contentType); mediatype {
+	case ntriplesMediaType, "text/plain":
+		return NewLenientNTEncoder(w), nil
+	case binaryMediaType:
+		return NewBinaryEncoder(w), nil
+	case protobufMediaType:
+		return NewProtoEncoder(w), nil
+	case msgpackMediaType:
+		return NewMsgpackEncoder(w), nil
+	case avroMediaType:
+		return NewAvroEncoder(w), nil
+	case cborMediaType:
+		return NewCBOREncoder(w), nil
+	case turtleMediaType:
+		return NewTurtleEncoder(w), nil
+	case jsonldMediaType:
+		return NewJSONLDEncoder(w), nil
+	case "text/vnd.graphviz":
+		return NewDotEncoder(w, nil), nil
+	default:
+		return nil, fmt.Errorf("no encoder for content type '%s'", mediatype)
+	}
+}
+
+// DecoderForContentType returns the decoder matching the given media type
+// (ex: "application/n-triples"). Media type parameters are ignored.
+func DecoderForContentType(r io.Reader, contentType string) (Decoder, error) {
+	switch mediatype := parseMediaType(contentType); mediatype {
+	case ntriplesMediaType, "text/plain":
+		return NewLenientNTDecoder(r), nil
+	case binaryMediaType:
+		return NewBinaryDecoder(r), nil
+	case protobufMediaType:
+		return NewProtoDecoder(r), nil
+	case msgpackMediaType:
+		return NewMsgpackDecoder(r), nil
+	case avroMediaType:
+		return NewAvroDecoder(r), nil
+	case cborMediaType:
+		return NewCBORDecoder(r), nil
+	case nquadsMediaType:
+		return NewNQuadsDecoder(r), nil
+	case turtleMediaType:
+		return NewTurtleDecoder(r), nil
+	case jsonldMediaType:
+		return NewJSONLDDecoder(r), nil
+	case jsonMediaType:
+		return NewJSONDecoder(r), nil
+	default:
+		return nil, fmt.Errorf("no decoder for content type '%s'", mediatype)
+	}
+}
+
+func parseMediaType(contentType string) string {
+	mediatype, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediatype
+}
@@ -0,0 +1,79 @@
+package triplestore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCodecsForContentType(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").StringLiteral("three"),
+		SubjPred("one", "four").Resource("five"),
+	}
+
+	for _, contentType := range []string{"application/n-triples", "application/n-triples; charset=utf-8", "text/plain", "application/octet-stream", "application/x-protobuf", "application/x-msgpack", "avro/binary", "application/cbor", "text/turtle", "application/ld+json"} {
+		var buf bytes.Buffer
+		enc, err := EncoderForContentType(&buf, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(tris...); err != nil {
+			t.Fatal(err)
+		}
+		dec, err := DecoderForContentType(&buf, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := Triples(decoded), tris; !got.Equal(want) {
+			t.Fatalf("%s: got %v, want %v", contentType, got, want)
+		}
+	}
+
+	if _, err := EncoderForContentType(nil, "image/png"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := DecoderForContentType(nil, "image/png"); err == nil {
+		t.Fatal("expected error")
+	}
+	// JSON-LD and arbitrary JSON are served as application/json
+	if _, err := DecoderForContentType(nil, "application/json"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDecodedMediaTypes(t *testing.T) {
+	for _, mediatype := range decodedMediaTypes {
+		if _, err := DecoderForContentType(nil, mediatype); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNegotiateMediaType(t *testing.T) {
+	tcases := []struct {
+		accept, expected string
+	}{
+		{"", "application/n-triples"},
+		{"*/*", "application/n-triples"},
+		{"application/octet-stream", "application/octet-stream"},
+		{"image/png, text/plain;q=0.5", "text/plain"},
+		{"image/png", ""},
+		{"text/plain;q=0.5, text/turtle", "text/turtle"},
+		{"text/turtle;q=0.8, application/ld+json;q=0.9", "application/ld+json"},
+		{"text/turtle;q=0", ""},
+		{"text/turtle;q=0, application/n-triples", "application/n-triples"},
+		{"*/*, application/n-triples;q=0", "application/octet-stream"},
+		{"application/*;q=0.1, text/turtle;q=0.5", "text/turtle"},
+		{"*/*;q=0", ""},
+		{"text/turtle;q=abc", ""},
+	}
+	for i, tc := range tcases {
+		if got, want := negotiateMediaType(tc.accept), tc.expected; got != want {
+			t.Fatalf("case %d: got %s, want %s", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package triplestore
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVMapping declares how CSV records are converted to triples
+type CSVMapping struct {
+	// SubjectColumn is the header of the column holding the subject of each record
+	SubjectColumn string
+	// SubjectTemplate, when set, derives the subject from the record values
+	// with {header} placeholders (ex: "person/{id}"). It takes precedence over SubjectColumn.
+	SubjectTemplate string
+	// Columns maps column headers to their predicate and datatype.
+	// Unmapped columns are ignored.
+	Columns map[string]CSVColumn
+	// Comma is the field delimiter (defaults to ',', use '\t' for TSV)
+	Comma rune
+}
+
+// CSVColumn declares the predicate and object type of a CSV column.
+// Values are resources when Resource is true, literals of Datatype otherwise
+// (xsd:string by default). Empty values are skipped.
+type CSVColumn struct {
+	Predicate string
+	Datatype  XsdType
+	Resource  bool
+}
+
+type csvDecoder struct {
+	r       io.Reader
+	mapping CSVMapping
+}
+
+// NewCSVDecoder returns a decoder converting each record of a CSV with
+// a header row into triples according to the given mapping.
+func NewCSVDecoder(r io.Reader, mapping CSVMapping) Decoder {
+	return &csvDecoder{r: r, mapping: mapping}
+}
+
+func (d *csvDecoder) Decode() ([]Triple, error) {
+	if d.mapping.SubjectColumn == "" && d.mapping.SubjectTemplate == "" {
+		return nil, fmt.Errorf("csv: mapping has no subject column or template")
+	}
+
+	reader := csv.NewReader(d.r)
+	if d.mapping.Comma != 0 {
+		reader.Comma = d.mapping.Comma
+	}
+
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv: reading header: %s", err)
+	}
+	indexes := make(map[string]int, len(headers))
+	for i, h := range headers {
+		indexes[strings.TrimSpace(h)] = i
+	}
+	if d.mapping.SubjectTemplate == "" {
+		if _, ok := indexes[d.mapping.SubjectColumn]; !ok {
+			return nil, fmt.Errorf("csv: unknown subject column '%s'", d.mapping.SubjectColumn)
+		}
+	}
+	for h := range d.mapping.Columns {
+		if _, ok := indexes[h]; !ok {
+			return nil, fmt.Errorf("csv: unknown column '%s'", h)
+		}
+	}
+	var mapped []string
+	for _, h := range headers {
+		if _, ok := d.mapping.Columns[strings.TrimSpace(h)]; ok {
+			mapped = append(mapped, strings.TrimSpace(h))
+		}
+	}
+
+	var out []Triple
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, fmt.Errorf("csv: %s", err)
+		}
+
+		sub, err := d.subject(indexes, record)
+		if err != nil {
+			return out, fmt.Errorf("csv: line %d: %s", line, err)
+		}
+		for _, h := range mapped {
+			col := d.mapping.Columns[h]
+			val := record[indexes[h]]
+			if val == "" {
+				continue
+			}
+			if col.Resource {
+				out = append(out, SubjPred(sub, col.Predicate).Resource(val))
+				continue
+			}
+			typ := col.Datatype
+			if typ == "" {
+				typ = XsdString
+			}
+			obj := object{isLit: true, lit: literal{typ: typ, val: val}}
+			if err := validateLiteral(obj); err != nil {
+				return out, fmt.Errorf("csv: line %d: column '%s': %s", line, h, err)
+			}
+			out = append(out, SubjPred(sub, col.Predicate).Object(obj))
+		}
+	}
+}
+
+func (d *csvDecoder) subject(indexes map[string]int, record []string) (string, error) {
+	if d.mapping.SubjectTemplate == "" {
+		sub := record[indexes[d.mapping.SubjectColumn]]
+		if sub == "" {
+			return "", fmt.Errorf("empty subject")
+		}
+		return sub, nil
+	}
+	var err error
+	sub := subjTplPlaceholder.ReplaceAllStringFunc(d.mapping.SubjectTemplate, func(m string) string {
+		i, ok := indexes[m[1:len(m)-1]]
+		if !ok {
+			err = fmt.Errorf("unknown column '%s' in subject template", m[1:len(m)-1])
+			return m
+		}
+		return record[i]
+	})
+	return sub, err
+}
+
+var csvHeader = []string{"subject", "predicate", "object", "datatype", "lang"}
+
+type csvEncoder struct {
+	w     io.Writer
+	comma rune
+}
+
+// NewCSVEncoder returns an encoder writing triples as CSV rows with
+// subject, predicate, object, datatype and lang columns, after a header row.
+// Datatype and lang are empty for resources and bnodes; bnodes are prefixed with "_:".
+func NewCSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: w, comma: ','}
+}
+
+// NewTSVEncoder returns an encoder writing triples as tab separated rows (see NewCSVEncoder)
+func NewTSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: w, comma: '\t'}
+}
+
+func (enc *csvEncoder) Encode(tris ...Triple) error {
+	writer := csv.NewWriter(enc.w)
+	writer.Comma = enc.comma
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, t := range tris {
+		tri := t.(*triple)
+		row := []string{nodeString(subjectObject(tri)), tri.pred, "", "", ""}
+		if tri.obj.isLit {
+			row[2] = tri.obj.lit.val
+			if tri.obj.lit.langtag != "" {
+				row[4] = tri.obj.lit.langtag
+			} else {
+				row[3] = string(tri.obj.lit.typ)
+			}
+		} else {
+			row[2] = nodeString(tri.obj)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func nodeString(o object) string {
+	if o.isBnode {
+		return "_:" + o.bnode
+	}
+	return o.resource
+}
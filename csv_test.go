@@ -0,0 +1,101 @@
+package triplestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCSVDecoder(t *testing.T) {
+	data := `id,name,age,friend,ignored
+1,John,42,person/2,x
+2,Jane,,,y
+`
+	mapping := CSVMapping{
+		SubjectTemplate: "person/{id}",
+		Columns: map[string]CSVColumn{
+			"name":   {Predicate: "name"},
+			"age":    {Predicate: "age", Datatype: XsdInteger},
+			"friend": {Predicate: "knows", Resource: true},
+		},
+	}
+	tris, err := NewCSVDecoder(strings.NewReader(data), mapping).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Triples{
+		SubjPred("person/1", "name").StringLiteral("John"),
+		SubjPred("person/1", "age").IntegerLiteral(42),
+		SubjPred("person/1", "knows").Resource("person/2"),
+		SubjPred("person/2", "name").StringLiteral("Jane"),
+	}
+	if got, want := Triples(tris), exp; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	tsv := "id\tname\n1\tJohn\n"
+	tris, err = NewCSVDecoder(strings.NewReader(tsv), CSVMapping{SubjectColumn: "id", Comma: '\t', Columns: map[string]CSVColumn{"name": {Predicate: "name"}}}).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(tris), (Triples{SubjPred("1", "name").StringLiteral("John")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCSVDecoderErrors(t *testing.T) {
+	tcases := []struct {
+		data    string
+		mapping CSVMapping
+		err     string
+	}{
+		{"id\n1\n", CSVMapping{}, "no subject column"},
+		{"id\n1\n", CSVMapping{SubjectColumn: "unknown"}, "unknown subject column"},
+		{"id\n1\n", CSVMapping{SubjectColumn: "id", Columns: map[string]CSVColumn{"age": {Predicate: "age"}}}, "unknown column 'age'"},
+		{"id\n1\n", CSVMapping{SubjectTemplate: "{other}"}, "line 2: unknown column 'other'"},
+		{"id\n\n,\n", CSVMapping{SubjectColumn: "id"}, "wrong number of fields"},
+		{"id,age\n1,notanint\n", CSVMapping{SubjectColumn: "id", Columns: map[string]CSVColumn{"age": {Predicate: "age", Datatype: XsdInteger}}}, "line 2: column 'age'"},
+	}
+	for i, tc := range tcases {
+		_, err := NewCSVDecoder(strings.NewReader(tc.data), tc.mapping).Decode()
+		if err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %q, want it to contain %q", i, err, tc.err)
+		}
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	tris := []Triple{
+		SubjPred("one", "name").StringLiteral("John, \"Jr\""),
+		SubjPred("one", "age").IntegerLiteral(42),
+		SubjPred("one", "label").StringLiteralWithLang("john", "en"),
+		SubjPred("one", "knows").Resource("two"),
+		BnodePred("b1", "owns").Bnode("b2"),
+	}
+
+	var buff bytes.Buffer
+	if err := NewCSVEncoder(&buff).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	exp := `subject,predicate,object,datatype,lang
+one,name,"John, ""Jr""",xsd:string,
+one,age,42,xsd:integer,
+one,label,john,,en
+one,knows,two,,
+_:b1,owns,_:b2,,
+`
+	if got, want := buff.String(), exp; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	buff.Reset()
+	if err := NewTSVEncoder(&buff).Encode(tris[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buff.String(), "subject\tpredicate\tobject\tdatatype\tlang\none\tage\t42\txsd:integer\t\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
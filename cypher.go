@@ -0,0 +1,109 @@
+package triplestore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type cypherEncoder struct {
+	w io.Writer
+}
+
+// NewCypherEncoder returns an encoder converting triples to a property graph
+// written as Cypher statements (ex: to import in Neo4j).
+//
+// Subjects and resources become nodes labelled Resource and identified by their iri property.
+// Literal objects become node properties (lists when a predicate has several values)
+// and resource objects become relationships typed by the predicate.
+func NewCypherEncoder(w io.Writer) Encoder {
+	return &cypherEncoder{w: w}
+}
+
+func (enc *cypherEncoder) Encode(tris ...Triple) error {
+	props := make(map[string]map[string][]string)
+	var nodes []string
+	addNode := func(id string) {
+		if _, ok := props[id]; !ok {
+			props[id] = make(map[string][]string)
+			nodes = append(nodes, id)
+		}
+	}
+
+	var rels []string
+	for _, t := range tris {
+		tri := t.(*triple)
+		sub := nodeString(subjectObject(tri))
+		addNode(sub)
+		if tri.obj.isLit {
+			props[sub][tri.pred] = append(props[sub][tri.pred], cypherValue(tri.obj))
+			continue
+		}
+		obj := nodeString(tri.obj)
+		addNode(obj)
+		rels = append(rels, fmt.Sprintf("MATCH (a:Resource {iri: %s}), (b:Resource {iri: %s}) MERGE (a)-[:%s]->(b);\n",
+			cypherString(sub), cypherString(obj), cypherIdentifier(tri.pred)))
+	}
+
+	sort.Strings(nodes)
+	sort.Strings(rels)
+
+	var buff bytes.Buffer
+	for _, n := range nodes {
+		fmt.Fprintf(&buff, "MERGE (n:Resource {iri: %s})", cypherString(n))
+		var keys []string
+		for k := range props[n] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i == 0 {
+				buff.WriteString(" SET ")
+			} else {
+				buff.WriteString(", ")
+			}
+			vals := props[n][k]
+			val := vals[0]
+			if len(vals) > 1 {
+				sort.Strings(vals)
+				val = "[" + strings.Join(vals, ", ") + "]"
+			}
+			fmt.Fprintf(&buff, "n.%s = %s", cypherIdentifier(k), val)
+		}
+		buff.WriteString(";\n")
+	}
+	for _, r := range rels {
+		buff.WriteString(r)
+	}
+
+	_, err := enc.w.Write(buff.Bytes())
+	return err
+}
+
+// cypherValue returns the Cypher literal of a RDF literal, as native
+// number or boolean when possible, as string otherwise
+func cypherValue(o object) string {
+	switch o.lit.typ {
+	case XsdBoolean, XsdInteger, XsdByte, XsdShort, XsdUinteger, XsdUnsignedByte, XsdUnsignedShort, XsdDouble, XsdFloat, XsdDecimal:
+		if _, err := ParseLiteral(o); err == nil {
+			switch o.lit.val {
+			case "INF", "-INF", "NaN":
+			default:
+				return o.lit.val
+			}
+		}
+	}
+	return cypherString(o.lit.val)
+}
+
+var cypherEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func cypherString(s string) string {
+	return `"` + cypherEscaper.Replace(s) + `"`
+}
+
+func cypherIdentifier(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
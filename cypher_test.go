@@ -0,0 +1,31 @@
+package triplestore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCypherEncoder(t *testing.T) {
+	tris := []Triple{
+		SubjPred("one", "name").StringLiteral(`John "Jr"`),
+		SubjPred("one", "age").IntegerLiteral(42),
+		SubjPred("one", "nick").StringLiteral("jo"),
+		SubjPred("one", "nick").StringLiteral("jj"),
+		SubjPred("one", "alive").BooleanLiteral(true),
+		SubjPred("one", "knows").Resource("two"),
+		SubjPred("two", "rdf:type").Resource("Person"),
+	}
+
+	var buff bytes.Buffer
+	if err := NewCypherEncoder(&buff).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	exp := "MERGE (n:Resource {iri: \"Person\"});\n" +
+		"MERGE (n:Resource {iri: \"one\"}) SET n.`age` = 42, n.`alive` = true, n.`name` = \"John \\\"Jr\\\"\", n.`nick` = [\"jj\", \"jo\"];\n" +
+		"MERGE (n:Resource {iri: \"two\"});\n" +
+		"MATCH (a:Resource {iri: \"one\"}), (b:Resource {iri: \"two\"}) MERGE (a)-[:`knows`]->(b);\n" +
+		"MATCH (a:Resource {iri: \"two\"}), (b:Resource {iri: \"Person\"}) MERGE (a)-[:`rdf:type`]->(b);\n"
+	if got, want := buff.String(), exp; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
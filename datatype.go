@@ -0,0 +1,110 @@
+package triplestore
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Datatype describes a custom literal datatype (ex: geo:wktLiteral)
+// registered with RegisterDatatype.
+type Datatype struct {
+	// Validate checks a lexical form. Optional
+	Validate func(string) error
+	// Parse converts a lexical form into a Go value
+	Parse func(string) (interface{}, error)
+	// Format converts a Go value into a lexical form. Optional
+	Format func(interface{}) (string, error)
+	// GoType is the Go type mapped to this datatype by ObjectLiteral
+	// and the struct mapper. Optional, requires Format
+	GoType reflect.Type
+}
+
+var datatypes = struct {
+	sync.RWMutex
+	byIRI    map[XsdType]Datatype
+	byGoType map[reflect.Type]XsdType
+}{
+	byIRI:    make(map[XsdType]Datatype),
+	byGoType: make(map[reflect.Type]XsdType),
+}
+
+// RegisterDatatype registers a custom datatype then used when
+// building literals from Go values, parsing literals and mapping structs.
+// Registering an already registered datatype replaces it.
+func RegisterDatatype(typ XsdType, dt Datatype) error {
+	if typ == "" {
+		return errors.New("register datatype: empty datatype")
+	}
+	if dt.Parse == nil {
+		return fmt.Errorf("register datatype %s: missing parse function", typ)
+	}
+	if dt.GoType != nil && dt.Format == nil {
+		return fmt.Errorf("register datatype %s: missing format function for Go type %s", typ, dt.GoType)
+	}
+
+	datatypes.Lock()
+	defer datatypes.Unlock()
+	if old, ok := datatypes.byIRI[typ]; ok && old.GoType != nil {
+		delete(datatypes.byGoType, old.GoType)
+	}
+	datatypes.byIRI[typ] = dt
+	if dt.GoType != nil {
+		datatypes.byGoType[dt.GoType] = typ
+	}
+	return nil
+}
+
+// UnregisterDatatype removes a registered custom datatype
+func UnregisterDatatype(typ XsdType) {
+	datatypes.Lock()
+	defer datatypes.Unlock()
+	if dt, ok := datatypes.byIRI[typ]; ok && dt.GoType != nil {
+		delete(datatypes.byGoType, dt.GoType)
+	}
+	delete(datatypes.byIRI, typ)
+}
+
+// LookupDatatype returns the registered custom datatype if any
+func LookupDatatype(typ XsdType) (Datatype, bool) {
+	datatypes.RLock()
+	defer datatypes.RUnlock()
+	dt, ok := datatypes.byIRI[typ]
+	return dt, ok
+}
+
+func lookupDatatypeByGoType(t reflect.Type) (XsdType, Datatype, bool) {
+	datatypes.RLock()
+	defer datatypes.RUnlock()
+	typ, ok := datatypes.byGoType[t]
+	if !ok {
+		return "", Datatype{}, false
+	}
+	return typ, datatypes.byIRI[typ], true
+}
+
+func (dt Datatype) parse(typ XsdType, val string) (interface{}, error) {
+	if dt.Validate != nil {
+		if err := dt.Validate(val); err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': %s", typ, val, err)
+		}
+	}
+	return dt.Parse(val)
+}
+
+func customLiteral(typ XsdType, dt Datatype, i interface{}) (Object, error) {
+	val, err := dt.Format(i)
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %s", typ, err)
+	}
+	if dt.Validate != nil {
+		if err := dt.Validate(val); err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': %s", typ, val, err)
+		}
+	}
+	return object{
+		isLit: true,
+		lit:   literal{typ: typ, val: val},
+	}, nil
+}
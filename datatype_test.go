@@ -0,0 +1,119 @@
+package triplestore
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type wktPoint struct {
+	X, Y float64
+}
+
+func registerWKTPoint() error {
+	return RegisterDatatype("geo:wktLiteral", Datatype{
+		Validate: func(s string) error {
+			if !strings.HasPrefix(s, "POINT(") || !strings.HasSuffix(s, ")") {
+				return errors.New("not a point")
+			}
+			return nil
+		},
+		Parse: func(s string) (interface{}, error) {
+			coords := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(s, "POINT("), ")"))
+			if len(coords) != 2 {
+				return nil, errors.New("expected 2 coordinates")
+			}
+			x, err := strconv.ParseFloat(coords[0], 64)
+			if err != nil {
+				return nil, err
+			}
+			y, err := strconv.ParseFloat(coords[1], 64)
+			if err != nil {
+				return nil, err
+			}
+			return wktPoint{x, y}, nil
+		},
+		Format: func(i interface{}) (string, error) {
+			p := i.(wktPoint)
+			return fmt.Sprintf("POINT(%v %v)", p.X, p.Y), nil
+		},
+		GoType: reflect.TypeOf(wktPoint{}),
+	})
+}
+
+func TestCustomDatatypeRegistry(t *testing.T) {
+	if err := registerWKTPoint(); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDatatype("geo:wktLiteral")
+
+	obj, err := ObjectLiteral(wktPoint{2.35, 48.85})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lit, _ := obj.Literal()
+	if got, want := lit.Type(), XsdType("geo:wktLiteral"); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := lit.Value(), "POINT(2.35 48.85)"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	parsed, err := ParseLiteral(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parsed, (wktPoint{2.35, 48.85}); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	invalid := object{isLit: true, lit: literal{typ: "geo:wktLiteral", val: "LINE(1 2)"}}
+	if _, err := ParseLiteral(invalid); err == nil {
+		t.Fatal("expected error")
+	}
+
+	t.Run("struct mapping", func(t *testing.T) {
+		type place struct {
+			Location wktPoint `predicate:"location"`
+		}
+		tris := TriplesFromStruct("paris", place{wktPoint{2.35, 48.85}})
+		if got, want := len(tris), 1; got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+		src := NewSource()
+		src.Add(tris...)
+		var got place
+		if err := StructFromTriples(src.Snapshot(), "paris", &got); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := got.Location, (wktPoint{2.35, 48.85}); got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		UnregisterDatatype("geo:wktLiteral")
+		if _, ok := LookupDatatype("geo:wktLiteral"); ok {
+			t.Fatal("expected datatype to be unregistered")
+		}
+		if _, err := ObjectLiteral(wktPoint{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("invalid registration", func(t *testing.T) {
+		if err := RegisterDatatype("", Datatype{}); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := RegisterDatatype("any", Datatype{}); err == nil {
+			t.Fatal("expected error")
+		}
+		parse := func(string) (interface{}, error) { return nil, nil }
+		if err := RegisterDatatype("any", Datatype{Parse: parse, GoType: reflect.TypeOf(0)}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
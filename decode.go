@@ -5,11 +5,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 	"sync"
+	"unicode"
 )
 
 type Decoder interface {
@@ -25,13 +28,123 @@ type StreamDecoder interface {
 	StreamDecode(context.Context) <-chan DecodeResult
 }
 
-// Use for retro compatibilty when changing file format on existing stores
+type autoDecoder struct {
+	r io.Reader
+}
+
+// NewAutoDecoder returns a decoder detecting the format of the input from its
+// first bytes: gzip, binary, Protocol Buffers, MessagePack, CBOR, Avro, JSON,
+// JSON-LD, N-Triples or Turtle. RDF/XML is detected but not supported. As N-Triples is a
+// subset of Turtle, text starting with an IRI or a blank node is decoded as N-Triples,
+// falling back on Turtle when it uses Turtle abbreviations (ex: ';', 'a').
+// Input in an unrecognized format is decoded as binary, for retro compatibility
+// when changing file format on existing stores.
 func NewAutoDecoder(r io.Reader) Decoder {
-	ok, newR := IsNTFormat(r)
-	if ok {
-		return NewLenientNTDecoder(newR)
+	return &autoDecoder{r: r}
+}
+
+func (d *autoDecoder) Decode() ([]Triple, error) {
+	buffered := bufio.NewReader(d.r)
+	head, _ := buffered.Peek(sniffLen)
+	if bytes.HasPrefix(head, gzipMagic) {
+		return NewGzipDecoder(NewAutoDecoder, buffered).Decode()
+	}
+	mediatype := sniffMediaType(head)
+	if mediatype == rdfXMLMediaType {
+		return nil, errors.New("auto decoder: RDF/XML is not supported")
+	}
+	if mediatype == ntriplesMediaType {
+		return decodeNTriplesOrTurtle(buffered)
+	}
+	dec, err := DecoderForContentType(buffered, mediatype)
+	if err != nil {
+		return nil, fmt.Errorf("auto decoder: %s", err)
+	}
+	return dec.Decode()
+}
+
+// decodeNTriplesOrTurtle decodes the input as N-Triples, or as Turtle
+// when it is not valid N-Triples
+func decodeNTriplesOrTurtle(r io.Reader) ([]Triple, error) {
+	doc, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if tris, err := NewLenientNTDecoder(bytes.NewReader(doc)).Decode(); err == nil {
+		return tris, nil
+	}
+	return NewTurtleDecoder(bytes.NewReader(doc)).Decode()
+}
+
+const (
+	sniffLen        = 512
+	rdfXMLMediaType = "application/rdf+xml"
+)
+
+// sniffMediaType returns the media type of the format of data starting with head
+func sniffMediaType(head []byte) string {
+	if len(head) == 0 {
+		return ntriplesMediaType
+	}
+	switch c := head[0]; {
+	case bytes.HasPrefix(head, []byte(avroMagic)):
+		return avroMediaType
+	case c == 0 || c == 1: // is subject bnode
+		return binaryMediaType
+	case c >= 0x80 && c <= 0x8f: // fixmap
+		return msgpackMediaType
+	case c >= 0xa0 && c <= 0xbb: // map
+		return cborMediaType
+	}
+	// length delimited messages starting with a subject term (field 1), of kind
+	// (field 1) or value (field 2). Such control characters are not found in text.
+	if l, n := binary.Uvarint(head); n > 0 && l > 0 && len(head) > n+2 && head[n] == 0x0a && (head[n+2] == 0x08 || head[n+2] == 0x12) {
+		return protobufMediaType
+	}
+
+	text := skipSpacesAndComments(head)
+	switch {
+	case len(text) == 0: // only spaces and comments
+		return ntriplesMediaType
+	case text[0] == '{' || text[0] == '[' && bytes.HasPrefix(bytes.TrimLeft(text[1:], " \t\r\n"), []byte("{")):
+		if isJSONLD(text) {
+			return jsonldMediaType
+		}
+		return jsonMediaType
+	case bytes.HasPrefix(text, []byte("<?xml")) || bytes.HasPrefix(text, []byte("<rdf:RDF")):
+		return rdfXMLMediaType
+	case text[0] == '<' || bytes.HasPrefix(text, []byte("_:")):
+		return ntriplesMediaType
+	case text[0] == '@' || text[0] == '[' || text[0] == ':' || unicode.IsLetter(rune(text[0])):
+		return turtleMediaType
+	}
+	return binaryMediaType
+}
+
+// isJSONLD returns true if the JSON text uses JSON-LD keywords
+// rather than the terms of JSON triples
+func isJSONLD(text []byte) bool {
+	for _, keyword := range []string{`"@id"`, `"@graph"`, `"@context"`} {
+		if bytes.Contains(text, []byte(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipSpacesAndComments trims the leading spaces and '#' comment lines
+func skipSpacesAndComments(b []byte) []byte {
+	for {
+		b = bytes.TrimLeft(b, " \t\r\n")
+		if len(b) == 0 || b[0] != '#' {
+			return b
+		}
+		end := bytes.IndexByte(b, '\n')
+		if end < 0 {
+			return nil
+		}
+		b = b[end+1:]
 	}
-	return NewBinaryDecoder(newR)
 }
 
 // Loosely detect if a ntriples format contrary to a binary format
@@ -54,12 +167,32 @@ func NewLenientNTStreamDecoder(r io.Reader) StreamDecoder {
 	return &ntDecoder{r: r}
 }
 
+// NewNTriplesDecoder returns a N-Triples decoder configured with the given options
+func NewNTriplesDecoder(r io.Reader, opts ...DecoderOption) Decoder {
+	return &ntDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
 type ntDecoder struct {
-	r io.Reader
+	r    io.Reader
+	opts decoderOptions
 }
 
 func (d *ntDecoder) Decode() ([]Triple, error) {
-	return newLenientNTParser(d.r).Parse()
+	tracker := newProgressTracker(d.opts.progress)
+	p := getNTParser(tracker.reader(d.r))
+	p.progress = tracker
+	defer putNTParser(p)
+	tris, err := p.Parse()
+	if err != nil {
+		return tris, err
+	}
+	for i, t := range tris {
+		if err := d.opts.checkTermSize(t.(*triple)); err != nil {
+			return tris[:i], err
+		}
+	}
+	tracker.done()
+	return tris, nil
 }
 
 func (d *ntDecoder) StreamDecode(ctx context.Context) <-chan DecodeResult {
@@ -68,22 +201,34 @@ func (d *ntDecoder) StreamDecode(ctx context.Context) <-chan DecodeResult {
 	go func() {
 		defer close(decC)
 
-		scanner := bufio.NewScanner(d.r)
+		p := getNTParser(nil)
+		defer putNTParser(p)
+
+		tracker := newProgressTracker(d.opts.progress)
+		scanner := bufio.NewScanner(tracker.reader(d.r))
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			default:
 				if scanner.Scan() {
-					tris, err := newLenientNTParser(strings.NewReader(scanner.Text())).Parse()
+					p.Reset(bytes.NewReader(scanner.Bytes()))
+					tris, err := p.Parse()
 					if err != nil {
 						decC <- DecodeResult{Err: err}
 					} else if len(tris) == 1 {
-						decC <- DecodeResult{Tri: tris[0]}
+						if err := d.opts.checkTermSize(tris[0].(*triple)); err != nil {
+							decC <- DecodeResult{Err: err}
+						} else {
+							decC <- DecodeResult{Tri: tris[0]}
+							tracker.addTriple()
+						}
 					}
 				} else {
 					if err := scanner.Err(); err != nil {
 						decC <- DecodeResult{Err: err}
+					} else {
+						tracker.done()
 					}
 					return
 				}
@@ -98,6 +243,7 @@ type binaryDecoder struct {
 	r       io.Reader
 	rc      io.ReadCloser // for stream decoding
 	triples []Triple
+	opts    decoderOptions
 }
 
 func NewBinaryStreamDecoder(r io.ReadCloser) StreamDecoder {
@@ -114,7 +260,7 @@ func (dec *binaryDecoder) StreamDecode(ctx context.Context) <-chan DecodeResult
 			case <-ctx.Done():
 				return
 			default:
-				tri, done, err := decodeTriple(dec.rc)
+				tri, done, err := decodeTriple(dec.rc, dec.opts.maxTermSize)
 				if done {
 					return
 				}
@@ -130,12 +276,20 @@ func NewBinaryDecoder(r io.Reader) Decoder {
 	return &binaryDecoder{r: r}
 }
 
+// NewBinaryDecoderWithOptions returns a binary decoder configured with the given options
+func NewBinaryDecoderWithOptions(r io.Reader, opts ...DecoderOption) Decoder {
+	return &binaryDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
 func (dec *binaryDecoder) Decode() ([]Triple, error) {
 	var out []Triple
+	tracker := newProgressTracker(dec.opts.progress)
+	r := tracker.reader(dec.r)
 	for {
-		tri, done, err := decodeTriple(dec.r)
+		tri, done, err := decodeTriple(r, dec.opts.maxTermSize)
 		if tri != nil {
 			out = append(out, tri)
+			tracker.addTriple()
 		}
 		if done {
 			break
@@ -144,10 +298,11 @@ func (dec *binaryDecoder) Decode() ([]Triple, error) {
 		}
 	}
 
+	tracker.done()
 	return out, nil
 }
 
-func decodeTriple(r io.Reader) (Triple, bool, error) {
+func decodeTriple(r io.Reader, maxTermSize int) (Triple, bool, error) {
 	var isSubBNode bool
 	err := binary.Read(r, binary.BigEndian, &isSubBNode)
 	if err == io.EOF {
@@ -156,32 +311,41 @@ func decodeTriple(r io.Reader) (Triple, bool, error) {
 		return nil, false, fmt.Errorf("is subject bnode: %s", err)
 	}
 
-	sub, err := readWord(r)
+	sub, err := readWord(r, maxTermSize)
 	if err != nil {
 		return nil, false, fmt.Errorf("subject: %s", err)
 	}
 
-	pred, err := readWord(r)
+	pred, err := readWord(r, maxTermSize)
 	if err != nil {
 		return nil, false, fmt.Errorf("predicate: %s", err)
 	}
 
+	decodedObj, err := decodeBinObject(r, maxTermSize)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return makeTriple(string(sub), isSubBNode, string(pred), decodedObj), false, nil
+}
+
+func decodeBinObject(r io.Reader, maxTermSize int) (object, error) {
 	var objType uint8
 	if err := binary.Read(r, binary.BigEndian, &objType); err != nil {
-		return nil, false, fmt.Errorf("object type: %s", err)
+		return object{}, fmt.Errorf("object type: %s", err)
 	}
 
 	var decodedObj object
 	if objType == resourceTypeEncoding {
-		resource, err := readWord(r)
+		resource, err := readWord(r, maxTermSize)
 		if err != nil {
-			return nil, false, fmt.Errorf("resource: %s", err)
+			return object{}, fmt.Errorf("resource: %s", err)
 		}
 		decodedObj.resource = string(resource)
 	} else if objType == bnodeTypeEncoding {
-		bnode, err := readWord(r)
+		bnode, err := readWord(r, maxTermSize)
 		if err != nil {
-			return nil, false, fmt.Errorf("bnode object: %s", err)
+			return object{}, fmt.Errorf("bnode object: %s", err)
 		}
 		decodedObj.bnode = string(bnode)
 		decodedObj.isBnode = true
@@ -190,22 +354,22 @@ func decodeTriple(r io.Reader) (Triple, bool, error) {
 		var decodedLiteral literal
 
 		if objType == literalWithLangEncoding {
-			lang, err := readWord(r)
+			lang, err := readWord(r, maxTermSize)
 			if err != nil {
-				return nil, false, fmt.Errorf("lang: %s", err)
+				return object{}, fmt.Errorf("lang: %s", err)
 			}
 			decodedLiteral.langtag = string(lang)
 		} else {
-			litType, err := readWord(r)
+			litType, err := readWord(r, maxTermSize)
 			if err != nil {
-				return nil, false, fmt.Errorf("literate type: %s", err)
+				return object{}, fmt.Errorf("literate type: %s", err)
 			}
 			decodedLiteral.typ = XsdType(litType)
 		}
 
-		val, err := readWord(r)
+		val, err := readWord(r, maxTermSize)
 		if err != nil {
-			return nil, false, fmt.Errorf("literate: %s", err)
+			return object{}, fmt.Errorf("literate: %s", err)
 		}
 		if decodedLiteral.typ == XsdString || objType == literalWithLangEncoding {
 			decodedLiteral.val = unescapeStringLiteral(string(val))
@@ -215,20 +379,17 @@ func decodeTriple(r io.Reader) (Triple, bool, error) {
 
 		decodedObj.lit = decodedLiteral
 	}
-
-	return &triple{
-		isSubBnode: isSubBNode,
-		sub:        string(sub),
-		pred:       string(pred),
-		obj:        decodedObj,
-	}, false, nil
+	return decodedObj, nil
 }
 
-func readWord(r io.Reader) ([]byte, error) {
+func readWord(r io.Reader, maxTermSize int) ([]byte, error) {
 	var len wordLength
 	if err := binary.Read(r, binary.BigEndian, &len); err != nil {
 		return nil, err
 	}
+	if maxTermSize > 0 && int(len) > maxTermSize {
+		return nil, fmt.Errorf("triplestore: binary: word of length %d bytes exceeds maximum term size of %d bytes", len, maxTermSize)
+	}
 
 	word := make([]byte, len)
 	if _, err := io.ReadFull(r, word); err != nil {
@@ -239,13 +400,21 @@ func readWord(r io.Reader) ([]byte, error) {
 }
 
 type datasetDecoder struct {
+	ctx            context.Context
 	newDecoderFunc func(io.Reader) Decoder
 	rs             []io.Reader
 }
 
 // NewDatasetDecoder - a dataset is a basically a collection of RDFGraph.
 func NewDatasetDecoder(fn func(io.Reader) Decoder, readers ...io.Reader) Decoder {
-	return &datasetDecoder{newDecoderFunc: fn, rs: readers}
+	return NewDatasetDecoderContext(context.Background(), fn, readers...)
+}
+
+// NewDatasetDecoderContext is like NewDatasetDecoder but decoding is aborted
+// when the context is done: no further reader is decoded, reads of pending
+// readers fail and Decode returns the context error.
+func NewDatasetDecoderContext(ctx context.Context, fn func(io.Reader) Decoder, readers ...io.Reader) Decoder {
+	return &datasetDecoder{ctx: ctx, newDecoderFunc: fn, rs: readers}
 }
 
 func (dec *datasetDecoder) Decode() ([]Triple, error) {
@@ -256,20 +425,17 @@ func (dec *datasetDecoder) Decode() ([]Triple, error) {
 	}
 
 	results := make(chan *result, len(dec.rs))
-	done := make(chan struct{})
-	defer close(done)
 
 	var wg sync.WaitGroup
 	for _, reader := range dec.rs {
+		if dec.ctx.Err() != nil {
+			break
+		}
 		wg.Add(1)
 		go func(r io.Reader) {
 			defer wg.Done()
-			tris, err := dec.newDecoderFunc(r).Decode()
-			select {
-			case results <- &result{tris: tris, err: err, reader: r}:
-			case <-done:
-				return
-			}
+			tris, err := dec.newDecoderFunc(&contextReader{ctx: dec.ctx, r: r}).Decode()
+			results <- &result{tris: tris, err: err, reader: r}
 		}(reader)
 	}
 
@@ -279,19 +445,41 @@ func (dec *datasetDecoder) Decode() ([]Triple, error) {
 	}()
 
 	var all []Triple
-	for r := range results {
-		if r.err != nil {
-			switch rr := r.reader.(type) {
-			case *os.File:
-				return all, fmt.Errorf("file '%s': %s", rr.Name(), r.err)
-			default:
-				return all, r.err
+	for {
+		select {
+		case <-dec.ctx.Done():
+			return all, dec.ctx.Err()
+		case r, ok := <-results:
+			if !ok {
+				return all, dec.ctx.Err()
+			}
+			if r.err != nil {
+				if err := dec.ctx.Err(); err != nil {
+					return all, err
+				}
+				switch rr := r.reader.(type) {
+				case *os.File:
+					return all, fmt.Errorf("file '%s': %s", rr.Name(), r.err)
+				default:
+					return all, r.err
+				}
 			}
+			all = append(all, r.tris...)
 		}
-		all = append(all, r.tris...)
 	}
+}
+
+// contextReader fails reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
 
-	return all, nil
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
 
 var unescaper = strings.NewReplacer("\\n", "\n", "\\r", "\r")
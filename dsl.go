@@ -1,35 +1,30 @@
 package triplestore
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
 func SubjPredRes(s, p, r string) *triple {
-	return &triple{
-		sub: s, pred: p,
-		obj: Resource(r).(object),
-	}
+	return makeTriple(s, false, p, Resource(r).(object))
 }
 
 func BnodePredRes(s, p, r string) *triple {
-	return &triple{
-		isSubBnode: true,
-		sub:        s,
-		pred:       p,
-		obj:        Resource(r).(object),
-	}
+	return makeTriple(s, true, p, Resource(r).(object))
 }
 
 func SubjPredBnode(s, p, r string) *triple {
-	return &triple{
-		sub:  s,
-		pred: p,
-		obj:  object{bnode: r, isBnode: true},
-	}
+	return makeTriple(s, false, p, object{bnode: r, isBnode: true})
 }
 
 func SubjPredLit(s, p string, l interface{}) (*triple, error) {
@@ -37,11 +32,7 @@ func SubjPredLit(s, p string, l interface{}) (*triple, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &triple{
-		sub:  s,
-		pred: p,
-		obj:  o.(object),
-	}, nil
+	return makeTriple(s, false, p, o.(object)), nil
 }
 
 type tripleBuilder struct {
@@ -62,36 +53,42 @@ func Resource(s string) Object {
 	return object{resource: s}
 }
 
+// Bnode creates a blank node object with the given id
+func Bnode(id string) Object {
+	return object{bnode: id, isBnode: true}
+}
+
+// TypedLiteral creates a literal of the given lexical value and datatype without
+// validation. XML Schema datatypes given as full IRIs are shortened
+// (ex: "http://www.w3.org/2001/XMLSchema#integer" becomes "xsd:integer").
+func TypedLiteral(value, datatype string) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: shortXsdType(XsdType(datatype)), val: value},
+	}
+}
+
+// LangLiteral creates a string literal tagged with the given language
+// (same as StringLiteralWithLang)
+func LangLiteral(value, lang string) Object {
+	return StringLiteralWithLang(value, lang)
+}
+
 func (b *tripleBuilder) Lang(l string) *tripleBuilder {
 	b.langtag = l
 	return b
 }
 
 func (b *tripleBuilder) Resource(s string) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        Resource(s).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Resource(s).(object))
 }
 
 func (b *tripleBuilder) Object(o Object) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        o.(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, o.(object))
 }
 
 func (b *tripleBuilder) Bnode(s string) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        object{bnode: s, isBnode: true},
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Bnode(s).(object))
 }
 
 type UnsupportedLiteralTypeError struct {
@@ -103,6 +100,9 @@ func (e UnsupportedLiteralTypeError) Error() string {
 }
 
 func ObjectLiteral(i interface{}) (Object, error) {
+	if typ, dt, ok := lookupDatatypeByGoType(reflect.TypeOf(i)); ok {
+		return customLiteral(typ, dt, i)
+	}
 	switch ii := i.(type) {
 	case string:
 		return StringLiteral(ii), nil
@@ -134,8 +134,47 @@ func ObjectLiteral(i interface{}) (Object, error) {
 		return DateTimeLiteral(ii), nil
 	case *time.Time:
 		return DateTimeLiteral(*ii), nil
+	case time.Duration:
+		return DurationLiteral(ii), nil
+	case []byte:
+		return Base64BinaryLiteral(ii), nil
+	case *big.Float:
+		if ii.IsInf() {
+			return nil, fmt.Errorf("%s cannot hold infinite value %s", XsdDecimal, ii)
+		}
+		return BigDecimalLiteral(ii), nil
 	case fmt.Stringer:
 		return StringLiteral(ii.String()), nil
+	default:
+		return objectLiteralFromKind(i)
+	}
+}
+
+// objectLiteralFromKind converts named types (ex: type Celsius float64)
+// according to their underlying kind
+func objectLiteralFromKind(i interface{}) (Object, error) {
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.String:
+		return StringLiteral(v.String()), nil
+	case reflect.Bool:
+		return BooleanLiteral(v.Bool()), nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return IntegerLiteral(int(v.Int())), nil
+	case reflect.Int16:
+		return Int16Literal(int16(v.Int())), nil
+	case reflect.Int8:
+		return Int8Literal(int8(v.Int())), nil
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return UintegerLiteral(uint(v.Uint())), nil
+	case reflect.Uint16:
+		return Uint16Literal(uint16(v.Uint())), nil
+	case reflect.Uint8:
+		return Uint8Literal(uint8(v.Uint())), nil
+	case reflect.Float32:
+		return Float32Literal(float32(v.Float())), nil
+	case reflect.Float64:
+		return Float64Literal(v.Float()), nil
 	default:
 		return nil, UnsupportedLiteralTypeError{i}
 	}
@@ -148,6 +187,18 @@ func ParseLiteral(obj Object) (interface{}, error) {
 			return ParseBoolean(obj)
 		case XsdDateTime:
 			return ParseDateTime(obj)
+		case XsdDate:
+			return ParseDate(obj)
+		case XsdTime:
+			return ParseTime(obj)
+		case XsdDuration:
+			return ParseDuration(obj)
+		case XsdBase64Binary, XsdHexBinary:
+			return ParseBinary(obj)
+		case XsdDecimal:
+			return ParseDecimal(obj)
+		case XsdGYear, XsdGYearMonth, XsdGMonthDay, XsdGMonth, XsdGDay:
+			return parseGregorian(obj, lit.Type())
 		case XsdInteger:
 			return ParseInteger(obj)
 		case XsdByte:
@@ -167,7 +218,10 @@ func ParseLiteral(obj Object) (interface{}, error) {
 		case XsdString:
 			return ParseString(obj)
 		default:
-			return nil, fmt.Errorf("unknown literal type: %s", lit.Type())
+			if dt, ok := LookupDatatype(lit.Type()); ok {
+				return dt.parse(lit.Type(), lit.Value())
+			}
+			return nil, unknownLiteralTypeError(lit.Type())
 		}
 	}
 	return nil, errors.New("cannot parse literal: object is not literal")
@@ -181,12 +235,7 @@ func BooleanLiteral(bl bool) Object {
 }
 
 func (b *tripleBuilder) BooleanLiteral(bl bool) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        BooleanLiteral(bl).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, BooleanLiteral(bl).(object))
 }
 
 func ParseBoolean(obj Object) (bool, error) {
@@ -209,12 +258,7 @@ func IntegerLiteral(i int) Object {
 }
 
 func (b *tripleBuilder) IntegerLiteral(i int) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        IntegerLiteral(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, IntegerLiteral(i).(object))
 }
 
 func ParseInteger(obj Object) (int, error) {
@@ -237,12 +281,7 @@ func Int8Literal(i int8) Object {
 }
 
 func (b *tripleBuilder) Int8Literal(i int8) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        Int8Literal(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Int8Literal(i).(object))
 }
 
 func ParseInt8(obj Object) (int8, error) {
@@ -269,12 +308,7 @@ func Int16Literal(i int16) Object {
 }
 
 func (b *tripleBuilder) Int16Literal(i int16) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        Int16Literal(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Int16Literal(i).(object))
 }
 
 func ParseInt16(obj Object) (int16, error) {
@@ -301,12 +335,7 @@ func UintegerLiteral(i uint) Object {
 }
 
 func (b *tripleBuilder) UintegerLiteral(i uint) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        UintegerLiteral(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, UintegerLiteral(i).(object))
 }
 
 func ParseUinteger(obj Object) (uint, error) {
@@ -333,12 +362,7 @@ func Uint8Literal(i uint8) Object {
 }
 
 func (b *tripleBuilder) Uint8(i uint8) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        Uint8Literal(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Uint8Literal(i).(object))
 }
 
 func ParseUint8(obj Object) (uint8, error) {
@@ -365,12 +389,7 @@ func Uint16Literal(i uint16) Object {
 }
 
 func (b *tripleBuilder) Uint16(i uint16) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        Uint16Literal(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Uint16Literal(i).(object))
 }
 
 func ParseUint16(obj Object) (uint16, error) {
@@ -389,20 +408,17 @@ func ParseUint16(obj Object) (uint16, error) {
 	return 0, fmt.Errorf("cannot parse %s: object is not literal", XsdUnsignedShort)
 }
 
+// Float64Literal creates a xsd:double literal using the canonical lexical form
+// (ex: 1.5E0, -1.0E-3, INF, -INF, NaN)
 func Float64Literal(i float64) Object {
 	return object{
 		isLit: true,
-		lit:   literal{typ: XsdDouble, val: fmt.Sprint(i)},
+		lit:   literal{typ: XsdDouble, val: formatXsdFloat(i, 64)},
 	}
 }
 
 func (b *tripleBuilder) Float64Literal(i float64) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        Float64Literal(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Float64Literal(i).(object))
 }
 
 func ParseFloat64(obj Object) (float64, error) {
@@ -417,20 +433,17 @@ func ParseFloat64(obj Object) (float64, error) {
 	return 0, fmt.Errorf("cannot parse %s: object is not literal", XsdDouble)
 }
 
+// Float32Literal creates a xsd:float literal using the canonical lexical form
+// (ex: 1.5E0, -1.0E-3, INF, -INF, NaN)
 func Float32Literal(i float32) Object {
 	return object{
 		isLit: true,
-		lit:   literal{typ: XsdFloat, val: fmt.Sprint(i)},
+		lit:   literal{typ: XsdFloat, val: formatXsdFloat(float64(i), 32)},
 	}
 }
 
 func (b *tripleBuilder) Float32Literal(i float32) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        Float32Literal(i).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Float32Literal(i).(object))
 }
 
 func ParseFloat32(obj Object) (float32, error) {
@@ -449,6 +462,49 @@ func ParseFloat32(obj Object) (float32, error) {
 	return 0, fmt.Errorf("cannot parse %s: object is not literal", XsdFloat)
 }
 
+// ParseFloat parses either a xsd:double or a xsd:float literal
+func ParseFloat(obj Object) (float64, error) {
+	if lit, ok := obj.Literal(); ok {
+		switch lit.Type() {
+		case XsdDouble:
+			return strconv.ParseFloat(lit.Value(), 64)
+		case XsdFloat:
+			return strconv.ParseFloat(lit.Value(), 32)
+		default:
+			return 0, fmt.Errorf("literal is not an %s or %s but %s", XsdDouble, XsdFloat, lit.Type())
+		}
+	}
+
+	return 0, fmt.Errorf("cannot parse %s: object is not literal", XsdDouble)
+}
+
+func formatXsdFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+
+	formatted := strconv.FormatFloat(f, 'E', -1, bitSize)
+	splits := strings.SplitN(formatted, "E", 2)
+	mantissa, exp := splits[0], splits[1]
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	sign := ""
+	if strings.HasPrefix(exp, "-") {
+		sign = "-"
+	}
+	exp = strings.TrimLeft(exp, "+-0")
+	if exp == "" {
+		return mantissa + "E0"
+	}
+	return mantissa + "E" + sign + exp
+}
+
 func StringLiteral(s string) Object {
 	return object{
 		isLit: true,
@@ -464,21 +520,11 @@ func StringLiteralWithLang(s, l string) Object {
 }
 
 func (b *tripleBuilder) StringLiteral(s string) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        StringLiteral(s).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, StringLiteral(s).(object))
 }
 
 func (b *tripleBuilder) StringLiteralWithLang(s, l string) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        StringLiteralWithLang(s, l).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, StringLiteralWithLang(s, l).(object))
 }
 
 func ParseString(obj Object) (string, error) {
@@ -506,12 +552,7 @@ func DateTimeLiteral(tm time.Time) Object {
 }
 
 func (b *tripleBuilder) DateTimeLiteral(tm time.Time) *triple {
-	return &triple{
-		isSubBnode: b.isSubBnode,
-		sub:        b.sub,
-		pred:       b.pred,
-		obj:        DateTimeLiteral(tm).(object),
-	}
+	return makeTriple(b.sub, b.isSubBnode, b.pred, DateTimeLiteral(tm).(object))
 }
 
 func ParseDateTime(obj Object) (time.Time, error) {
@@ -530,3 +571,252 @@ func ParseDateTime(obj Object) (time.Time, error) {
 
 	return t, fmt.Errorf("cannot parse %s: object is not literal", XsdDateTime)
 }
+
+const (
+	xsdDateLayout = "2006-01-02"
+	xsdTimeLayout = "15:04:05.999999999"
+	xsdTZLayout   = "Z07:00"
+
+	xsdDateTimeLayout = xsdDateLayout + "T" + xsdTimeLayout
+)
+
+// DateLiteral creates a xsd:date literal keeping the timezone
+// of the given time (ex: 2017-11-21Z, 2017-11-21+02:00)
+func DateLiteral(tm time.Time) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdDate, val: tm.Format(xsdDateLayout + xsdTZLayout)},
+	}
+}
+
+func (b *tripleBuilder) DateLiteral(tm time.Time) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, DateLiteral(tm).(object))
+}
+
+// ParseDate parses a xsd:date literal. Without timezone the date is considered UTC
+func ParseDate(obj Object) (time.Time, error) {
+	return parseTemporal(obj, XsdDate, xsdDateLayout)
+}
+
+// TimeLiteral creates a xsd:time literal keeping the timezone
+// of the given time (ex: 13:20:00Z, 13:20:00.5+02:00)
+func TimeLiteral(tm time.Time) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdTime, val: tm.Format(xsdTimeLayout + xsdTZLayout)},
+	}
+}
+
+func (b *tripleBuilder) TimeLiteral(tm time.Time) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, TimeLiteral(tm).(object))
+}
+
+// ParseTime parses a xsd:time literal on the zero date (i.e. 0000-01-01).
+// Without timezone the time is considered UTC
+func ParseTime(obj Object) (time.Time, error) {
+	return parseTemporal(obj, XsdTime, xsdTimeLayout)
+}
+
+func parseTemporal(obj Object, typ XsdType, layout string) (time.Time, error) {
+	if lit, ok := obj.Literal(); ok {
+		if lit.Type() != typ {
+			return time.Time{}, fmt.Errorf("literal is not an %s but %s", typ, lit.Type())
+		}
+
+		if t, err := time.Parse(layout+xsdTZLayout, lit.Value()); err == nil {
+			return t, nil
+		}
+		return time.Parse(layout, lit.Value())
+	}
+
+	return time.Time{}, fmt.Errorf("cannot parse %s: object is not literal", typ)
+}
+
+// DurationLiteral creates a xsd:duration literal
+// using the ISO 8601 lexical form (ex: PT1H30M, -PT0.5S)
+func DurationLiteral(d time.Duration) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdDuration, val: formatXsdDuration(d)},
+	}
+}
+
+func (b *tripleBuilder) DurationLiteral(d time.Duration) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, DurationLiteral(d).(object))
+}
+
+// ParseDuration parses a xsd:duration literal. Days are considered to last 24 hours,
+// while years and months having no fixed duration are rejected
+func ParseDuration(obj Object) (time.Duration, error) {
+	if lit, ok := obj.Literal(); ok {
+		if lit.Type() != XsdDuration {
+			return 0, fmt.Errorf("literal is not an %s but %s", XsdDuration, lit.Type())
+		}
+
+		return parseXsdDuration(lit.Value())
+	}
+
+	return 0, fmt.Errorf("cannot parse %s: object is not literal", XsdDuration)
+}
+
+func formatXsdDuration(d time.Duration) string {
+	var buf bytes.Buffer
+	if d < 0 {
+		buf.WriteByte('-')
+		d = -d
+	}
+	buf.WriteString("PT")
+	if d == 0 {
+		buf.WriteString("0S")
+		return buf.String()
+	}
+	if h := d / time.Hour; h > 0 {
+		buf.WriteString(strconv.FormatInt(int64(h), 10) + "H")
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		buf.WriteString(strconv.FormatInt(int64(m), 10) + "M")
+		d -= m * time.Minute
+	}
+	if d > 0 {
+		buf.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S")
+	}
+	return buf.String()
+}
+
+// xsdDurationRegexp matches the xsd:duration lexical space: fixed ordered
+// components, a fraction being only allowed for seconds
+var xsdDurationRegexp = regexp.MustCompile(`^-?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d*)?|\.\d+)S)?)?$`)
+
+func parseXsdDuration(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid %s '%s'", XsdDuration, s)
+
+	m := xsdDurationRegexp.FindStringSubmatch(s)
+	// at least one component, and one after T
+	if m == nil || strings.HasSuffix(s, "P") || strings.HasSuffix(s, "T") {
+		return 0, invalid
+	}
+	if (m[1] != "" && strings.Trim(m[1], "0") != "") || (m[2] != "" && strings.Trim(m[2], "0") != "") {
+		return 0, fmt.Errorf("%s '%s': years and months have no fixed duration", XsdDuration, s)
+	}
+
+	var d time.Duration
+	secs := strings.SplitN(m[6], ".", 2)
+	for _, c := range []struct {
+		digits string
+		unit   time.Duration
+	}{{m[3], 24 * time.Hour}, {m[4], time.Hour}, {m[5], time.Minute}, {secs[0], time.Second}} {
+		if c.digits == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(c.digits, 10, 64)
+		if err != nil || n > (math.MaxInt64-int64(d))/int64(c.unit) {
+			return 0, fmt.Errorf("%s '%s' overflows time.Duration", XsdDuration, s)
+		}
+		d += time.Duration(n) * c.unit
+	}
+	if len(secs) == 2 && secs[1] != "" {
+		// nanoseconds, finer digits being truncated
+		frac := (secs[1] + "000000000")[:9]
+		n, _ := strconv.ParseInt(frac, 10, 64)
+		if n > math.MaxInt64-int64(d) {
+			return 0, fmt.Errorf("%s '%s' overflows time.Duration", XsdDuration, s)
+		}
+		d += time.Duration(n)
+	}
+
+	if strings.HasPrefix(s, "-") {
+		d = -d
+	}
+	return d, nil
+}
+
+func Base64BinaryLiteral(b []byte) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdBase64Binary, val: base64.StdEncoding.EncodeToString(b)},
+	}
+}
+
+func (b *tripleBuilder) Base64BinaryLiteral(bin []byte) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, Base64BinaryLiteral(bin).(object))
+}
+
+func HexBinaryLiteral(b []byte) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdHexBinary, val: strings.ToUpper(hex.EncodeToString(b))},
+	}
+}
+
+func (b *tripleBuilder) HexBinaryLiteral(bin []byte) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, HexBinaryLiteral(bin).(object))
+}
+
+// ParseBinary decodes either a xsd:base64Binary or a xsd:hexBinary literal
+func ParseBinary(obj Object) ([]byte, error) {
+	if lit, ok := obj.Literal(); ok {
+		switch lit.Type() {
+		case XsdBase64Binary:
+			return base64.StdEncoding.DecodeString(lit.Value())
+		case XsdHexBinary:
+			return hex.DecodeString(lit.Value())
+		default:
+			return nil, fmt.Errorf("literal is not an %s or %s but %s", XsdBase64Binary, XsdHexBinary, lit.Type())
+		}
+	}
+
+	return nil, fmt.Errorf("cannot parse %s: object is not literal", XsdBase64Binary)
+}
+
+// DecimalLiteral creates a xsd:decimal literal from its lexical form (ex: "-12.50"),
+// preserving its exact value
+func DecimalLiteral(s string) (Object, error) {
+	canonical, ok := canonicalDecimal(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s '%s'", XsdDecimal, s)
+	}
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdDecimal, val: canonical},
+	}, nil
+}
+
+// BigDecimalLiteral creates a xsd:decimal literal holding
+// the exact value of the given arbitrary precision number.
+// It panics if the number is infinite, xsd:decimal having no infinity.
+func BigDecimalLiteral(f *big.Float) Object {
+	if f.IsInf() {
+		panic(fmt.Errorf("decimal literal: %s cannot hold infinite value %s", XsdDecimal, f))
+	}
+	canonical, _ := canonicalDecimal(f.Text('f', -1))
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdDecimal, val: canonical},
+	}
+}
+
+func (b *tripleBuilder) BigDecimalLiteral(f *big.Float) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, BigDecimalLiteral(f).(object))
+}
+
+// ParseDecimal parses a xsd:decimal literal into an arbitrary precision number
+// with enough precision to hold all its significant digits
+func ParseDecimal(obj Object) (*big.Float, error) {
+	if lit, ok := obj.Literal(); ok {
+		if lit.Type() != XsdDecimal {
+			return nil, fmt.Errorf("literal is not an %s but %s", XsdDecimal, lit.Type())
+		}
+
+		canonical, ok := canonicalDecimal(lit.Value())
+		if !ok {
+			return nil, fmt.Errorf("invalid %s '%s'", XsdDecimal, lit.Value())
+		}
+		// ~3.33 bits per decimal digit
+		prec := uint(len(canonical))*4 + 64
+		f, _, err := big.ParseFloat(canonical, 10, prec, big.ToNearestEven)
+		return f, err
+	}
+
+	return nil, fmt.Errorf("cannot parse %s: object is not literal", XsdDecimal)
+}
@@ -1,6 +1,9 @@
 package triplestore
 
 import (
+	"math"
+	"math/big"
+	"strings"
 	"testing"
 	"time"
 )
@@ -84,6 +87,40 @@ func TestBuildTripleWithBnode(t *testing.T) {
 	})
 }
 
+func TestObjectConstructors(t *testing.T) {
+	tcases := []struct {
+		obj Object
+		exp object
+	}{
+		// Resource already existed, checked along with the constructors added next to it
+		{Resource("me"), object{resource: "me"}},
+		{Bnode("b1"), object{bnode: "b1", isBnode: true}},
+		{TypedLiteral("2", "xsd:integer"), object{isLit: true, lit: literal{typ: XsdInteger, val: "2"}}},
+		{TypedLiteral("2", "http://www.w3.org/2001/XMLSchema#integer"), object{isLit: true, lit: literal{typ: XsdInteger, val: "2"}}},
+		{TypedLiteral("2", "http://ex.com/myint"), object{isLit: true, lit: literal{typ: "http://ex.com/myint", val: "2"}}},
+		{LangLiteral("chat", "fr"), object{isLit: true, lit: literal{typ: XsdString, val: "chat", langtag: "fr"}}},
+	}
+	for i, tc := range tcases {
+		if got, want := tc.obj, tc.exp; !got.Equal(want) {
+			t.Fatalf("case %d: got %v, want %v", i+1, got, want)
+		}
+	}
+
+	if got, want := SubjPred("me", "age").Object(TypedLiteral("32", "xsd:integer")), SubjPred("me", "age").IntegerLiteral(32); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	lit, _ := TypedLiteral("1", "http://www.w3.org/2001/XMLSchema#integer").Literal()
+	if got, want := lit.Type(), XsdInteger; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := TypedLiteral("1", "http://www.w3.org/2001/XMLSchema#integer"), IntegerLiteral(1); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := SubjPred("me", "knows").Object(Bnode("b1")), SubjPred("me", "knows").Bnode("b1"); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
 func TestBuildTriple(t *testing.T) {
 	tri := SubjPred("subject", "predicate").StringLiteral("any")
 	if got, want := tri.Subject(), "subject"; got != want {
@@ -314,3 +351,294 @@ func TestObjectHasResource(t *testing.T) {
 		t.Fatalf("got %t, want %t", got, want)
 	}
 }
+
+func TestDoubleAndFloatLiterals(t *testing.T) {
+	type celsius float32
+	objectLiteral := func(i interface{}) Object {
+		o, err := ObjectLiteral(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+	tcases := []struct {
+		in  Object
+		typ XsdType
+		val string
+	}{
+		{Float64Literal(1.5), XsdDouble, "1.5E0"},
+		{Float64Literal(100), XsdDouble, "1.0E2"},
+		{Float64Literal(-0.001), XsdDouble, "-1.0E-3"},
+		{Float64Literal(0), XsdDouble, "0.0E0"},
+		{Float64Literal(1e21), XsdDouble, "1.0E21"},
+		{Float64Literal(math.Inf(1)), XsdDouble, "INF"},
+		{Float64Literal(math.Inf(-1)), XsdDouble, "-INF"},
+		{Float64Literal(math.NaN()), XsdDouble, "NaN"},
+		{Float32Literal(2.25), XsdFloat, "2.25E0"},
+		{Float32Literal(float32(math.Inf(1))), XsdFloat, "INF"},
+		{Float32Literal(0.1), XsdFloat, "1.0E-1"},
+		{objectLiteral(1.5), XsdDouble, "1.5E0"},
+		{objectLiteral(float32(2.25)), XsdFloat, "2.25E0"},
+		{objectLiteral(celsius(-40)), XsdFloat, "-4.0E1"},
+	}
+
+	for _, tc := range tcases {
+		lit, _ := tc.in.Literal()
+		if got, want := lit.Type(), tc.typ; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+		if got, want := lit.Value(), tc.val; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	tri := SubjPred("sensor", "temp").Float64Literal(21.5)
+	f, err := ParseFloat(tri.Object())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f, 21.5; got != want {
+		t.Fatalf("got %f, want %f", got, want)
+	}
+
+	f, err = ParseFloat(SubjPred("sensor", "temp").Float32Literal(-2.5).Object())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f, -2.5; got != want {
+		t.Fatalf("got %f, want %f", got, want)
+	}
+
+	for _, o := range []Object{Float64Literal(math.Inf(-1)), Float64Literal(math.NaN())} {
+		f, err := ParseFloat(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !math.IsInf(f, -1) && !math.IsNaN(f) {
+			t.Fatalf("unexpected %f", f)
+		}
+	}
+
+	if _, err := ParseFloat(IntegerLiteral(1)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDateAndTimeLiterals(t *testing.T) {
+	paris := time.FixedZone("CET", 3600)
+	tm := time.Date(2017, 11, 21, 13, 20, 5, 500000000, paris)
+
+	tcases := []struct {
+		in  Object
+		typ XsdType
+		val string
+	}{
+		{DateLiteral(tm), XsdDate, "2017-11-21+01:00"},
+		{DateLiteral(tm.UTC()), XsdDate, "2017-11-21Z"},
+		{TimeLiteral(tm), XsdTime, "13:20:05.5+01:00"},
+		{TimeLiteral(tm.UTC()), XsdTime, "12:20:05.5Z"},
+	}
+	for _, tc := range tcases {
+		lit, _ := tc.in.Literal()
+		if got, want := lit.Type(), tc.typ; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+		if got, want := lit.Value(), tc.val; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	date, err := ParseDate(SubjPred("me", "birth").DateLiteral(tm).Object())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := date, time.Date(2017, 11, 21, 0, 0, 0, 0, paris); !got.Equal(want) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	parsed, err := ParseLiteral(SubjPred("me", "wakeup").TimeLiteral(tm).Object())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := parsed.(time.Time)
+	if clock.Hour() != 13 || clock.Minute() != 20 || clock.Second() != 5 || clock.Nanosecond() != 500000000 {
+		t.Fatalf("unexpected time %s", clock)
+	}
+	if _, offset := clock.Zone(); offset != 3600 {
+		t.Fatalf("got offset %d, want 3600", offset)
+	}
+
+	noTZ := object{isLit: true, lit: literal{typ: XsdDate, val: "2017-11-21"}}
+	date, err = ParseDate(noTZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := date, time.Date(2017, 11, 21, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if _, err := ParseDate(DateTimeLiteral(tm)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDurationLiteral(t *testing.T) {
+	tcases := []struct {
+		in  time.Duration
+		val string
+	}{
+		{0, "PT0S"},
+		{90 * time.Minute, "PT1H30M"},
+		{-500 * time.Millisecond, "-PT0.5S"},
+		{26*time.Hour + 5*time.Second, "PT26H5S"},
+		{time.Minute + 1500*time.Microsecond, "PT1M0.0015S"},
+	}
+	for _, tc := range tcases {
+		lit, _ := DurationLiteral(tc.in).Literal()
+		if got, want := lit.Type(), XsdDuration; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+		if got, want := lit.Value(), tc.val; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+		d, err := ParseDuration(SubjPred("me", "timeout").DurationLiteral(tc.in).Object())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d, tc.in; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	parsed := []struct {
+		val string
+		exp time.Duration
+	}{
+		{"P1D", 24 * time.Hour},
+		{"P1DT2H", 26 * time.Hour},
+		{"P0Y0M1D", 24 * time.Hour},
+		{"-P1DT1M", -24*time.Hour - time.Minute},
+		{"PT1.S", time.Second},
+		{"PT.5S", 500 * time.Millisecond},
+		{"PT0.0000000019S", time.Nanosecond},
+		{"PT2562047H47M16.854775807S", math.MaxInt64},
+	}
+	for _, tc := range parsed {
+		d, err := ParseDuration(object{isLit: true, lit: literal{typ: XsdDuration, val: tc.val}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d, tc.exp; got != want {
+			t.Fatalf("%s: got %s, want %s", tc.val, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "P", "PT", "-P", "1H", "PT1D", "P1H", "P1Y", "PTxS", "P1DT", "PT1.5M", "PT1e3S", "PT+1S", "+PT1S", "PT1H1H", "PT1S1M", "P1DT-1H", "PT.S", " PT1S"} {
+		if _, err := ParseDuration(object{isLit: true, lit: literal{typ: XsdDuration, val: invalid}}); err == nil {
+			t.Fatalf("%s: expected error", invalid)
+		}
+	}
+
+	for _, overflow := range []string{"PT9999999999999H", "P106752D", "PT2562047H47M16.854775808S", "PT99999999999999999999S"} {
+		_, err := ParseDuration(object{isLit: true, lit: literal{typ: XsdDuration, val: overflow}})
+		if err == nil || !strings.Contains(err.Error(), "overflows") {
+			t.Fatalf("%s: got %v, want overflow error", overflow, err)
+		}
+	}
+}
+
+func TestBinaryLiterals(t *testing.T) {
+	data := []byte("hello\x00world")
+
+	lit, _ := Base64BinaryLiteral(data).Literal()
+	if got, want := lit.Value(), "aGVsbG8Ad29ybGQ="; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	lit, _ = HexBinaryLiteral([]byte{0xca, 0xfe}).Literal()
+	if got, want := lit.Value(), "CAFE"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	for _, tri := range []Triple{
+		SubjPred("file", "hash").Base64BinaryLiteral(data),
+		SubjPred("file", "hash").HexBinaryLiteral(data),
+	} {
+		b, err := ParseBinary(tri.Object())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(b), string(data); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	obj, err := ObjectLiteral(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := obj, Base64BinaryLiteral(data); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if _, err := ParseBinary(StringLiteral("CAFE")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDecimalLiteral(t *testing.T) {
+	obj, err := DecimalLiteral("+0012345678901234567890.1234567890123456789000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lit, _ := obj.Literal()
+	if got, want := lit.Type(), XsdDecimal; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := lit.Value(), "12345678901234567890.1234567890123456789"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	f, err := ParseDecimal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.Text('f', 19), "12345678901234567890.1234567890123456789"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	parsed, err := ParseLiteral(SubjPred("invoice", "amount").BigDecimalLiteral(big.NewFloat(12.5)).Object())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parsed.(*big.Float).String(), "12.5"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	obj, err = ObjectLiteral(big.NewFloat(-0.25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lit, _ := obj.Literal(); lit.Value() != "-0.25" || lit.Type() != XsdDecimal {
+		t.Fatalf("unexpected literal %v", lit)
+	}
+	if _, err = ObjectLiteral(*big.NewFloat(-0.25)); err == nil {
+		t.Fatal("expected error for big.Float value, only *big.Float being supported")
+	}
+	if _, err = ObjectLiteral(new(big.Float).SetInf(false)); err == nil {
+		t.Fatal("expected error for infinite value")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic for infinite value")
+			}
+		}()
+		BigDecimalLiteral(new(big.Float).SetInf(true))
+	}()
+
+	for _, invalid := range []string{"", "abc", "1.2.3", "1e10", "."} {
+		if _, err := DecimalLiteral(invalid); err == nil {
+			t.Fatalf("%s: expected error", invalid)
+		}
+	}
+}
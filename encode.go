@@ -3,11 +3,12 @@ package triplestore
 import (
 	"bytes"
 	"context"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type Encoder interface {
@@ -18,6 +19,31 @@ type StreamEncoder interface {
 	StreamEncode(context.Context, <-chan Triple) error
 }
 
+// BufferEncoder encodes triples into a caller provided buffer rather than
+// a writer. Binary and N-Triples encoders implement it.
+type BufferEncoder interface {
+	EncodeTo(buf []byte, tris ...Triple) ([]byte, error)
+}
+
+// maxPooledBufferSize avoids keeping large buffers alive in the pool
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
 func NewContext() *Context {
 	return &Context{Prefixes: make(map[string]string)}
 }
@@ -45,29 +71,38 @@ const (
 )
 
 type binaryEncoder struct {
-	w io.Writer
+	w        io.Writer
+	c        *Context
+	sorted   bool
+	progress func(Progress)
 }
 
 func NewBinaryStreamEncoder(w io.Writer) StreamEncoder {
-	return &binaryEncoder{w}
+	return &binaryEncoder{w: w}
 }
 
 func NewBinaryEncoder(w io.Writer) Encoder {
-	return &binaryEncoder{w}
+	return &binaryEncoder{w: w}
 }
 
 func (enc *binaryEncoder) StreamEncode(ctx context.Context, triples <-chan Triple) error {
 	if triples == nil {
 		return nil
 	}
-	var buf bytes.Buffer
+	if enc.sorted {
+		return enc.Encode(collectTriples(ctx, triples)...)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	tracker := newProgressTracker(enc.progress)
 	for {
 		select {
 		case tri, ok := <-triples:
 			if !ok {
+				tracker.done()
 				return nil
 			}
-			if err := enc.writeTriple(tri, &buf); err != nil {
+			if err := enc.writeTriple(tri, buf, tracker); err != nil {
 				return err
 			}
 		case <-ctx.Done():
@@ -77,79 +112,181 @@ func (enc *binaryEncoder) StreamEncode(ctx context.Context, triples <-chan Tripl
 }
 
 func (enc *binaryEncoder) Encode(tris ...Triple) error {
-	var buf bytes.Buffer
+	if enc.sorted {
+		tris = sortedTriples(tris)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	tracker := newProgressTracker(enc.progress)
 	for _, t := range tris {
-		if err := enc.writeTriple(t, &buf); err != nil {
+		if err := enc.writeTriple(t, buf, tracker); err != nil {
 			return err
 		}
 	}
+	tracker.done()
 	return nil
 }
 
-func (enc *binaryEncoder) writeTriple(t Triple, buf *bytes.Buffer) error {
+// EncodeTo appends the binary encoding of the triples to buf
+func (enc *binaryEncoder) EncodeTo(buf []byte, tris ...Triple) ([]byte, error) {
+	buff := bytes.NewBuffer(buf)
+	for _, t := range expandTriples(enc.c, tris) {
+		if err := encodeBinTriple(t, buff); err != nil {
+			return buf, err
+		}
+	}
+	return buff.Bytes(), nil
+}
+
+func (enc *binaryEncoder) writeTriple(t Triple, buf *bytes.Buffer, tracker *progressTracker) error {
+	if enc.c != nil {
+		t = expandTriples(enc.c, []Triple{t})[0]
+	}
 	if err := encodeBinTriple(t, buf); err != nil {
 		return err
 	}
 	if _, err := enc.w.Write(buf.Bytes()); err != nil {
 		return err
 	}
+	tracker.addBytes(buf.Len())
+	tracker.addTriple()
 	buf.Reset()
 	return nil
 }
 
 func encodeBinTriple(t Triple, buff *bytes.Buffer) error {
-	sub, pred := t.Subject(), t.Predicate()
+	tt := t.(*triple)
 
-	binary.Write(buff, binary.BigEndian, t.(*triple).isSubBnode)
-
-	binary.Write(buff, binary.BigEndian, wordLength(len(sub)))
-	buff.WriteString(sub)
+	if tt.isSubBnode {
+		buff.WriteByte(1)
+	} else {
+		buff.WriteByte(0)
+	}
+	writeWord(buff, tt.sub)
+	writeWord(buff, tt.pred)
+	encodeBinObject(tt.obj, buff)
 
-	binary.Write(buff, binary.BigEndian, wordLength(len(pred)))
-	buff.WriteString(pred)
+	return nil
+}
 
-	obj := t.Object()
-	if lit, isLit := obj.Literal(); isLit {
-		if lang := lit.Lang(); len(lang) > 0 {
-			binary.Write(buff, binary.BigEndian, literalWithLangEncoding)
-			binary.Write(buff, binary.BigEndian, wordLength(len(lang)))
-			buff.WriteString(string(lang))
+func encodeBinObject(obj object, buff *bytes.Buffer) {
+	switch {
+	case obj.isLit:
+		if lang := obj.lit.langtag; len(lang) > 0 {
+			buff.WriteByte(literalWithLangEncoding)
+			writeWord(buff, lang)
 		} else {
-			binary.Write(buff, binary.BigEndian, literalTypeEncoding)
-			typ := lit.Type()
-			binary.Write(buff, binary.BigEndian, wordLength(len(typ)))
-			buff.WriteString(string(typ))
+			buff.WriteByte(literalTypeEncoding)
+			writeWord(buff, string(obj.lit.typ))
 		}
 
-		litVal := lit.Value()
-		if lit.Type() == XsdString {
+		litVal := obj.lit.val
+		if obj.lit.typ == XsdString {
 			litVal = escapeStringLiteral(litVal)
 		}
-		binary.Write(buff, binary.BigEndian, wordLength(len(litVal)))
-		buff.WriteString(litVal)
-	} else if bnode, isBnode := obj.Bnode(); isBnode {
-		binary.Write(buff, binary.BigEndian, bnodeTypeEncoding)
-		binary.Write(buff, binary.BigEndian, wordLength(len(bnode)))
-		buff.WriteString(bnode)
-	} else {
-		binary.Write(buff, binary.BigEndian, resourceTypeEncoding)
-		res, _ := obj.Resource()
-		binary.Write(buff, binary.BigEndian, wordLength(len(res)))
-		buff.WriteString(res)
+		writeWord(buff, litVal)
+	case obj.isBnode:
+		buff.WriteByte(bnodeTypeEncoding)
+		writeWord(buff, obj.bnode)
+	default:
+		buff.WriteByte(resourceTypeEncoding)
+		writeWord(buff, obj.resource)
+	}
+}
+
+// writeWord writes the big endian wordLength of s followed by s
+func writeWord(buff *bytes.Buffer, s string) {
+	l := wordLength(len(s))
+	buff.WriteByte(byte(l >> 24))
+	buff.WriteByte(byte(l >> 16))
+	buff.WriteByte(byte(l >> 8))
+	buff.WriteByte(byte(l))
+	buff.WriteString(s)
+}
+
+type parallelEncoder struct {
+	w       io.Writer
+	workers int
+	newEnc  func(io.Writer) Encoder
+}
+
+// NewParallelEncoder returns an encoder partitioning the triples in chunks
+// encoded concurrently by at most workers goroutines, using encoders built with newEnc.
+// Chunks are written in order to w. It suits formats whose encoding of a triple
+// does not depend on others such as binary and N-Triples.
+func NewParallelEncoder(w io.Writer, workers int, newEnc func(io.Writer) Encoder) Encoder {
+	if workers < 1 {
+		workers = 1
 	}
+	return &parallelEncoder{w: w, workers: workers, newEnc: newEnc}
+}
+
+func (enc *parallelEncoder) Encode(tris ...Triple) error {
+	chunks := enc.workers
+	if len(tris) < chunks {
+		chunks = len(tris)
+	}
+	if chunks <= 1 {
+		return enc.newEnc(enc.w).Encode(tris...)
+	}
+
+	bufs := make([]bytes.Buffer, chunks)
+	errs := make([]error, chunks)
+	size := (len(tris) + chunks - 1) / chunks
 
+	var wg sync.WaitGroup
+	for i := 0; i < chunks; i++ {
+		start, end := i*size, (i+1)*size
+		if end > len(tris) {
+			end = len(tris)
+		}
+		wg.Add(1)
+		go func(i int, chunk []Triple) {
+			defer wg.Done()
+			errs[i] = enc.newEnc(&bufs[i]).Encode(chunk...)
+		}(i, tris[start:end])
+	}
+	wg.Wait()
+
+	for i := range bufs {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		if _, err := bufs[i].WriteTo(enc.w); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 type ntriplesEncoder struct {
-	w io.Writer
-	c *Context
+	w         io.Writer
+	c         *Context
+	canonical bool
+	sorted    bool
+	progress  func(Progress)
+}
+
+// NewNTriplesEncoder returns a N-Triples encoder configured with the given options
+func NewNTriplesEncoder(w io.Writer, opts ...EncoderOption) Encoder {
+	o := newEncoderOptions(opts)
+	return &ntriplesEncoder{w: w, c: o.context, canonical: o.canonical, sorted: o.sorted, progress: o.progress}
+}
+
+// NewBinaryEncoderWithOptions returns a binary encoder configured with the given options
+func NewBinaryEncoderWithOptions(w io.Writer, opts ...EncoderOption) Encoder {
+	o := newEncoderOptions(opts)
+	return &binaryEncoder{w: w, c: o.context, sorted: o.sorted, progress: o.progress}
 }
 
 func NewLenientNTStreamEncoder(w io.Writer) StreamEncoder {
 	return &ntriplesEncoder{w: w}
 }
 
+func NewLenientNTStreamEncoderWithContext(w io.Writer, c *Context) StreamEncoder {
+	return &ntriplesEncoder{w: w, c: c}
+}
+
 func NewLenientNTEncoder(w io.Writer) Encoder {
 	return &ntriplesEncoder{w: w}
 }
@@ -162,7 +299,13 @@ func (enc *ntriplesEncoder) StreamEncode(ctx context.Context, triples <-chan Tri
 	if triples == nil {
 		return nil
 	}
-	var buf bytes.Buffer
+	if enc.canonical || enc.sorted {
+		// canonicalization and sorting need all triples
+		return enc.Encode(collectTriples(ctx, triples)...)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	tracker := newProgressTracker(enc.progress)
 	finalWrite := func() error {
 		_, err := enc.w.Write(buf.Bytes())
 		return err
@@ -171,9 +314,13 @@ func (enc *ntriplesEncoder) StreamEncode(ctx context.Context, triples <-chan Tri
 		select {
 		case tri, ok := <-triples:
 			if !ok {
-				return finalWrite()
+				if err := finalWrite(); err != nil {
+					return err
+				}
+				tracker.done()
+				return nil
 			}
-			encodeNTriple(tri, enc.c, &buf)
+			enc.encodeTriple(tri, buf, tracker)
 		case <-ctx.Done():
 			return finalWrite()
 		}
@@ -181,50 +328,174 @@ func (enc *ntriplesEncoder) StreamEncode(ctx context.Context, triples <-chan Tri
 }
 
 func (enc *ntriplesEncoder) Encode(tris ...Triple) error {
-	var buff bytes.Buffer
+	if enc.canonical {
+		n, err := enc.w.Write(CanonicalNQuads(expandTriples(enc.c, tris)))
+		if err == nil && enc.progress != nil {
+			enc.progress(Progress{Bytes: int64(n), Triples: len(tris), Done: true})
+		}
+		return err
+	}
+	if enc.sorted {
+		tris = sortedTriples(tris)
+	}
+
+	buff := getBuffer()
+	defer putBuffer(buff)
+	tracker := newProgressTracker(enc.progress)
 
 	for _, t := range tris {
-		encodeNTriple(t, enc.c, &buff)
+		enc.encodeTriple(t, buff, tracker)
 	}
-	_, err := enc.w.Write(buff.Bytes())
-	return err
+	if _, err := enc.w.Write(buff.Bytes()); err != nil {
+		return err
+	}
+	tracker.done()
+	return nil
+}
+
+func (enc *ntriplesEncoder) encodeTriple(t Triple, buff *bytes.Buffer, tracker *progressTracker) {
+	before := buff.Len()
+	encodeNTriple(t, enc.c, buff)
+	tracker.addBytes(buff.Len() - before)
+	tracker.addTriple()
+}
+
+// EncodeTo appends the N-Triples encoding of the triples to buf
+func (enc *ntriplesEncoder) EncodeTo(buf []byte, tris ...Triple) ([]byte, error) {
+	if enc.canonical {
+		return append(buf, CanonicalNQuads(expandTriples(enc.c, tris))...), nil
+	}
+	buff := bytes.NewBuffer(buf)
+	for _, t := range tris {
+		encodeNTriple(t, enc.c, buff)
+	}
+	return buff.Bytes(), nil
 }
 
+// encodeNTriple writes the triple term by term, without allocating
+// when no context is given
 func encodeNTriple(t Triple, ctx *Context, buff *bytes.Buffer) {
-	var sub string
-	if tt := t.(*triple); tt.isSubBnode {
-		sub = "_:" + buildIRI(ctx, t.Subject())
+	tt := t.(*triple)
+	if tt.isSubBnode {
+		buff.WriteString("_:")
+		buff.WriteString(tt.sub)
 	} else {
-		sub = "<" + buildIRI(ctx, t.Subject()) + ">"
+		writeIRI(buff, buildIRI(ctx, tt.sub))
 	}
-	buff.WriteString(sub + " <" + buildIRI(ctx, t.Predicate()) + "> ")
+	buff.WriteByte(' ')
+	writeIRI(buff, buildIRI(ctx, tt.pred))
+	buff.WriteByte(' ')
+
+	switch obj := tt.obj; {
+	case obj.isBnode:
+		buff.WriteString("_:")
+		buff.WriteString(obj.bnode)
+	case !obj.isLit:
+		writeIRI(buff, buildIRI(ctx, obj.resource))
+	case obj.lit.langtag != "":
+		writeStringLiteral(buff, obj.lit.val)
+		buff.WriteByte('@')
+		buff.WriteString(obj.lit.langtag)
+	case obj.lit.typ == XsdString:
+		// namespace empty as per spec
+		writeStringLiteral(buff, obj.lit.val)
+	case ctx != nil:
+		if _, ok := ctx.Prefixes["xsd"]; ok {
+			writeTypedLiteral(buff, obj.lit.val, obj.lit.typ.NTriplesNamespaced())
+		}
+	default:
+		writeTypedLiteral(buff, obj.lit.val, string(obj.lit.typ))
+	}
+	buff.WriteString(" .\n")
+}
 
-	if bnode, isBnode := t.Object().Bnode(); isBnode {
-		buff.WriteString("_:" + bnode)
-	} else {
-		if rid, ok := t.Object().Resource(); ok {
-			buff.WriteString("<" + buildIRI(ctx, rid) + ">")
-		} else if lit, ok := t.Object().Literal(); ok {
-			if lit.Lang() != "" {
-				buff.WriteString("\"" + escapeStringLiteral(lit.Value()) + "\"@" + lit.Lang())
-			} else {
-				switch lit.Type() {
-				case XsdString:
-					// namespace empty as per spec
-					buff.WriteString("\"" + escapeStringLiteral(lit.Value()) + "\"")
-				default:
-					if ctx != nil {
-						if _, ok := ctx.Prefixes["xsd"]; ok {
-							buff.WriteString("\"" + lit.Value() + "\"^^<" + lit.Type().NTriplesNamespaced() + ">")
-						}
-					} else {
-						buff.WriteString("\"" + lit.Value() + "\"^^<" + string(lit.Type()) + ">")
-					}
-				}
+func writeIRI(buff *bytes.Buffer, iri string) {
+	buff.WriteByte('<')
+	buff.WriteString(iri)
+	buff.WriteByte('>')
+}
+
+func writeStringLiteral(buff *bytes.Buffer, val string) {
+	buff.WriteByte('"')
+	writeEscapedLiteral(buff, val)
+	buff.WriteByte('"')
+}
+
+func writeTypedLiteral(buff *bytes.Buffer, val, typ string) {
+	buff.WriteByte('"')
+	buff.WriteString(val)
+	buff.WriteString(`"^^<`)
+	buff.WriteString(typ)
+	buff.WriteByte('>')
+}
+
+// collectTriples reads the triples of the stream until it is closed or the context is done
+func collectTriples(ctx context.Context, triples <-chan Triple) (all []Triple) {
+	for {
+		select {
+		case tri, ok := <-triples:
+			if !ok {
+				return
 			}
+			all = append(all, tri)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type sortedEncoder struct {
+	enc Encoder
+}
+
+// NewSortedEncoder returns an encoder sorting the triples by subject,
+// predicate and object before encoding them with enc
+func NewSortedEncoder(enc Encoder) Encoder {
+	return &sortedEncoder{enc: enc}
+}
+
+func (s *sortedEncoder) Encode(tris ...Triple) error {
+	return s.enc.Encode(sortedTriples(tris)...)
+}
+
+// sortedTriples returns a copy of the triples sorted by subject, predicate and object
+func sortedTriples(tris []Triple) []Triple {
+	sorted := make([]Triple, len(tris))
+	copy(sorted, tris)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].(*triple), sorted[j].(*triple)
+		if a.sub != b.sub {
+			return a.sub < b.sub
+		}
+		if a.isSubBnode != b.isSubBnode {
+			return !a.isSubBnode
 		}
+		if a.pred != b.pred {
+			return a.pred < b.pred
+		}
+		return a.obj.key() < b.obj.key()
+	})
+	return sorted
+}
+
+// expandTriples returns the triples with their IRIs built against the context
+func expandTriples(ctx *Context, tris []Triple) []Triple {
+	if ctx == nil {
+		return tris
 	}
-	buff.Write([]byte(" .\n"))
+	out := make([]Triple, len(tris))
+	for i, t := range tris {
+		tri := t.(*triple)
+		sub, obj := tri.sub, tri.obj
+		if !tri.isSubBnode {
+			sub = buildIRI(ctx, tri.sub)
+		}
+		if !tri.obj.isLit && !tri.obj.isBnode {
+			obj.resource = buildIRI(ctx, tri.obj.resource)
+		}
+		out[i] = makeTriple(sub, tri.isSubBnode, buildIRI(ctx, tri.pred), obj)
+	}
+	return out
 }
 
 func buildIRI(ctx *Context, id string) string {
@@ -304,8 +575,123 @@ func (dg *dotGraphEncoder) Encode(tris ...Triple) error {
 	return nil
 }
 
+// DotOptions configures the DOT encoder returned by NewDotEncoder
+type DotOptions struct {
+	// MaxLabelLength truncates node and edge labels longer than this number of characters. Zero means no truncation.
+	MaxLabelLength int
+	// Context prefixes are used to compact IRIs in labels (ex: "http://xmlns.com/foaf/0.1/name" as "foaf:name")
+	Context *Context
+}
+
+type dotEncoder struct {
+	w    io.Writer
+	opts DotOptions
+}
+
+// NewDotEncoder returns an encoder rendering all triples as a DOT digraph:
+// resources and bnodes are nodes, predicates are edge labels and literals are leaf nodes.
+// Options can be nil.
+func NewDotEncoder(w io.Writer, opts *DotOptions) Encoder {
+	enc := &dotEncoder{w: w}
+	if opts != nil {
+		enc.opts = *opts
+	}
+	return enc
+}
+
+func (enc *dotEncoder) Encode(tris ...Triple) error {
+	sorted := make([]Triple, len(tris))
+	copy(sorted, tris)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].(*triple).key() < sorted[j].(*triple).key() })
+
+	var buff bytes.Buffer
+	labelled := make(map[string]bool)
+	node := func(o object) string {
+		id := o.resource
+		if o.isBnode {
+			id = "_:" + o.bnode
+		}
+		if label := enc.label(id); !labelled[id] && label != id {
+			fmt.Fprintf(&buff, "%s [label=%s];\n", dotQuote(id), dotQuote(label))
+		}
+		labelled[id] = true
+		return dotQuote(id)
+	}
+
+	buff.WriteString("digraph {\n")
+	for i, t := range sorted {
+		tri := t.(*triple)
+		sub := node(subjectObject(tri))
+		pred := dotQuote(enc.label(tri.pred))
+		if lit, isLit := tri.obj.Literal(); isLit {
+			id := fmt.Sprintf("lit%d", i)
+			fmt.Fprintf(&buff, "%s [label=%s, shape=box];\n", id, dotQuote(enc.label(lit.Value())))
+			fmt.Fprintf(&buff, "%s -> %s [label=%s];\n", sub, id, pred)
+			continue
+		}
+		obj := node(tri.obj)
+		fmt.Fprintf(&buff, "%s -> %s [label=%s];\n", sub, obj, pred)
+	}
+	buff.WriteString("}\n")
+
+	_, err := enc.w.Write(buff.Bytes())
+	return err
+}
+
+func (enc *dotEncoder) label(s string) string {
+	if enc.opts.Context != nil {
+		s = compactIRI(enc.opts.Context, s)
+	}
+	if max := enc.opts.MaxLabelLength; max > 0 {
+		if runes := []rune(s); len(runes) > max {
+			s = string(runes[:max]) + "..."
+		}
+	}
+	return s
+}
+
+// compactIRI replaces the longest matching namespace of the context by its prefix
+func compactIRI(ctx *Context, iri string) string {
+	var prefix, ns string
+	for k, uri := range ctx.Prefixes {
+		if len(uri) > len(ns) && strings.HasPrefix(iri, uri) {
+			prefix, ns = k, uri
+		}
+	}
+	if ns == "" {
+		return iri
+	}
+	return prefix + ":" + strings.TrimPrefix(iri, ns)
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
 var escaper = strings.NewReplacer("\n", "\\n", "\r", "\\r")
 
 func escapeStringLiteral(s string) string {
 	return escaper.Replace(s)
 }
+
+// writeEscapedLiteral writes the literal value escaped as escapeStringLiteral does
+func writeEscapedLiteral(buff *bytes.Buffer, s string) {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		var esc string
+		switch s[i] {
+		case '\n':
+			esc = `\n`
+		case '\r':
+			esc = `\r`
+		default:
+			continue
+		}
+		buff.WriteString(s[last:i])
+		buff.WriteString(esc)
+		last = i + 1
+	}
+	buff.WriteString(s[last:])
+}
@@ -0,0 +1,355 @@
+package triplestore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	sparqlQueryMediaType   = "application/sparql-query"
+	sparqlUpdateMediaType  = "application/sparql-update"
+	sparqlResultsMediaType = "application/sparql-results+json"
+	formMediaType          = "application/x-www-form-urlencoded"
+)
+
+// NewSPARQLHandler returns an http.Handler exposing the source through
+// the SPARQL Protocol. Queries are accepted via GET (query parameter),
+// POST form (query parameter) or POST body (application/sparql-query).
+// Results are written in the SPARQL 1.1 Query Results JSON Format.
+//
+// The handler is read-only: updates are rejected as any unsupported request.
+// Each request is evaluated against a snapshot of the source.
+func NewSPARQLHandler(s Source) http.Handler {
+	return &sparqlHandler{source: s}
+}
+
+// NewSPARQLUpdateHandler returns an http.Handler answering queries as the
+// one of NewSPARQLHandler, and also accepting updates via POST form (update
+// parameter) or POST body (application/sparql-update) applied to the source.
+func NewSPARQLUpdateHandler(s Source) http.Handler {
+	return &sparqlHandler{source: s, updates: true}
+}
+
+type sparqlHandler struct {
+	source  Source
+	updates bool
+}
+
+func (h *sparqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.updates {
+		h.serveQuery(w, r)
+		return
+	}
+	if update, ok, err := sparqlUpdateFromRequest(r); ok {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		u, err := ParseUpdate(update)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := u.Exec(h.source); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.serveQuery(w, r)
+}
+
+func (h *sparqlHandler) serveQuery(w http.ResponseWriter, r *http.Request) {
+	query, status, err := sparqlQueryFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	q, err := ParseQuery(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if q.IsAsk() {
+		ok, err := q.Ask(h.source.Snapshot())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", sparqlResultsMediaType)
+		writeJSONBoolean(w, ok)
+		return
+	}
+
+	res, err := q.Eval(h.source.Snapshot())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", sparqlResultsMediaType)
+	res.WriteJSON(w)
+}
+
+func sparqlQueryFromRequest(r *http.Request) (string, int, error) {
+	switch r.Method {
+	case http.MethodGet:
+		if query := r.URL.Query().Get("query"); query != "" {
+			return query, 0, nil
+		}
+		return "", http.StatusBadRequest, fmt.Errorf("missing query parameter")
+	case http.MethodPost:
+		mediatype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediatype {
+		case formMediaType:
+			if query := r.PostFormValue("query"); query != "" {
+				return query, 0, nil
+			}
+			return "", http.StatusBadRequest, fmt.Errorf("missing query parameter")
+		case sparqlQueryMediaType:
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return "", http.StatusBadRequest, err
+			}
+			return string(b), 0, nil
+		default:
+			return "", http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type '%s'", mediatype)
+		}
+	default:
+		return "", http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)
+	}
+}
+
+// sparqlUpdateFromRequest returns the update carried by the request, if any
+func sparqlUpdateFromRequest(r *http.Request) (string, bool, error) {
+	if r.Method != http.MethodPost {
+		return "", false, nil
+	}
+	mediatype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediatype {
+	case formMediaType:
+		update := r.PostFormValue("update")
+		return update, update != "", nil
+	case sparqlUpdateMediaType:
+		b, err := ioutil.ReadAll(r.Body)
+		return string(b), true, err
+	default:
+		return "", false, nil
+	}
+}
+
+type jsonResults struct {
+	Head struct {
+		Vars []string `json:"vars"`
+	} `json:"head"`
+	Results struct {
+		Bindings []map[string]jsonTerm `json:"bindings"`
+	} `json:"results"`
+}
+
+type jsonBoolean struct {
+	Head    struct{} `json:"head"`
+	Boolean bool     `json:"boolean"`
+}
+
+// writeJSONBoolean writes an ASK result in the SPARQL 1.1 Query Results JSON Format
+func writeJSONBoolean(w io.Writer, b bool) error {
+	return json.NewEncoder(w).Encode(jsonBoolean{Boolean: b})
+}
+
+type jsonTerm struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Lang     string `json:"xml:lang,omitempty"`
+	Datatype string `json:"datatype,omitempty"`
+}
+
+// WriteJSON writes the results in the SPARQL 1.1 Query Results JSON Format
+func (r *QueryResults) WriteJSON(w io.Writer) error {
+	var out jsonResults
+	out.Head.Vars = r.Vars
+	if out.Head.Vars == nil {
+		out.Head.Vars = []string{}
+	}
+	out.Results.Bindings = make([]map[string]jsonTerm, len(r.Bindings))
+	for i, b := range r.Bindings {
+		m := make(map[string]jsonTerm, len(b))
+		for v, o := range b {
+			m[v] = toJSONTerm(o)
+		}
+		out.Results.Bindings[i] = m
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+func toJSONTerm(o Object) jsonTerm {
+	if lit, ok := o.Literal(); ok {
+		t := jsonTerm{Type: "literal", Value: lit.Value()}
+		if lit.Lang() != "" {
+			t.Lang = lit.Lang()
+		} else if lit.Type() != XsdString {
+			t.Datatype = lit.Type().NTriplesNamespaced()
+		}
+		return t
+	}
+	if bnode, ok := o.Bnode(); ok {
+		return jsonTerm{Type: "bnode", Value: bnode}
+	}
+	res, _ := o.Resource()
+	return jsonTerm{Type: "uri", Value: res}
+}
+
+// NewGraphStoreHandler returns an http.Handler implementing the SPARQL 1.1
+// Graph Store HTTP Protocol on the default graph of the source:
+// GET retrieves the triples, PUT replaces them, POST adds to them and DELETE removes them.
+// Named graphs are addressed with the graph query parameter (see NamedGraphs).
+// Without it, GET retrieves the union of all graphs while PUT and DELETE only
+// replace and remove the triples of the default graph, named graphs being kept.
+//
+// Triples are sent and received in the format negotiated through the Accept and
+// Content-Type headers (see EncoderForContentType), NTriples being the default.
+func NewGraphStoreHandler(s Source) http.Handler {
+	return &graphStoreHandler{source: s}
+}
+
+type graphStoreHandler struct {
+	source Source
+}
+
+func (h *graphStoreHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	src := h.source
+	if name := r.URL.Query().Get("graph"); name != "" {
+		graphs, ok := h.source.(NamedGraphs)
+		if !ok || r.Method != http.MethodPut && r.Method != http.MethodPost && !hasGraph(graphs, name) {
+			http.Error(w, fmt.Sprintf("graph %s not found", name), http.StatusNotFound)
+			return
+		}
+		src = graphs.Graph(name)
+		if r.Method == http.MethodDelete {
+			graphs.DropGraph(name)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mediatype := negotiateMediaType(r.Header.Get("Accept"))
+		if mediatype == "" {
+			http.Error(w, "no acceptable content type", http.StatusNotAcceptable)
+			return
+		}
+		var buf bytes.Buffer
+		enc, err := EncoderForContentType(&buf, mediatype)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := enc.Encode(src.CopyTriples()...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", mediatype)
+		buf.WriteTo(w)
+	case http.MethodPut, http.MethodPost:
+		dec, err := DecoderForContentType(r.Body, r.Header.Get("Content-Type"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		tris, err := dec.Decode()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPut {
+			Replace(src, tris...)
+		} else {
+			src.Add(tris...)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		Replace(src)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+func hasGraph(s NamedGraphs, name string) bool {
+	for _, n := range s.GraphNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateMediaType returns the media type of the Accept header having an
+// encoder with the highest quality value, the first one on ties, defaulting to
+// NTriples. Media types with a quality value of 0 are not acceptable, even
+// through a wildcard (ex: "*/*, application/n-triples;q=0").
+func negotiateMediaType(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return ntriplesMediaType
+	}
+	type accepted struct {
+		mediatype string
+		q         float64
+	}
+	var accepts []accepted
+	refused := make(map[string]bool)
+	for _, a := range strings.Split(accept, ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(a))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		if q == 0 {
+			refused[mediatype] = true
+			continue
+		}
+		accepts = append(accepts, accepted{mediatype: mediatype, q: q})
+	}
+
+	var best string
+	var bestQ float64
+	for _, a := range accepts {
+		if a.q <= bestQ {
+			continue
+		}
+		mediatype := a.mediatype
+		switch mediatype {
+		case "*/*", "application/*":
+			mediatype = ""
+			for _, fallback := range []string{ntriplesMediaType, binaryMediaType} {
+				if !refused[fallback] {
+					mediatype = fallback
+					break
+				}
+			}
+		}
+		if mediatype == "" || refused[mediatype] {
+			continue
+		}
+		if _, err := EncoderForContentType(ioutil.Discard, mediatype); err == nil {
+			best, bestQ = mediatype, a.q
+		}
+	}
+	return best
+}
@@ -0,0 +1,329 @@
+package triplestore
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSPARQLHandler(t *testing.T) {
+	s := NewSource()
+	s.Add(
+		SubjPred("alice", "name").StringLiteral("Alice"),
+		SubjPred("alice", "age").IntegerLiteral(42),
+		SubjPred("alice", "label").Object(StringLiteralWithLang("Alice", "en")),
+		SubjPred("alice", "knows").Bnode("b1"),
+		SubjPred("alice", "code").Object(TypedLiteral("x", "http://example.org/dt")),
+	)
+	srv := httptest.NewServer(NewSPARQLHandler(s))
+	defer srv.Close()
+
+	query := "SELECT ?p ?o WHERE { <alice> ?p ?o }"
+
+	get := func() (*http.Response, error) {
+		return http.Get(srv.URL + "?query=" + url.QueryEscape(query))
+	}
+	postForm := func() (*http.Response, error) {
+		return http.PostForm(srv.URL, url.Values{"query": {query}})
+	}
+	postDirect := func() (*http.Response, error) {
+		return http.Post(srv.URL, "application/sparql-query", strings.NewReader(query))
+	}
+
+	for i, do := range []func() (*http.Response, error){get, postForm, postDirect} {
+		resp, err := do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("case %d: got %d, want %d", i, got, want)
+		}
+		if got, want := resp.Header.Get("Content-Type"), "application/sparql-results+json"; got != want {
+			t.Fatalf("case %d: got %s, want %s", i, got, want)
+		}
+		var results jsonResults
+		if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got, want := results.Head.Vars, []string{"p", "o"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("case %d: got %v, want %v", i, got, want)
+		}
+		byPred := make(map[string]jsonTerm)
+		for _, b := range results.Results.Bindings {
+			byPred[b["p"].Value] = b["o"]
+		}
+		expected := map[string]jsonTerm{
+			"name":  {Type: "literal", Value: "Alice"},
+			"age":   {Type: "literal", Value: "42", Datatype: "http://www.w3.org/2001/XMLSchema#integer"},
+			"label": {Type: "literal", Value: "Alice", Lang: "en"},
+			"knows": {Type: "bnode", Value: "b1"},
+			"code":  {Type: "literal", Value: "x", Datatype: "http://example.org/dt"},
+		}
+		if got, want := byPred, expected; !reflect.DeepEqual(got, want) {
+			t.Fatalf("case %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSPARQLHandlerAsk(t *testing.T) {
+	s := NewSource()
+	s.Add(SubjPred("alice", "knows").Resource("bob"))
+	srv := httptest.NewServer(NewSPARQLHandler(s))
+	defer srv.Close()
+
+	tcases := []struct {
+		query string
+		exp   bool
+	}{
+		{"ASK { <alice> <knows> ?o }", true},
+		{"ASK { <bob> <knows> ?o }", false},
+	}
+	for i, tc := range tcases {
+		resp, err := http.Get(srv.URL + "?query=" + url.QueryEscape(tc.query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.Header.Get("Content-Type"), "application/sparql-results+json"; got != want {
+			t.Fatalf("case %d: got %s, want %s", i, got, want)
+		}
+		var result jsonBoolean
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got, want := result.Boolean, tc.exp; got != want {
+			t.Fatalf("case %d: got %t, want %t", i, got, want)
+		}
+	}
+}
+
+func TestSPARQLHandlerUpdate(t *testing.T) {
+	s := NewSource()
+	s.Add(SubjPred("alice", "knows").Resource("bob"))
+	srv := httptest.NewServer(NewSPARQLUpdateHandler(s))
+	defer srv.Close()
+
+	resp, err := http.PostForm(srv.URL, url.Values{"update": {"INSERT DATA { <bob> <knows> <carol> }"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	resp, err = http.Post(srv.URL, "application/sparql-update", strings.NewReader("DELETE WHERE { <alice> ?p ?o }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	if got, want := s.Snapshot().Triples(), []Triple{SubjPred("bob", "knows").Resource("carol")}; !Triples(got).Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	resp, err = http.Post(srv.URL, "application/sparql-update", strings.NewReader("INSERT DATA { ?s ?p ?o }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func TestSPARQLHandlerIsReadOnly(t *testing.T) {
+	s := NewSource()
+	s.Add(SubjPred("alice", "knows").Resource("bob"))
+	srv := httptest.NewServer(NewSPARQLHandler(s))
+	defer srv.Close()
+
+	resp, err := http.PostForm(srv.URL, url.Values{"update": {"INSERT DATA { <bob> <knows> <carol> }"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	resp, err = http.Post(srv.URL, "application/sparql-update", strings.NewReader("DELETE WHERE { <alice> ?p ?o }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusUnsupportedMediaType; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	if got, want := s.Snapshot().Triples(), []Triple{SubjPred("alice", "knows").Resource("bob")}; !Triples(got).Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSPARQLHandlerErrors(t *testing.T) {
+	srv := httptest.NewServer(NewSPARQLHandler(NewSource()))
+	defer srv.Close()
+
+	tcases := []struct {
+		method, contentType, body, query string
+		status                           int
+	}{
+		{method: "GET", status: http.StatusBadRequest},
+		{method: "GET", query: "SELECT nothing", status: http.StatusBadRequest},
+		{method: "POST", contentType: "text/plain", body: "SELECT * { ?s ?p ?o }", status: http.StatusUnsupportedMediaType},
+		{method: "POST", contentType: "application/x-www-form-urlencoded", body: "other=1", status: http.StatusBadRequest},
+		{method: "DELETE", status: http.StatusMethodNotAllowed},
+	}
+	for i, tc := range tcases {
+		u := srv.URL
+		if tc.query != "" {
+			u += "?query=" + url.QueryEscape(tc.query)
+		}
+		req, err := http.NewRequest(tc.method, u, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got, want := resp.StatusCode, tc.status; got != want {
+			t.Fatalf("case %d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestGraphStoreHandler(t *testing.T) {
+	s := NewSource()
+	s.Add(SubjPred("one", "two").StringLiteral("three"))
+	srv := httptest.NewServer(NewGraphStoreHandler(s))
+	defer srv.Close()
+
+	do := func(method, contentType, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+"?default", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := do("GET", "", "")
+	tris, err := NewLenientNTDecoder(resp.Body).Decode()
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(tris), Triples(s.CopyTriples()); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	resp = do("POST", "application/n-triples", "<four> <five> <six> .\n")
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := s.Snapshot().Count(), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	resp = do("PUT", "application/n-triples", "<seven> <eight> <nine> .\n")
+	resp.Body.Close()
+	if got, want := Triples(s.CopyTriples()), Triples([]Triple{SubjPred("seven", "eight").Resource("nine")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	resp = do("DELETE", "", "")
+	resp.Body.Close()
+	if got, want := s.Snapshot().Count(), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	resp = do("PUT", "text/html", "")
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusUnsupportedMediaType; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	resp, err = http.Get(srv.URL + "?graph=http://example.org/g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	doGraph := func(method, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+"?graph="+url.QueryEscape("http://example.org/g"), strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/n-triples")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	s.Add(SubjPred("one", "two").StringLiteral("three"))
+	resp = doGraph("PUT", "<ten> <eleven> <twelve> .\n")
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	resp = doGraph("GET", "")
+	tris, err = NewLenientNTDecoder(resp.Body).Decode()
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(tris), Triples([]Triple{SubjPred("ten", "eleven").Resource("twelve")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := s.Snapshot().Count(), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	resp = doGraph("DELETE", "")
+	resp.Body.Close()
+	if got, want := len(s.(NamedGraphs).GraphNames()), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := s.Snapshot().Count(), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	resp = doGraph("PUT", "<ten> <eleven> <twelve> .\n")
+	resp.Body.Close()
+	resp = do("PUT", "application/n-triples", "<seven> <eight> <nine> .\n")
+	resp.Body.Close()
+	if got, want := Triples(s.CopyTriples()), Triples([]Triple{SubjPred("seven", "eight").Resource("nine"), SubjPred("ten", "eleven").Resource("twelve")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	resp = do("DELETE", "", "")
+	resp.Body.Close()
+	if got, want := Triples(s.CopyTriples()), Triples([]Triple{SubjPred("ten", "eleven").Resource("twelve")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,247 @@
+package triplestore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// filter restricts the solutions of a query to the ones it accepts
+type filter interface {
+	// vars returns the variables the filter depends on
+	vars() []string
+	accept(b Binding) bool
+	String() string
+}
+
+// regexFilter accepts the solutions binding its variable to a literal
+// with a lexical value matching the regular expression
+type regexFilter struct {
+	variable       string
+	pattern, flags string
+	re             *regexp.Regexp
+}
+
+func (f regexFilter) vars() []string {
+	return []string{f.variable}
+}
+
+func (f regexFilter) String() string {
+	if f.flags != "" {
+		return fmt.Sprintf("FILTER regex(?%s, %q, %q)", f.variable, f.pattern, f.flags)
+	}
+	return fmt.Sprintf("FILTER regex(?%s, %q)", f.variable, f.pattern)
+}
+
+func (f regexFilter) accept(b Binding) bool {
+	o, ok := b[f.variable]
+	if !ok {
+		return false
+	}
+	obj := o.(object)
+	return obj.isLit && f.re.MatchString(obj.lit.val)
+}
+
+// compareFilter accepts the solutions binding its variable to a literal comparing
+// to the value as required by the operator, in the value space of their datatypes
+// (see CompareLiterals). As in WithPredTimeRange, dates and dateTimes are compared
+// with each other as instants. Incomparable literals are rejected.
+type compareFilter struct {
+	variable string
+	op       string
+	value    object
+}
+
+func (f compareFilter) vars() []string {
+	return []string{f.variable}
+}
+
+func (f compareFilter) String() string {
+	return fmt.Sprintf("FILTER (?%s %s %s)", f.variable, f.op, term{value: f.value})
+}
+
+func (f compareFilter) accept(b Binding) bool {
+	o, ok := b[f.variable]
+	if !ok {
+		return false
+	}
+	obj := o.(object)
+	if !obj.isLit {
+		return false
+	}
+	var c int
+	if t, ok := timeValue(obj); ok {
+		v, ok := timeValue(f.value)
+		if !ok {
+			return false
+		}
+		c = t.Compare(v)
+	} else {
+		var err error
+		if c, err = CompareLiterals(obj.lit, f.value.lit); err != nil {
+			return false
+		}
+	}
+	switch f.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// flippedOps gives the operator comparing the operands the other way around
+var flippedOps = map[string]string{"=": "=", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+// compileSPARQLRegex compiles a SPARQL regex pattern with its flags
+// (i: case insensitive, s: dot matches newlines, m: multi-line, q: literal)
+func compileSPARQLRegex(pattern, flags string) (*regexp.Regexp, error) {
+	var goFlags string
+	for _, f := range flags {
+		switch f {
+		case 'i', 's', 'm':
+			goFlags += string(f)
+		case 'q':
+			pattern = regexp.QuoteMeta(pattern)
+		default:
+			return nil, fmt.Errorf("unsupported regex flag '%c'", f)
+		}
+	}
+	if goFlags != "" {
+		pattern = "(?" + goFlags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %s", strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+	return re, nil
+}
+
+// step is a triple pattern followed by the filters to apply as soon as
+// it is matched, i.e. once all their variables are bound
+type step struct {
+	pattern triplePattern
+	filters []filter
+	// objLookup, when set, returns the candidate triples of a pattern with only
+	// a bound predicate through the graph indexes, restricting the object as required by the filters
+	objLookup *objLookup
+}
+
+// objLookup finds the triples of a predicate with an object satisfying filters
+type objLookup struct {
+	// method is the name of the RDFGraph method used
+	method string
+	find   func(g RDFGraph, pred string) []Triple
+}
+
+// planSteps schedules the filters after the first of the ordered patterns binding
+// all their variables, considering the variables bound beforehand. Filters on
+// variables that are never bound are returned apart, to be applied to the final solutions.
+func planSteps(patterns []triplePattern, filters []filter, boundVars ...string) (steps []step, remaining []filter) {
+	steps = make([]step, len(patterns))
+	bound := make(map[string]bool)
+	for _, v := range boundVars {
+		bound[v] = true
+	}
+	remaining = filters
+	for i, p := range patterns {
+		steps[i].pattern = p
+		for _, t := range []term{p.sub, p.pred, p.obj} {
+			if t.isVar() {
+				bound[t.variable] = true
+			}
+		}
+		var pending []filter
+		for _, f := range remaining {
+			if allBound(f.vars(), bound) {
+				steps[i].filters = append(steps[i].filters, f)
+			} else {
+				pending = append(pending, f)
+			}
+		}
+		remaining = pending
+
+		if p.obj.isVar() && p.obj.variable != p.sub.variable && p.obj.variable != p.pred.variable {
+			steps[i].objLookup = newObjLookup(p.obj.variable, steps[i].filters)
+		}
+	}
+	return
+}
+
+// newObjLookup returns a lookup of the triples with an object satisfying the filters
+// on the variable: a regex match or a numeric or temporal range. It returns nil
+// when no filter can be evaluated through the indexes.
+func newObjLookup(variable string, filters []filter) *objLookup {
+	var min, max Literal
+	var numbers numericRange
+	var times timeRange
+	for _, f := range filters {
+		switch f := f.(type) {
+		case regexFilter:
+			if f.variable == variable {
+				return &objLookup{method: "WithPredObjMatching", find: func(g RDFGraph, pred string) []Triple {
+					return WithPredObjMatching(g, pred, f.re)
+				}}
+			}
+		case compareFilter:
+			if f.variable != variable {
+				continue
+			}
+			lower := f.op == ">" || f.op == ">=" || f.op == "="
+			upper := f.op == "<" || f.op == "<=" || f.op == "="
+			if v, ok := numericValue(f.value); ok && times.from.IsZero() && times.to.IsZero() {
+				if lower && (numbers.min == nil || v.Cmp(numbers.min) > 0) {
+					numbers.min, min = v, f.value.lit
+				}
+				if upper && (numbers.max == nil || v.Cmp(numbers.max) < 0) {
+					numbers.max, max = v, f.value.lit
+				}
+			} else if t, ok := timeValue(f.value); ok && numbers.min == nil && numbers.max == nil {
+				if lower && (times.from.IsZero() || t.After(times.from)) {
+					times.from = t
+				}
+				if upper && (times.to.IsZero() || t.Before(times.to)) {
+					times.to = t
+				}
+			}
+		}
+	}
+	switch {
+	case numbers.min != nil || numbers.max != nil:
+		return &objLookup{method: "WithPredRange", find: func(g RDFGraph, pred string) []Triple {
+			return WithPredRange(g, pred, min, max)
+		}}
+	case !times.from.IsZero() || !times.to.IsZero():
+		return &objLookup{method: "WithPredTimeRange", find: func(g RDFGraph, pred string) []Triple {
+			return WithPredTimeRange(g, pred, times.from, times.to)
+		}}
+	}
+	return nil
+}
+
+func allBound(vars []string, bound map[string]bool) bool {
+	for _, v := range vars {
+		if !bound[v] {
+			return false
+		}
+	}
+	return true
+}
+
+func acceptAll(filters []filter, b Binding) bool {
+	for _, f := range filters {
+		if !f.accept(b) {
+			return false
+		}
+	}
+	return true
+}
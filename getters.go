@@ -0,0 +1,62 @@
+package triplestore
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// GetResource returns the first resource (bnodes excluded) object
+// of the given subject and predicate in the graph
+func GetResource(g RDFGraph, sub, pred string) (string, bool) {
+	for _, tri := range g.WithSubjPred(sub, pred) {
+		if _, isBnode := tri.Object().Bnode(); isBnode {
+			continue
+		}
+		if res, ok := tri.Object().Resource(); ok {
+			return res, true
+		}
+	}
+	return "", false
+}
+
+// GetResources returns all the resource (bnodes excluded) objects
+// of the given subject and predicate in the graph, in no particular order
+func GetResources(g RDFGraph, sub, pred string) (out []string) {
+	for _, tri := range g.WithSubjPred(sub, pred) {
+		if _, isBnode := tri.Object().Bnode(); isBnode {
+			continue
+		}
+		if res, ok := tri.Object().Resource(); ok {
+			out = append(out, res)
+		}
+	}
+	return
+}
+
+// literalObjects returns the literal objects of the given subject and predicate
+func literalObjects(g RDFGraph, sub, pred string) (out []Object) {
+	for _, tri := range g.WithSubjPred(sub, pred) {
+		if _, ok := tri.Object().Literal(); ok {
+			out = append(out, tri.Object())
+		}
+	}
+	return
+}
+
+// setValueFromLiteral converts the literal into the value, as for struct fields,
+// interface values being given the parsed literal (see ParseLiteral)
+func setValueFromLiteral(v reflect.Value, obj Object) error {
+	if v.Kind() != reflect.Interface {
+		return setValueFromObject(v, obj)
+	}
+	parsed, err := ParseLiteral(obj)
+	if err != nil {
+		return err
+	}
+	pv := reflect.ValueOf(parsed)
+	if !pv.Type().AssignableTo(v.Type()) {
+		return fmt.Errorf("cannot assign %T to %s", parsed, v.Type())
+	}
+	v.Set(pv)
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build go1.18
+// +build go1.18
+
+package triplestore
+
+import "reflect"
+
+// GetLiteral returns the first literal object of the given subject and predicate
+// in the graph converted to T, with the same conversions as struct fields (see
+// StructFromTriples). It returns false if there is no such literal and an error
+// if it cannot be converted.
+// Ex: age, ok, err := GetLiteral[int64](snap, "me", "age")
+func GetLiteral[T any](g RDFGraph, sub, pred string) (T, bool, error) {
+	var v T
+	objs := literalObjects(g, sub, pred)
+	if len(objs) == 0 {
+		return v, false, nil
+	}
+	if err := setValueFromLiteral(reflect.ValueOf(&v).Elem(), objs[0]); err != nil {
+		return v, true, err
+	}
+	return v, true, nil
+}
+
+// GetLiterals returns all the literal objects of the given subject and predicate
+// in the graph converted to T, in no particular order (see GetLiteral)
+func GetLiterals[T any](g RDFGraph, sub, pred string) ([]T, error) {
+	objs := literalObjects(g, sub, pred)
+	out := make([]T, len(objs))
+	for i, obj := range objs {
+		if err := setValueFromLiteral(reflect.ValueOf(&out[i]).Elem(), obj); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
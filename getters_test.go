@@ -0,0 +1,90 @@
+//go:build go1.18
+// +build go1.18
+
+package triplestore
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGetters(t *testing.T) {
+	birth := time.Date(1934, 6, 9, 0, 0, 0, 0, time.UTC)
+	src := NewSource()
+	src.Add(
+		SubjPred("me", "age").IntegerLiteral(32),
+		SubjPred("me", "name").StringLiteral("donald"),
+		SubjPred("me", "birth").DateTimeLiteral(birth),
+		SubjPred("me", "timeout").DurationLiteral(time.Minute),
+		SubjPred("me", "ip").StringLiteral("10.0.0.1"),
+		SubjPred("me", "score").IntegerLiteral(1),
+		SubjPred("me", "score").IntegerLiteral(2),
+		SubjPred("me", "knows").Resource("mickey"),
+		SubjPred("me", "knows").Resource("goofy"),
+		SubjPred("me", "knows").Bnode("b1"),
+		SubjPred("me", "friend").Bnode("b1"),
+	)
+	snap := src.Snapshot()
+
+	age, ok, err := GetLiteral[int64](snap, "me", "age")
+	if err != nil || !ok || age != 32 {
+		t.Fatalf("got %d, %t, %v", age, ok, err)
+	}
+	name, ok, err := GetLiteral[string](snap, "me", "name")
+	if err != nil || !ok || name != "donald" {
+		t.Fatalf("got %s, %t, %v", name, ok, err)
+	}
+	got, ok, err := GetLiteral[time.Time](snap, "me", "birth")
+	if err != nil || !ok || !got.Equal(birth) {
+		t.Fatalf("got %s, %t, %v", got, ok, err)
+	}
+	timeout, ok, err := GetLiteral[time.Duration](snap, "me", "timeout")
+	if err != nil || !ok || timeout != time.Minute {
+		t.Fatalf("got %s, %t, %v", timeout, ok, err)
+	}
+	ip, ok, err := GetLiteral[net.IP](snap, "me", "ip")
+	if err != nil || !ok || !ip.Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("got %s, %t, %v", ip, ok, err)
+	}
+	parsed, ok, err := GetLiteral[interface{}](snap, "me", "age")
+	if err != nil || !ok || parsed != 32 {
+		t.Fatalf("got %v, %t, %v", parsed, ok, err)
+	}
+
+	if _, ok, err := GetLiteral[int](snap, "me", "unknown"); ok || err != nil {
+		t.Fatalf("got %t, %v, want no literal", ok, err)
+	}
+	if _, ok, err := GetLiteral[int](snap, "me", "knows"); ok || err != nil {
+		t.Fatalf("got %t, %v, want no literal", ok, err)
+	}
+	if _, ok, err := GetLiteral[int](snap, "me", "name"); !ok || err == nil {
+		t.Fatalf("got %t, %v, want conversion error", ok, err)
+	}
+
+	scores, err := GetLiterals[int](snap, "me", "score")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(scores)
+	if got, want := scores, []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, err := GetLiterals[bool](snap, "me", "score"); err == nil {
+		t.Fatal("expected conversion error")
+	}
+
+	if res, ok := GetResource(snap, "me", "friend"); ok {
+		t.Fatalf("got %s, want no resource for bnode", res)
+	}
+	knows := GetResources(snap, "me", "knows")
+	sort.Strings(knows)
+	if got, want := knows, []string{"goofy", "mickey"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if res, ok := GetResource(snap, "me", "knows"); !ok || (res != "mickey" && res != "goofy") {
+		t.Fatalf("got %s, %t", res, ok)
+	}
+}
@@ -0,0 +1,60 @@
+package triplestore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+)
+
+// Triples and objects implement gob.GobEncoder and gob.GobDecoder using the
+// binary encoding. Their concrete types are registered so that Triple and
+// Object values (ex: []Triple) can go through encoding/gob and net/rpc.
+func init() {
+	gob.Register(new(triple))
+	gob.Register(object{})
+}
+
+func (t *triple) GobEncode() ([]byte, error) {
+	var buff bytes.Buffer
+	if err := encodeBinTriple(t, &buff); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+// GobDecode decodes the triple. As triples are immutable,
+// only zero triples (i.e. new(triple)) can be decoded into.
+func (t *triple) GobDecode(data []byte) error {
+	if t.sub != "" || t.pred != "" {
+		return errors.New("cannot decode into an immutable triple")
+	}
+	tri, _, err := decodeTriple(bytes.NewReader(data), len(data))
+	if err != nil {
+		return err
+	}
+	if tri == nil {
+		return errors.New("empty triple")
+	}
+	*t = *tri.(*triple)
+	return nil
+}
+
+func (o object) GobEncode() ([]byte, error) {
+	var buff bytes.Buffer
+	encodeBinObject(o, &buff)
+	return buff.Bytes(), nil
+}
+
+func (o *object) GobDecode(data []byte) error {
+	r := bytes.NewReader(data)
+	obj, err := decodeBinObject(r, len(data))
+	if err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("object: %d trailing bytes", r.Len())
+	}
+	*o = obj
+	return nil
+}
@@ -0,0 +1,64 @@
+package triplestore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+func TestGob(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").Resource("three"),
+		BnodePred("one", "two").Bnode("three"),
+		SubjPred("one", "two").StringLiteral("a \"quoted\"\nline"),
+		SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+		SubjPred("one", "two").IntegerLiteral(42),
+		SubjPred("one", "two").DateTimeLiteral(time.Date(2017, 4, 10, 22, 15, 0, 0, time.UTC)),
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(tris); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Triples
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded, tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	buf.Reset()
+	type message struct {
+		Tri Triple
+		Obj Object
+	}
+	in := message{Tri: tris[1], Obj: IntegerLiteral(42)}
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out message
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Tri.Equal(in.Tri) {
+		t.Fatalf("got %v, want %v", out.Tri, in.Tri)
+	}
+	if got, want := out.Obj, in.Obj; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGobErrors(t *testing.T) {
+	if err := new(triple).GobDecode([]byte{0, 0xff, 0xff, 0xff, 0xff}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := SubjPred("one", "two").Resource("three").GobDecode(nil); err == nil {
+		t.Fatal("expected error when decoding into an existing triple")
+	}
+	data, _ := object{resource: "three"}.GobEncode()
+	if err := new(object).GobDecode(append(data, 0)); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,199 @@
+package triplestore
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Partial gregorian dates literals (i.e. xsd:gYear, xsd:gYearMonth, xsd:gMonthDay, xsd:gMonth, xsd:gDay).
+// Through ParseLiteral they are parsed into a time.Time where
+// missing components are set to their zero values (year 0, January, 1st day)
+
+func GYearLiteral(year int) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdGYear, val: formatGYear(year)},
+	}
+}
+
+func (b *tripleBuilder) GYearLiteral(year int) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, GYearLiteral(year).(object))
+}
+
+func ParseGYear(obj Object) (int, error) {
+	t, err := parseGregorian(obj, XsdGYear)
+	return t.Year(), err
+}
+
+func GYearMonthLiteral(year int, month time.Month) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdGYearMonth, val: fmt.Sprintf("%s-%02d", formatGYear(year), month)},
+	}
+}
+
+func (b *tripleBuilder) GYearMonthLiteral(year int, month time.Month) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, GYearMonthLiteral(year, month).(object))
+}
+
+func ParseGYearMonth(obj Object) (int, time.Month, error) {
+	t, err := parseGregorian(obj, XsdGYearMonth)
+	return t.Year(), t.Month(), err
+}
+
+func GMonthDayLiteral(month time.Month, day int) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdGMonthDay, val: fmt.Sprintf("--%02d-%02d", month, day)},
+	}
+}
+
+func (b *tripleBuilder) GMonthDayLiteral(month time.Month, day int) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, GMonthDayLiteral(month, day).(object))
+}
+
+func ParseGMonthDay(obj Object) (time.Month, int, error) {
+	t, err := parseGregorian(obj, XsdGMonthDay)
+	return t.Month(), t.Day(), err
+}
+
+func GMonthLiteral(month time.Month) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdGMonth, val: fmt.Sprintf("--%02d", month)},
+	}
+}
+
+func (b *tripleBuilder) GMonthLiteral(month time.Month) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, GMonthLiteral(month).(object))
+}
+
+func ParseGMonth(obj Object) (time.Month, error) {
+	t, err := parseGregorian(obj, XsdGMonth)
+	return t.Month(), err
+}
+
+func GDayLiteral(day int) Object {
+	return object{
+		isLit: true,
+		lit:   literal{typ: XsdGDay, val: fmt.Sprintf("---%02d", day)},
+	}
+}
+
+func (b *tripleBuilder) GDayLiteral(day int) *triple {
+	return makeTriple(b.sub, b.isSubBnode, b.pred, GDayLiteral(day).(object))
+}
+
+func ParseGDay(obj Object) (int, error) {
+	t, err := parseGregorian(obj, XsdGDay)
+	return t.Day(), err
+}
+
+func formatGYear(year int) string {
+	if year < 0 {
+		return fmt.Sprintf("-%04d", -year)
+	}
+	return fmt.Sprintf("%04d", year)
+}
+
+const gregorianTZ = `(Z|[+-]\d{2}:\d{2})?`
+
+var gregorianFormats = map[XsdType]*regexp.Regexp{
+	XsdGYear:      regexp.MustCompile(`^(-?\d{4,})` + gregorianTZ + `$`),
+	XsdGYearMonth: regexp.MustCompile(`^(-?\d{4,})-(\d{2})` + gregorianTZ + `$`),
+	XsdGMonthDay:  regexp.MustCompile(`^--(\d{2})-(\d{2})` + gregorianTZ + `$`),
+	XsdGMonth:     regexp.MustCompile(`^--(\d{2})` + gregorianTZ + `$`),
+	XsdGDay:       regexp.MustCompile(`^---(\d{2})` + gregorianTZ + `$`),
+}
+
+func parseGregorian(obj Object, typ XsdType) (time.Time, error) {
+	lit, ok := obj.Literal()
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot parse %s: object is not literal", typ)
+	}
+	if lit.Type() != typ {
+		return time.Time{}, fmt.Errorf("literal is not an %s but %s", typ, lit.Type())
+	}
+
+	matches := gregorianFormats[typ].FindStringSubmatch(lit.Value())
+	if matches == nil {
+		return time.Time{}, fmt.Errorf("invalid %s '%s'", typ, lit.Value())
+	}
+	parts, tz := matches[1:len(matches)-1], matches[len(matches)-1]
+
+	year, month, day := 0, 1, 1
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s '%s'", typ, lit.Value())
+		}
+		nums[i] = n
+	}
+	switch typ {
+	case XsdGYear:
+		year = nums[0]
+	case XsdGYearMonth:
+		year, month = nums[0], nums[1]
+	case XsdGMonthDay:
+		month, day = nums[0], nums[1]
+	case XsdGMonth:
+		month = nums[0]
+	case XsdGDay:
+		day = nums[0]
+	}
+	if month < 1 || month > 12 || day < 1 || day > daysInMonth(month, year, typ) {
+		return time.Time{}, fmt.Errorf("invalid %s '%s'", typ, lit.Value())
+	}
+
+	loc := time.UTC
+	if tz != "" && tz != "Z" {
+		offset, err := time.Parse("-07:00", tz)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s '%s'", typ, lit.Value())
+		}
+		_, secs := offset.Zone()
+		loc = time.FixedZone(tz, secs)
+	}
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc), nil
+}
+
+// canonicalGregorian rewrites a valid gregorian value with the year on at
+// least four digits and 'Z' for UTC (ex: "--02-29+00:00" -> "--02-29Z")
+func canonicalGregorian(typ XsdType, val string) (string, bool) {
+	t, err := parseGregorian(object{isLit: true, lit: literal{typ: typ, val: val}}, typ)
+	if err != nil {
+		return val, false
+	}
+	matches := gregorianFormats[typ].FindStringSubmatch(val)
+	tz := matches[len(matches)-1]
+	if tz == "+00:00" || tz == "-00:00" {
+		tz = "Z"
+	}
+	var canonical string
+	switch typ {
+	case XsdGYear:
+		canonical = formatGYear(t.Year())
+	case XsdGYearMonth:
+		canonical = fmt.Sprintf("%s-%02d", formatGYear(t.Year()), t.Month())
+	case XsdGMonthDay:
+		canonical = fmt.Sprintf("--%02d-%02d", t.Month(), t.Day())
+	case XsdGMonth:
+		canonical = fmt.Sprintf("--%02d", t.Month())
+	case XsdGDay:
+		canonical = fmt.Sprintf("---%02d", t.Day())
+	}
+	return canonical + tz, true
+}
+
+// daysInMonth returns the number of days of the month, which has no year
+// with xsd:gMonthDay so that --02-29 is valid
+func daysInMonth(month, year int, typ XsdType) int {
+	if typ == XsdGMonthDay {
+		year = 2000
+	}
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,95 @@
+package triplestore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGregorianLiterals(t *testing.T) {
+	tcases := []struct {
+		in  Object
+		typ XsdType
+		val string
+	}{
+		{GYearLiteral(2017), XsdGYear, "2017"},
+		{GYearLiteral(-44), XsdGYear, "-0044"},
+		{GYearMonthLiteral(2017, time.November), XsdGYearMonth, "2017-11"},
+		{GMonthDayLiteral(time.December, 25), XsdGMonthDay, "--12-25"},
+		{GMonthLiteral(time.May), XsdGMonth, "--05"},
+		{GDayLiteral(3), XsdGDay, "---03"},
+	}
+	for _, tc := range tcases {
+		lit, _ := tc.in.Literal()
+		if got, want := lit.Type(), tc.typ; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+		if got, want := lit.Value(), tc.val; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	year, err := ParseGYear(SubjPred("book", "published").GYearLiteral(1984).Object())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := year, 1984; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	year, month, err := ParseGYearMonth(GYearMonthLiteral(2017, time.November))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if year != 2017 || month != time.November {
+		t.Fatalf("got %d %s", year, month)
+	}
+
+	month, day, err := ParseGMonthDay(GMonthDayLiteral(time.December, 25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if month != time.December || day != 25 {
+		t.Fatalf("got %s %d", month, day)
+	}
+
+	if month, err = ParseGMonth(GMonthLiteral(time.May)); err != nil || month != time.May {
+		t.Fatalf("got %s, %v", month, err)
+	}
+	if day, err = ParseGDay(GDayLiteral(3)); err != nil || day != 3 {
+		t.Fatalf("got %d, %v", day, err)
+	}
+
+	withTZ := object{isLit: true, lit: literal{typ: XsdGYear, val: "2017+02:00"}}
+	parsed, err := ParseLiteral(withTZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := parsed.(time.Time).Zone(); offset != 7200 {
+		t.Fatalf("got offset %d, want 7200", offset)
+	}
+
+	invalids := []Object{
+		object{isLit: true, lit: literal{typ: XsdGYear, val: "17"}},
+		object{isLit: true, lit: literal{typ: XsdGYearMonth, val: "2017-13"}},
+		object{isLit: true, lit: literal{typ: XsdGDay, val: "--03"}},
+		object{isLit: true, lit: literal{typ: XsdGMonth, val: "--00"}},
+		object{isLit: true, lit: literal{typ: XsdGMonthDay, val: "--04-31"}},
+		object{isLit: true, lit: literal{typ: XsdGMonthDay, val: "--02-30"}},
+		object{isLit: true, lit: literal{typ: XsdGDay, val: "---32"}},
+	}
+	for i, o := range invalids {
+		if _, err := ParseLiteral(o); err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+	}
+
+	if month, day, err = ParseGMonthDay(object{isLit: true, lit: literal{typ: XsdGMonthDay, val: "--02-29"}}); err != nil || month != time.February || day != 29 {
+		t.Fatalf("got %s %d, %v", month, day, err)
+	}
+	if month, day, err = ParseGMonthDay(object{isLit: true, lit: literal{typ: XsdGMonthDay, val: "--12-31"}}); err != nil || month != time.December || day != 31 {
+		t.Fatalf("got %s %d, %v", month, day, err)
+	}
+	if _, err := ParseGMonth(GDayLiteral(3)); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,48 @@
+package triplestore
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsGzipFormat detects if the reader starts with the gzip magic bytes.
+// The returned reader must be used in place of the given one.
+func IsGzipFormat(r io.Reader) (bool, io.Reader) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil {
+		return false, buffered
+	}
+	return magic[0] == gzipMagic[0] && magic[1] == gzipMagic[1], buffered
+}
+
+type gzipDecoder struct {
+	newDecoderFunc func(io.Reader) Decoder
+	r              io.Reader
+}
+
+// NewGzipDecoder returns a decoder decompressing gzipped input (ex: .nt.gz or .bin.gz dumps)
+// before decoding it with the decoder built by fn. Input without the gzip magic bytes
+// is decoded as is. For instance:
+//
+//	NewGzipDecoder(NewBinaryDecoder, f)
+func NewGzipDecoder(fn func(io.Reader) Decoder, r io.Reader) Decoder {
+	return &gzipDecoder{newDecoderFunc: fn, r: r}
+}
+
+func (dec *gzipDecoder) Decode() ([]Triple, error) {
+	isGzip, r := IsGzipFormat(dec.r)
+	if !isGzip {
+		return dec.newDecoderFunc(r).Decode()
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %s", err)
+	}
+	defer gz.Close()
+	return dec.newDecoderFunc(gz).Decode()
+}
@@ -0,0 +1,63 @@
+package triplestore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGzipDecoder(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").Resource("three"),
+		SubjPred("one", "two").IntegerLiteral(42),
+	}
+
+	var bin bytes.Buffer
+	if err := NewBinaryEncoder(&bin).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	var nt bytes.Buffer
+	if err := NewLenientNTEncoder(&nt).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	gzipped := func(b []byte) *bytes.Buffer {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(b)
+		w.Close()
+		return &buf
+	}
+
+	tcases := []struct {
+		fn func(io.Reader) Decoder
+		in io.Reader
+	}{
+		{NewBinaryDecoder, gzipped(bin.Bytes())},
+		{NewBinaryDecoder, bytes.NewReader(bin.Bytes())},
+		{NewLenientNTDecoder, gzipped(nt.Bytes())},
+		{NewLenientNTDecoder, bytes.NewReader(nt.Bytes())},
+	}
+	for i, tc := range tcases {
+		decoded, err := NewGzipDecoder(tc.fn, tc.in).Decode()
+		if err != nil {
+			t.Fatalf("case %d: %s", i+1, err)
+		}
+		if got, want := Triples(decoded), tris; !got.Equal(want) {
+			t.Fatalf("case %d: got %v, want %v", i+1, got, want)
+		}
+	}
+
+	decoded, err := NewGzipDecoder(NewLenientNTDecoder, strings.NewReader("")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 0 {
+		t.Fatalf("got %v, want none", decoded)
+	}
+
+	if _, err := NewGzipDecoder(NewBinaryDecoder, bytes.NewReader([]byte{0x1f, 0x8b, 0x00})).Decode(); err == nil {
+		t.Fatal("expected error")
+	}
+}
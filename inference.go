@@ -0,0 +1,59 @@
+package triplestore
+
+import "fmt"
+
+const (
+	rdfNamespace  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rdfsNamespace = "http://www.w3.org/2000/01/rdf-schema#"
+)
+
+type rdfsVocabulary struct {
+	typ, subClassOf, subPropertyOf, domain, rng string
+}
+
+// RDFS terms are recognized either as prefixed names (ex: "rdfs:subClassOf")
+// or as full IRIs. Entailed triples use the same form as their premises.
+var rdfsVocabularies = []rdfsVocabulary{
+	{typ: "rdf:type", subClassOf: "rdfs:subClassOf", subPropertyOf: "rdfs:subPropertyOf", domain: "rdfs:domain", rng: "rdfs:range"},
+	{
+		typ:           rdfNamespace + "type",
+		subClassOf:    rdfsNamespace + "subClassOf",
+		subPropertyOf: rdfsNamespace + "subPropertyOf",
+		domain:        rdfsNamespace + "domain",
+		rng:           rdfsNamespace + "range",
+	},
+}
+
+var rdfsRules = func() (rules []*Rule) {
+	for _, v := range rdfsVocabularies {
+		rules = append(rules, v.rules()...)
+	}
+	return
+}()
+
+// InferRDFS materializes the RDFS entailments of the graph regarding
+// rdfs:subClassOf, rdfs:subPropertyOf, rdfs:domain and rdfs:range
+// (i.e. rules rdfs2, rdfs3, rdfs5, rdfs7, rdfs9 and rdfs11).
+// It returns only the inferred triples, i.e. the ones not already in the graph.
+func InferRDFS(g RDFGraph) []Triple {
+	return ApplyRules(g, rdfsRules...)
+}
+
+func (v rdfsVocabulary) rules() []*Rule {
+	return []*Rule{
+		// rdfs2 and rdfs3: domain and range
+		MustParseRule(fmt.Sprintf("{ ?p <%s> ?c . ?s ?p ?o } => { ?s <%s> ?c }", v.domain, v.typ)),
+		MustParseRule(fmt.Sprintf("{ ?p <%s> ?c . ?s ?p ?o } => { ?o <%s> ?c }", v.rng, v.typ)),
+		// rdfs5 and rdfs11: transitivity
+		MustParseRule(fmt.Sprintf("{ ?a <%[1]s> ?b . ?b <%[1]s> ?c } => { ?a <%[1]s> ?c }", v.subPropertyOf)),
+		MustParseRule(fmt.Sprintf("{ ?a <%[1]s> ?b . ?b <%[1]s> ?c } => { ?a <%[1]s> ?c }", v.subClassOf)),
+		// rdfs7: sub properties
+		MustParseRule(fmt.Sprintf("{ ?p <%s> ?q . ?s ?p ?o } => { ?s ?q ?o }", v.subPropertyOf)),
+		// rdfs9: instances of sub classes
+		MustParseRule(fmt.Sprintf("{ ?c <%s> ?d . ?s <%s> ?c } => { ?s <%s> ?d }", v.subClassOf, v.typ, v.typ)),
+	}
+}
+
+func newTriple(sub object, pred string, obj object) *triple {
+	return makeTriple(nodeID(sub), sub.isBnode, pred, obj)
+}
@@ -0,0 +1,41 @@
+package triplestore
+
+import "testing"
+
+func TestInferRDFS(t *testing.T) {
+	s := NewSource()
+	s.Add(
+		SubjPred("Dog", "rdfs:subClassOf").Resource("Mammal"),
+		SubjPred("Mammal", "rdfs:subClassOf").Resource("Animal"),
+		SubjPred("hasPuppy", "rdfs:subPropertyOf").Resource("hasChild"),
+		SubjPred("hasChild", "rdfs:domain").Resource("Parent"),
+		SubjPred("hasChild", "rdfs:range").Resource("Mammal"),
+		SubjPred("rex", "rdf:type").Resource("Dog"),
+		SubjPred("rex", "hasPuppy").Bnode("puppy"),
+		SubjPred("rex", "name").StringLiteral("Rex"),
+
+		SubjPred("http://ex.org/Cat", rdfsNamespace+"subClassOf").Resource("http://ex.org/Feline"),
+		SubjPred("http://ex.org/tom", rdfNamespace+"type").Resource("http://ex.org/Cat"),
+	)
+
+	inferred := InferRDFS(s.Snapshot())
+
+	exp := Triples{
+		SubjPred("Dog", "rdfs:subClassOf").Resource("Animal"),
+		SubjPred("rex", "rdf:type").Resource("Mammal"),
+		SubjPred("rex", "rdf:type").Resource("Animal"),
+		SubjPred("rex", "hasChild").Bnode("puppy"),
+		SubjPred("rex", "rdf:type").Resource("Parent"),
+		BnodePred("puppy", "rdf:type").Resource("Mammal"),
+		BnodePred("puppy", "rdf:type").Resource("Animal"),
+		SubjPred("http://ex.org/tom", rdfNamespace+"type").Resource("http://ex.org/Feline"),
+	}
+	if got, want := Triples(inferred), exp; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	s.Add(inferred...)
+	if got := InferRDFS(s.Snapshot()); len(got) != 0 {
+		t.Fatalf("expected no more inferences, got %v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package triplestore
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// ValidateIRI checks that the string is an absolute IRI as defined by RFC 3987:
+// a scheme followed by ':' and characters allowed in IRIs, with valid percent-encodings.
+func ValidateIRI(iri string) error {
+	colon := -1
+	for i := 0; i < len(iri); i++ {
+		c := iri[i]
+		if c == ':' {
+			colon = i
+			break
+		}
+		isAlpha := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if i == 0 && !isAlpha || !isAlpha && !(c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+			break
+		}
+	}
+	if colon < 1 {
+		return fmt.Errorf("invalid IRI '%s': missing scheme", iri)
+	}
+
+	for i, r := range iri {
+		switch {
+		case r == utf8.RuneError:
+			return fmt.Errorf("invalid IRI '%s': invalid UTF-8 at offset %d", iri, i)
+		case r == '%':
+			if i+2 >= len(iri) || !isHex(iri[i+1]) || !isHex(iri[i+2]) {
+				return fmt.Errorf("invalid IRI '%s': invalid percent-encoding at offset %d", iri, i)
+			}
+		case !isIRIChar(r):
+			return fmt.Errorf("invalid IRI '%s': forbidden character %q at offset %d", iri, r, i)
+		}
+	}
+	return nil
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// isIRIChar reports whether the rune is an unreserved, reserved or ucschar/iprivate IRI character
+func isIRIChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r < utf8.RuneSelf:
+		switch r {
+		case '-', '.', '_', '~', ':', '/', '?', '#', '[', ']', '@', '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=':
+			return true
+		}
+		return false
+	case r < 0xA0, r >= 0xFDD0 && r <= 0xFDEF, r&0xFFFE == 0xFFFE:
+		return false
+	}
+	return true
+}
+
+// ValidateTripleIRIs checks the subject, predicate and resource object of the triple
+// are valid IRIs (see ValidateIRI). Blank nodes are not checked.
+func ValidateTripleIRIs(t Triple) error {
+	tri := t.(*triple)
+	if !tri.isSubBnode {
+		if err := ValidateIRI(tri.sub); err != nil {
+			return fmt.Errorf("subject: %s", err)
+		}
+	}
+	if err := ValidateIRI(tri.pred); err != nil {
+		return fmt.Errorf("predicate: %s", err)
+	}
+	if !tri.obj.isLit && !tri.obj.isBnode {
+		if err := ValidateIRI(tri.obj.resource); err != nil {
+			return fmt.Errorf("object: %s", err)
+		}
+	}
+	return nil
+}
+
+type iriValidatingEncoder struct {
+	enc Encoder
+}
+
+// NewIRIValidatingEncoder wraps an encoder so that triples are rejected
+// with a descriptive error when they contain invalid IRIs (see ValidateTripleIRIs).
+// Nothing is encoded if any triple is invalid.
+func NewIRIValidatingEncoder(enc Encoder) Encoder {
+	return &iriValidatingEncoder{enc: enc}
+}
+
+func (v *iriValidatingEncoder) Encode(tris ...Triple) error {
+	for i, t := range tris {
+		if err := ValidateTripleIRIs(t); err != nil {
+			return fmt.Errorf("triple %d: %s", i, err)
+		}
+	}
+	return v.enc.Encode(tris...)
+}
@@ -0,0 +1,73 @@
+package triplestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidateIRI(t *testing.T) {
+	valid := []string{
+		"http://example.org/path?q=1#frag",
+		"urn:isbn:0451450523",
+		"rdf:type",
+		"http://例え.jp/引き割り",
+		"http://example.org/%20space",
+		"mailto:john@example.org",
+	}
+	for _, iri := range valid {
+		if err := ValidateIRI(iri); err != nil {
+			t.Fatalf("%s: %s", iri, err)
+		}
+	}
+
+	tcases := []struct {
+		iri, err string
+	}{
+		{"", "missing scheme"},
+		{"not a uri at all", "missing scheme"},
+		{":nothing", "missing scheme"},
+		{"1http://example.org", "missing scheme"},
+		{"http://example.org/with space", "forbidden character ' '"},
+		{"http://example.org/<tag>", "forbidden character '<'"},
+		{"http://example.org/\"quote", "forbidden character"},
+		{"http://example.org/%2", "invalid percent-encoding"},
+		{"http://example.org/%zz", "invalid percent-encoding"},
+		{"http://example.org/\x85", "invalid UTF-8"},
+	}
+	for i, tc := range tcases {
+		err := ValidateIRI(tc.iri)
+		if err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %q, want it to contain %q", i, err, tc.err)
+		}
+	}
+}
+
+func TestIRIValidatingEncoder(t *testing.T) {
+	var buff bytes.Buffer
+	enc := NewIRIValidatingEncoder(NewLenientNTEncoder(&buff))
+
+	valid := []Triple{
+		SubjPred("http://ex.org/s", "http://ex.org/p").Resource("http://ex.org/o"),
+		BnodePred("b", "http://ex.org/p").Bnode("c"),
+		SubjPred("http://ex.org/s", "http://ex.org/p").StringLiteral("not a uri at all"),
+	}
+	if err := enc.Encode(valid...); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []Triple{
+		SubjPred("http://ex.org/s", "http://ex.org/p").Resource("not a uri at all"),
+	}
+	buff.Reset()
+	err := enc.Encode(invalid...)
+	if err == nil || !strings.Contains(err.Error(), "triple 0: object: invalid IRI 'not a uri at all'") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if buff.Len() != 0 {
+		t.Fatal("nothing should be encoded")
+	}
+}
@@ -0,0 +1,210 @@
+package triplestore
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// IsomorphicGraphs returns true if both sets of triples are equal
+// up to a renaming of their blank nodes.
+func IsomorphicGraphs(a, b Triples) bool {
+	ga, groundA := newBnodeGraph(a)
+	gb, groundB := newBnodeGraph(b)
+	if len(ga.tris) != len(gb.tris) || len(ga.bnodes) != len(gb.bnodes) {
+		return false
+	}
+	if !Triples(groundA).Equal(groundB) {
+		return false
+	}
+	return isomorphicSearch(ga, gb, ga.initialColors(), gb.initialColors())
+}
+
+// bnodeGraph holds the triples involving blank nodes
+type bnodeGraph struct {
+	tris    []*triple
+	bnodes  []string
+	byBnode map[string][]*triple
+}
+
+// newBnodeGraph splits the deduplicated triples between the ones involving blank nodes and the ground ones
+func newBnodeGraph(ts Triples) (*bnodeGraph, []Triple) {
+	g := &bnodeGraph{byBnode: make(map[string][]*triple)}
+	var ground []Triple
+	seen := make(map[string]bool)
+	for _, t := range ts {
+		tri := t.(*triple)
+		if seen[tri.key()] {
+			continue
+		}
+		seen[tri.key()] = true
+		if !tri.isSubBnode && !tri.obj.isBnode {
+			ground = append(ground, tri)
+			continue
+		}
+		g.tris = append(g.tris, tri)
+		if tri.isSubBnode {
+			g.addBnode(tri.sub, tri)
+		}
+		if tri.obj.isBnode && !(tri.isSubBnode && tri.sub == tri.obj.bnode) {
+			g.addBnode(tri.obj.bnode, tri)
+		}
+	}
+	sort.Strings(g.bnodes)
+	return g, ground
+}
+
+func (g *bnodeGraph) addBnode(bnode string, t *triple) {
+	if _, ok := g.byBnode[bnode]; !ok {
+		g.bnodes = append(g.bnodes, bnode)
+	}
+	g.byBnode[bnode] = append(g.byBnode[bnode], t)
+}
+
+func (g *bnodeGraph) initialColors() map[string]string {
+	colors := make(map[string]string, len(g.bnodes))
+	for _, bn := range g.bnodes {
+		colors[bn] = ""
+	}
+	return colors
+}
+
+// refine computes the next colors of blank nodes from the colors of their neighbourhood
+func (g *bnodeGraph) refine(colors map[string]string) map[string]string {
+	next := make(map[string]string, len(colors))
+	for _, bn := range g.bnodes {
+		sigs := make([]string, 0, len(g.byBnode[bn]))
+		for _, t := range g.byBnode[bn] {
+			sigs = append(sigs, bnodeSignature(t, bn, colors))
+		}
+		sort.Strings(sigs)
+		next[bn] = hashString(colors[bn] + "|" + strings.Join(sigs, "|"))
+	}
+	return next
+}
+
+func bnodeSignature(t *triple, self string, colors map[string]string) string {
+	node := func(id string) string {
+		if id == self {
+			return "@"
+		}
+		return colors[id]
+	}
+	sub := "<" + t.sub + ">"
+	if t.isSubBnode {
+		sub = "_:" + node(t.sub)
+	}
+	obj := t.obj.key()
+	if t.obj.isBnode {
+		obj = "_:" + node(t.obj.bnode)
+	}
+	return sub + "<" + t.pred + ">" + obj
+}
+
+func hashString(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// refineBoth refines the colors of both graphs jointly until their partitions are stable
+func refineBoth(ga, gb *bnodeGraph, ca, cb map[string]string) (map[string]string, map[string]string) {
+	for {
+		na, nb := ga.refine(ca), gb.refine(cb)
+		if countClasses(na) == countClasses(ca) && countClasses(nb) == countClasses(cb) {
+			return na, nb
+		}
+		ca, cb = na, nb
+	}
+}
+
+func countClasses(colors map[string]string) int {
+	classes := make(map[string]bool)
+	for _, c := range colors {
+		classes[c] = true
+	}
+	return len(classes)
+}
+
+func colorCounts(colors map[string]string) map[string]int {
+	counts := make(map[string]int)
+	for _, c := range colors {
+		counts[c]++
+	}
+	return counts
+}
+
+func isomorphicSearch(ga, gb *bnodeGraph, ca, cb map[string]string) bool {
+	ca, cb = refineBoth(ga, gb, ca, cb)
+	countsA, countsB := colorCounts(ca), colorCounts(cb)
+	if len(countsA) != len(countsB) {
+		return false
+	}
+	for c, n := range countsA {
+		if countsB[c] != n {
+			return false
+		}
+	}
+
+	// pick a blank node of the smallest ambiguous class to branch on
+	var pick string
+	for _, bn := range ga.bnodes {
+		if n := countsA[ca[bn]]; n > 1 && (pick == "" || n < countsA[ca[pick]]) {
+			pick = bn
+		}
+	}
+
+	if pick == "" {
+		byColor := make(map[string]string, len(cb))
+		for bn, c := range cb {
+			byColor[c] = bn
+		}
+		mapping := make(map[string]string, len(ca))
+		for bn, c := range ca {
+			mapping[bn] = byColor[c]
+		}
+		return ga.relabelEqual(gb, mapping)
+	}
+
+	for _, candidate := range gb.bnodes {
+		if cb[candidate] != ca[pick] {
+			continue
+		}
+		branchA, branchB := copyColors(ca), copyColors(cb)
+		branchA[pick] = hashString(ca[pick] + "*")
+		branchB[candidate] = branchA[pick]
+		if isomorphicSearch(ga, gb, branchA, branchB) {
+			return true
+		}
+	}
+	return false
+}
+
+func copyColors(colors map[string]string) map[string]string {
+	c := make(map[string]string, len(colors))
+	for k, v := range colors {
+		c[k] = v
+	}
+	return c
+}
+
+// relabelEqual checks that renaming the blank nodes of g with the mapping gives other
+func (g *bnodeGraph) relabelEqual(other *bnodeGraph, mapping map[string]string) bool {
+	keys := make(map[string]bool, len(other.tris))
+	for _, t := range other.tris {
+		keys[t.key()] = true
+	}
+	for _, t := range g.tris {
+		sub, obj := t.sub, t.obj
+		if t.isSubBnode {
+			sub = mapping[t.sub]
+		}
+		if t.obj.isBnode {
+			obj = object{isBnode: true, bnode: mapping[t.obj.bnode]}
+		}
+		if !keys[makeTriple(sub, t.isSubBnode, t.pred, obj).key()] {
+			return false
+		}
+	}
+	return true
+}
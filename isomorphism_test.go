@@ -0,0 +1,96 @@
+package triplestore
+
+import "testing"
+
+func TestIsomorphicGraphs(t *testing.T) {
+	tcases := []struct {
+		a, b Triples
+		exp  bool
+	}{
+		{
+			a:   Triples{SubjPred("s", "p").Resource("o")},
+			b:   Triples{SubjPred("s", "p").Resource("o")},
+			exp: true,
+		},
+		{
+			a:   Triples{SubjPred("s", "p").Resource("o")},
+			b:   Triples{SubjPred("s", "p").Resource("other")},
+			exp: false,
+		},
+		{
+			a: Triples{
+				SubjPred("s", "p").Bnode("a1"),
+				BnodePred("a1", "name").StringLiteral("x"),
+			},
+			b: Triples{
+				SubjPred("s", "p").Bnode("b1"),
+				BnodePred("b1", "name").StringLiteral("x"),
+			},
+			exp: true,
+		},
+		{
+			a: Triples{
+				SubjPred("s", "p").Bnode("a1"),
+				BnodePred("a1", "name").StringLiteral("x"),
+			},
+			b: Triples{
+				SubjPred("s", "p").Bnode("b1"),
+				BnodePred("b2", "name").StringLiteral("x"),
+			},
+			exp: false,
+		},
+		{
+			// symmetric cycles needing backtracking
+			a: Triples{
+				BnodePred("a", "next").Bnode("b"),
+				BnodePred("b", "next").Bnode("c"),
+				BnodePred("c", "next").Bnode("a"),
+			},
+			b: Triples{
+				BnodePred("z", "next").Bnode("x"),
+				BnodePred("x", "next").Bnode("y"),
+				BnodePred("y", "next").Bnode("z"),
+			},
+			exp: true,
+		},
+		{
+			// one 6-cycle vs two 3-cycles: not distinguishable by refinement alone
+			a: Triples{
+				BnodePred("a1", "next").Bnode("a2"),
+				BnodePred("a2", "next").Bnode("a3"),
+				BnodePred("a3", "next").Bnode("a4"),
+				BnodePred("a4", "next").Bnode("a5"),
+				BnodePred("a5", "next").Bnode("a6"),
+				BnodePred("a6", "next").Bnode("a1"),
+			},
+			b: Triples{
+				BnodePred("b1", "next").Bnode("b2"),
+				BnodePred("b2", "next").Bnode("b3"),
+				BnodePred("b3", "next").Bnode("b1"),
+				BnodePred("b4", "next").Bnode("b5"),
+				BnodePred("b5", "next").Bnode("b6"),
+				BnodePred("b6", "next").Bnode("b4"),
+			},
+			exp: false,
+		},
+		{
+			a:   Triples{BnodePred("a", "self").Bnode("a")},
+			b:   Triples{BnodePred("b", "self").Bnode("c")},
+			exp: false,
+		},
+		{
+			a:   Triples{SubjPred("s", "p").Bnode("a"), SubjPred("s", "p").Bnode("a")},
+			b:   Triples{SubjPred("s", "p").Bnode("b")},
+			exp: true,
+		},
+	}
+
+	for i, tc := range tcases {
+		if got, want := IsomorphicGraphs(tc.a, tc.b), tc.exp; got != want {
+			t.Fatalf("case %d: got %t, want %t", i, got, want)
+		}
+		if got, want := IsomorphicGraphs(tc.b, tc.a), tc.exp; got != want {
+			t.Fatalf("case %d (reversed): got %t, want %t", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package triplestore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Triples and objects are marshaled to JSON with the terms representation
+// of the SPARQL 1.1 Query Results JSON Format. A triple is an object of terms:
+//
+//	{
+//	  "subject": {"type": "uri", "value": "http://ex.org/bob"},
+//	  "predicate": {"type": "uri", "value": "http://xmlns.com/foaf/0.1/age"},
+//	  "object": {"type": "literal", "value": "42", "datatype": "http://www.w3.org/2001/XMLSchema#integer"}
+//	}
+//
+// Term types are "uri", "bnode" and "literal". Literals have either a
+// "xml:lang" or a "datatype" (omitted for xsd:string).
+// Decoded XML schema datatypes are prefixed (ex: "xsd:integer") as done by literal builders.
+//
+// As triples and objects are interfaces, use UnmarshalTriple, UnmarshalObject
+// or the Triples type to decode them.
+
+type jsonTriple struct {
+	Subject   jsonTerm `json:"subject"`
+	Predicate jsonTerm `json:"predicate"`
+	Object    jsonTerm `json:"object"`
+}
+
+func (t *triple) MarshalJSON() ([]byte, error) {
+	sub := jsonTerm{Type: "uri", Value: t.sub}
+	if t.isSubBnode {
+		sub.Type = "bnode"
+	}
+	return json.Marshal(jsonTriple{
+		Subject:   sub,
+		Predicate: jsonTerm{Type: "uri", Value: t.pred},
+		Object:    toJSONTerm(t.obj),
+	})
+}
+
+// UnmarshalJSON decodes the triple. As triples are immutable,
+// only zero triples (i.e. new(triple)) can be decoded into.
+func (t *triple) UnmarshalJSON(data []byte) error {
+	if t.sub != "" || t.pred != "" {
+		return errors.New("cannot unmarshal into an immutable triple")
+	}
+	var jt jsonTriple
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return err
+	}
+	sub, err := fromJSONTerm(jt.Subject)
+	if err != nil {
+		return fmt.Errorf("subject: %s", err)
+	}
+	if sub.isLit {
+		return fmt.Errorf("subject: literal not allowed")
+	}
+	if jt.Predicate.Type != "uri" {
+		return fmt.Errorf("predicate: expected uri, got %q", jt.Predicate.Type)
+	}
+	obj, err := fromJSONTerm(jt.Object)
+	if err != nil {
+		return fmt.Errorf("object: %s", err)
+	}
+	*t = *newTriple(sub, jt.Predicate.Value, obj)
+	return nil
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	return json.Marshal(toJSONTerm(o))
+}
+
+func (o *object) UnmarshalJSON(data []byte) error {
+	var jt jsonTerm
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return err
+	}
+	obj, err := fromJSONTerm(jt)
+	if err != nil {
+		return err
+	}
+	*o = obj
+	return nil
+}
+
+func fromJSONTerm(t jsonTerm) (object, error) {
+	switch t.Type {
+	case "uri":
+		return object{resource: t.Value}, nil
+	case "bnode":
+		return object{isBnode: true, bnode: t.Value}, nil
+	case "literal", "typed-literal":
+		lit := literal{typ: XsdString, val: t.Value, langtag: t.Lang}
+		if t.Datatype != "" && t.Lang == "" {
+			lit.typ = shortXsdType(XsdType(t.Datatype))
+		}
+		return object{isLit: true, lit: lit}, nil
+	}
+	return object{}, fmt.Errorf("unknown term type %q", t.Type)
+}
+
+// UnmarshalTriple decodes a triple marshaled to JSON
+func UnmarshalTriple(data []byte) (Triple, error) {
+	t := new(triple)
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// UnmarshalObject decodes an object marshaled to JSON
+func UnmarshalObject(data []byte) (Object, error) {
+	var o object
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// UnmarshalJSON decodes a JSON array of triples
+func (ts *Triples) UnmarshalJSON(data []byte) error {
+	var tris []*triple
+	if err := json.Unmarshal(data, &tris); err != nil {
+		return err
+	}
+	*ts = make(Triples, len(tris))
+	for i, t := range tris {
+		(*ts)[i] = t
+	}
+	return nil
+}
+
+type jsonDecoder struct {
+	r    io.Reader
+	opts decoderOptions
+}
+
+// NewJSONDecoder returns a decoder of JSON triples. The input is a sequence of
+// triples or arrays of triples (ex: a JSON array or newline delimited JSON).
+func NewJSONDecoder(r io.Reader, opts ...DecoderOption) Decoder {
+	return &jsonDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
+func (d *jsonDecoder) Decode() ([]Triple, error) {
+	var out []Triple
+	tracker := newProgressTracker(d.opts.progress)
+	dec := json.NewDecoder(tracker.reader(d.r))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return out, fmt.Errorf("json: %s", err)
+		}
+		var tris Triples
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(raw, &tris); err != nil {
+				return out, fmt.Errorf("json: %s", err)
+			}
+		} else {
+			t, err := UnmarshalTriple(raw)
+			if err != nil {
+				return out, fmt.Errorf("json: %s", err)
+			}
+			tris = Triples{t}
+		}
+		for _, t := range tris {
+			if err := d.opts.checkTermSize(t.(*triple)); err != nil {
+				return out, err
+			}
+			out = append(out, t)
+			tracker.addTriple()
+		}
+	}
+	tracker.done()
+	return out, nil
+}
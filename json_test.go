@@ -0,0 +1,83 @@
+package triplestore
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTripleJSON(t *testing.T) {
+	tris := Triples{
+		SubjPred("http://ex.org/bob", "http://xmlns.com/foaf/0.1/age").IntegerLiteral(42),
+		BnodePred("b0", "name").StringLiteral("bob"),
+		SubjPred("bob", "label").StringLiteralWithLang("Bob", "en"),
+		SubjPred("bob", "knows").Bnode("b0"),
+		SubjPred("bob", "knows").Resource("alice"),
+	}
+
+	data, err := json.Marshal(tris[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"subject":{"type":"uri","value":"http://ex.org/bob"},"predicate":{"type":"uri","value":"http://xmlns.com/foaf/0.1/age"},"object":{"type":"literal","value":"42","datatype":"http://www.w3.org/2001/XMLSchema#integer"}}`
+	if got, want := string(data), exp; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	decoded, err := UnmarshalTriple(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(tris[0]) {
+		t.Fatalf("got %v, want %v", decoded, tris[0])
+	}
+
+	payload := struct {
+		Triples Triples `json:"triples"`
+		Object  Object  `json:"object"`
+	}{Triples: tris, Object: StringLiteralWithLang("chat", "fr")}
+	data, err = json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"object":{"type":"literal","value":"chat","xml:lang":"fr"}`) {
+		t.Fatalf("unexpected object in %s", data)
+	}
+
+	var payloadBack struct {
+		Triples Triples         `json:"triples"`
+		Object  json.RawMessage `json:"object"`
+	}
+	if err := json.Unmarshal(data, &payloadBack); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := payloadBack.Triples, tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	obj, err := UnmarshalObject(payloadBack.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := obj, StringLiteralWithLang("chat", "fr"); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTripleJSONErrors(t *testing.T) {
+	tcases := []struct {
+		in, err string
+	}{
+		{in: `{"subject":{"type":"literal","value":"s"},"predicate":{"type":"uri","value":"p"},"object":{"type":"uri","value":"o"}}`, err: "subject: literal not allowed"},
+		{in: `{"subject":{"type":"uri","value":"s"},"predicate":{"type":"bnode","value":"p"},"object":{"type":"uri","value":"o"}}`, err: `predicate: expected uri, got "bnode"`},
+		{in: `{"subject":{"type":"uri","value":"s"},"predicate":{"type":"uri","value":"p"},"object":{"type":"iri","value":"o"}}`, err: `object: unknown term type "iri"`},
+	}
+	for i, tc := range tcases {
+		_, err := UnmarshalTriple([]byte(tc.in))
+		if err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+		if got, want := err.Error(), tc.err; got != want {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package triplestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSON-LD documents are supported in expanded form only: contexts are not
+// processed, so properties, @id and @type values are full IRIs (or blank node
+// identifiers "_:label") and values are value objects or node objects:
+//
+//	[{
+//	  "@id": "http://ex.org/bob",
+//	  "http://xmlns.com/foaf/0.1/age": [{"@value": "42", "@type": "http://www.w3.org/2001/XMLSchema#integer"}],
+//	  "http://xmlns.com/foaf/0.1/knows": [{"@id": "_:b1"}]
+//	}]
+
+type jsonldEncoder struct {
+	w io.Writer
+	c *Context
+}
+
+// NewJSONLDEncoder returns an encoder writing the triples as a JSON-LD document
+// in expanded form: an array of node objects, one per subject. Prefixed names
+// are expanded with the prefixes given with WithPrefixes.
+func NewJSONLDEncoder(w io.Writer, opts ...EncoderOption) Encoder {
+	o := newEncoderOptions(opts)
+	return &jsonldEncoder{w: w, c: o.context}
+}
+
+func (enc *jsonldEncoder) Encode(tris ...Triple) error {
+	nodes := []map[string]interface{}{}
+	bySubject := make(map[string]map[string]interface{})
+	for _, t := range expandTriples(enc.c, tris) {
+		tri := t.(*triple)
+		sub := subjectObject(tri)
+		node, ok := bySubject[sub.key()]
+		if !ok {
+			node = map[string]interface{}{"@id": toJSONLDID(sub)}
+			bySubject[sub.key()] = node
+			nodes = append(nodes, node)
+		}
+		values, _ := node[tri.pred].([]map[string]string)
+		node[tri.pred] = append(values, toJSONLDValue(tri.obj))
+	}
+	return json.NewEncoder(enc.w).Encode(nodes)
+}
+
+func toJSONLDID(o object) string {
+	if o.isBnode {
+		return "_:" + o.bnode
+	}
+	return o.resource
+}
+
+func toJSONLDValue(o object) map[string]string {
+	switch {
+	case !o.isLit:
+		return map[string]string{"@id": toJSONLDID(o)}
+	case o.lit.langtag != "":
+		return map[string]string{"@value": o.lit.val, "@language": o.lit.langtag}
+	case o.lit.typ == "" || o.lit.typ == XsdString:
+		return map[string]string{"@value": o.lit.val}
+	default:
+		return map[string]string{"@value": o.lit.val, "@type": o.lit.typ.NTriplesNamespaced()}
+	}
+}
+
+type jsonldDecoder struct {
+	r      io.Reader
+	opts   decoderOptions
+	tris   []Triple
+	bnodes int
+	used   map[string]bool
+}
+
+// NewJSONLDDecoder returns a decoder of JSON-LD documents in expanded form (see
+// NewJSONLDEncoder), possibly flattened in a @graph. Documents with a @context
+// are rejected, as are lists (@list) and reverse properties (@reverse). The
+// triples of named graphs are decoded in the default graph. Node objects without
+// @id are labelled "jsonld-1", "jsonld-2", etc., skipping the labels of the
+// document. As done by the JSON-LD to RDF algorithm, JSON numbers are
+// xsd:integer or xsd:double literals and JSON booleans xsd:boolean literals.
+func NewJSONLDDecoder(r io.Reader, opts ...DecoderOption) Decoder {
+	return &jsonldDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
+func (d *jsonldDecoder) Decode() ([]Triple, error) {
+	tracker := newProgressTracker(d.opts.progress)
+	dec := json.NewDecoder(tracker.reader(d.r))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("json-ld: %s", err)
+	}
+	d.used = make(map[string]bool)
+	collectJSONLDBnodes(doc, d.used)
+	if err := d.decodeNodes(doc); err != nil {
+		return d.tris, fmt.Errorf("json-ld: %s", err)
+	}
+	for i, t := range d.tris {
+		if err := d.opts.checkTermSize(t.(*triple)); err != nil {
+			return d.tris[:i], err
+		}
+		tracker.addTriple()
+	}
+	tracker.done()
+	return d.tris, nil
+}
+
+// decodeNodes decodes a node object or an array of node objects
+func (d *jsonldDecoder) decodeNodes(v interface{}) error {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, n := range v {
+			if err := d.decodeNodes(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		// a lone @graph only holds the node objects of the document
+		if graph, ok := v["@graph"]; ok && len(v) == 1 {
+			return d.decodeNodes(graph)
+		}
+		_, err := d.decodeNode(v)
+		return err
+	}
+	return fmt.Errorf("expected node object, got %v", v)
+}
+
+// decodeNode decodes the triples of the node object and returns its subject
+func (d *jsonldDecoder) decodeNode(node map[string]interface{}) (object, error) {
+	if _, ok := node["@context"]; ok {
+		return object{}, errors.New("@context is not supported, documents must be expanded")
+	}
+	if _, ok := node["@reverse"]; ok {
+		return object{}, errors.New("@reverse is not supported")
+	}
+
+	var sub object
+	switch id := node["@id"].(type) {
+	case nil:
+		sub = object{isBnode: true, bnode: d.newBnode()}
+	case string:
+		sub = fromJSONLDID(id)
+	default:
+		return object{}, fmt.Errorf("invalid @id %v", id)
+	}
+
+	if graph, ok := node["@graph"]; ok {
+		if err := d.decodeNodes(graph); err != nil {
+			return object{}, err
+		}
+	}
+
+	var types []interface{}
+	switch typ := node["@type"].(type) {
+	case nil:
+	case []interface{}:
+		types = typ
+	default:
+		types = []interface{}{typ}
+	}
+	for _, typ := range types {
+		id, ok := typ.(string)
+		if !ok {
+			return object{}, fmt.Errorf("invalid @type %v", typ)
+		}
+		d.tris = append(d.tris, newTriple(sub, rdfNamespace+"type", fromJSONLDID(id)))
+	}
+
+	// properties are decoded in order for the output not to depend on map iteration
+	var props []string
+	for prop := range node {
+		if !strings.HasPrefix(prop, "@") {
+			props = append(props, prop)
+		}
+	}
+	sort.Strings(props)
+	for _, prop := range props {
+		values, ok := node[prop].([]interface{})
+		if !ok {
+			values = []interface{}{node[prop]}
+		}
+		if err := d.decodeValues(sub, prop, values); err != nil {
+			return object{}, err
+		}
+	}
+	return sub, nil
+}
+
+func (d *jsonldDecoder) decodeValues(sub object, pred string, values []interface{}) error {
+	for _, v := range values {
+		var obj object
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if set, ok := v["@set"].([]interface{}); ok {
+				if err := d.decodeValues(sub, pred, set); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, ok := v["@list"]; ok {
+				return fmt.Errorf("%s: @list is not supported", pred)
+			}
+			if val, ok := v["@value"]; ok {
+				lit, err := fromJSONLDValue(val, v["@type"], v["@language"])
+				if err != nil {
+					return fmt.Errorf("%s: %s", pred, err)
+				}
+				obj = lit
+				break
+			}
+			node, err := d.decodeNode(v)
+			if err != nil {
+				return err
+			}
+			obj = node
+		default:
+			lit, err := fromJSONLDValue(v, nil, nil)
+			if err != nil {
+				return fmt.Errorf("%s: %s", pred, err)
+			}
+			obj = lit
+		}
+		d.tris = append(d.tris, newTriple(sub, pred, obj))
+	}
+	return nil
+}
+
+func (d *jsonldDecoder) newBnode() string {
+	for {
+		d.bnodes++
+		if label := fmt.Sprintf("jsonld-%d", d.bnodes); !d.used[label] {
+			return label
+		}
+	}
+}
+
+// collectJSONLDBnodes adds the labels of the blank node identifiers of the document to used
+func collectJSONLDBnodes(v interface{}, used map[string]bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, e := range v {
+			collectJSONLDBnodes(e, used)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			collectJSONLDBnodes(e, used)
+		}
+	case string:
+		if strings.HasPrefix(v, "_:") {
+			used[v[2:]] = true
+		}
+	}
+}
+
+func fromJSONLDID(id string) object {
+	if strings.HasPrefix(id, "_:") {
+		return object{isBnode: true, bnode: id[2:]}
+	}
+	return object{resource: id}
+}
+
+func fromJSONLDValue(val, typ, lang interface{}) (object, error) {
+	var lit object
+	switch val := val.(type) {
+	case string:
+		lit = object{isLit: true, lit: literal{typ: XsdString, val: val}}
+	case bool:
+		lit = BooleanLiteral(val).(object)
+	case json.Number:
+		if _, err := strconv.ParseInt(string(val), 10, 64); err == nil && typ == nil {
+			lit = object{isLit: true, lit: literal{typ: XsdInteger, val: string(val)}}
+			break
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return object{}, err
+		}
+		lit = Float64Literal(f).(object)
+		if typ != nil {
+			lit.lit.val = string(val)
+		}
+	default:
+		return object{}, fmt.Errorf("invalid @value %v", val)
+	}
+
+	switch {
+	case typ != nil:
+		dt, ok := typ.(string)
+		if !ok {
+			return object{}, fmt.Errorf("invalid @type %v", typ)
+		}
+		lit.lit.typ = shortXsdType(XsdType(dt))
+	case lang != nil:
+		l, ok := lang.(string)
+		if !ok {
+			return object{}, fmt.Errorf("invalid @language %v", lang)
+		}
+		lit.lit.langtag = l
+	}
+	return lit, nil
+}
@@ -0,0 +1,90 @@
+package triplestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJSONLDEncoder(t *testing.T) {
+	tris := Triples{
+		SubjPred("http://ex.org/bob", "http://xmlns.com/foaf/0.1/age").IntegerLiteral(42),
+		SubjPred("http://ex.org/bob", "http://xmlns.com/foaf/0.1/knows").Bnode("b0"),
+		SubjPred("http://ex.org/bob", "http://xmlns.com/foaf/0.1/knows").Resource("http://ex.org/alice"),
+		BnodePred("b0", "http://xmlns.com/foaf/0.1/name").StringLiteralWithLang("Bob", "en"),
+		BnodePred("b0", "http://ex.org/code").Object(TypedLiteral("x1", "http://ex.org/dt")),
+		BnodePred("b0", "http://ex.org/note").StringLiteral(`a "note"`),
+	}
+
+	var buf bytes.Buffer
+	if err := NewJSONLDEncoder(&buf).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	exp := `[{"@id":"http://ex.org/bob","http://xmlns.com/foaf/0.1/age":[{"@type":"http://www.w3.org/2001/XMLSchema#integer","@value":"42"}],"http://xmlns.com/foaf/0.1/knows":[{"@id":"_:b0"},{"@id":"http://ex.org/alice"}]},` +
+		`{"@id":"_:b0","http://ex.org/code":[{"@type":"http://ex.org/dt","@value":"x1"}],"http://ex.org/note":[{"@value":"a \"note\""}],"http://xmlns.com/foaf/0.1/name":[{"@language":"en","@value":"Bob"}]}]` + "\n"
+	if got, want := buf.String(), exp; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	decoded, err := NewJSONLDDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	buf.Reset()
+	if err := NewJSONLDEncoder(&buf, WithPrefixes(map[string]string{"ex": "http://ex.org/"})).Encode(SubjPred("ex:me", "ex:p").Resource("ex:you")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `[{"@id":"http://ex.org/me","http://ex.org/p":[{"@id":"http://ex.org/you"}]}]`+"\n"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestJSONLDDecoder(t *testing.T) {
+	doc := `{"@graph": [
+	{
+		"@id": "http://ex.org/bob",
+		"@type": ["http://xmlns.com/foaf/0.1/Person"],
+		"http://xmlns.com/foaf/0.1/age": [{"@value": 42}],
+		"http://ex.org/height": {"@value": 1.8},
+		"http://ex.org/weight": [{"@value": 80, "@type": "http://www.w3.org/2001/XMLSchema#decimal"}],
+		"http://ex.org/active": [{"@value": true}],
+		"http://xmlns.com/foaf/0.1/knows": [{"http://xmlns.com/foaf/0.1/name": [{"@value": "Alice"}]}, {"@id": "_:jsonld-1"}]
+	},
+	{"@id": "_:jsonld-1", "@type": "http://xmlns.com/foaf/0.1/Person"}
+]}`
+	tris, err := NewJSONLDDecoder(strings.NewReader(doc)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Triples{
+		SubjPredRes("http://ex.org/bob", rdfNamespace+"type", "http://xmlns.com/foaf/0.1/Person"),
+		SubjPred("http://ex.org/bob", "http://ex.org/active").BooleanLiteral(true),
+		SubjPred("http://ex.org/bob", "http://ex.org/height").Float64Literal(1.8),
+		SubjPred("http://ex.org/bob", "http://ex.org/weight").Object(TypedLiteral("80", "http://www.w3.org/2001/XMLSchema#decimal")),
+		SubjPred("http://ex.org/bob", "http://xmlns.com/foaf/0.1/age").IntegerLiteral(42),
+		BnodePred("jsonld-2", "http://xmlns.com/foaf/0.1/name").StringLiteral("Alice"),
+		SubjPred("http://ex.org/bob", "http://xmlns.com/foaf/0.1/knows").Bnode("jsonld-2"),
+		SubjPred("http://ex.org/bob", "http://xmlns.com/foaf/0.1/knows").Bnode("jsonld-1"),
+		BnodePredRes("jsonld-1", rdfNamespace+"type", "http://xmlns.com/foaf/0.1/Person"),
+	}
+	if got, want := Triples(tris), expected; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{
+		`{"@context": {"name": "http://xmlns.com/foaf/0.1/name"}, "name": "Bob"}`,
+		`[{"@id": "bob", "http://ex.org/p": [{"@list": [1, 2]}]}]`,
+		`[{"@id": "bob", "@reverse": {}}]`,
+		`[{"@id": 1}]`,
+		`"bob"`,
+		`[{"@id": "bob"`,
+	} {
+		if _, err := NewJSONLDDecoder(strings.NewReader(invalid)).Decode(); err == nil {
+			t.Fatalf("%s: expected error", invalid)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package triplestore
+
+import (
+	"container/list"
+	"errors"
+)
+
+// ErrLimitReached is returned when triples are rejected by the limits of a source
+var ErrLimitReached = errors.New("triplestore: source limit reached")
+
+// A LimitPolicy decides what to do when a source reaches its limits (see WithLimits).
+// It is called with the lock of the source held.
+type LimitPolicy interface {
+	// Added and Removed are called as triples are stored in or removed from the source
+	Added(Triple)
+	Removed(Triple)
+	// Evict returns a stored triple to evict to make room for the incoming one,
+	// or false to reject the incoming triple
+	Evict(incoming Triple) (Triple, bool)
+}
+
+type sourceLimits struct {
+	maxTriples, maxBytes int
+	newPolicy            func() LimitPolicy
+
+	policy LimitPolicy
+	bytes  int
+}
+
+// WithLimits caps the number of triples and the approximate memory used by the
+// triples of a source (0 meaning no limit), so that an unbounded ingest cannot
+// exhaust memory. When adding a triple would exceed a limit, the policy either evicts
+// stored triples or rejects the triple. Triples larger than the bytes limit are always rejected. Rejected triples are silently dropped by Add
+// and AddBatch while LoadFrom stops with ErrLimitReached.
+// Each named graph is limited on its own, with its own policy built by newPolicy.
+func WithLimits(maxTriples, maxBytes int, newPolicy func() LimitPolicy) SourceOption {
+	return func(s *source) {
+		if maxTriples > 0 || maxBytes > 0 {
+			s.limits = &sourceLimits{maxTriples: maxTriples, maxBytes: maxBytes, newPolicy: newPolicy, policy: newPolicy()}
+		}
+	}
+}
+
+func (l *sourceLimits) clone() *sourceLimits {
+	if l == nil {
+		return nil
+	}
+	return &sourceLimits{maxTriples: l.maxTriples, maxBytes: l.maxBytes, newPolicy: l.newPolicy, policy: l.newPolicy()}
+}
+
+// makeRoom evicts triples until the triple fits in the source. It returns false if it is rejected.
+func (l *sourceLimits) makeRoom(s *source, t *triple, change *graphChange) bool {
+	size := approxTripleBytes(t)
+	if l.maxBytes > 0 && size > l.maxBytes { // would evict everything in vain
+		return false
+	}
+	for (l.maxTriples > 0 && len(s.triples)+1 > l.maxTriples) || (l.maxBytes > 0 && l.bytes+size > l.maxBytes) {
+		evicted, ok := l.policy.Evict(t)
+		if !ok {
+			return false
+		}
+		stored, ok := s.triples[evicted.(*triple).key()]
+		if !ok { // not stored, the policy cannot make room
+			return false
+		}
+		delete(s.triples, evicted.(*triple).key())
+		s.counts.count(stored.(*triple), -1)
+		l.removed(stored.(*triple))
+		if s.history != nil {
+			change.removed = append(change.removed, stored.(*triple))
+		}
+	}
+	return true
+}
+
+func (l *sourceLimits) added(t *triple) {
+	if l == nil {
+		return
+	}
+	l.bytes += approxTripleBytes(t)
+	l.policy.Added(t)
+}
+
+func (l *sourceLimits) removed(t *triple) {
+	if l == nil {
+		return
+	}
+	l.bytes -= approxTripleBytes(t)
+	l.policy.Removed(t)
+}
+
+// approxTripleBytes approximates the memory used by a stored triple: its terms,
+// its key holding them again and the overhead of the structures and of the map entry
+func approxTripleBytes(t *triple) int {
+	terms := len(t.sub) + len(t.pred) + len(t.obj.resource) + len(t.obj.bnode) + len(t.obj.lit.val) + len(t.obj.lit.typ) + len(t.obj.lit.langtag)
+	return 2*terms + 160
+}
+
+type rejectPolicy struct {
+	onReject func(Triple)
+}
+
+// NewRejectPolicy returns a policy rejecting triples once the limits are reached.
+// When not nil, onReject is called with each rejected triple.
+func NewRejectPolicy(onReject func(Triple)) LimitPolicy {
+	return &rejectPolicy{onReject: onReject}
+}
+
+func (p *rejectPolicy) Added(Triple)   {}
+func (p *rejectPolicy) Removed(Triple) {}
+
+func (p *rejectPolicy) Evict(incoming Triple) (Triple, bool) {
+	if p.onReject != nil {
+		p.onReject(incoming)
+	}
+	return nil, false
+}
+
+type fifoPolicy struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+// NewFIFOPolicy returns a policy evicting the oldest triples to make room for new ones
+func NewFIFOPolicy() LimitPolicy {
+	return &fifoPolicy{order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+func (p *fifoPolicy) Added(t Triple) {
+	p.elements[t.(*triple).key()] = p.order.PushBack(t)
+}
+
+func (p *fifoPolicy) Removed(t Triple) {
+	k := t.(*triple).key()
+	if e, ok := p.elements[k]; ok {
+		p.order.Remove(e)
+		delete(p.elements, k)
+	}
+}
+
+func (p *fifoPolicy) Evict(Triple) (Triple, bool) {
+	oldest := p.order.Front()
+	if oldest == nil {
+		return nil, false
+	}
+	return oldest.Value.(Triple), true
+}
@@ -0,0 +1,84 @@
+package triplestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSourceLimits(t *testing.T) {
+	tri := func(i int) Triple {
+		return SubjPred(fmt.Sprint(i), "p").IntegerLiteral(i)
+	}
+
+	var rejected []Triple
+	s := NewSource(WithLimits(3, 0, func() LimitPolicy {
+		return NewRejectPolicy(func(t Triple) { rejected = append(rejected, t) })
+	}))
+	s.Add(tri(1), tri(2), tri(3), tri(3), tri(4))
+	AddBatch(s, []Triple{tri(5)})
+	if got, want := Triples(s.CopyTriples()), Triples([]Triple{tri(1), tri(2), tri(3)}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := Triples(rejected), Triples([]Triple{tri(4), tri(5)}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	s.Remove(tri(1))
+	s.Add(tri(4))
+	if got, want := Triples(s.CopyTriples()), Triples([]Triple{tri(2), tri(3), tri(4)}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// each named graph is limited on its own
+	s.(NamedGraphs).Graph("other").Add(tri(1), tri(2), tri(3), tri(4))
+	if got, want := s.(NamedGraphs).Graph("other").Snapshot().Count(), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	fifo := NewVersionedSource(WithLimits(2, 0, NewFIFOPolicy))
+	fifo.Add(tri(1), tri(2), tri(3))
+	fifo.Remove(tri(2))
+	fifo.Add(tri(4), tri(5))
+	if got, want := Triples(fifo.CopyTriples()), Triples([]Triple{tri(4), tri(5)}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := fifo.History()[0].Removed, 1; got != want {
+		t.Fatalf("evictions recorded in history: got %d, want %d", got, want)
+	}
+	snap, _ := fifo.SnapshotAt(1)
+	if got, want := Triples(snap.Triples()), Triples([]Triple{tri(2), tri(3)}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSourceBytesLimit(t *testing.T) {
+	small := SubjPred("s", "p").StringLiteral("o")
+	size := approxTripleBytes(small)
+	s := NewSource(WithLimits(0, 2*size, NewFIFOPolicy))
+	s.Add(small, SubjPred("s", "p").StringLiteral("x"), SubjPred("s", "p").StringLiteral("y"))
+	if got, want := Triples(s.CopyTriples()), Triples([]Triple{SubjPred("s", "p").StringLiteral("x"), SubjPred("s", "p").StringLiteral("y")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// too large triples are rejected without evicting
+	s.Add(SubjPred("s", "p").StringLiteral(strings.Repeat("z", 2*size)))
+	if got, want := len(s.CopyTriples()), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func TestLoadFromLimitReached(t *testing.T) {
+	var nt strings.Builder
+	for i := 0; i < 2*loadBatchSize; i++ {
+		fmt.Fprintf(&nt, "<s%d> <p> <o> .\n", i)
+	}
+	s := NewSource(WithLimits(loadBatchSize+10, 0, func() LimitPolicy { return NewRejectPolicy(nil) }))
+	err := LoadFrom(context.Background(), s, NewLenientNTStreamDecoder(strings.NewReader(nt.String())))
+	if got, want := err, ErrLimitReached; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := len(s.CopyTriples()), loadBatchSize+10; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,208 @@
+package triplestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Triples are encoded in MessagePack as a stream of maps shaped as
+// their JSON marshaling (see appendTermMapTriple).
+// Maps can therefore be read one after another by any msgpack unpacker (ex: msgpack.Unpacker in Python).
+
+type msgpackEncoder struct {
+	w io.Writer
+}
+
+// NewMsgpackEncoder returns an encoder writing triples as a stream of MessagePack maps
+func NewMsgpackEncoder(w io.Writer) Encoder {
+	return &msgpackEncoder{w: w}
+}
+
+func (enc *msgpackEncoder) Encode(tris ...Triple) error {
+	var buf []byte
+	for _, t := range tris {
+		buf = appendMsgpackTriple(buf[:0], t.(*triple))
+		if _, err := enc.w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendMsgpackTriple(buf []byte, t *triple) []byte {
+	return appendTermMapTriple(buf, t, appendMsgpackMapHeader, appendMsgpackString)
+}
+
+func appendMsgpackMapHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x80|byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, 0xdf), uint32(n))
+	}
+}
+
+func appendMsgpackString(buf []byte, s string) []byte {
+	switch l := len(s); {
+	case l < 32:
+		buf = append(buf, 0xa0|byte(l))
+	case l <= 0xff:
+		buf = append(buf, 0xd9, byte(l))
+	case l <= 0xffff:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(l))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(l))
+	}
+	return append(buf, s...)
+}
+
+type msgpackDecoder struct {
+	r    io.Reader
+	opts decoderOptions
+}
+
+// NewMsgpackDecoder returns a decoder reading a stream of MessagePack maps
+// as written by NewMsgpackEncoder. Unknown map entries are ignored.
+func NewMsgpackDecoder(r io.Reader, opts ...DecoderOption) Decoder {
+	return &msgpackDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
+func (dec *msgpackDecoder) Decode() ([]Triple, error) {
+	var out []Triple
+	tracker := newProgressTracker(dec.opts.progress)
+	r := &msgpackReader{r: bufio.NewReader(tracker.reader(dec.r)), maxTermSize: dec.opts.maxTermSize}
+	for {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			tracker.done()
+			return out, nil
+		}
+		tri, err := readTermMapTriple(r)
+		if err != nil {
+			return out, fmt.Errorf("msgpack: triple %d: %s", len(out)+1, err)
+		}
+		out = append(out, tri)
+		tracker.addTriple()
+	}
+}
+
+// maxMsgpackDepth bounds the nesting of skipped values
+const maxMsgpackDepth = 32
+
+type msgpackReader struct {
+	r           *bufio.Reader
+	maxTermSize int
+}
+
+func (m *msgpackReader) readMapHeader() (int, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case b&0xf0 == 0x80:
+		return int(b & 0x0f), nil
+	case b == 0xde:
+		n, err := m.readUint(2)
+		return int(n), err
+	case b == 0xdf:
+		n, err := m.readUint(4)
+		return int(n), err
+	}
+	return 0, fmt.Errorf("expected map, got type 0x%02x", b)
+}
+
+func (m *msgpackReader) readString() (string, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var l uint64
+	switch {
+	case b&0xe0 == 0xa0:
+		l = uint64(b & 0x1f)
+	case b == 0xd9, b == 0xda, b == 0xdb:
+		if l, err = m.readUint(1 << (b - 0xd9)); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("expected string, got type 0x%02x", b)
+	}
+	if m.maxTermSize > 0 && l > uint64(m.maxTermSize) {
+		return "", fmt.Errorf("term of length %d bytes exceeds maximum term size of %d bytes", l, m.maxTermSize)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(m.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (m *msgpackReader) readUint(size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(m.r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// skip discards the next value whatever its type
+func (m *msgpackReader) skip(depth int) error {
+	if depth > maxMsgpackDepth {
+		return errors.New("values nested too deeply")
+	}
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	var discard, elems uint64
+	switch {
+	case b <= 0x7f, b >= 0xe0, b == 0xc0, b == 0xc2, b == 0xc3:
+	case b&0xf0 == 0x80:
+		elems = 2 * uint64(b&0x0f)
+	case b&0xf0 == 0x90:
+		elems = uint64(b & 0x0f)
+	case b&0xe0 == 0xa0:
+		discard = uint64(b & 0x1f)
+	case b == 0xcc, b == 0xd0:
+		discard = 1
+	case b == 0xcd, b == 0xd1:
+		discard = 2
+	case b == 0xca, b == 0xce, b == 0xd2:
+		discard = 4
+	case b == 0xcb, b == 0xcf, b == 0xd3:
+		discard = 8
+	case b >= 0xd4 && b <= 0xd8: // fixext
+		discard = 1 + 1<<(b-0xd4)
+	case b == 0xc4, b == 0xc5, b == 0xc6: // bin
+		discard, err = m.readUint(1 << (b - 0xc4))
+	case b == 0xc7, b == 0xc8, b == 0xc9: // ext
+		discard, err = m.readUint(1 << (b - 0xc7))
+		discard++
+	case b == 0xd9, b == 0xda, b == 0xdb: // str
+		discard, err = m.readUint(1 << (b - 0xd9))
+	case b == 0xdc, b == 0xdd: // array
+		elems, err = m.readUint(2 << (b - 0xdc))
+	case b == 0xde, b == 0xdf: // map
+		elems, err = m.readUint(2 << (b - 0xde))
+		elems *= 2
+	default:
+		return fmt.Errorf("unknown type 0x%02x", b)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := m.r.Discard(int(discard)); err != nil {
+		return err
+	}
+	for i := uint64(0); i < elems; i++ {
+		if err := m.skip(depth + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
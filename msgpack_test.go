@@ -0,0 +1,89 @@
+package triplestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMsgpackCodec(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").Resource("three"),
+		BnodePred("one", "two").Bnode("three"),
+		SubjPred("one", "two").StringLiteral(""),
+		SubjPred("one", "two").StringLiteral(strings.Repeat("long ", 100)),
+		SubjPred("one", "two").StringLiteral(strings.Repeat("longer ", 10000)),
+		SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+		SubjPred("one", "two").IntegerLiteral(42),
+	}
+
+	var buf bytes.Buffer
+	if err := NewMsgpackEncoder(&buf).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewMsgpackDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestMsgpackWireFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMsgpackEncoder(&buf).Encode(SubjPred("s", "p").Resource("o")); err != nil {
+		t.Fatal(err)
+	}
+	term := func(value string) string {
+		return "\x82\xa4type\xa3uri\xa5value\xa1" + value
+	}
+	expected := "\x83\xa7subject" + term("s") + "\xa9predicate" + term("p") + "\xa6object" + term("o")
+	if got, want := buf.String(), expected; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// unknown entries of any type are skipped
+	withUnknown := "\x85" +
+		"\xa5extra\x93\xc0\xc3\xcd\x01\x02" +
+		"\xa7subject\x83\xa4type\xa5bnode\xa5value\xa1s\xa2id\xcb\x00\x00\x00\x00\x00\x00\x00\x00" +
+		"\xa9predicate" + term("p") +
+		"\xa6object\x83\xa4type\xa7literal\xa5value\xa242\xa8xml:lang\xa2en" +
+		"\xa4meta\x81\xa3bin\xc4\x02\x00\x01"
+	decoded, err := NewMsgpackDecoder(strings.NewReader(withUnknown)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := TripleKey(decoded[0]), `_:s <p> "42"@en`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestMsgpackDecodeErrors(t *testing.T) {
+	tcases := []struct {
+		in, err string
+	}{
+		{in: "\xa3one", err: "expected map, got type 0xa3"},
+		{in: "\x81\x01", err: "expected string, got type 0x01"},
+		{in: "\x81\xa7subject\x82\xa4type", err: "EOF"},
+		{in: "\x81\xa7subject\x82\xa4type\xa3uri\xa5value\xa1s", err: "requires a subject, a predicate and an object"},
+		{in: "\x81\xa5extra\xc1", err: "unknown type 0xc1"},
+		{in: "\x83\xa7subject\x82\xa4type\xa7literal\xa5value\xa1s\xa9predicate\x82\xa4type\xa3uri\xa5value\xa1p\xa6object\x82\xa4type\xa3uri\xa5value\xa1o", err: "subject: literal not allowed"},
+		{in: "\x83\xa7subject\x82\xa4type\xa3uri\xa5value\xa1s\xa9predicate\x82\xa4type\xa5bnode\xa5value\xa1p\xa6object\x82\xa4type\xa3uri\xa5value\xa1o", err: `predicate: expected uri, got "bnode"`},
+	}
+	for i, tc := range tcases {
+		_, err := NewMsgpackDecoder(strings.NewReader(tc.in)).Decode()
+		if err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+		if got, want := err.Error(), tc.err; !strings.Contains(got, want) {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	NewMsgpackEncoder(&buf).Encode(SubjPred("one", "two").Resource(strings.Repeat("a", 1000)))
+	if _, err := NewMsgpackDecoder(&buf, WithMaxTermSize(10)).Decode(); err == nil {
+		t.Fatal("expected error")
+	}
+}
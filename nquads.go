@@ -0,0 +1,115 @@
+package triplestore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Quad is a triple with the name of the graph it belongs to.
+// Graph is empty for the default graph, and is prefixed with "_:" for blank node graphs.
+type Quad struct {
+	Triple
+	Graph string
+}
+
+// QuadDecoder decodes quads. As a Decoder, it returns the triples of all graphs.
+type QuadDecoder interface {
+	Decoder
+	DecodeQuads() ([]Quad, error)
+}
+
+type nquadsDecoder struct {
+	r    io.Reader
+	opts decoderOptions
+}
+
+// NewNQuadsDecoder returns a lenient N-Quads decoder. Lines without
+// graph (i.e. N-Triples) are decoded as quads of the default graph.
+func NewNQuadsDecoder(r io.Reader, opts ...DecoderOption) QuadDecoder {
+	return &nquadsDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
+func (d *nquadsDecoder) Decode() ([]Triple, error) {
+	quads, err := d.DecodeQuads()
+	tris := make([]Triple, len(quads))
+	for i, q := range quads {
+		tris[i] = q.Triple
+	}
+	return tris, err
+}
+
+func (d *nquadsDecoder) DecodeQuads() (out []Quad, err error) {
+	tracker := newProgressTracker(d.opts.progress)
+	scanner := bufio.NewScanner(tracker.reader(d.r))
+	var count int
+	for scanner.Scan() {
+		count++
+		line := bytes.TrimLeft(scanner.Bytes(), " \t")
+		if len(line) < 1 || line[0] == '#' {
+			continue
+		}
+		q, err := parseQuad(line)
+		if err != nil {
+			return out, fmt.Errorf("lenient parsing: line %d: %s", count, err)
+		}
+		if err := d.opts.checkTermSize(q.Triple.(*triple)); err != nil {
+			return out, err
+		}
+		if err := d.opts.checkTermSize(&triple{sub: q.Graph}); err != nil {
+			return out, err
+		}
+		out = append(out, q)
+		tracker.addTriple()
+	}
+	if err := scanner.Err(); err != nil {
+		return out, err
+	}
+	tracker.done()
+	return out, nil
+}
+
+// parseQuad splits the graph from the end of the line and parses
+// the remaining triple with the N-Triples parser. Lines for which
+// the remaining triple is invalid are parsed as plain triples.
+func parseQuad(line []byte) (Quad, error) {
+	if head, graph := splitGraph(line); graph != "" {
+		if t, err := parseTriple(append(head, " ."...)); err == nil {
+			return Quad{Triple: t, Graph: graph}, nil
+		}
+	}
+	t, err := parseTriple(line)
+	return Quad{Triple: t}, err
+}
+
+// splitGraph returns a copy of the line without its last term and final
+// dot, and this last term as a graph name, if it is an IRI or a blank node
+func splitGraph(line []byte) ([]byte, string) {
+	b := bytes.TrimRight(line, " \t\r")
+	if !bytes.HasSuffix(b, []byte{'.'}) {
+		return nil, ""
+	}
+	b = bytes.TrimRight(b[:len(b)-1], " \t")
+
+	var start int
+	var graph string
+	if bytes.HasSuffix(b, []byte{'>'}) {
+		if start = bytes.LastIndexByte(b, '<'); start < 0 {
+			return nil, ""
+		}
+		graph = string(b[start+1 : len(b)-1])
+	} else {
+		start = bytes.LastIndexAny(b, " \t") + 1
+		if !bytes.HasPrefix(b[start:], []byte("_:")) {
+			return nil, ""
+		}
+		graph = string(b[start:])
+	}
+	head := bytes.TrimRight(b[:start], " \t")
+	if bytes.HasSuffix(head, []byte("^^")) {
+		// the last term is the datatype of a literal
+		return nil, ""
+	}
+	return append([]byte(nil), head...), graph
+}
@@ -0,0 +1,83 @@
+package triplestore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNQuadsDecoder(t *testing.T) {
+	in := `# a comment
+<s> <p> <o> <g> .
+<s> <p> <o> .
+_:s <p> _:o _:g .
+<s> <p> "chat"@fr <g> .
+<s> <p> "42"^^<xsd:integer> <g>.
+<s> <p> "42"^^<xsd:integer> .
+<s> <p> "not <a> graph" .
+<s> <p> "literal" <http://example.org/graph#1>   .
+
+<s><p><o><g>.
+`
+	expected := []struct {
+		tri   Triple
+		graph string
+	}{
+		{SubjPred("s", "p").Resource("o"), "g"},
+		{SubjPred("s", "p").Resource("o"), ""},
+		{BnodePred("s", "p").Bnode("o"), "_:g"},
+		{SubjPred("s", "p").StringLiteralWithLang("chat", "fr"), "g"},
+		{SubjPred("s", "p").IntegerLiteral(42), "g"},
+		{SubjPred("s", "p").IntegerLiteral(42), ""},
+		{SubjPred("s", "p").StringLiteral("not <a> graph"), ""},
+		{SubjPred("s", "p").StringLiteral("literal"), "http://example.org/graph#1"},
+		{SubjPred("s", "p").Resource("o"), "g"},
+	}
+
+	quads, err := NewNQuadsDecoder(strings.NewReader(in)).DecodeQuads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(quads), len(expected); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	for i, exp := range expected {
+		if got, want := quads[i].Triple, exp.tri; !got.Equal(want) {
+			t.Fatalf("quad %d: got %v, want %v", i+1, got, want)
+		}
+		if got, want := quads[i].Graph, exp.graph; got != want {
+			t.Fatalf("quad %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	tris, err := NewNQuadsDecoder(strings.NewReader(in)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(tris), len(expected); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func TestNQuadsDecoderErrors(t *testing.T) {
+	tcases := []struct {
+		in, err string
+	}{
+		{in: "<s> <p> <o> <g>\n", err: "line 1"},
+		{in: "<s> <p> .\n", err: "line 1"},
+		{in: "<s> <p> <o> <g> .\n\"s\" <p> <o> <g> .\n", err: "line 2"},
+	}
+	for i, tc := range tcases {
+		_, err := NewNQuadsDecoder(strings.NewReader(tc.in)).DecodeQuads()
+		if err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+		if got, want := err.Error(), tc.err; !strings.Contains(got, want) {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	in := "<s> <p> <o> <" + strings.Repeat("g", 100) + "> .\n"
+	if _, err := NewNQuadsDecoder(strings.NewReader(in), WithMaxTermSize(10)).DecodeQuads(); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -6,20 +6,54 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"unicode/utf8"
 )
 
-type lenientNTParser struct {
-	r io.Reader
+// NTParser is a lenient N-Triples parser. It can be reset to parse another
+// input, reusing its line buffer, so that a single parser (or a pool of them)
+// serves many small payloads.
+type NTParser struct {
+	r   io.Reader
+	buf []byte
+
+	progress *progressTracker
+}
+
+// NewNTParser returns a parser reading N-Triples from r
+func NewNTParser(r io.Reader) *NTParser {
+	return &NTParser{r: r}
+}
+
+// Reset makes the parser read from r, keeping its internal buffer
+func (p *NTParser) Reset(r io.Reader) {
+	p.r = r
 }
 
-func newLenientNTParser(r io.Reader) *lenientNTParser {
-	return &lenientNTParser{r: r}
+var ntParserPool = sync.Pool{
+	New: func() interface{} { return new(NTParser) },
 }
 
-func (p *lenientNTParser) Parse() (out []Triple, err error) {
+func getNTParser(r io.Reader) *NTParser {
+	p := ntParserPool.Get().(*NTParser)
+	p.Reset(r)
+	return p
+}
+
+func putNTParser(p *NTParser) {
+	p.Reset(nil)
+	p.progress = nil
+	ntParserPool.Put(p)
+}
+
+// Parse parses all the triples of the input, stopping at the first invalid line
+func (p *NTParser) Parse() (out []Triple, err error) {
+	if p.buf == nil {
+		p.buf = make([]byte, 4096)
+	}
 	var count int
 	scanner := bufio.NewScanner(p.r)
+	scanner.Buffer(p.buf, bufio.MaxScanTokenSize)
 	for scanner.Scan() {
 		count++
 		line := bytes.TrimLeft(scanner.Bytes(), " \t")
@@ -34,6 +68,7 @@ func (p *lenientNTParser) Parse() (out []Triple, err error) {
 			return out, fmt.Errorf("lenient parsing: line %d: %s", count, terr)
 		}
 		out = append(out, t)
+		p.progress.addTriple()
 	}
 
 	err = scanner.Err()
@@ -7,8 +7,29 @@ import (
 	"testing"
 )
 
+func TestResetNTParser(t *testing.T) {
+	p := NewNTParser(strings.NewReader("<one> <two> \"three\" ."))
+	first, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Reset(strings.NewReader("<four> <five> <six> .\n<seven> <eight> _:nine ."))
+	second, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := Triples(first), (Triples{SubjPred("one", "two").StringLiteral("three")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := Triples(second), (Triples{SubjPred("four", "five").Resource("six"), SubjPred("seven", "eight").Bnode("nine")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
 func TestMultilineEmptyAndCommentLine(t *testing.T) {
-	p := newLenientNTParser(strings.NewReader(`  # my triples
+	p := NewNTParser(strings.NewReader(`  # my triples
 
 # starting
 <sub><pred>"obj"@en .
@@ -116,7 +137,7 @@ func TestParsing(t *testing.T) {
 	}
 
 	for j, tcase := range tcases {
-		p := newLenientNTParser(strings.NewReader(tcase.input))
+		p := NewNTParser(strings.NewReader(tcase.input))
 		tris, err := p.Parse()
 		if err != nil {
 			t.Fatalf("input=[%s]: %s", tcase.input, err)
@@ -287,7 +308,7 @@ func TestParserErrorHandling(t *testing.T) {
 	}
 
 	for _, tcase := range tcases {
-		tris, err := newLenientNTParser(strings.NewReader(tcase.input)).Parse()
+		tris, err := NewNTParser(strings.NewReader(tcase.input)).Parse()
 		if err == nil {
 			t.Fatalf("expected err, got none. Triples parsed:\n%#v", Triples(tris).Map(func(tr Triple) string { return fmt.Sprint(tr) }))
 		}
@@ -0,0 +1,277 @@
+package triplestore
+
+import (
+	"context"
+	"math/big"
+	"regexp"
+	"time"
+)
+
+// The sources and graphs of this package implement more methods than the ones
+// of the Source and RDFGraph interfaces, which other implementations are not
+// required to provide. The functions below use those methods when available
+// and otherwise fall back on the methods of the interfaces.
+
+// NamedGraphs is implemented by the sources holding named graphs,
+// as the ones returned by NewSource.
+type NamedGraphs interface {
+	// Graph returns the named graph, created if needed. Its snapshots only
+	// hold its own triples. An empty name returns the source itself.
+	Graph(name string) Source
+	// GraphNames returns the sorted names of the named graphs
+	GraphNames() []string
+	// DropGraph removes the named graph and its triples
+	DropGraph(name string)
+}
+
+// AddBatch adds many triples at once to the source. The sources of NewSource
+// intern the terms shared by the triples so that the stored triples share their strings.
+func AddBatch(s Source, ts []Triple) {
+	if b, ok := s.(interface {
+		AddBatch([]Triple)
+	}); ok {
+		b.AddBatch(ts)
+		return
+	}
+	s.Add(ts...)
+}
+
+// LoadFrom adds the triples of the stream decoder to the source as they are decoded,
+// in batches, without holding all of them in memory. It stops at the first decoding
+// error, triples decoded so far being kept.
+func LoadFrom(ctx context.Context, s Source, dec StreamDecoder) error {
+	if l, ok := s.(interface {
+		LoadFrom(context.Context, StreamDecoder) error
+	}); ok {
+		return l.LoadFrom(ctx, dec)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	results := dec.StreamDecode(ctx)
+	defer func() {
+		cancel()
+		for range results {
+		}
+	}()
+
+	batch := make([]Triple, 0, loadBatchSize)
+	for res := range results {
+		if res.Err != nil {
+			AddBatch(s, batch)
+			return res.Err
+		}
+		batch = append(batch, res.Tri)
+		if len(batch) == loadBatchSize {
+			AddBatch(s, batch)
+			batch = batch[:0]
+		}
+	}
+	AddBatch(s, batch)
+	return ctx.Err()
+}
+
+// Replace replaces the triples of the source by the given ones. The sources of
+// NewSource replace them atomically and only replace the default graph of a root
+// source, its named graphs being kept. Other sources have their triples removed
+// before the given ones are added.
+func Replace(s Source, ts ...Triple) {
+	if r, ok := s.(interface {
+		Replace(...Triple)
+	}); ok {
+		r.Replace(ts...)
+		return
+	}
+	s.Remove(s.CopyTriples()...)
+	s.Add(ts...)
+}
+
+// SourceStatsOf returns the counts of triples of the source,
+// maintained as they are added and removed by the sources of NewSource.
+// The triples of other sources are scanned.
+func SourceStatsOf(s Source) SourceStats {
+	if st, ok := s.(interface {
+		Stats() SourceStats
+	}); ok {
+		return st.Stats()
+	}
+	counts := newTripleCounts()
+	for _, t := range s.CopyTriples() {
+		counts.count(t.(*triple), 1)
+	}
+	st := SourceStats{Predicates: make(map[string]int), Types: make(map[string]int)}
+	counts.addTo(&st)
+	return st
+}
+
+// GC removes the triples of blank nodes not reachable from a resource subject
+// and returns the number of removed triples
+func GC(s Source) int {
+	if c, ok := s.(interface {
+		GC() int
+	}); ok {
+		return c.GC()
+	}
+	var tris []*triple
+	for _, t := range s.CopyTriples() {
+		tris = append(tris, t.(*triple))
+	}
+	reachable := reachableBnodes(tris)
+	var garbage []Triple
+	for _, t := range tris {
+		if t.isSubBnode && !reachable[t.sub] {
+			garbage = append(garbage, t)
+		}
+	}
+	s.Remove(garbage...)
+	return len(garbage)
+}
+
+// WithPredObjMatching returns the triples of the graph with the given predicate whose
+// object is a literal with a lexical value matching the regular expression
+func WithPredObjMatching(g RDFGraph, p string, re *regexp.Regexp) []Triple {
+	if m, ok := g.(interface {
+		WithPredObjMatching(string, *regexp.Regexp) []Triple
+	}); ok {
+		return m.WithPredObjMatching(p, re)
+	}
+	var out []Triple
+	for _, t := range g.WithPredicate(p) {
+		if lit, ok := t.Object().Literal(); ok && re.MatchString(lit.Value()) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// WithPredRange returns the triples of the graph with the given predicate whose object is
+// a numeric literal within [min, max], compared in the value space of numeric
+// datatypes (ex: "10"^^xsd:integer > "9.5"^^xsd:decimal). A nil bound means
+// unbounded. Invalid bounds (ex: non numeric) match no triple.
+func WithPredRange(g RDFGraph, p string, min, max Literal) []Triple {
+	if m, ok := g.(interface {
+		WithPredRange(string, Literal, Literal) []Triple
+	}); ok {
+		return m.WithPredRange(p, min, max)
+	}
+	r, ok := newNumericRange(min, max)
+	if !ok {
+		return nil
+	}
+	var out []Triple
+	values := make(map[string]*big.Rat)
+	for _, t := range g.WithPredicate(p) {
+		obj := t.(*triple).obj
+		v, ok := values[obj.key()]
+		if !ok {
+			v, _ = numericValue(obj)
+			values[obj.key()] = v
+		}
+		if v != nil && r.contains(v) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// WithPredTimeRange returns the triples of the graph with the given predicate whose object
+// is a xsd:dateTime or xsd:date literal within [from, to], comparing instants
+// across timezones (dates start at midnight, UTC being assumed without timezone).
+// A zero bound means unbounded: a zero from matches triples before to,
+// a zero to matches triples after from.
+func WithPredTimeRange(g RDFGraph, p string, from, to time.Time) []Triple {
+	if m, ok := g.(interface {
+		WithPredTimeRange(string, time.Time, time.Time) []Triple
+	}); ok {
+		return m.WithPredTimeRange(p, from, to)
+	}
+	r := timeRange{from: from, to: to}
+	var out []Triple
+	for _, t := range g.WithPredicate(p) {
+		if v, ok := timeValue(t.(*triple).obj); ok && r.contains(v) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Subgrapher is implemented by the graphs looking up subgraphs through their
+// subject index, as the snapshots of NewSource, columnar and union graphs.
+type Subgrapher interface {
+	// Subgraph returns the triples describing the given root node, following
+	// resource and bnode objects up to the given depth (see Subgraph).
+	Subgraph(root string, depth int) Triples
+}
+
+// Subgraph returns the triples of the graph describing the given root node, following
+// resource and bnode objects up to the given depth (i.e. a concise bounded description).
+// A depth of 0 returns only the triples having root as subject. A negative depth means no limit.
+// Graphs not implementing Subgrapher are looked up subject by subject.
+func Subgraph(g RDFGraph, root string, depth int) Triples {
+	if m, ok := g.(Subgrapher); ok {
+		return m.Subgraph(root, depth)
+	}
+	return subgraph(g, root, depth)
+}
+
+// CountWith returns the number of triples of the graph matching the given
+// subject, predicate and object, nil meaning any
+func CountWith(g RDFGraph, s, p *string, o Object) int {
+	if m, ok := g.(interface {
+		CountWith(*string, *string, Object) int
+	}); ok {
+		return m.CountWith(s, p, o)
+	}
+	var n int
+	for _, t := range lookupWith(g, s, p, o) {
+		if matches(t, s, p, o) {
+			n++
+		}
+	}
+	return n
+}
+
+// Exists returns true if a triple of the graph matches the given subject,
+// predicate and object, nil meaning any
+func Exists(g RDFGraph, s, p *string, o Object) bool {
+	if m, ok := g.(interface {
+		Exists(*string, *string, Object) bool
+	}); ok {
+		return m.Exists(s, p, o)
+	}
+	for _, t := range lookupWith(g, s, p, o) {
+		if matches(t, s, p, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// StatsReporter is implemented by the graphs knowing their content and memory
+// usage, as the snapshots of NewSource, columnar and union graphs.
+type StatsReporter interface {
+	// Stats describes the content of the graph (see StatsOf)
+	Stats() Stats
+}
+
+// StatsOf describes the content of the graph. The memory used is only known
+// for the graphs implementing StatsReporter, the triples of other graphs being scanned.
+func StatsOf(g RDFGraph) Stats {
+	if m, ok := g.(StatsReporter); ok {
+		return m.Stats()
+	}
+	return termStats(g.Triples())
+}
+
+// termStats counts the triples and their distinct terms
+func termStats(tris []Triple) (st Stats) {
+	subjects, predicates, objects := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+	for _, t := range tris {
+		tri := t.(*triple)
+		st.Triples++
+		subjects[subjectObject(tri).key()] = true
+		predicates[tri.pred] = true
+		objects[tri.obj.key()] = true
+	}
+	st.Subjects, st.Predicates, st.Objects = len(subjects), len(predicates), len(objects)
+	return
+}
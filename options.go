@@ -0,0 +1,186 @@
+package triplestore
+
+import (
+	"fmt"
+	"io"
+)
+
+// EncoderOption configures encoders built with NewNTriplesEncoder or NewBinaryEncoderWithOptions
+type EncoderOption func(*encoderOptions)
+
+type encoderOptions struct {
+	context   *Context
+	canonical bool
+	sorted    bool
+	progress  func(Progress)
+}
+
+func newEncoderOptions(opts []EncoderOption) encoderOptions {
+	var o encoderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o *encoderOptions) ctx() *Context {
+	if o.context == nil {
+		o.context = NewContext()
+	}
+	return o.context
+}
+
+// WithPrefixes expands the prefixed names of triples (ex: "rdf:type") with the given namespaces
+func WithPrefixes(prefixes map[string]string) EncoderOption {
+	return func(o *encoderOptions) {
+		for k, v := range prefixes {
+			o.ctx().Prefixes[k] = v
+		}
+	}
+}
+
+// WithBase resolves the relative IRIs of triples against the given base
+func WithBase(base string) EncoderOption {
+	return func(o *encoderOptions) {
+		o.ctx().Base = base
+	}
+}
+
+// WithCanonical writes canonical N-Triples: blank nodes are relabelled
+// and triples sorted as done by CanonicalNQuads. It has no effect on binary encoders.
+func WithCanonical() EncoderOption {
+	return func(o *encoderOptions) {
+		o.canonical = true
+	}
+}
+
+// WithSortedOutput sorts triples by subject, predicate and object before
+// writing them, so that encoding the same triples always gives the same output.
+// Stream encoders then write once the stream is closed.
+func WithSortedOutput() EncoderOption {
+	return func(o *encoderOptions) {
+		o.sorted = true
+	}
+}
+
+// DecoderOption configures decoders built with NewNTriplesDecoder, NewBinaryDecoderWithOptions or NewProtoDecoder
+type DecoderOption func(*decoderOptions)
+
+type decoderOptions struct {
+	maxTermSize int
+	progress    func(Progress)
+}
+
+func newDecoderOptions(opts []DecoderOption) decoderOptions {
+	var o decoderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMaxTermSize fails decoding of terms (IRIs, blank nodes, literal values, ...)
+// longer than n bytes. The binary decoder checks sizes before allocating,
+// protecting against corrupted or malicious inputs.
+func WithMaxTermSize(n int) DecoderOption {
+	return func(o *decoderOptions) {
+		o.maxTermSize = n
+	}
+}
+
+func (o decoderOptions) checkTermSize(t *triple) error {
+	if o.maxTermSize <= 0 {
+		return nil
+	}
+	for _, term := range []string{t.sub, t.pred, t.obj.resource, t.obj.bnode, t.obj.lit.val, string(t.obj.lit.typ), t.obj.lit.langtag} {
+		if len(term) > o.maxTermSize {
+			return fmt.Errorf("term of length %d bytes exceeds maximum term size of %d bytes", len(term), o.maxTermSize)
+		}
+	}
+	return nil
+}
+
+// Progress reports the advancement of an encoding or a decoding
+type Progress struct {
+	// Bytes read by decoders or encoded by encoders so far
+	Bytes int64
+	// Triples decoded or encoded so far
+	Triples int
+	// Done is set on the last report, once the input is exhausted
+	Done bool
+}
+
+// progressInterval is the number of triples between two progress reports
+const progressInterval = 1000
+
+// WithEncodeProgress calls fn every 1000 encoded triples and once
+// when an encoding is done
+func WithEncodeProgress(fn func(Progress)) EncoderOption {
+	return func(o *encoderOptions) {
+		o.progress = fn
+	}
+}
+
+// WithDecodeProgress calls fn every 1000 decoded triples and once
+// when the input has been decoded
+func WithDecodeProgress(fn func(Progress)) DecoderOption {
+	return func(o *decoderOptions) {
+		o.progress = fn
+	}
+}
+
+// progressTracker counts bytes and triples of an encoding or decoding.
+// A nil tracker does nothing.
+type progressTracker struct {
+	fn func(Progress)
+	p  Progress
+}
+
+func newProgressTracker(fn func(Progress)) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn}
+}
+
+func (t *progressTracker) addBytes(n int) {
+	if t != nil {
+		t.p.Bytes += int64(n)
+	}
+}
+
+func (t *progressTracker) addTriple() {
+	if t == nil {
+		return
+	}
+	t.p.Triples++
+	if t.p.Triples%progressInterval == 0 {
+		t.fn(t.p)
+	}
+}
+
+func (t *progressTracker) done() {
+	if t != nil {
+		t.p.Done = true
+		t.fn(t.p)
+	}
+}
+
+// reader returns r counting the bytes read
+func (t *progressTracker) reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &progressReader{r: r, t: t}
+}
+
+type progressReader struct {
+	r io.Reader
+	t *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.t.addBytes(n)
+	return n, err
+}
@@ -0,0 +1,189 @@
+package triplestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncoderOptions(t *testing.T) {
+	tris := []Triple{
+		SubjPred("ex:b", "rdf:type").Resource("ex:Thing"),
+		BnodePred("x", "ex:name").StringLiteral("bnode"),
+		SubjPred("a", "ex:knows").Bnode("x"),
+	}
+
+	var buff bytes.Buffer
+	enc := NewNTriplesEncoder(&buff,
+		WithPrefixes(map[string]string{"ex": "http://ex.org/"}),
+		WithPrefixes(RDFContext.Prefixes),
+		WithBase("http://base.org/"),
+	)
+	if err := enc.Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	exp := `<http://ex.org/b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://ex.org/Thing> .
+_:x <http://ex.org/name> "bnode" .
+<http://base.org/a> <http://ex.org/knows> _:x .
+`
+	if got, want := buff.String(), exp; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	buff.Reset()
+	enc = NewNTriplesEncoder(&buff, WithPrefixes(map[string]string{"ex": "http://ex.org/"}), WithCanonical())
+	if err := enc.Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	exp = `<a> <http://ex.org/knows> _:c14n0 .
+<http://ex.org/b> <rdf:type> <http://ex.org/Thing> .
+_:c14n0 <http://ex.org/name> "bnode" .
+`
+	if got, want := buff.String(), exp; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	buff.Reset()
+	if err := NewBinaryEncoderWithOptions(&buff, WithPrefixes(map[string]string{"ex": "http://ex.org/"})).Encode(tris[0]); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewBinaryDecoder(&buff).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), (Triples{SubjPred("http://ex.org/b", "rdf:type").Resource("http://ex.org/Thing")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDecoderMaxTermSize(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	tris := []Triple{
+		SubjPred("one", "two").Resource("three"),
+		SubjPred("one", "two").StringLiteral(long),
+	}
+
+	var bin, nt bytes.Buffer
+	if err := NewBinaryEncoder(&bin).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewLenientNTEncoder(&nt).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+
+	tcases := []struct {
+		dec Decoder
+		err string
+	}{
+		{dec: NewBinaryDecoderWithOptions(bytes.NewReader(bin.Bytes()), WithMaxTermSize(50)), err: "word of length 100 bytes exceeds maximum term size of 50 bytes"},
+		{dec: NewNTriplesDecoder(bytes.NewReader(nt.Bytes()), WithMaxTermSize(50)), err: "term of length 100 bytes exceeds maximum term size of 50 bytes"},
+	}
+	for i, tc := range tcases {
+		decoded, err := tc.dec.Decode()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %v, want %s", i+1, err, tc.err)
+		}
+		if got, want := len(decoded), 1; got != want {
+			t.Fatalf("case %d: got %d, want %d", i+1, got, want)
+		}
+	}
+
+	for i, dec := range []Decoder{
+		NewBinaryDecoderWithOptions(bytes.NewReader(bin.Bytes()), WithMaxTermSize(100)),
+		NewNTriplesDecoder(bytes.NewReader(nt.Bytes()), WithMaxTermSize(100)),
+	} {
+		decoded, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("case %d: %s", i+1, err)
+		}
+		if got, want := Triples(decoded), Triples(tris); !got.Equal(want) {
+			t.Fatalf("case %d: got %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var tris []Triple
+	for i := 0; i < 2500; i++ {
+		tris = append(tris, SubjPred(fmt.Sprint(i), "digit").IntegerLiteral(i))
+	}
+
+	tcases := []struct {
+		newEnc func(io.Writer, ...EncoderOption) Encoder
+		newDec func(io.Reader, ...DecoderOption) Decoder
+	}{
+		{newEnc: NewNTriplesEncoder, newDec: NewNTriplesDecoder},
+		{newEnc: NewBinaryEncoderWithOptions, newDec: NewBinaryDecoderWithOptions},
+	}
+	for i, tc := range tcases {
+		var buff bytes.Buffer
+		var encoded []Progress
+		if err := tc.newEnc(&buff, WithEncodeProgress(func(p Progress) { encoded = append(encoded, p) })).Encode(tris...); err != nil {
+			t.Fatal(err)
+		}
+		size := int64(buff.Len())
+
+		var decoded []Progress
+		if _, err := tc.newDec(&buff, WithDecodeProgress(func(p Progress) { decoded = append(decoded, p) })).Decode(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, reports := range [][]Progress{encoded, decoded} {
+			if got, want := len(reports), 3; got != want {
+				t.Fatalf("case %d: got %d, want %d", i+1, got, want)
+			}
+			if got, want := fmt.Sprint(reports[0].Triples, reports[1].Triples), "1000 2000"; got != want {
+				t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+			}
+			if got, want := reports[2], (Progress{Bytes: size, Triples: 2500, Done: true}); got != want {
+				t.Fatalf("case %d: got %+v, want %+v", i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestSortedOutput(t *testing.T) {
+	tris := []Triple{
+		SubjPred("b", "p").Resource("a"),
+		SubjPred("a", "q").IntegerLiteral(2),
+		BnodePred("a", "p").Resource("c"),
+		SubjPred("a", "p").StringLiteral("z"),
+		SubjPred("a", "p").Resource("c"),
+	}
+	exp := `<a> <p> "z" .
+<a> <p> <c> .
+<a> <q> "2"^^<xsd:integer> .
+_:a <p> <c> .
+<b> <p> <a> .
+`
+
+	var sorted, wrapped bytes.Buffer
+	if err := NewNTriplesEncoder(&sorted, WithSortedOutput()).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewSortedEncoder(NewLenientNTEncoder(&wrapped)).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	for _, buff := range []*bytes.Buffer{&sorted, &wrapped} {
+		if got, want := buff.String(), exp; got != want {
+			t.Fatalf("got\n%s\nwant\n%s", got, want)
+		}
+	}
+	if got, want := tris[0], SubjPred("b", "p").Resource("a"); !got.(*triple).Equal(want) {
+		t.Fatalf("input triples should not be sorted in place, got %v", got)
+	}
+
+	triC := make(chan Triple)
+	go tripleChan(tris, triC)
+	var streamed bytes.Buffer
+	enc := NewNTriplesEncoder(&streamed, WithSortedOutput()).(StreamEncoder)
+	if err := enc.StreamEncode(context.Background(), triC); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := streamed.String(), exp; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
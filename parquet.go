@@ -0,0 +1,304 @@
+package triplestore
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Parquet columns of the triples written by NewParquetEncoder
+var parquetColumns = []struct {
+	name     string
+	optional bool
+}{
+	{name: "subject"},
+	{name: "predicate"},
+	{name: "object_value"},
+	{name: "object_type"},
+	{name: "lang", optional: true},
+	{name: "graph", optional: true},
+}
+
+type parquetEncoder struct {
+	w io.Writer
+}
+
+// NewParquetEncoder returns an encoder writing triples as an Apache Parquet file,
+// queryable by Athena, Spark, ... Each call to Encode writes a complete file
+// with a single row group of uncompressed UTF8 columns:
+//   - subject: the subject IRI, or "_:" followed by the label of blank nodes
+//   - predicate: the predicate IRI
+//   - object_value: the object IRI, blank node label or literal lexical form
+//   - object_type: "uri", "bnode" or the datatype IRI of literals (rdf:langString for language tagged ones)
+//   - lang: the language tag of literals, null otherwise
+//   - graph: the named graph, null for triples of the default graph
+func NewParquetEncoder(w io.Writer) Encoder {
+	return &parquetEncoder{w: w}
+}
+
+const parquetMagic = "PAR1"
+
+// Parquet enum values (see parquet.thrift)
+const (
+	parquetTypeByteArray     = 6
+	parquetRequired          = 0
+	parquetOptional          = 1
+	parquetConvertedTypeUTF8 = 0
+	parquetEncodingPlain     = 0
+	parquetEncodingRLE       = 3
+	parquetCodecUncompressed = 0
+	parquetPageTypeDataPage  = 0
+	parquetFormatVersion     = 1
+)
+
+const parquetLangStringDatatype = rdfNamespace + "langString"
+
+func (enc *parquetEncoder) Encode(tris ...Triple) error {
+	rows := make([][]*string, len(parquetColumns))
+	for _, t := range tris {
+		tt := t.(*triple)
+		sub := tt.sub
+		if tt.isSubBnode {
+			sub = "_:" + sub
+		}
+		var val, typ string
+		var lang *string
+		switch o := tt.obj; {
+		case o.isLit:
+			val, typ = o.lit.val, o.lit.typ.NTriplesNamespaced()
+			if o.lit.langtag != "" {
+				typ, lang = parquetLangStringDatatype, &o.lit.langtag
+			}
+		case o.isBnode:
+			val, typ = o.bnode, "bnode"
+		default:
+			val, typ = o.resource, "uri"
+		}
+		for i, v := range []*string{&sub, &tt.pred, &val, &typ, lang, nil} {
+			rows[i] = append(rows[i], v)
+		}
+	}
+
+	w := &countingWriter{w: enc.w}
+	if _, err := io.WriteString(w, parquetMagic); err != nil {
+		return err
+	}
+	var chunks []parquetChunk
+	if len(tris) > 0 {
+		for i, col := range parquetColumns {
+			chunk, err := writeParquetColumn(w, rows[i], col.optional)
+			if err != nil {
+				return err
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+	footer := parquetFileMetaData(len(tris), chunks)
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, parquetMagic...)
+	_, err := w.Write(footer)
+	return err
+}
+
+type parquetChunk struct {
+	offset, size int64
+}
+
+// writeParquetColumn writes the values as a single PLAIN encoded data page,
+// preceded by their RLE encoded definition levels for optional columns
+func writeParquetColumn(w *countingWriter, vals []*string, optional bool) (parquetChunk, error) {
+	var page []byte
+	if optional {
+		var levels []byte
+		for i := 0; i < len(vals); {
+			run := 1
+			for i+run < len(vals) && (vals[i+run] == nil) == (vals[i] == nil) {
+				run++
+			}
+			levels = binary.AppendUvarint(levels, uint64(run)<<1)
+			if vals[i] == nil {
+				levels = append(levels, 0)
+			} else {
+				levels = append(levels, 1)
+			}
+			i += run
+		}
+		page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+	}
+	for _, v := range vals {
+		if v != nil {
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(*v)))
+			page = append(page, *v...)
+		}
+	}
+
+	var h thriftCompactWriter
+	h.beginStruct()
+	h.i32(1, parquetPageTypeDataPage)
+	h.i32(2, int32(len(page)))
+	h.i32(3, int32(len(page)))
+	h.fieldStruct(5)
+	h.i32(1, int32(len(vals)))
+	h.i32(2, parquetEncodingPlain)
+	h.i32(3, parquetEncodingRLE)
+	h.i32(4, parquetEncodingRLE)
+	h.endStruct()
+	h.endStruct()
+
+	chunk := parquetChunk{offset: w.n, size: int64(len(h.buf) + len(page))}
+	if _, err := w.Write(h.buf); err != nil {
+		return chunk, err
+	}
+	_, err := w.Write(page)
+	return chunk, err
+}
+
+func parquetFileMetaData(numRows int, chunks []parquetChunk) []byte {
+	var m thriftCompactWriter
+	m.beginStruct()
+	m.i32(1, parquetFormatVersion)
+
+	m.list(2, thriftStruct, len(parquetColumns)+1)
+	m.beginStruct()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(parquetColumns)))
+	m.endStruct()
+	for _, col := range parquetColumns {
+		m.beginStruct()
+		m.i32(1, parquetTypeByteArray)
+		if col.optional {
+			m.i32(3, parquetOptional)
+		} else {
+			m.i32(3, parquetRequired)
+		}
+		m.binary(4, col.name)
+		m.i32(6, parquetConvertedTypeUTF8)
+		m.endStruct()
+	}
+
+	m.i64(3, int64(numRows))
+
+	m.list(4, thriftStruct, len(chunks)/len(parquetColumns))
+	if len(chunks) > 0 {
+		var total int64
+		for _, c := range chunks {
+			total += c.size
+		}
+		m.beginStruct()
+		m.list(1, thriftStruct, len(chunks))
+		for i, c := range chunks {
+			m.beginStruct()
+			m.i64(2, c.offset)
+			m.fieldStruct(3)
+			m.i32(1, parquetTypeByteArray)
+			m.list(2, thriftI32, 2)
+			m.listI32(parquetEncodingPlain)
+			m.listI32(parquetEncodingRLE)
+			m.list(3, thriftBinary, 1)
+			m.listBinary(parquetColumns[i].name)
+			m.i32(4, parquetCodecUncompressed)
+			m.i64(5, int64(numRows))
+			m.i64(6, c.size)
+			m.i64(7, c.size)
+			m.i64(9, c.offset)
+			m.endStruct()
+			m.endStruct()
+		}
+		m.i64(2, total)
+		m.i64(3, int64(numRows))
+		m.endStruct()
+	}
+
+	m.binary(6, "triplestore")
+	m.endStruct()
+	return m.buf
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftCompactWriter writes structs with the Thrift compact protocol,
+// as used by Parquet metadata
+type thriftCompactWriter struct {
+	buf []byte
+	// last holds the last field id of each struct being written
+	last []int16
+}
+
+func (w *thriftCompactWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.buf = binary.AppendVarint(w.buf, int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftCompactWriter) beginStruct() {
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftCompactWriter) fieldStruct(id int16) {
+	w.field(id, thriftStruct)
+	w.beginStruct()
+}
+
+func (w *thriftCompactWriter) endStruct() {
+	w.buf = append(w.buf, 0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftCompactWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.listI32(v)
+}
+
+func (w *thriftCompactWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *thriftCompactWriter) binary(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.listBinary(s)
+}
+
+// list writes the header of a list of n elements, to be followed by
+// listI32, listBinary or beginStruct/endStruct calls for each element
+func (w *thriftCompactWriter) list(id int16, elemType byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|elemType)
+	} else {
+		w.buf = append(w.buf, 0xf0|elemType)
+		w.buf = binary.AppendUvarint(w.buf, uint64(n))
+	}
+}
+
+func (w *thriftCompactWriter) listI32(v int32) {
+	w.buf = binary.AppendVarint(w.buf, int64(v))
+}
+
+func (w *thriftCompactWriter) listBinary(s string) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
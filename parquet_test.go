@@ -0,0 +1,179 @@
+package triplestore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParquetEncoder(t *testing.T) {
+	tris := []Triple{
+		SubjPred("one", "two").Resource("three"),
+		BnodePred("one", "two").Bnode("three"),
+		SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+		SubjPred("one", "two").IntegerLiteral(42),
+		SubjPred("one", "two").Object(TypedLiteral("x", "http://example.org/dt")),
+	}
+	var buf bytes.Buffer
+	if err := NewParquetEncoder(&buf).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	if got, want := string(file[:4]), "PAR1"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := string(file[len(file)-4:]), "PAR1"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	r := &thriftCompactReader{buf: file[len(file)-8-footerLen : len(file)-8]}
+	meta := r.readStruct()
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	if got, want := meta[3], int64(5); got != want {
+		t.Fatalf("num rows: got %v, want %v", got, want)
+	}
+	var names []string
+	for _, elem := range meta[2].([]interface{})[1:] {
+		names = append(names, elem.(map[int16]interface{})[4].(string))
+	}
+	if got, want := names, []string{"subject", "predicate", "object_value", "object_type", "lang", "graph"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	expected := [][]interface{}{
+		{"one", "_:one", "one", "one", "one"},
+		{"two", "two", "two", "two", "two"},
+		{"three", "three", "chat", "42", "x"},
+		{"uri", "bnode", "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString", "http://www.w3.org/2001/XMLSchema#integer", "http://example.org/dt"},
+		{nil, nil, "fr", nil, nil},
+		{nil, nil, nil, nil, nil},
+	}
+	rowGroup := meta[4].([]interface{})[0].(map[int16]interface{})
+	for i, chunk := range rowGroup[1].([]interface{}) {
+		colMeta := chunk.(map[int16]interface{})[3].(map[int16]interface{})
+		offset := colMeta[9].(int64)
+		r := &thriftCompactReader{buf: file[offset:]}
+		header := r.readStruct()
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		page := file[offset+int64(r.pos) : offset+int64(r.pos)+header[3].(int64)]
+		vals := decodeParquetTestPage(page, parquetColumns[i].optional, 5)
+		if got, want := vals, expected[i]; !reflect.DeepEqual(got, want) {
+			t.Fatalf("column %d: got %v, want %v", i, got, want)
+		}
+	}
+
+	buf.Reset()
+	if err := NewParquetEncoder(&buf).Encode(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.Len(), 4+len(parquetFileMetaData(0, nil))+8; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func decodeParquetTestPage(page []byte, optional bool, n int) []interface{} {
+	defined := make([]bool, 0, n)
+	if optional {
+		levels := page[4 : 4+binary.LittleEndian.Uint32(page)]
+		page = page[4+len(levels):]
+		for len(levels) > 0 {
+			header, l := binary.Uvarint(levels)
+			for i := 0; i < int(header>>1); i++ {
+				defined = append(defined, levels[l] == 1)
+			}
+			levels = levels[l+1:]
+		}
+	}
+	var vals []interface{}
+	for i := 0; i < n; i++ {
+		if optional && !defined[i] {
+			vals = append(vals, nil)
+			continue
+		}
+		l := binary.LittleEndian.Uint32(page)
+		vals = append(vals, string(page[4:4+l]))
+		page = page[4+l:]
+	}
+	return vals
+}
+
+// thriftCompactReader reads structs of the Thrift compact protocol
+// as maps of field id to values
+type thriftCompactReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *thriftCompactReader) byte() byte {
+	if r.pos >= len(r.buf) {
+		r.err = fmt.Errorf("unexpected end at %d", r.pos)
+		return 0
+	}
+	r.pos++
+	return r.buf[r.pos-1]
+}
+
+func (r *thriftCompactReader) varint() int64 {
+	v, n := binary.Varint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftCompactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftCompactReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for r.err == nil {
+		b := r.byte()
+		if b == 0 {
+			break
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.readValue(b & 0x0f)
+		last = id
+	}
+	return fields
+}
+
+func (r *thriftCompactReader) readValue(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		l := int(r.uvarint())
+		s := string(r.buf[r.pos : r.pos+l])
+		r.pos += l
+		return s
+	case thriftList:
+		b := r.byte()
+		n := int(b >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		var elems []interface{}
+		for i := 0; i < n; i++ {
+			elems = append(elems, r.readValue(b&0x0f))
+		}
+		return elems
+	case thriftStruct:
+		return r.readStruct()
+	}
+	r.err = fmt.Errorf("unsupported type %d", typ)
+	return nil
+}
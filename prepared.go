@@ -0,0 +1,99 @@
+package triplestore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PreparedQuery is a query parsed once to be evaluated many times with different
+// parameters, i.e. values of some of its variables bound before evaluation
+// (ex: "SELECT ?name WHERE { ?p <name> ?name ; <id> ?id }" with ?id as parameter).
+//
+// Evaluation plans are cached by set of parameter names. A prepared query
+// is safe for concurrent use.
+type PreparedQuery struct {
+	query *Query
+
+	mu    sync.RWMutex
+	plans map[string]*queryPlan
+}
+
+// PrepareQuery parses a SPARQL query to evaluate it with parameters
+func PrepareQuery(q string) (*PreparedQuery, error) {
+	query, err := ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedQuery{query: query, plans: make(map[string]*queryPlan)}, nil
+}
+
+// Query returns the prepared query
+func (p *PreparedQuery) Query() *Query {
+	return p.query
+}
+
+// Eval evaluates the query against the given graph, its parameters bound
+// to the given values. Parameters are returned in solutions when projected.
+func (p *PreparedQuery) Eval(g RDFGraph, params Binding) (*QueryResults, error) {
+	plan, initial, err := p.plan(params)
+	if err != nil {
+		return nil, err
+	}
+	return p.query.evalPlan(g, plan, initial), nil
+}
+
+// Ask returns true if the query, its parameters bound to the given values,
+// has at least one solution in the given graph (see Query.Ask)
+func (p *PreparedQuery) Ask(g RDFGraph, params Binding) (bool, error) {
+	plan, initial, err := p.plan(params)
+	if err != nil {
+		return false, err
+	}
+	return hasSolution(g, plan.steps, plan.remaining, initial), nil
+}
+
+// plan returns the cached plan for the parameters and their initial binding
+func (p *PreparedQuery) plan(params Binding) (*queryPlan, Binding, error) {
+	names := make([]string, 0, len(params))
+	initial := make(Binding, len(params))
+	for name, val := range params {
+		name = strings.TrimPrefix(name, "?")
+		if !p.query.hasVar(name) {
+			return nil, nil, fmt.Errorf("sparql: unknown parameter ?%s", name)
+		}
+		if _, ok := val.(object); !ok {
+			return nil, nil, fmt.Errorf("sparql: invalid value for parameter ?%s", name)
+		}
+		names = append(names, name)
+		initial[name] = val
+	}
+	sort.Strings(names)
+	key := strings.Join(names, " ")
+
+	p.mu.RLock()
+	plan, ok := p.plans[key]
+	p.mu.RUnlock()
+	if ok {
+		return plan, initial, nil
+	}
+
+	plan = p.query.newPlan(names...)
+	p.mu.Lock()
+	p.plans[key] = plan
+	p.mu.Unlock()
+	return plan, initial, nil
+}
+
+// hasVar returns true if the variable appears in a pattern of the query
+func (q *Query) hasVar(name string) bool {
+	for _, p := range q.patterns {
+		for _, t := range []term{p.sub, p.pred, p.obj} {
+			if t.variable == name {
+				return true
+			}
+		}
+	}
+	return false
+}
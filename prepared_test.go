@@ -0,0 +1,109 @@
+package triplestore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreparedQuery(t *testing.T) {
+	g := sparqlTestGraph()
+	alice, bob := `<alice> "Alice"`, `<bob> "Bob"`
+	q, err := PrepareQuery("SELECT ?p ?name WHERE { ?p <name> ?name ; a ?type }")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcases := []struct {
+		params Binding
+		exp    []string
+	}{
+		{Binding{"type": Resource("Person")}, []string{alice, bob}},
+		{Binding{"?type": Resource("Dog")}, nil},
+		{Binding{"p": Resource("alice")}, []string{alice}},
+		{Binding{"p": Resource("bob"), "type": Resource("Person")}, []string{bob}},
+		{Binding{"name": StringLiteral("Bob")}, []string{bob}},
+		{Binding{}, []string{alice, bob}},
+		{nil, []string{alice, bob}},
+	}
+	for i, tc := range tcases {
+		for run := 0; run < 2; run++ {
+			res, err := q.Eval(g, tc.params)
+			if err != nil {
+				t.Fatalf("case %d: %s", i, err)
+			}
+			if got, want := resultRows(res), tc.exp; !reflect.DeepEqual(got, want) {
+				t.Fatalf("case %d, run %d: got %q, want %q", i, run, got, want)
+			}
+			ok, err := q.Ask(g, tc.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := ok, len(tc.exp) > 0; got != want {
+				t.Fatalf("case %d: ask: got %t, want %t", i, got, want)
+			}
+		}
+	}
+	if got, want := len(q.plans), 5; got != want {
+		t.Fatalf("got %d cached plans, want %d", got, want)
+	}
+
+	if _, err := q.Eval(g, Binding{"unknown": Resource("alice")}); err == nil || !strings.Contains(err.Error(), "unknown parameter ?unknown") {
+		t.Fatalf("expected unknown parameter error, got %v", err)
+	}
+	if _, err := q.Eval(g, Binding{"p": nil}); err == nil || !strings.Contains(err.Error(), "invalid value") {
+		t.Fatalf("expected invalid value error, got %v", err)
+	}
+}
+
+func TestPreparedQueryFilters(t *testing.T) {
+	g := sparqlTestGraph()
+	q, err := PrepareQuery(`SELECT ?p WHERE { ?p <age> ?age FILTER (?age > 30) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		age Object
+		exp int
+	}{
+		{IntegerLiteral(42), 1},
+		{IntegerLiteral(24), 0},
+	} {
+		res, err := q.Eval(g, Binding{"age": tc.age})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(res.Bindings), tc.exp; got != want {
+			t.Fatalf("case %d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+// BenchmarkPreparedQuery compares parsing and evaluating a query at each request
+// with evaluating a prepared query:
+//
+// BenchmarkPreparedQuery/parse-each-time         	  120378	     10604 ns/op
+// BenchmarkPreparedQuery/prepared                	  292880	      4686 ns/op
+func BenchmarkPreparedQuery(b *testing.B) {
+	g := sparqlTestGraph()
+	query := "SELECT ?name WHERE { ?p <name> ?name ; <age> ?age ; <knows> ?o . ?o a <Person> }"
+	b.Run("parse-each-time", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			q, err := ParseQuery(strings.Replace(query, "?p", "<alice>", 1))
+			if err != nil {
+				b.Fatal(err)
+			}
+			q.Eval(g)
+		}
+	})
+	b.Run("prepared", func(b *testing.B) {
+		q, err := PrepareQuery(query)
+		if err != nil {
+			b.Fatal(err)
+		}
+		params := Binding{"p": Resource("alice")}
+		for i := 0; i < b.N; i++ {
+			q.Eval(g, params)
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package triplestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Fields numbers and term kinds of triplestore.proto
+const (
+	protoTripleSubject   = 1
+	protoTriplePredicate = 2
+	protoTripleObject    = 3
+	protoTripleGraph     = 4
+
+	protoTermKind     = 1
+	protoTermValue    = 2
+	protoTermDatatype = 3
+	protoTermLang     = 4
+
+	protoKindIRI     = 0
+	protoKindBnode   = 1
+	protoKindLiteral = 2
+)
+
+// Protocol buffers wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type protoEncoder struct {
+	w io.Writer
+}
+
+// NewProtoEncoder returns an encoder writing triples as length-delimited
+// Triple messages of triplestore.proto, as expected by gRPC streams
+// or protobuf delimited readers (ex: parseDelimitedFrom in Java).
+func NewProtoEncoder(w io.Writer) Encoder {
+	return &protoEncoder{w: w}
+}
+
+func (enc *protoEncoder) Encode(tris ...Triple) error {
+	var msg, buf []byte
+	for _, t := range tris {
+		msg = appendProtoTriple(msg[:0], t.(*triple))
+		buf = binary.AppendUvarint(buf[:0], uint64(len(msg)))
+		buf = append(buf, msg...)
+		if _, err := enc.w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalProto returns the encoding of the triple as a Triple message of triplestore.proto
+func MarshalProto(t Triple) []byte {
+	return appendProtoTriple(nil, t.(*triple))
+}
+
+// UnmarshalProto decodes a Triple message of triplestore.proto
+func UnmarshalProto(data []byte) (Triple, error) {
+	return parseProtoTriple(data)
+}
+
+func appendProtoTriple(buf []byte, t *triple) []byte {
+	sub := object{resource: t.sub}
+	if t.isSubBnode {
+		sub = object{isBnode: true, bnode: t.sub}
+	}
+	var term []byte
+	term = appendProtoTerm(term, sub)
+	buf = appendProtoBytes(buf, protoTripleSubject, term)
+	buf = appendProtoString(buf, protoTriplePredicate, t.pred)
+	term = appendProtoTerm(term[:0], t.obj)
+	return appendProtoBytes(buf, protoTripleObject, term)
+}
+
+func appendProtoTerm(buf []byte, o object) []byte {
+	switch {
+	case o.isLit:
+		buf = appendProtoVarint(buf, protoTermKind, protoKindLiteral)
+		buf = appendProtoString(buf, protoTermValue, o.lit.val)
+		if o.lit.langtag != "" {
+			return appendProtoString(buf, protoTermLang, o.lit.langtag)
+		}
+		if dt := o.lit.typ.NTriplesNamespaced(); dt != XsdString.NTriplesNamespaced() {
+			buf = appendProtoString(buf, protoTermDatatype, dt)
+		}
+		return buf
+	case o.isBnode:
+		buf = appendProtoVarint(buf, protoTermKind, protoKindBnode)
+		return appendProtoString(buf, protoTermValue, o.bnode)
+	default:
+		return appendProtoString(buf, protoTermValue, o.resource)
+	}
+}
+
+func appendProtoVarint(buf []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendProtoString(buf []byte, field int, s string) []byte {
+	if s == "" {
+		return buf
+	}
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendProtoBytes(buf []byte, field int, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+type protoDecoder struct {
+	r    io.Reader
+	opts decoderOptions
+}
+
+// NewProtoDecoder returns a decoder reading length-delimited Triple messages
+// of triplestore.proto, as written by NewProtoEncoder
+func NewProtoDecoder(r io.Reader, opts ...DecoderOption) Decoder {
+	return &protoDecoder{r: r, opts: newDecoderOptions(opts)}
+}
+
+func (dec *protoDecoder) Decode() ([]Triple, error) {
+	var out []Triple
+	tracker := newProgressTracker(dec.opts.progress)
+	r := bufio.NewReader(tracker.reader(dec.r))
+	var msg []byte
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			tracker.done()
+			return out, nil
+		} else if err != nil {
+			return out, fmt.Errorf("protobuf: message size: %s", err)
+		}
+		// a message holds at most 7 terms, plus their framing
+		if max := dec.opts.maxTermSize; max > 0 && size > uint64(8*max+128) {
+			return out, fmt.Errorf("protobuf: message of %d bytes exceeds maximum term size of %d bytes", size, max)
+		}
+		if uint64(cap(msg)) < size {
+			msg = make([]byte, size)
+		}
+		msg = msg[:size]
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return out, fmt.Errorf("protobuf: cannot read message of %d bytes: %s", size, err)
+		}
+		tri, err := parseProtoTriple(msg)
+		if err != nil {
+			return out, err
+		}
+		if err := dec.opts.checkTermSize(tri); err != nil {
+			return out, fmt.Errorf("protobuf: %s", err)
+		}
+		out = append(out, tri)
+		tracker.addTriple()
+	}
+}
+
+func parseProtoTriple(data []byte) (*triple, error) {
+	var sub, obj object
+	var pred string
+	var hasSub, hasObj bool
+	err := parseProtoFields(data, func(field int, v uint64, b []byte) error {
+		var err error
+		switch field {
+		case protoTripleSubject:
+			sub, err = parseProtoTerm(b)
+			hasSub = true
+		case protoTriplePredicate:
+			pred = string(b)
+		case protoTripleObject:
+			obj, err = parseProtoTerm(b)
+			hasObj = true
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("protobuf: %s", err)
+	}
+	switch {
+	case !hasSub || !hasObj || pred == "":
+		return nil, errors.New("protobuf: triple requires a subject, a predicate and an object")
+	case sub.isLit:
+		return nil, errors.New("protobuf: subject: literal not allowed")
+	}
+	return newTriple(sub, pred, obj), nil
+}
+
+func parseProtoTerm(data []byte) (object, error) {
+	var kind uint64
+	var value, datatype, lang string
+	err := parseProtoFields(data, func(field int, v uint64, b []byte) error {
+		switch field {
+		case protoTermKind:
+			kind = v
+		case protoTermValue:
+			value = string(b)
+		case protoTermDatatype:
+			datatype = string(b)
+		case protoTermLang:
+			lang = string(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return object{}, err
+	}
+	switch kind {
+	case protoKindIRI:
+		return object{resource: value}, nil
+	case protoKindBnode:
+		return object{isBnode: true, bnode: value}, nil
+	case protoKindLiteral:
+		lit := literal{typ: XsdString, val: value, langtag: lang}
+		if datatype != "" && lang == "" {
+			lit.typ = shortXsdType(XsdType(datatype))
+		}
+		return object{isLit: true, lit: lit}, nil
+	}
+	return object{}, fmt.Errorf("unknown term kind %d", kind)
+}
+
+// parseProtoFields calls fn with each field of the message, passing
+// the value of varint fields or the content of length-delimited fields.
+// Unknown fields of other wire types are skipped.
+func parseProtoFields(data []byte, fn func(field int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("invalid field key")
+		}
+		data = data[n:]
+		field := int(key >> 3)
+		var v uint64
+		var b []byte
+		switch key & 7 {
+		case wireVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return fmt.Errorf("field %d: invalid varint", field)
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return fmt.Errorf("field %d: unexpected end of message", field)
+			}
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return fmt.Errorf("field %d: unexpected end of message", field)
+			}
+			data = data[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return fmt.Errorf("field %d: invalid length", field)
+			}
+			b, data = data[n:n+int(l)], data[n+int(l):]
+		default:
+			return fmt.Errorf("field %d: unsupported wire type %d", field, key&7)
+		}
+		if err := fn(field, v, b); err != nil {
+			return fmt.Errorf("field %d: %s", field, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package triplestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProtoCodec(t *testing.T) {
+	tris := Triples{
+		SubjPred("one", "two").Resource("three"),
+		BnodePred("one", "two").Bnode("three"),
+		SubjPred("one", "two").StringLiteral(""),
+		SubjPred("one", "two").StringLiteral("a \"quoted\"\nline"),
+		SubjPred("one", "two").StringLiteralWithLang("chat", "fr"),
+		SubjPred("one", "two").IntegerLiteral(42),
+		SubjPred("one", "two").BooleanLiteral(true),
+		SubjPred("one", "two").Object(TypedLiteral("x", "http://example.org/dt")),
+	}
+
+	var buf bytes.Buffer
+	if err := NewProtoEncoder(&buf).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewProtoDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), tris; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for i, tri := range tris {
+		decoded, err := UnmarshalProto(MarshalProto(tri))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(tri) {
+			t.Fatalf("case %d: got %v, want %v", i+1, decoded, tri)
+		}
+	}
+
+	custom, err := UnmarshalProto(MarshalProto(SubjPred("one", "two").Object(TypedLiteral("x", "http://example.org/dt"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lit, _ := custom.Object().Literal(); lit.Type() != "http://example.org/dt" {
+		t.Fatalf("got datatype %s, want http://example.org/dt", lit.Type())
+	}
+}
+
+func TestProtoWireFormat(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewProtoEncoder(buf).Encode(SubjPred("s", "p").Resource("o")); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x0d,                        // message size
+		0x0a, 0x03, 0x12, 0x01, 's', // subject: {value: "s"}
+		0x12, 0x01, 'p', // predicate: "p"
+		0x1a, 0x03, 0x12, 0x01, 'o', // object: {value: "o"}
+	}
+	if got, want := buf.Bytes(), expected; !bytes.Equal(got, want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+
+	// graph (4), unknown varint (9) and fixed32 (10) fields are skipped
+	withUnknown := []byte{
+		0x0a, 0x05, 0x08, 0x01, 0x12, 0x01, 's', // subject: {kind: BNODE, value: "s"}
+		0x12, 0x01, 'p', // predicate: "p"
+		0x22, 0x01, 'g', // graph: "g"
+		0x48, 0x96, 0x01, // 9: 150
+		0x55, 0x01, 0x02, 0x03, 0x04, // 10: fixed32
+		0x1a, 0x0a, 0x08, 0x02, 0x12, 0x02, '4', '2', 0x22, 0x02, 'e', 'n', // object: {kind: LITERAL, value: "42", lang: "en"}
+	}
+	tri, err := UnmarshalProto(withUnknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := TripleKey(tri), `_:s <p> "42"@en`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestProtoDecodeErrors(t *testing.T) {
+	tcases := []struct {
+		in  []byte
+		err string
+	}{
+		{in: []byte{0x05, 0x12, 0x01}, err: "cannot read message"},
+		{in: []byte{0x03, 0x12, 0x05, 'p'}, err: "field 2: invalid length"},
+		{in: []byte{0x03, 0x12, 0x01, 'p'}, err: "requires a subject, a predicate and an object"},
+		{in: []byte{0x0c, 0x0a, 0x02, 0x08, 0x02, 0x12, 0x01, 'p', 0x1a, 0x03, 0x12, 0x01, 'o'}, err: "subject: literal not allowed"},
+		{in: []byte{0x0c, 0x0a, 0x03, 0x12, 0x01, 's', 0x12, 0x01, 'p', 0x1a, 0x02, 0x08, 0x07}, err: "unknown term kind 7"},
+		{in: []byte{0x02, 0x0b, 0x00}, err: "unsupported wire type 3"},
+	}
+	for i, tc := range tcases {
+		_, err := NewProtoDecoder(bytes.NewReader(tc.in)).Decode()
+		if err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+		if got, want := err.Error(), tc.err; !strings.Contains(got, want) {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	NewProtoEncoder(&buf).Encode(SubjPred("one", "two").Resource(strings.Repeat("a", 1000)))
+	if _, err := NewProtoDecoder(&buf, WithMaxTermSize(10)).Decode(); err == nil {
+		t.Fatal("expected error")
+	}
+}
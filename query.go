@@ -0,0 +1,399 @@
+package triplestore
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Binding maps variable names to their value (resource, bnode or literal)
+type Binding map[string]Object
+
+// QueryResults holds the solutions of a query
+type QueryResults struct {
+	Vars     []string
+	Bindings []Binding
+}
+
+// IsAsk returns true for ASK queries
+func (q *Query) IsAsk() bool {
+	return q.ask
+}
+
+// Vars returns the variables projected by the query, in order.
+// ASK queries project no variable.
+func (q *Query) Vars() []string {
+	if q.ask {
+		return nil
+	}
+	if len(q.vars) > 0 {
+		return q.vars
+	}
+	var vars []string
+	seen := make(map[string]bool)
+	for _, p := range q.patterns {
+		for _, t := range []term{p.sub, p.pred, p.obj} {
+			if t.isVar() && !seen[t.variable] {
+				seen[t.variable] = true
+				vars = append(vars, t.variable)
+			}
+		}
+	}
+	return vars
+}
+
+// Ask returns true if the query has at least one solution in the given
+// graph. Evaluation stops at the first solution found, making it cheaper
+// than Eval for existence checks. It works for both ASK and SELECT queries,
+// solution modifiers (LIMIT, OFFSET) being ignored.
+func (q *Query) Ask(g RDFGraph) (bool, error) {
+	plan := q.newPlan()
+	return hasSolution(g, plan.steps, plan.remaining, Binding{}), nil
+}
+
+// Eval evaluates the query against the given graph. An ASK query
+// results in a single empty binding when true, none otherwise.
+func (q *Query) Eval(g RDFGraph) (*QueryResults, error) {
+	return q.evalPlan(g, q.newPlan(), Binding{}), nil
+}
+
+// queryPlan is the evaluation plan of a query, given the variables bound beforehand
+type queryPlan struct {
+	steps     []step
+	remaining []filter
+}
+
+func (q *Query) newPlan(bound ...string) *queryPlan {
+	plan := &queryPlan{}
+	plan.steps, plan.remaining = planSteps(planPatterns(q.patterns, bound...), q.filters, bound...)
+	return plan
+}
+
+// evalPlan evaluates the query following the plan, from the initial binding
+func (q *Query) evalPlan(g RDFGraph, plan *queryPlan, initial Binding) *QueryResults {
+	if q.ask {
+		res := &QueryResults{}
+		if hasSolution(g, plan.steps, plan.remaining, initial) {
+			res.Bindings = []Binding{{}}
+		}
+		return res
+	}
+
+	solutions := solveSteps(g, plan.steps, plan.remaining, initial)
+
+	res := &QueryResults{Vars: q.Vars()}
+	seen := make(map[string]bool)
+	for _, b := range solutions {
+		projected := make(Binding, len(res.Vars))
+		for _, v := range res.Vars {
+			if o, ok := b[v]; ok {
+				projected[v] = o
+			}
+		}
+		if q.distinct {
+			k := bindingKey(res.Vars, projected)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		res.Bindings = append(res.Bindings, projected)
+	}
+
+	if q.offset >= len(res.Bindings) {
+		res.Bindings = nil
+	} else {
+		res.Bindings = res.Bindings[q.offset:]
+	}
+	if q.limit >= 0 && q.limit < len(res.Bindings) {
+		res.Bindings = res.Bindings[:q.limit]
+	}
+	return res
+}
+
+// solvePatterns returns the bindings matching all the patterns, joined in order,
+// and accepted by the filters
+func solvePatterns(g RDFGraph, patterns []triplePattern, filters ...filter) []Binding {
+	steps, remaining := planSteps(patterns, filters)
+	return solveSteps(g, steps, remaining, Binding{})
+}
+
+// solveSteps returns the extensions of the initial binding matching the steps
+// and accepted by the remaining filters
+func solveSteps(g RDFGraph, steps []step, remaining []filter, initial Binding) []Binding {
+	solutions := []Binding{initial}
+	for _, st := range steps {
+		var next []Binding
+		for _, b := range solutions {
+			for _, extended := range matchPattern(g, st.pattern, b, st.objLookup) {
+				if acceptAll(st.filters, extended) {
+					next = append(next, extended)
+				}
+			}
+		}
+		solutions = next
+		if len(solutions) == 0 {
+			break
+		}
+	}
+	if len(remaining) == 0 {
+		return solutions
+	}
+	var out []Binding
+	for _, b := range solutions {
+		if acceptAll(remaining, b) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// hasSolution walks the steps depth first and returns as soon as
+// a binding satisfying all of them is found
+func hasSolution(g RDFGraph, steps []step, remaining []filter, b Binding) bool {
+	if len(steps) == 0 {
+		return acceptAll(remaining, b)
+	}
+	st := steps[0]
+	for _, next := range matchPattern(g, st.pattern, b, st.objLookup) {
+		if acceptAll(st.filters, next) && hasSolution(g, steps[1:], remaining, next) {
+			return true
+		}
+	}
+	return false
+}
+
+// Explain describes the evaluation of the query: the order in which its triple
+// patterns are matched, the RDFGraph lookup used for each of them given the
+// variables bound by the previous ones, and when the filters are applied.
+// Patterns evaluated with a full scan or with few bound terms are the slow ones.
+func (q *Query) Explain() string {
+	plan := q.newPlan()
+	var buff bytes.Buffer
+	bound := make(map[string]bool)
+	for i, st := range plan.steps {
+		isBound := func(t term) bool {
+			return !t.isVar() || bound[t.variable]
+		}
+		p := st.pattern
+		l := chooseLookup(isBound(p.sub), isBound(p.pred), isBound(p.obj), st.objLookup != nil)
+		method := lookupMethods[l]
+		if l == filteredLookup {
+			method = st.objLookup.method
+		}
+		fmt.Fprintf(&buff, "%d. %s (%s)\n", i+1, p, method)
+		for _, f := range st.filters {
+			fmt.Fprintf(&buff, "   %s\n", f)
+		}
+		for _, t := range []term{p.sub, p.pred, p.obj} {
+			if t.isVar() {
+				bound[t.variable] = true
+			}
+		}
+	}
+	for _, f := range plan.remaining {
+		fmt.Fprintf(&buff, "%s (unbound variables, no solution)\n", f)
+	}
+	return buff.String()
+}
+
+// planPatterns orders the triple patterns so that the most bound ones,
+// considering variables bound beforehand or by previous patterns, are evaluated first
+func planPatterns(patterns []triplePattern, boundVars ...string) []triplePattern {
+	remaining := make([]triplePattern, len(patterns))
+	copy(remaining, patterns)
+	bound := make(map[string]bool)
+	for _, v := range boundVars {
+		bound[v] = true
+	}
+
+	var ordered []triplePattern
+	for len(remaining) > 0 {
+		best, bestScore := 0, -1
+		for i, p := range remaining {
+			var score int
+			for _, t := range []term{p.sub, p.pred, p.obj} {
+				if !t.isVar() || bound[t.variable] {
+					score++
+				}
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		p := remaining[best]
+		for _, t := range []term{p.sub, p.pred, p.obj} {
+			if t.isVar() {
+				bound[t.variable] = true
+			}
+		}
+		ordered = append(ordered, p)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+	return ordered
+}
+
+// lookup is the way candidate triples of a pattern are found, depending
+// on the terms of the pattern that are bound
+type lookup int
+
+const (
+	filteredLookup lookup = iota
+	subjPredLookup
+	subjObjLookup
+	predObjLookup
+	subjectLookup
+	predicateLookup
+	objectLookup
+	fullScan
+)
+
+var lookupMethods = map[lookup]string{
+	subjPredLookup:  "WithSubjPred",
+	subjObjLookup:   "WithSubjObj",
+	predObjLookup:   "WithPredObj",
+	subjectLookup:   "WithSubject",
+	predicateLookup: "WithPredicate",
+	objectLookup:    "WithObject",
+	fullScan:        "Triples, full scan",
+}
+
+func chooseLookup(subOk, predOk, objOk, hasObjLookup bool) lookup {
+	switch {
+	case !subOk && predOk && !objOk && hasObjLookup:
+		return filteredLookup
+	case subOk && predOk:
+		return subjPredLookup
+	case subOk && objOk:
+		return subjObjLookup
+	case predOk && objOk:
+		return predObjLookup
+	case subOk:
+		return subjectLookup
+	case predOk:
+		return predicateLookup
+	case objOk:
+		return objectLookup
+	default:
+		return fullScan
+	}
+}
+
+// matchPattern extends the binding with the triples matching the pattern. When
+// set, objLookup finds the triples of a pattern with only a bound predicate.
+func matchPattern(g RDFGraph, p triplePattern, b Binding, objLookup *objLookup) (out []Binding) {
+	sub, subOk := resolveTerm(p.sub, b)
+	pred, predOk := resolveTerm(p.pred, b)
+	obj, objOk := resolveTerm(p.obj, b)
+
+	var subID, predID string
+	if subOk {
+		if sub.isLit {
+			return nil
+		}
+		subID = nodeID(sub)
+	}
+	if predOk {
+		if pred.isLit || pred.isBnode {
+			return nil
+		}
+		predID = pred.resource
+	}
+
+	var candidates []Triple
+	switch chooseLookup(subOk, predOk, objOk, objLookup != nil) {
+	case filteredLookup:
+		candidates = objLookup.find(g, predID)
+	case subjPredLookup:
+		candidates = g.WithSubjPred(subID, predID)
+	case subjObjLookup:
+		candidates = g.WithSubjObj(subID, obj)
+	case predObjLookup:
+		candidates = g.WithPredObj(predID, obj)
+	case subjectLookup:
+		candidates = g.WithSubject(subID)
+	case predicateLookup:
+		candidates = g.WithPredicate(predID)
+	case objectLookup:
+		candidates = g.WithObject(obj)
+	default:
+		candidates = g.Triples()
+	}
+
+	for _, t := range candidates {
+		tri := t.(*triple)
+		s := subjectObject(tri)
+		if subOk && s.key() != sub.key() {
+			continue
+		}
+		if objOk && tri.obj.key() != obj.key() {
+			continue
+		}
+		extended := b
+		var ok bool
+		if extended, ok = bindTerm(extended, p.sub, s); !ok {
+			continue
+		}
+		if extended, ok = bindTerm(extended, p.pred, object{resource: tri.pred}); !ok {
+			continue
+		}
+		if extended, ok = bindTerm(extended, p.obj, tri.obj); !ok {
+			continue
+		}
+		out = append(out, extended)
+	}
+	return
+}
+
+// resolveTerm returns the constant value of a term, i.e. its value
+// or the value bound to its variable
+func resolveTerm(t term, b Binding) (object, bool) {
+	if !t.isVar() {
+		return t.value, true
+	}
+	if o, ok := b[t.variable]; ok {
+		return o.(object), true
+	}
+	return object{}, false
+}
+
+// bindTerm binds the variable of the term to the given value, returning
+// a new binding. It fails if the variable is already bound to another value.
+func bindTerm(b Binding, t term, val object) (Binding, bool) {
+	if !t.isVar() {
+		return b, true
+	}
+	if existing, ok := b[t.variable]; ok {
+		return b, existing.(object).key() == val.key()
+	}
+	extended := make(Binding, len(b)+1)
+	for k, v := range b {
+		extended[k] = v
+	}
+	extended[t.variable] = val
+	return extended, true
+}
+
+func subjectObject(t *triple) object {
+	if t.isSubBnode {
+		return object{bnode: t.sub, isBnode: true}
+	}
+	return object{resource: t.sub}
+}
+
+func nodeID(o object) string {
+	if o.isBnode {
+		return o.bnode
+	}
+	return o.resource
+}
+
+func bindingKey(vars []string, b Binding) string {
+	var k string
+	for _, v := range vars {
+		if o, ok := b[v]; ok {
+			k += o.(object).key()
+		}
+		k += "\x00"
+	}
+	return k
+}
@@ -0,0 +1,172 @@
+package triplestore
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// WithRangeIndex builds, with each snapshot, sorted indexes of the numeric and
+// temporal literal objects of the given predicates, so that WithPredRange and
+// WithPredTimeRange on them are binary searches rather than scans parsing every
+// object of the predicate.
+func WithRangeIndex(preds ...string) SourceOption {
+	return func(s *source) {
+		s.rangePreds = append(s.rangePreds, preds...)
+	}
+}
+
+// numericValue returns the exact value of a numeric literal. Non finite
+// floating point values (NaN, INF) have no exact value.
+func numericValue(o object) (*big.Rat, bool) {
+	if !o.isLit {
+		return nil, false
+	}
+	typ := shortXsdType(o.lit.typ)
+	if !isNumericType(typ) {
+		return nil, false
+	}
+	if isFloatingType(typ) {
+		f, err := strconv.ParseFloat(o.lit.val, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(f), true
+	}
+	return exactNumericValue(typ, o.lit.val)
+}
+
+// timeValue returns the instant of a xsd:dateTime or xsd:date literal,
+// a date starting at midnight. Without timezone, UTC is assumed.
+func timeValue(o object) (time.Time, bool) {
+	if !o.isLit {
+		return time.Time{}, false
+	}
+	switch typ := shortXsdType(o.lit.typ); typ {
+	case XsdDateTime, XsdDate:
+		t, err := temporalValue(literal{typ: typ, val: o.lit.val})
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// numericRange is an inclusive range of numeric values, nil bounds
+// meaning unbounded
+type numericRange struct {
+	min, max *big.Rat
+}
+
+// newNumericRange returns false if a bound is not a valid numeric literal
+func newNumericRange(min, max Literal) (r numericRange, ok bool) {
+	for _, b := range []struct {
+		lit Literal
+		val **big.Rat
+	}{{min, &r.min}, {max, &r.max}} {
+		if b.lit == nil {
+			continue
+		}
+		if *b.val, ok = numericValue(object{isLit: true, lit: literal{typ: b.lit.Type(), val: b.lit.Value()}}); !ok {
+			return r, false
+		}
+	}
+	return r, true
+}
+
+func (r numericRange) contains(v *big.Rat) bool {
+	return (r.min == nil || v.Cmp(r.min) >= 0) && (r.max == nil || v.Cmp(r.max) <= 0)
+}
+
+// timeRange is an inclusive range of instants, zero bounds meaning unbounded
+type timeRange struct {
+	from, to time.Time
+}
+
+func (r timeRange) contains(t time.Time) bool {
+	return (r.from.IsZero() || !t.Before(r.from)) && (r.to.IsZero() || !t.After(r.to))
+}
+
+// rangeIndex holds the triples of a predicate having a numeric or a temporal
+// literal object, sorted by object value
+type rangeIndex struct {
+	numbers []numericEntry
+	times   []timeEntry
+}
+
+type numericEntry struct {
+	value *big.Rat
+	tri   uint32
+}
+
+type timeEntry struct {
+	value time.Time
+	tri   uint32
+}
+
+func newRangeIndex(g *graph, pid termID) *rangeIndex {
+	idx := &rangeIndex{}
+	numbers := make(map[termID]*big.Rat)
+	times := make(map[termID]time.Time)
+	for _, i := range g.p[pid] {
+		oid := g.tris[i].o
+		v, ok := numbers[oid]
+		if !ok {
+			v, _ = numericValue(g.terms[oid])
+			numbers[oid] = v
+		}
+		if v != nil {
+			idx.numbers = append(idx.numbers, numericEntry{value: v, tri: i})
+			continue
+		}
+		t, ok := times[oid]
+		if !ok {
+			t, _ = timeValue(g.terms[oid])
+			times[oid] = t
+		}
+		if !t.IsZero() {
+			idx.times = append(idx.times, timeEntry{value: t, tri: i})
+		}
+	}
+	sort.Slice(idx.numbers, func(i, j int) bool { return idx.numbers[i].value.Cmp(idx.numbers[j].value) < 0 })
+	sort.Slice(idx.times, func(i, j int) bool { return idx.times[i].value.Before(idx.times[j].value) })
+	return idx
+}
+
+// numbersIn returns the positions of the triples with a numeric value within the range
+func (idx *rangeIndex) numbersIn(r numericRange) []uint32 {
+	from, to := 0, len(idx.numbers)
+	if r.min != nil {
+		from = sort.Search(len(idx.numbers), func(i int) bool { return idx.numbers[i].value.Cmp(r.min) >= 0 })
+	}
+	if r.max != nil {
+		to = sort.Search(len(idx.numbers), func(i int) bool { return idx.numbers[i].value.Cmp(r.max) > 0 })
+	}
+	if from >= to {
+		return nil
+	}
+	out := make([]uint32, 0, to-from)
+	for _, e := range idx.numbers[from:to] {
+		out = append(out, e.tri)
+	}
+	return out
+}
+
+// timesIn returns the positions of the triples with an instant within the range
+func (idx *rangeIndex) timesIn(r timeRange) []uint32 {
+	from, to := 0, len(idx.times)
+	if !r.from.IsZero() {
+		from = sort.Search(len(idx.times), func(i int) bool { return !idx.times[i].value.Before(r.from) })
+	}
+	if !r.to.IsZero() {
+		to = sort.Search(len(idx.times), func(i int) bool { return idx.times[i].value.After(r.to) })
+	}
+	if from >= to {
+		return nil
+	}
+	out := make([]uint32, 0, to-from)
+	for _, e := range idx.times[from:to] {
+		out = append(out, e.tri)
+	}
+	return out
+}
@@ -1,7 +1,16 @@
 // Package triplestore provides APIs to manage, store and query triples, sources and RDFGraphs
 package triplestore
 
-// Triple consists of a subject, a predicate and a object
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// Triple consists of a subject, a predicate and a object.
+//
+// Triples are immutable: no method modifies them once built, so they can be
+// shared between sources, snapshots, decoders and goroutines. Objects are values,
+// modifying an object returned by Object() does not modify the triple.
 type Triple interface {
 	Subject() string
 	Predicate() string
@@ -43,29 +52,56 @@ func (t *triple) Predicate() string {
 	return t.pred
 }
 
+// makeTriple builds a triple with its key, computed once so that
+// the triple is never modified afterwards
+func makeTriple(sub string, isSubBnode bool, pred string, obj object) *triple {
+	t := &triple{sub: sub, isSubBnode: isSubBnode, pred: pred, obj: obj}
+	t.triKey = t.computeKey()
+	return t
+}
+
+// key returns the canonical form of the triple (see TripleKey)
 func (t *triple) key() string {
-	if t.triKey == "" {
-		var sub string
-		if t.isSubBnode {
-			sub = "_:" + t.sub
-		} else {
-			sub = "<" + t.sub + ">"
-		}
-		t.triKey = sub + "<" + t.pred + ">" + t.obj.key()
-		return t.triKey
+	if t.triKey == "" { // zero triple
+		return t.computeKey()
 	}
 	return t.triKey
 }
 
+func (t *triple) computeKey() string {
+	return subjectObject(t).key() + " <" + t.pred + "> " + t.obj.key()
+}
+
 func (t *triple) clone() *triple {
 	return &triple{
-		sub:    t.sub,
-		pred:   t.pred,
-		obj:    t.obj,
-		triKey: t.triKey,
+		sub:        t.sub,
+		pred:       t.pred,
+		isSubBnode: t.isSubBnode,
+		obj:        t.obj,
+		triKey:     t.triKey,
 	}
 }
 
+// TripleKey returns a canonical representation of the triple, usable as a map key:
+// its N-Triples serialization with datatypes as full IRIs (xsd:string being omitted),
+// escaped literal values and lowercased language tags.
+// Equal triples have the same key, as compared by Triples.Equal and Triples.Dedup.
+func TripleKey(t Triple) string {
+	return t.(*triple).key()
+}
+
+// TripleHash returns the 64-bit FNV-1a hash of the TripleKey
+func TripleHash(t Triple) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(TripleKey(t)))
+	return h.Sum64()
+}
+
+// CloneTriple returns a copy of the triple
+func CloneTriple(t Triple) Triple {
+	return t.(*triple).clone()
+}
+
 func (t *triple) Equal(other Triple) bool {
 	switch {
 	case t == nil:
@@ -99,12 +135,10 @@ func (o object) Bnode() (string, bool) {
 	return o.bnode, o.isBnode
 }
 
+// key returns the canonical N-Triples form of the object (see TripleKey)
 func (o object) key() string {
 	if o.isLit {
-		if o.lit.langtag != "" {
-			return "\"" + o.lit.val + "\"@" + o.lit.langtag
-		}
-		return "\"" + o.lit.val + "\"^^<" + string(o.lit.typ) + ">"
+		return o.lit.key()
 	}
 	if o.isBnode {
 		return "_:" + o.bnode
@@ -148,3 +182,19 @@ func (l literal) Value() string {
 func (l literal) Lang() string {
 	return l.langtag
 }
+
+// key returns the canonical N-Triples form of the literal: its escaped value
+// followed by its lowercased language tag or its datatype IRI, if not xsd:string
+func (l literal) key() string {
+	k := `"` + escapeNQuadsLiteral(l.val) + `"`
+	switch {
+	case l.langtag != "":
+		return k + "@" + strings.ToLower(l.langtag)
+	case l.typ == "" || l.typ == XsdString:
+		return k
+	}
+	if dt := l.typ.NTriplesNamespaced(); dt != XsdString.NTriplesNamespaced() {
+		return k + "^^<" + dt + ">"
+	}
+	return k
+}
@@ -1,6 +1,9 @@
 package triplestore
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -48,26 +51,26 @@ func TestTripleKey(t *testing.T) {
 		one *triple
 		exp string
 	}{
-		{one: SubjPred("", "").Resource(""), exp: "<><><>"},
-		{one: SubjPred("", "").StringLiteral(""), exp: "<><>\"\"^^<xsd:string>"},
-		{one: SubjPred("sub", "pred").Resource("Bonobo"), exp: "<sub><pred><Bonobo>"},
-		{one: SubjPred("su<b", "pr>ed").Resource("Bonobo"), exp: "<su<b><pr>ed><Bonobo>"},
-		{one: SubjPred("sub", "pred").StringLiteral("Bonobo"), exp: "<sub><pred>\"Bonobo\"^^<xsd:string>"},
-		{one: SubjPred("sub", "pred").BooleanLiteral(true), exp: "<sub><pred>\"true\"^^<xsd:boolean>"},
-		{one: SubjPred("sub", "pred").StringLiteral("true"), exp: "<sub><pred>\"true\"^^<xsd:string>"},
-		{one: SubjPred("sub", "pred").IntegerLiteral(42), exp: "<sub><pred>\"42\"^^<xsd:integer>"},
-		{one: SubjPred("sub", "pred").StringLiteral("42"), exp: "<sub><pred>\"42\"^^<xsd:string>"},
+		{one: SubjPred("", "").Resource(""), exp: "<> <> <>"},
+		{one: SubjPred("", "").StringLiteral(""), exp: "<> <> \"\""},
+		{one: SubjPred("sub", "pred").Resource("Bonobo"), exp: "<sub> <pred> <Bonobo>"},
+		{one: SubjPred("su<b", "pr>ed").Resource("Bonobo"), exp: "<su<b> <pr>ed> <Bonobo>"},
+		{one: SubjPred("sub", "pred").StringLiteral("Bonobo"), exp: "<sub> <pred> \"Bonobo\""},
+		{one: SubjPred("sub", "pred").BooleanLiteral(true), exp: "<sub> <pred> \"true\"^^<http://www.w3.org/2001/XMLSchema#boolean>"},
+		{one: SubjPred("sub", "pred").StringLiteral("true"), exp: "<sub> <pred> \"true\""},
+		{one: SubjPred("sub", "pred").IntegerLiteral(42), exp: "<sub> <pred> \"42\"^^<http://www.w3.org/2001/XMLSchema#integer>"},
+		{one: SubjPred("sub", "pred").StringLiteral("42"), exp: "<sub> <pred> \"42\""},
 
 		// bnodes
-		{one: BnodePred("", "").Resource(""), exp: "_:<><>"},
-		{one: BnodePred("", "").StringLiteral(""), exp: "_:<>\"\"^^<xsd:string>"},
-		{one: BnodePred("sub", "pred").Resource("Bonobo"), exp: "_:sub<pred><Bonobo>"},
+		{one: BnodePred("", "").Resource(""), exp: "_: <> <>"},
+		{one: BnodePred("", "").StringLiteral(""), exp: "_: <> \"\""},
+		{one: BnodePred("sub", "pred").Resource("Bonobo"), exp: "_:sub <pred> <Bonobo>"},
 
-		{one: SubjPred("", "").Bnode(""), exp: "<><>_:"},
-		{one: SubjPred("", "").Bnode("any"), exp: "<><>_:any"},
+		{one: SubjPred("", "").Bnode(""), exp: "<> <> _:"},
+		{one: SubjPred("", "").Bnode("any"), exp: "<> <> _:any"},
 
 		// langtag
-		{one: SubjPred("sub", "pred").StringLiteralWithLang("obj", "en"), exp: "<sub><pred>\"obj\"@en"},
+		{one: SubjPred("sub", "pred").StringLiteralWithLang("obj", "en"), exp: "<sub> <pred> \"obj\"@en"},
 	}
 	for i, tcase := range tcases {
 		if got, want := tcase.one.key(), tcase.exp; got != want {
@@ -75,3 +78,116 @@ func TestTripleKey(t *testing.T) {
 		}
 	}
 }
+
+func TestTripleKeyAndHash(t *testing.T) {
+	tcases := []struct {
+		tri      Triple
+		expected string
+	}{
+		{SubjPred("s", "p").Resource("o"), `<s> <p> <o>`},
+		{BnodePred("s", "p").Bnode("o"), `_:s <p> _:o`},
+		{SubjPred("s", "p").StringLiteral("a \"quoted\"\nline"), `<s> <p> "a \"quoted\"\nline"`},
+		{SubjPred("s", "p").StringLiteralWithLang("chat", "FR"), `<s> <p> "chat"@fr`},
+		{SubjPred("s", "p").IntegerLiteral(42), `<s> <p> "42"^^<http://www.w3.org/2001/XMLSchema#integer>`},
+	}
+	for i, tc := range tcases {
+		if got, want := TripleKey(tc.tri), tc.expected; got != want {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	same := [][2]Triple{
+		{SubjPred("s", "p").IntegerLiteral(42), SubjPred("s", "p").Object(object{isLit: true, lit: literal{typ: XsdType(XMLSchemaNamespace + "#integer"), val: "42"}})},
+		{SubjPred("s", "p").StringLiteralWithLang("chat", "FR"), SubjPred("s", "p").StringLiteralWithLang("chat", "fr")},
+		{SubjPred("s", "p").StringLiteral("42"), SubjPred("s", "p").Object(object{isLit: true, lit: literal{val: "42"}})},
+	}
+	for i, pair := range same {
+		if TripleKey(pair[0]) != TripleKey(pair[1]) || TripleHash(pair[0]) != TripleHash(pair[1]) {
+			t.Fatalf("case %d: expected same key and hash for %v", i+1, pair)
+		}
+		if !pair[0].Equal(pair[1]) {
+			t.Fatalf("case %d: expected %v to equal %v", i+1, pair[0], pair[1])
+		}
+		if got, want := len(Triples(pair[:]).Dedup()), 1; got != want {
+			t.Fatalf("case %d: got %d, want %d", i+1, got, want)
+		}
+	}
+
+	distinct := []Triple{
+		SubjPred("s", "p").StringLiteral("42"),
+		SubjPred("s", "p").IntegerLiteral(42),
+		SubjPred("s", "p").Resource("42"),
+		BnodePred("s", "p").Resource("42"),
+		SubjPred("s", "p").StringLiteralWithLang("42", "en"),
+	}
+	hashes := make(map[uint64]bool)
+	for _, tri := range distinct {
+		hashes[TripleHash(tri)] = true
+	}
+	if got, want := len(hashes), len(distinct); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func TestCloneTriple(t *testing.T) {
+	tris := []Triple{
+		SubjPred("s", "p").Resource("o"),
+		BnodePred("s", "p").Bnode("o"),
+		SubjPred("s", "p").StringLiteralWithLang("chat", "fr"),
+	}
+	for i, tri := range tris {
+		clone := CloneTriple(tri)
+		if !clone.Equal(tri) {
+			t.Fatalf("case %d: got %v, want %v", i+1, clone, tri)
+		}
+		if got, want := TripleKey(clone), TripleKey(tri); got != want {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+		if clone.(*triple) == tri.(*triple) {
+			t.Fatalf("case %d: expected a new triple", i+1)
+		}
+	}
+
+	src := NewSource()
+	src.Add(BnodePred("s", "p").Resource("o"))
+	if got, want := TripleKey(src.CopyTriples()[0]), "_:s <p> <o>"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	tri := SubjPred("s", "p").Resource("o")
+	if err := json.Unmarshal([]byte(`{"subject":{"type":"uri","value":"x"},"predicate":{"type":"uri","value":"p"},"object":{"type":"uri","value":"o"}}`), tri); err == nil {
+		t.Fatal("expected error when unmarshaling into an existing triple")
+	}
+	if got, want := tri.Subject(), "s"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestTriplesAreBuiltWithTheirKey(t *testing.T) {
+	var buff bytes.Buffer
+	if err := NewBinaryEncoder(&buff).Encode(SubjPred("s", "p").IntegerLiteral(42)); err != nil {
+		t.Fatal(err)
+	}
+	binary, err := NewBinaryDecoder(&buff).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nt, err := NewLenientNTDecoder(strings.NewReader("_:s <p> \"chat\"@FR .\n")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := UnmarshalTriple([]byte(`{"subject":{"type":"uri","value":"s"},"predicate":{"type":"uri","value":"p"},"object":{"type":"bnode","value":"o"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewSource()
+	src.Add(SubjPred("s", "p").Resource("o"))
+	tris := []Triple{SubjPred("s", "p").StringLiteral("a"), binary[0], nt[0], fromJSON, src.Snapshot().Triples()[0]}
+	for i, tri := range tris {
+		// computed once when built, triples being shared between goroutines
+		if got, want := tri.(*triple).triKey, tri.(*triple).computeKey(); got != want {
+			t.Fatalf("case %d: got %q, want %q", i+1, got, want)
+		}
+	}
+}
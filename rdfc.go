@@ -0,0 +1,333 @@
+package triplestore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CanonicalizeBlankNodes relabels the blank nodes of the triples with deterministic
+// labels (c14n0, c14n1, ...) following the RDF Dataset Canonicalization algorithm
+// (RDFC-1.0, formerly URDNA2015). Triples are considered in the default graph.
+// It returns the relabelled triples, in canonical order, and the issued labels by original label.
+func CanonicalizeBlankNodes(tris []Triple) ([]Triple, map[string]string) {
+	c := newCanonicalizer(tris)
+	labels := c.canonicalize()
+
+	out := make([]Triple, len(c.tris))
+	for i, t := range c.tris {
+		sub, obj := t.sub, t.obj
+		if t.isSubBnode {
+			sub = labels[t.sub]
+		}
+		if t.obj.isBnode {
+			obj = object{isBnode: true, bnode: labels[t.obj.bnode]}
+		}
+		out[i] = makeTriple(sub, t.isSubBnode, t.pred, obj)
+	}
+	sort.Slice(out, func(i, j int) bool { return nquad(out[i].(*triple), "", "") < nquad(out[j].(*triple), "", "") })
+	return out, labels
+}
+
+// CanonicalNQuads returns the canonical N-Quads serialization of the triples
+// (see CanonicalizeBlankNodes), suitable for hashing or signing.
+func CanonicalNQuads(tris []Triple) []byte {
+	canonical, _ := CanonicalizeBlankNodes(tris)
+	var buff bytes.Buffer
+	for _, t := range canonical {
+		buff.WriteString(nquad(t.(*triple), "", ""))
+	}
+	return buff.Bytes()
+}
+
+type identifierIssuer struct {
+	prefix  string
+	counter int
+	issued  map[string]string
+	order   []string
+}
+
+func newIdentifierIssuer(prefix string) *identifierIssuer {
+	return &identifierIssuer{prefix: prefix, issued: make(map[string]string)}
+}
+
+func (i *identifierIssuer) issue(id string) string {
+	if issued, ok := i.issued[id]; ok {
+		return issued
+	}
+	issued := fmt.Sprintf("%s%d", i.prefix, i.counter)
+	i.counter++
+	i.issued[id] = issued
+	i.order = append(i.order, id)
+	return issued
+}
+
+func (i *identifierIssuer) clone() *identifierIssuer {
+	c := &identifierIssuer{prefix: i.prefix, counter: i.counter, issued: make(map[string]string, len(i.issued))}
+	for k, v := range i.issued {
+		c.issued[k] = v
+	}
+	c.order = append(c.order, i.order...)
+	return c
+}
+
+type canonicalizer struct {
+	tris          []*triple
+	bnodeToQuads  map[string][]*triple
+	canonicalIssr *identifierIssuer
+}
+
+func newCanonicalizer(tris []Triple) *canonicalizer {
+	c := &canonicalizer{bnodeToQuads: make(map[string][]*triple), canonicalIssr: newIdentifierIssuer("c14n")}
+	seen := make(map[string]bool)
+	for _, t := range tris {
+		tri := t.(*triple)
+		if seen[tri.key()] {
+			continue
+		}
+		seen[tri.key()] = true
+		c.tris = append(c.tris, tri)
+		if tri.isSubBnode {
+			c.bnodeToQuads[tri.sub] = append(c.bnodeToQuads[tri.sub], tri)
+		}
+		if tri.obj.isBnode && !(tri.isSubBnode && tri.sub == tri.obj.bnode) {
+			c.bnodeToQuads[tri.obj.bnode] = append(c.bnodeToQuads[tri.obj.bnode], tri)
+		}
+	}
+	return c
+}
+
+func (c *canonicalizer) canonicalize() map[string]string {
+	hashToBnodes := make(map[string][]string)
+	for bn := range c.bnodeToQuads {
+		h := c.hashFirstDegreeQuads(bn)
+		hashToBnodes[h] = append(hashToBnodes[h], bn)
+	}
+
+	var hashes []string
+	for h := range hashToBnodes {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	var nonUnique []string
+	for _, h := range hashes {
+		if bnodes := hashToBnodes[h]; len(bnodes) == 1 {
+			c.canonicalIssr.issue(bnodes[0])
+		} else {
+			nonUnique = append(nonUnique, h)
+		}
+	}
+
+	for _, h := range nonUnique {
+		type result struct {
+			hash   string
+			issuer *identifierIssuer
+		}
+		var results []result
+		for _, bn := range hashToBnodes[h] {
+			if _, ok := c.canonicalIssr.issued[bn]; ok {
+				continue
+			}
+			tmp := newIdentifierIssuer("b")
+			tmp.issue(bn)
+			hash, issuer := c.hashNDegreeQuads(bn, tmp)
+			results = append(results, result{hash, issuer})
+		}
+		sort.SliceStable(results, func(i, j int) bool { return results[i].hash < results[j].hash })
+		for _, r := range results {
+			for _, bn := range r.issuer.order {
+				c.canonicalIssr.issue(bn)
+			}
+		}
+	}
+	return c.canonicalIssr.issued
+}
+
+func hashHex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *canonicalizer) hashFirstDegreeQuads(bn string) string {
+	var nquads []string
+	for _, t := range c.bnodeToQuads[bn] {
+		nquads = append(nquads, nquad(t, bn, ""))
+	}
+	sort.Strings(nquads)
+	return hashHex(strings.Join(nquads, ""))
+}
+
+func (c *canonicalizer) hashRelatedBlankNode(related string, t *triple, issuer *identifierIssuer, position string) string {
+	input := position
+	if position != "g" {
+		input += "<" + t.pred + ">"
+	}
+	if id, ok := c.canonicalIssr.issued[related]; ok {
+		input += "_:" + id
+	} else if id, ok := issuer.issued[related]; ok {
+		input += "_:" + id
+	} else {
+		input += c.hashFirstDegreeQuads(related)
+	}
+	return hashHex(input)
+}
+
+func (c *canonicalizer) hashNDegreeQuads(bn string, issuer *identifierIssuer) (string, *identifierIssuer) {
+	hashToRelated := make(map[string][]string)
+	for _, t := range c.bnodeToQuads[bn] {
+		if t.isSubBnode && t.sub != bn {
+			h := c.hashRelatedBlankNode(t.sub, t, issuer, "s")
+			hashToRelated[h] = append(hashToRelated[h], t.sub)
+		}
+		if t.obj.isBnode && t.obj.bnode != bn {
+			h := c.hashRelatedBlankNode(t.obj.bnode, t, issuer, "o")
+			hashToRelated[h] = append(hashToRelated[h], t.obj.bnode)
+		}
+	}
+
+	var relatedHashes []string
+	for h := range hashToRelated {
+		relatedHashes = append(relatedHashes, h)
+	}
+	sort.Strings(relatedHashes)
+
+	var data bytes.Buffer
+	for _, relatedHash := range relatedHashes {
+		data.WriteString(relatedHash)
+		var chosenPath string
+		var chosenIssuer *identifierIssuer
+
+		permute(hashToRelated[relatedHash], func(perm []string) {
+			issuerCopy := issuer.clone()
+			var path string
+			var recursion []string
+			longer := func() bool {
+				return chosenPath != "" && len(path) >= len(chosenPath) && path > chosenPath
+			}
+			for _, related := range perm {
+				if id, ok := c.canonicalIssr.issued[related]; ok {
+					path += "_:" + id
+				} else {
+					if _, ok := issuerCopy.issued[related]; !ok {
+						recursion = append(recursion, related)
+					}
+					path += "_:" + issuerCopy.issue(related)
+				}
+				if longer() {
+					return
+				}
+			}
+			for _, related := range recursion {
+				hash, resultIssuer := c.hashNDegreeQuads(related, issuerCopy)
+				path += "_:" + issuerCopy.issue(related)
+				path += "<" + hash + ">"
+				issuerCopy = resultIssuer
+				if longer() {
+					return
+				}
+			}
+			if chosenPath == "" || path < chosenPath {
+				chosenPath, chosenIssuer = path, issuerCopy
+			}
+		})
+
+		data.WriteString(chosenPath)
+		issuer = chosenIssuer
+	}
+	return hashHex(data.String()), issuer
+}
+
+// permute calls fn with every permutation of the list
+func permute(list []string, fn func([]string)) {
+	perm := make([]string, len(list))
+	copy(perm, list)
+	sort.Strings(perm)
+	var rec func(int)
+	rec = func(k int) {
+		if k == len(perm) {
+			fn(perm)
+			return
+		}
+		for i := k; i < len(perm); i++ {
+			perm[k], perm[i] = perm[i], perm[k]
+			rec(k + 1)
+			perm[k], perm[i] = perm[i], perm[k]
+		}
+	}
+	rec(0)
+}
+
+// escapeNQuadsLiteral escapes a literal value as required by canonical N-Quads
+func escapeNQuadsLiteral(s string) string {
+	var buff bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '\\':
+			buff.WriteString(`\\`)
+		case '"':
+			buff.WriteString(`\"`)
+		case '\n':
+			buff.WriteString(`\n`)
+		case '\r':
+			buff.WriteString(`\r`)
+		case '\t':
+			buff.WriteString(`\t`)
+		case '\b':
+			buff.WriteString(`\b`)
+		case '\f':
+			buff.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&buff, "\\u%04X", r)
+			} else {
+				buff.WriteRune(r)
+			}
+		}
+	}
+	return buff.String()
+}
+
+// nquad serializes the triple as a canonical N-Quads line. When reference
+// is set, the reference blank node is written _:a and other blank nodes _:z.
+func nquad(t *triple, reference, graph string) string {
+	bnode := func(id string) string {
+		switch {
+		case reference == "":
+			return "_:" + id
+		case id == reference:
+			return "_:a"
+		default:
+			return "_:z"
+		}
+	}
+
+	var buff bytes.Buffer
+	if t.isSubBnode {
+		buff.WriteString(bnode(t.sub))
+	} else {
+		buff.WriteString("<" + t.sub + ">")
+	}
+	buff.WriteString(" <" + t.pred + "> ")
+	switch {
+	case t.obj.isBnode:
+		buff.WriteString(bnode(t.obj.bnode))
+	case t.obj.isLit:
+		buff.WriteString(`"` + escapeNQuadsLiteral(t.obj.lit.val) + `"`)
+		if t.obj.lit.langtag != "" {
+			buff.WriteString("@" + t.obj.lit.langtag)
+		} else if dt := t.obj.lit.typ.NTriplesNamespaced(); dt != XsdString.NTriplesNamespaced() {
+			buff.WriteString("^^<" + dt + ">")
+		}
+	default:
+		buff.WriteString("<" + t.obj.resource + ">")
+	}
+	if graph != "" {
+		buff.WriteString(" <" + graph + ">")
+	}
+	buff.WriteString(" .\n")
+	return buff.String()
+}
@@ -0,0 +1,85 @@
+package triplestore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalNQuads(t *testing.T) {
+	tcases := []struct {
+		in, exp string
+	}{
+		{
+			in: `<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#r> _:e1 .
+_:e0 <http://example.com/#s> <http://example.com/#u> .
+_:e1 <http://example.com/#t> <http://example.com/#u> .
+`,
+			exp: `<http://example.com/#p> <http://example.com/#q> _:c14n0 .
+<http://example.com/#p> <http://example.com/#r> _:c14n1 .
+_:c14n0 <http://example.com/#s> <http://example.com/#u> .
+_:c14n1 <http://example.com/#t> <http://example.com/#u> .
+`,
+		},
+		{
+			in: `<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#q> _:e1 .
+_:e0 <http://example.com/#p> _:e2 .
+_:e1 <http://example.com/#p> _:e3 .
+_:e2 <http://example.com/#r> _:e3 .
+`,
+			exp: `<http://example.com/#p> <http://example.com/#q> _:c14n2 .
+<http://example.com/#p> <http://example.com/#q> _:c14n3 .
+_:c14n0 <http://example.com/#r> _:c14n1 .
+_:c14n2 <http://example.com/#p> _:c14n1 .
+_:c14n3 <http://example.com/#p> _:c14n0 .
+`,
+		},
+		{
+			in: `<http://example.com/#p> <http://example.com/#r> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.com/#p> <http://example.com/#q> "x"^^<http://example.org/dt> .
+`,
+			exp: `<http://example.com/#p> <http://example.com/#q> "x"^^<http://example.org/dt> .
+<http://example.com/#p> <http://example.com/#r> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+`,
+		},
+	}
+
+	for i, tc := range tcases {
+		tris, err := NewLenientNTDecoder(strings.NewReader(tc.in)).Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(CanonicalNQuads(tris)), tc.exp; got != want {
+			t.Fatalf("case %d: got\n%s\nwant\n%s", i, got, want)
+		}
+	}
+}
+
+func TestCanonicalizeBlankNodesIsLabelIndependent(t *testing.T) {
+	build := func(a, b, c string) []Triple {
+		return []Triple{
+			BnodePred(a, "next").Bnode(b),
+			BnodePred(b, "next").Bnode(c),
+			BnodePred(c, "next").Bnode(a),
+			BnodePred(a, "name").StringLiteral("line\nwith \"quotes\"\t"),
+			SubjPred("root", "has").Bnode(b),
+		}
+	}
+	first := CanonicalNQuads(build("x", "y", "z"))
+	second := CanonicalNQuads(build("z", "x", "y"))
+	if got, want := string(second), string(first); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(string(first), `"line\nwith \"quotes\"\t"`) {
+		t.Fatalf("literal not escaped in\n%s", first)
+	}
+
+	canonical, labels := CanonicalizeBlankNodes(build("x", "y", "z"))
+	if got, want := len(labels), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if !IsomorphicGraphs(canonical, build("x", "y", "z")) {
+		t.Fatal("canonical triples should be isomorphic to input")
+	}
+}
@@ -0,0 +1,262 @@
+package triplestore
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemoteOptions configures HTTP requests made to remote RDF sources
+type RemoteOptions struct {
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+	// Username and Password are sent with basic authentication when Username is set
+	Username, Password string
+	// Header is added to each request (ex: "Authorization: Bearer ...")
+	Header http.Header
+	// Retries is the number of retries on network errors, 5xx or 429 responses
+	Retries int
+	// RetryDelay is waited before the first retry and doubled for the next ones
+	RetryDelay time.Duration
+}
+
+type sparqlDecoder struct {
+	endpoint, query string
+	opts            RemoteOptions
+}
+
+// NewSPARQLDecoder returns a decoder running a CONSTRUCT or DESCRIBE query
+// against a remote SPARQL endpoint and decoding the resulting triples.
+// Options can be nil.
+func NewSPARQLDecoder(endpoint, query string, opts *RemoteOptions) Decoder {
+	dec := &sparqlDecoder{endpoint: endpoint, query: query}
+	if opts != nil {
+		dec.opts = *opts
+	}
+	return dec
+}
+
+func (d *sparqlDecoder) Decode() ([]Triple, error) {
+	form := url.Values{"query": {d.query}}.Encode()
+	body, contentType, err := d.opts.do(func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, d.endpoint, strings.NewReader(form))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", formMediaType)
+		req.Header.Set("Accept", ntriplesMediaType)
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sparql endpoint %s: %s", d.endpoint, err)
+	}
+
+	if contentType == "" {
+		contentType = ntriplesMediaType
+	}
+	dec, err := DecoderForContentType(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("sparql endpoint %s: %s", d.endpoint, err)
+	}
+	return dec.Decode()
+}
+
+// do sends the request built by newReq, retrying according to the options,
+// and returns the body and content type of a successful response
+func (o RemoteOptions) do(newReq func() (*http.Request, error)) ([]byte, string, error) {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	delay := o.RetryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, "", err
+		}
+		if o.Username != "" {
+			req.SetBasicAuth(o.Username, o.Password)
+		}
+		for k, vals := range o.Header {
+			for _, v := range vals {
+				req.Header.Add(k, v)
+			}
+		}
+
+		body, contentType, retryable, err := o.send(client, req)
+		if err == nil || !retryable || attempt >= o.Retries {
+			return body, contentType, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (o RemoteOptions) send(client *http.Client, req *http.Request) ([]byte, string, bool, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", true, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, "", retryable, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return body, resp.Header.Get("Content-Type"), false, nil
+}
+
+// Fetcher dereferences IRIs over HTTP ("follow your nose") and merges
+// the triples of the retrieved RDF documents into a source.
+// Documents are cached by IRI (fragments are ignored) and fetched once when requested
+// concurrently. Turtle is preferred among the accepted formats. It is safe for concurrent use.
+type Fetcher struct {
+	opts        RemoteOptions
+	concurrency int
+	delay       time.Duration
+
+	mu       sync.Mutex
+	cache    map[string][]Triple
+	pending  map[string]*fetchCall
+	nextSlot map[string]time.Time
+}
+
+// fetchCall is a document being fetched, done being closed once fetched
+type fetchCall struct {
+	done chan struct{}
+	tris []Triple
+	err  error
+}
+
+// fetchAccept prefers Turtle and accepts all the formats of DecoderForContentType
+var fetchAccept = func() string {
+	accepted := make([]string, len(decodedMediaTypes))
+	for i, mediatype := range decodedMediaTypes {
+		switch q := 10 - i; {
+		case q == 10:
+			accepted[i] = mediatype
+		case q > 0:
+			accepted[i] = fmt.Sprintf("%s;q=0.%d", mediatype, q)
+		default:
+			accepted[i] = mediatype + ";q=0.1"
+		}
+	}
+	return strings.Join(accepted, ", ")
+}()
+
+// NewFetcher returns a fetcher running at most concurrency requests at once
+// and waiting at least delay between two requests to the same host.
+// Options can be nil.
+func NewFetcher(opts *RemoteOptions, concurrency int, delay time.Duration) *Fetcher {
+	f := &Fetcher{
+		concurrency: concurrency,
+		delay:       delay,
+		cache:       make(map[string][]Triple),
+		pending:     make(map[string]*fetchCall),
+		nextSlot:    make(map[string]time.Time),
+	}
+	if opts != nil {
+		f.opts = *opts
+	}
+	if f.concurrency < 1 {
+		f.concurrency = 1
+	}
+	return f
+}
+
+// Fetch dereferences the given IRIs and adds the retrieved triples to the source.
+// All IRIs are fetched even if some fail, the first error being returned.
+func (f *Fetcher) Fetch(src Source, iris ...string) error {
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	sem := make(chan struct{}, f.concurrency)
+
+	for _, iri := range iris {
+		wg.Add(1)
+		go func(iri string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			tris, err := f.fetch(iri)
+			if err != nil {
+				errOnce.Do(func() { firstErr = fmt.Errorf("fetching %s: %s", iri, err) })
+				return
+			}
+			src.Add(tris...)
+		}(iri)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func (f *Fetcher) fetch(iri string) ([]Triple, error) {
+	u, err := url.Parse(iri)
+	if err != nil {
+		return nil, err
+	}
+	u.Fragment = ""
+	doc := u.String()
+
+	f.mu.Lock()
+	if tris, ok := f.cache[doc]; ok {
+		f.mu.Unlock()
+		return tris, nil
+	}
+	if call, ok := f.pending[doc]; ok {
+		f.mu.Unlock()
+		<-call.done
+		return call.tris, call.err
+	}
+	call := &fetchCall{done: make(chan struct{})}
+	f.pending[doc] = call
+	now := time.Now()
+	slot := f.nextSlot[u.Host]
+	if slot.Before(now) {
+		slot = now
+	}
+	f.nextSlot[u.Host] = slot.Add(f.delay)
+	f.mu.Unlock()
+
+	time.Sleep(slot.Sub(now))
+
+	call.tris, call.err = f.get(doc)
+	f.mu.Lock()
+	if call.err == nil {
+		f.cache[doc] = call.tris
+	}
+	delete(f.pending, doc)
+	f.mu.Unlock()
+	close(call.done)
+	return call.tris, call.err
+}
+
+func (f *Fetcher) get(doc string) ([]Triple, error) {
+	body, contentType, err := f.opts.do(func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, doc, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", fetchAccept)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	dec, err := DecoderForContentType(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, err
+	}
+	return dec.Decode()
+}
@@ -0,0 +1,137 @@
+package triplestore
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSPARQLDecoder(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if got, want := r.PostFormValue("query"), "CONSTRUCT WHERE { ?s ?p ?o }"; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		if got, want := r.Header.Get("Accept"), "application/n-triples"; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		w.Header().Set("Content-Type", "application/n-triples; charset=utf-8")
+		w.Write([]byte("<one> <two> \"three\" .\n"))
+	}))
+	defer srv.Close()
+
+	opts := &RemoteOptions{Username: "user", Password: "secret", Retries: 1}
+	tris, err := NewSPARQLDecoder(srv.URL, "CONSTRUCT WHERE { ?s ?p ?o }", opts).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(tris), (Triples{SubjPred("one", "two").StringLiteral("three")}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := calls, 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	calls = 0
+	_, err = NewSPARQLDecoder(srv.URL, "CONSTRUCT WHERE { ?s ?p ?o }", nil).Decode()
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+
+	opts.Username = ""
+	calls = 1
+	_, err = NewSPARQLDecoder(srv.URL, "CONSTRUCT WHERE { ?s ?p ?o }", opts).Decode()
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+	if got, want := calls, 2; got != want {
+		t.Fatalf("unauthorized should not be retried: got %d calls, want %d", got, want)
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	var accept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		accept = r.Header.Get("Accept")
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/alice":
+			w.Header().Set("Content-Type", "application/n-triples")
+			fmt.Fprintf(w, "<%s/alice> <knows> <%s/bob> .\n", "http://"+r.Host, "http://"+r.Host)
+		case "/bob":
+			w.Header().Set("Content-Type", "application/n-triples")
+			fmt.Fprintf(w, "<%s/bob> <name> \"Bob\" .\n", "http://"+r.Host)
+		case "/carol":
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/turtle")
+			fmt.Fprintf(w, "<%s/carol> <name> \"Carol\" ; <knows> <%s/alice> .\n", "http://"+r.Host, "http://"+r.Host)
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	src := NewSource()
+	f := NewFetcher(nil, 2, 10*time.Millisecond)
+	start := time.Now()
+	if err := f.Fetch(src, srv.URL+"/alice", srv.URL+"/bob#me"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("expected politeness delay between requests, took %s", elapsed)
+	}
+	if got, want := src.Snapshot().Count(), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	if err := f.Fetch(src, srv.URL+"/alice#other"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	aliceHits := hits["/alice"]
+	mu.Unlock()
+	if got, want := aliceHits, 1; got != want {
+		t.Fatalf("expected cached document: got %d hits, want %d", got, want)
+	}
+
+	// concurrent fetches of the same document share one request
+	if err := NewFetcher(nil, 3, 0).Fetch(src, srv.URL+"/carol", srv.URL+"/carol#a", srv.URL+"/carol#b"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	carolHits, carolAccept := hits["/carol"], accept
+	mu.Unlock()
+	if got, want := carolHits, 1; got != want {
+		t.Fatalf("expected one request: got %d hits, want %d", got, want)
+	}
+	if got, want := src.Snapshot().Count(), 4; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if !strings.HasPrefix(carolAccept, "text/turtle, application/n-triples;q=0.9, ") {
+		t.Fatalf("expected Turtle to be preferred, got %s", carolAccept)
+	}
+
+	err := f.Fetch(src, srv.URL+"/html", srv.URL+"/missing", srv.URL+"/bob")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,159 @@
+package triplestore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Rule is a forward-chaining rule: each solution of its body patterns
+// instantiates its head patterns as new triples.
+type Rule struct {
+	body, head []triplePattern
+	filters    []filter
+}
+
+// ParseRule parses a rule written as two SPARQL group graph patterns
+// separated by "=>", optionally preceded by PREFIX declarations:
+//
+//	PREFIX ex: <http://example.org/>
+//	{ ?a ex:owns ?b . ?b ex:owns ?c } => { ?a ex:owns ?c }
+//
+// Variables of the head must appear in the body. Only the body can have filters.
+func ParseRule(rule string) (*Rule, error) {
+	toks, err := lexSPARQL(rule)
+	if err != nil {
+		return nil, fmt.Errorf("rule: %s", err)
+	}
+	p := &sparqlParser{toks: toks}
+	r, err := p.parseRule()
+	if err != nil {
+		return nil, fmt.Errorf("rule: %s", err)
+	}
+	return r, nil
+}
+
+// MustParseRule is like ParseRule but panics if the rule cannot be parsed
+func MustParseRule(rule string) *Rule {
+	r, err := ParseRule(rule)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (p *sparqlParser) parseRule() (*Rule, error) {
+	p.q = &Query{prefixes: make(map[string]string), limit: -1}
+	if err := p.parsePrologue(); err != nil {
+		return nil, err
+	}
+
+	r := &Rule{}
+	if err := p.parseWhereClause(); err != nil {
+		return nil, err
+	}
+	r.body, p.q.patterns = p.q.patterns, nil
+	r.filters, p.q.filters = p.q.filters, nil
+	if err := p.expectPunct("=>"); err != nil {
+		return nil, err
+	}
+	if err := p.parseWhereClause(); err != nil {
+		return nil, err
+	}
+	r.head = p.q.patterns
+	if len(p.q.filters) > 0 {
+		return nil, errors.New("filters not allowed in head")
+	}
+
+	if t := p.peek(); t.typ != tokEOF {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	if len(r.body) == 0 || len(r.head) == 0 {
+		return nil, errors.New("empty body or head")
+	}
+
+	bound := make(map[string]bool)
+	for _, pattern := range r.body {
+		for _, t := range []term{pattern.sub, pattern.pred, pattern.obj} {
+			bound[t.variable] = true
+		}
+	}
+	for _, pattern := range r.head {
+		for _, t := range []term{pattern.sub, pattern.pred, pattern.obj} {
+			if t.isVar() && !bound[t.variable] {
+				return nil, fmt.Errorf("variable ?%s of head not bound in body", t.variable)
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *Rule) String() string {
+	var buff bytes.Buffer
+	for i, patterns := range [][]triplePattern{r.body, r.head} {
+		if i > 0 {
+			buff.WriteString(" => ")
+		}
+		buff.WriteString("{ ")
+		for j, p := range patterns {
+			if j > 0 {
+				buff.WriteString(" . ")
+			}
+			buff.WriteString(p.String())
+		}
+		if i == 0 {
+			for _, f := range r.filters {
+				buff.WriteString(" . ")
+				buff.WriteString(f.String())
+			}
+		}
+		buff.WriteString(" }")
+	}
+	return buff.String()
+}
+
+// ApplyRules materializes the triples derived by the rules from the graph,
+// applying them until no new triple is derived.
+// It returns only the derived triples, i.e. the ones not already in the graph.
+// Instantiated heads that are not valid triples (ex: literal subject) are ignored.
+func ApplyRules(g RDFGraph, rules ...*Rule) []Triple {
+	src := NewSource()
+	src.Add(g.Triples()...)
+
+	var derived []Triple
+	for {
+		snap := src.Snapshot()
+		var fresh []Triple
+		seen := make(map[string]bool)
+		for _, r := range rules {
+			for _, b := range solvePatterns(snap, planPatterns(r.body), r.filters...) {
+				for _, p := range r.head {
+					t, ok := instantiate(p, b)
+					if !ok {
+						continue
+					}
+					if k := t.key(); !seen[k] && !snap.Contains(t) {
+						seen[k] = true
+						fresh = append(fresh, t)
+					}
+				}
+			}
+		}
+		if len(fresh) == 0 {
+			return derived
+		}
+		src.Add(fresh...)
+		derived = append(derived, fresh...)
+	}
+}
+
+// instantiate builds the triple of a pattern given a binding of its variables
+func instantiate(p triplePattern, b Binding) (*triple, bool) {
+	sub, _ := resolveTerm(p.sub, b)
+	pred, _ := resolveTerm(p.pred, b)
+	obj, _ := resolveTerm(p.obj, b)
+	if sub.isLit || pred.isLit || pred.isBnode {
+		return nil, false
+	}
+	return newTriple(sub, pred.resource, obj), true
+}
@@ -0,0 +1,70 @@
+package triplestore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRuleErrors(t *testing.T) {
+	tcases := []struct {
+		rule, err string
+	}{
+		{rule: "{ ?a <owns> ?b }", err: "expected '=>'"},
+		{rule: "{ ?a <owns> ?b } => { ?a <owns> ?c }", err: "variable ?c of head not bound in body"},
+		{rule: "{ } => { <a> <owns> <b> }", err: "empty body or head"},
+		{rule: "{ ?a <owns> ?b } => { ?a <owns> ?b } .", err: "unexpected '.'"},
+		{rule: "{ ?a <owns> ?b } => { ?a <owns> ?b FILTER regex(?b, \"a\") }", err: "filters not allowed in head"},
+	}
+	for i, tc := range tcases {
+		_, err := ParseRule(tc.rule)
+		if err == nil {
+			t.Fatalf("case %d: expected error", i+1)
+		}
+		if got, want := err.Error(), tc.err; !strings.Contains(got, want) {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	s := NewSource()
+	s.Add(
+		SubjPred("http://ex.org/holding", "http://ex.org/owns").Resource("http://ex.org/company"),
+		SubjPred("http://ex.org/company", "http://ex.org/owns").Resource("http://ex.org/factory"),
+		SubjPred("http://ex.org/factory", "http://ex.org/owns").Resource("http://ex.org/machine"),
+		SubjPred("http://ex.org/machine", "http://ex.org/price").IntegerLiteral(100),
+	)
+
+	transitivity := MustParseRule(`PREFIX ex: <http://ex.org/>
+		{ ?a ex:owns ?b . ?b ex:owns ?c } => { ?a ex:owns ?c }`)
+	if got, want := transitivity.String(), "{ ?a <http://ex.org/owns> ?b . ?b <http://ex.org/owns> ?c } => { ?a <http://ex.org/owns> ?c }"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	asset := MustParseRule(`{ ?a <http://ex.org/owns> ?b . ?b <http://ex.org/price> ?p } => { ?b a <http://ex.org/Asset> . ?p <http://ex.org/of> ?b }`)
+
+	derived := ApplyRules(s.Snapshot(), transitivity, asset)
+
+	exp := Triples{
+		SubjPred("http://ex.org/holding", "http://ex.org/owns").Resource("http://ex.org/factory"),
+		SubjPred("http://ex.org/holding", "http://ex.org/owns").Resource("http://ex.org/machine"),
+		SubjPred("http://ex.org/company", "http://ex.org/owns").Resource("http://ex.org/machine"),
+		SubjPred("http://ex.org/machine", "rdf:type").Resource("http://ex.org/Asset"),
+	}
+	if got, want := Triples(derived), exp; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	s.Add(derived...)
+	if got := ApplyRules(s.Snapshot(), transitivity, asset); len(got) != 0 {
+		t.Fatalf("expected no more derivations, got %v", got)
+	}
+
+	hundreds := MustParseRule(`{ ?b <http://ex.org/price> ?p FILTER regex(?p, "^[1-9]00$") } => { ?b a <http://ex.org/Hundreds> }`)
+	if got, want := hundreds.String(), `{ ?b <http://ex.org/price> ?p . FILTER regex(?p, "^[1-9]00$") } => { ?b <rdf:type> <http://ex.org/Hundreds> }`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	exp = Triples{SubjPred("http://ex.org/machine", "rdf:type").Resource("http://ex.org/Hundreds")}
+	if got, want := Triples(ApplyRules(s.Snapshot(), hundreds)), exp; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package triplestore
+
+const owlNamespace = "http://www.w3.org/2002/07/owl#"
+
+func isSameAs(pred string) bool {
+	return pred == "owl:sameAs" || pred == owlNamespace+"sameAs"
+}
+
+// SmushSameAs merges the resources connected by owl:sameAs (as prefixed name
+// or full IRI): each equivalence class is replaced in all triples by its
+// canonical IRI, i.e. the lowest one in lexicographic order.
+// The owl:sameAs triples are dropped as well as the resulting duplicates.
+// The returned mapping gives the canonical IRI of each replaced resource.
+func SmushSameAs(tris []Triple) ([]Triple, map[string]string) {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(s string) string {
+		p, ok := parent[s]
+		if !ok || p == s {
+			return s
+		}
+		root := find(p)
+		parent[s] = root
+		return root
+	}
+
+	for _, t := range tris {
+		tri := t.(*triple)
+		if !isSameAs(tri.pred) || tri.isSubBnode || tri.obj.isLit || tri.obj.isBnode {
+			continue
+		}
+		a, b := find(tri.sub), find(tri.obj.resource)
+		if a == b {
+			continue
+		}
+		if b < a {
+			a, b = b, a
+		}
+		parent[a] = a
+		parent[b] = a
+	}
+
+	mapping := make(map[string]string)
+	for s := range parent {
+		if root := find(s); root != s {
+			mapping[s] = root
+		}
+	}
+	canonical := func(s string) string {
+		if c, ok := mapping[s]; ok {
+			return c
+		}
+		return s
+	}
+
+	var out []Triple
+	seen := make(map[string]bool)
+	for _, t := range tris {
+		tri := t.(*triple)
+		if isSameAs(tri.pred) && !tri.isSubBnode && !tri.obj.isLit && !tri.obj.isBnode {
+			continue
+		}
+		sub, obj := tri.sub, tri.obj
+		if !tri.isSubBnode {
+			sub = canonical(tri.sub)
+		}
+		if !tri.obj.isLit && !tri.obj.isBnode {
+			obj = object{resource: canonical(tri.obj.resource)}
+		}
+		smushed := makeTriple(sub, tri.isSubBnode, canonical(tri.pred), obj)
+		if k := smushed.key(); !seen[k] {
+			seen[k] = true
+			out = append(out, smushed)
+		}
+	}
+	return out, mapping
+}
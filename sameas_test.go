@@ -0,0 +1,36 @@
+package triplestore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSmushSameAs(t *testing.T) {
+	tris := []Triple{
+		SubjPred("c", "owl:sameAs").Resource("b"),
+		SubjPred("b", owlNamespace+"sameAs").Resource("a"),
+		SubjPred("x", "owl:sameAs").Resource("y"),
+		SubjPred("a", "name").StringLiteral("John"),
+		SubjPred("c", "name").StringLiteral("John"),
+		SubjPred("b", "knows").Resource("y"),
+		SubjPred("y", "knows").Resource("c"),
+		BnodePred("b1", "about").Resource("c"),
+		SubjPred("z", "name").StringLiteral("Other"),
+	}
+
+	smushed, mapping := SmushSameAs(tris)
+
+	exp := Triples{
+		SubjPred("a", "name").StringLiteral("John"),
+		SubjPred("a", "knows").Resource("x"),
+		SubjPred("x", "knows").Resource("a"),
+		BnodePred("b1", "about").Resource("a"),
+		SubjPred("z", "name").StringLiteral("Other"),
+	}
+	if got, want := Triples(smushed), exp; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := mapping, map[string]string{"b": "a", "c": "a", "y": "x"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,349 @@
+package triplestore
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const shaclNamespace = "http://www.w3.org/ns/shacl#"
+
+// ValidationReport is the result of a SHACL validation
+type ValidationReport struct {
+	Conforms bool
+	Results  []ValidationResult
+}
+
+// ValidationResult describes a constraint violation of a focus node
+type ValidationResult struct {
+	FocusNode Object
+	// Path is the predicate of the property shape, empty for node shapes
+	Path string
+	// Value is the offending value, nil for cardinality constraints
+	Value Object
+	// SourceShape is the violated shape
+	SourceShape Object
+	// Constraint is the constraint component (ex: "sh:MinCountConstraintComponent")
+	Constraint string
+	Message    string
+}
+
+// ValidateSHACL validates the data graph against the shapes graph.
+//
+// Only a subset of SHACL core is supported:
+//   - targets: sh:targetClass, sh:targetNode, sh:targetSubjectsOf and sh:targetObjectsOf
+//   - property shapes (sh:property) with a predicate as sh:path
+//   - constraints: sh:minCount, sh:maxCount, sh:datatype, sh:class, sh:nodeKind and sh:pattern (with sh:flags)
+//
+// SHACL, RDF and RDFS terms are recognized either as prefixed names (ex: "sh:minCount") or as full IRIs.
+// An error is returned for malformed shapes.
+func ValidateSHACL(data, shapes RDFGraph) (*ValidationReport, error) {
+	v := &shaclValidator{data: data, shapes: shapes}
+	report := &ValidationReport{}
+	for _, shape := range v.nodeShapes() {
+		for _, focus := range v.focusNodes(shape) {
+			results, err := v.validateNode(shape, focus)
+			if err != nil {
+				return nil, err
+			}
+			report.Results = append(report.Results, results...)
+		}
+	}
+	report.Conforms = len(report.Results) == 0
+	return report, nil
+}
+
+// Triples returns the report as a sh:ValidationReport graph
+func (r *ValidationReport) Triples() []Triple {
+	report := "report"
+	out := []Triple{
+		BnodePred(report, "rdf:type").Resource("sh:ValidationReport"),
+		BnodePred(report, "sh:conforms").BooleanLiteral(r.Conforms),
+	}
+	for i, res := range r.Results {
+		id := fmt.Sprintf("result%d", i+1)
+		out = append(out,
+			BnodePred(report, "sh:result").Bnode(id),
+			BnodePred(id, "rdf:type").Resource("sh:ValidationResult"),
+			BnodePred(id, "sh:resultSeverity").Resource("sh:Violation"),
+			BnodePred(id, "sh:focusNode").Object(res.FocusNode),
+			BnodePred(id, "sh:sourceShape").Object(res.SourceShape),
+			BnodePred(id, "sh:sourceConstraintComponent").Resource(res.Constraint),
+			BnodePred(id, "sh:resultMessage").StringLiteral(res.Message),
+		)
+		if res.Path != "" {
+			out = append(out, BnodePred(id, "sh:resultPath").Resource(res.Path))
+		}
+		if res.Value != nil {
+			out = append(out, BnodePred(id, "sh:value").Object(res.Value))
+		}
+	}
+	return out
+}
+
+type shaclValidator struct {
+	data, shapes RDFGraph
+}
+
+var (
+	rdfTypePreds    = []string{"rdf:type", rdfNamespace + "type"}
+	subClassOfPreds = []string{"rdfs:subClassOf", rdfsNamespace + "subClassOf"}
+)
+
+func shaclTerm(local string) []string {
+	return []string{"sh:" + local, shaclNamespace + local}
+}
+
+func isSHACLTerm(o object, local string) bool {
+	return !o.isLit && !o.isBnode && (o.resource == "sh:"+local || o.resource == shaclNamespace+local)
+}
+
+// values returns the objects of the given node for any of the given predicates
+func values(g RDFGraph, node object, preds ...string) (out []object) {
+	for _, pred := range preds {
+		for _, t := range g.WithSubjPred(nodeID(node), pred) {
+			if tri := t.(*triple); tri.isSubBnode == node.isBnode {
+				out = append(out, tri.obj)
+			}
+		}
+	}
+	return
+}
+
+func (v *shaclValidator) nodeShapes() []object {
+	found := make(map[string]object)
+	for _, typ := range rdfTypePreds {
+		for _, t := range v.shapes.WithPredicate(typ) {
+			if tri := t.(*triple); isSHACLTerm(tri.obj, "NodeShape") {
+				s := subjectObject(tri)
+				found[s.key()] = s
+			}
+		}
+	}
+	for _, target := range []string{"targetClass", "targetNode", "targetSubjectsOf", "targetObjectsOf"} {
+		for _, pred := range shaclTerm(target) {
+			for _, t := range v.shapes.WithPredicate(pred) {
+				s := subjectObject(t.(*triple))
+				found[s.key()] = s
+			}
+		}
+	}
+	return sortedNodes(found)
+}
+
+func (v *shaclValidator) focusNodes(shape object) []object {
+	nodes := make(map[string]object)
+	for _, n := range values(v.shapes, shape, shaclTerm("targetNode")...) {
+		nodes[n.key()] = n
+	}
+	for _, class := range values(v.shapes, shape, shaclTerm("targetClass")...) {
+		for _, n := range v.instances(class) {
+			nodes[n.key()] = n
+		}
+	}
+	for _, p := range values(v.shapes, shape, shaclTerm("targetSubjectsOf")...) {
+		for _, t := range v.data.WithPredicate(p.resource) {
+			n := subjectObject(t.(*triple))
+			nodes[n.key()] = n
+		}
+	}
+	for _, p := range values(v.shapes, shape, shaclTerm("targetObjectsOf")...) {
+		for _, t := range v.data.WithPredicate(p.resource) {
+			n := t.(*triple).obj
+			nodes[n.key()] = n
+		}
+	}
+	return sortedNodes(nodes)
+}
+
+// instances returns the instances of the class and of its sub classes
+func (v *shaclValidator) instances(class object) []object {
+	var out []object
+	for _, c := range v.subClasses(class) {
+		for _, typ := range rdfTypePreds {
+			for _, t := range v.data.WithPredObj(typ, c) {
+				out = append(out, subjectObject(t.(*triple)))
+			}
+		}
+	}
+	return out
+}
+
+// subClasses returns the class and its sub classes, transitively
+func (v *shaclValidator) subClasses(class object) []object {
+	visited := map[string]bool{class.key(): true}
+	all := []object{class}
+	for i := 0; i < len(all); i++ {
+		for _, pred := range subClassOfPreds {
+			for _, t := range v.data.WithPredObj(pred, all[i]) {
+				sub := subjectObject(t.(*triple))
+				if !visited[sub.key()] {
+					visited[sub.key()] = true
+					all = append(all, sub)
+				}
+			}
+		}
+	}
+	return all
+}
+
+func (v *shaclValidator) isInstanceOf(node, class object) bool {
+	for _, c := range v.subClasses(class) {
+		for _, t := range v.data.WithSubjObj(nodeID(node), c) {
+			tri := t.(*triple)
+			if (tri.pred == rdfTypePreds[0] || tri.pred == rdfTypePreds[1]) && tri.isSubBnode == node.isBnode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *shaclValidator) validateNode(shape, focus object) ([]ValidationResult, error) {
+	results, err := v.checkConstraints(shape, focus, "", []object{focus})
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]object)
+	for _, prop := range values(v.shapes, shape, shaclTerm("property")...) {
+		props[prop.key()] = prop
+	}
+	for _, prop := range sortedNodes(props) {
+		paths := values(v.shapes, prop, shaclTerm("path")...)
+		if len(paths) != 1 || paths[0].isLit || paths[0].isBnode {
+			return nil, fmt.Errorf("shacl: property shape %s: sh:path must be a single predicate", prop.key())
+		}
+		path := paths[0].resource
+		vals := values(v.data, focus, path)
+		sort.Slice(vals, func(i, j int) bool { return vals[i].key() < vals[j].key() })
+		propResults, err := v.checkConstraints(prop, focus, path, vals)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, propResults...)
+	}
+	return results, nil
+}
+
+func (v *shaclValidator) checkConstraints(shape, focus object, path string, vals []object) ([]ValidationResult, error) {
+	var results []ValidationResult
+	violation := func(value Object, constraint, msg string, args ...interface{}) {
+		results = append(results, ValidationResult{
+			FocusNode:   focus,
+			Path:        path,
+			Value:       value,
+			SourceShape: shape,
+			Constraint:  "sh:" + constraint + "ConstraintComponent",
+			Message:     fmt.Sprintf(msg, args...),
+		})
+	}
+
+	for _, c := range values(v.shapes, shape, shaclTerm("minCount")...) {
+		min, err := shaclInt(c, "minCount")
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) < min {
+			violation(nil, "MinCount", "less than %d values", min)
+		}
+	}
+	for _, c := range values(v.shapes, shape, shaclTerm("maxCount")...) {
+		max, err := shaclInt(c, "maxCount")
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) > max {
+			violation(nil, "MaxCount", "more than %d values", max)
+		}
+	}
+	for _, c := range values(v.shapes, shape, shaclTerm("datatype")...) {
+		dt := XsdType(c.resource).NTriplesNamespaced()
+		for _, val := range vals {
+			if !val.isLit || val.lit.typ.NTriplesNamespaced() != dt {
+				violation(val, "Datatype", "value does not have datatype %s", c.resource)
+			}
+		}
+	}
+	for _, c := range values(v.shapes, shape, shaclTerm("class")...) {
+		for _, val := range vals {
+			if val.isLit || !v.isInstanceOf(val, c) {
+				violation(val, "Class", "value is not an instance of %s", c.resource)
+			}
+		}
+	}
+	for _, c := range values(v.shapes, shape, shaclTerm("nodeKind")...) {
+		for _, val := range vals {
+			ok, err := hasNodeKind(val, c)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				violation(val, "NodeKind", "value is not of node kind %s", c.resource)
+			}
+		}
+	}
+	for _, c := range values(v.shapes, shape, shaclTerm("pattern")...) {
+		expr := c.lit.val
+		for _, f := range values(v.shapes, shape, shaclTerm("flags")...) {
+			expr = "(?" + f.lit.val + ")" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("shacl: invalid sh:pattern: %s", err)
+		}
+		for _, val := range vals {
+			if val.isBnode || !re.MatchString(lexicalForm(val)) {
+				violation(val, "Pattern", "value does not match pattern '%s'", c.lit.val)
+			}
+		}
+	}
+	return results, nil
+}
+
+func shaclInt(o object, name string) (int, error) {
+	n, err := strconv.Atoi(o.lit.val)
+	if !o.isLit || err != nil || n < 0 {
+		return 0, fmt.Errorf("shacl: sh:%s must be a positive integer, got %s", name, o.key())
+	}
+	return n, nil
+}
+
+func hasNodeKind(val, kind object) (bool, error) {
+	isIRI := !val.isLit && !val.isBnode
+	switch {
+	case isSHACLTerm(kind, "IRI"):
+		return isIRI, nil
+	case isSHACLTerm(kind, "BlankNode"):
+		return val.isBnode, nil
+	case isSHACLTerm(kind, "Literal"):
+		return val.isLit, nil
+	case isSHACLTerm(kind, "BlankNodeOrIRI"):
+		return !val.isLit, nil
+	case isSHACLTerm(kind, "BlankNodeOrLiteral"):
+		return !isIRI, nil
+	case isSHACLTerm(kind, "IRIOrLiteral"):
+		return !val.isBnode, nil
+	}
+	return false, fmt.Errorf("shacl: unknown sh:nodeKind %s", kind.key())
+}
+
+func lexicalForm(o object) string {
+	if o.isLit {
+		return o.lit.val
+	}
+	return nodeID(o)
+}
+
+func sortedNodes(nodes map[string]object) []object {
+	keys := make([]string, 0, len(nodes))
+	for k := range nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]object, len(keys))
+	for i, k := range keys {
+		out[i] = nodes[k]
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package triplestore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSHACL(t *testing.T) {
+	shapes := NewSource()
+	shapes.Add(
+		SubjPred("PersonShape", "rdf:type").Resource("sh:NodeShape"),
+		SubjPred("PersonShape", "sh:targetClass").Resource("Person"),
+		SubjPred("PersonShape", "sh:nodeKind").Resource("sh:IRI"),
+		SubjPred("PersonShape", "sh:property").Bnode("name"),
+		BnodePred("name", "sh:path").Resource("name"),
+		BnodePred("name", "sh:minCount").IntegerLiteral(1),
+		BnodePred("name", "sh:maxCount").IntegerLiteral(1),
+		BnodePred("name", "sh:datatype").Resource("xsd:string"),
+		BnodePred("name", "sh:pattern").StringLiteral("^[a-z]"),
+		BnodePred("name", "sh:flags").StringLiteral("i"),
+		SubjPred("PersonShape", "sh:property").Bnode("knows"),
+		BnodePred("knows", "sh:path").Resource("knows"),
+		BnodePred("knows", "sh:class").Resource("Person"),
+		BnodePred("knows", "sh:nodeKind").Resource("sh:IRI"),
+	)
+
+	data := NewSource()
+	data.Add(
+		SubjPred("Employee", "rdfs:subClassOf").Resource("Person"),
+		SubjPred("alice", "rdf:type").Resource("Person"),
+		SubjPred("alice", "name").StringLiteral("Alice"),
+		SubjPred("alice", "knows").Resource("bob"),
+		SubjPred("bob", "rdf:type").Resource("Employee"),
+		SubjPred("bob", "name").StringLiteral("Bob"),
+		SubjPred("bob", "name").StringLiteral("Robert"),
+		SubjPred("bob", "knows").Resource("rex"),
+		SubjPred("bob", "knows").Bnode("someone"),
+		BnodePred("carl", "rdf:type").Resource("Person"),
+		BnodePred("carl", "name").IntegerLiteral(42),
+		BnodePred("carl", "name").StringLiteral("_carl"),
+	)
+
+	report, err := ValidateSHACL(data.Snapshot(), shapes.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Conforms {
+		t.Fatal("expected non conformance")
+	}
+
+	var got []string
+	for _, r := range report.Results {
+		val := "-"
+		if r.Value != nil {
+			val = r.Value.(object).key()
+		}
+		got = append(got, strings.Join([]string{r.FocusNode.(object).key(), r.Path, val, r.Constraint}, " "))
+	}
+	exp := []string{
+		"<bob> knows <rex> sh:ClassConstraintComponent",
+		"<bob> knows _:someone sh:ClassConstraintComponent",
+		"<bob> knows _:someone sh:NodeKindConstraintComponent",
+		"<bob> name - sh:MaxCountConstraintComponent",
+		"_:carl  _:carl sh:NodeKindConstraintComponent",
+		"_:carl name - sh:MaxCountConstraintComponent",
+		`_:carl name "42"^^<http://www.w3.org/2001/XMLSchema#integer> sh:DatatypeConstraintComponent`,
+		`_:carl name "42"^^<http://www.w3.org/2001/XMLSchema#integer> sh:PatternConstraintComponent`,
+		`_:carl name "_carl" sh:PatternConstraintComponent`,
+	}
+	if strings.Join(got, "\n") != strings.Join(exp, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(exp, "\n"))
+	}
+
+	reportGraph := NewSource()
+	reportGraph.Add(report.Triples()...)
+	snap := reportGraph.Snapshot()
+	if !snap.Contains(BnodePred("report", "sh:conforms").BooleanLiteral(false)) {
+		t.Fatal("expected sh:conforms false in report")
+	}
+	if got, want := len(snap.WithSubjPred("report", "sh:result")), len(exp); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	data.Remove(data.CopyTriples()...)
+	data.Add(SubjPred("alice", "rdf:type").Resource("Person"), SubjPred("alice", "name").StringLiteral("Alice"))
+	if report, err = ValidateSHACL(data.Snapshot(), shapes.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Conforms {
+		t.Fatalf("expected conformance, got %v", report.Results)
+	}
+
+	shapes.Add(BnodePred("name", "sh:pattern").StringLiteral("("))
+	if _, err := ValidateSHACL(data.Snapshot(), shapes.Snapshot()); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,271 @@
+package triplestore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	snapshotMagic   = "TSSNAP"
+	snapshotVersion = 1
+)
+
+const (
+	snapshotResource byte = iota
+	snapshotBnode
+	snapshotLiteral
+)
+
+// WriteTo dumps the snapshot in its indexed representation: the term dictionary
+// followed by the triples as term IDs, so that ReadSnapshotFrom rebuilds the
+// indexes without decoding nor interning terms of each triple.
+// Snapshots of sources implement io.WriterTo.
+func (g *graph) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	var buf []byte
+	flush := func() error {
+		_, err := bw.Write(buf)
+		buf = buf[:0]
+		return err
+	}
+
+	buf = append(buf, snapshotMagic...)
+	buf = append(buf, snapshotVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(g.terms)))
+	for _, term := range g.terms {
+		switch {
+		case term.isLit:
+			buf = append(buf, snapshotLiteral)
+			buf = appendSnapshotString(buf, term.lit.val)
+			buf = appendSnapshotString(buf, string(term.lit.typ))
+			buf = appendSnapshotString(buf, term.lit.langtag)
+		case term.isBnode:
+			buf = append(buf, snapshotBnode)
+			buf = appendSnapshotString(buf, term.bnode)
+		default:
+			buf = append(buf, snapshotResource)
+			buf = appendSnapshotString(buf, term.resource)
+		}
+		if err := flush(); err != nil {
+			return cw.n, err
+		}
+	}
+
+	// index sizes to allocate the indexes at once
+	for _, size := range []int{len(g.s), len(g.p), len(g.o), len(g.sp), len(g.so), len(g.po)} {
+		buf = binary.AppendUvarint(buf, uint64(size))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(g.tris)))
+	for _, it := range g.tris {
+		buf = binary.AppendUvarint(buf, uint64(it.s))
+		buf = binary.AppendUvarint(buf, uint64(it.p))
+		buf = binary.AppendUvarint(buf, uint64(it.o))
+		if err := flush(); err != nil {
+			return cw.n, err
+		}
+	}
+
+	if g.bloom == nil {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		buf = binary.AppendUvarint(buf, g.bloom.m)
+		buf = binary.AppendUvarint(buf, g.bloom.hashes)
+		for _, word := range g.bloom.bits {
+			buf = binary.LittleEndian.AppendUint64(buf, word)
+		}
+	}
+	if err := flush(); err != nil {
+		return cw.n, err
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func appendSnapshotString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// ReadSnapshotFrom restores a snapshot dumped with WriteTo
+func ReadSnapshotFrom(r io.Reader) (RDFGraph, error) {
+	sr := &snapshotReader{r: bufio.NewReader(r)}
+	g, err := sr.read()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %s", err)
+	}
+	return g, nil
+}
+
+type snapshotReader struct {
+	r *bufio.Reader
+}
+
+func (sr *snapshotReader) read() (*graph, error) {
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(sr.r, header); err != nil {
+		return nil, fmt.Errorf("header: %s", err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("not a snapshot")
+	}
+	if v := header[len(snapshotMagic)]; v != snapshotVersion {
+		return nil, fmt.Errorf("unsupported version %d", v)
+	}
+
+	termCount, err := sr.readCount()
+	if err != nil {
+		return nil, fmt.Errorf("terms: %s", err)
+	}
+	g := &graph{ids: make(map[string]termID, prealloc(termCount)), terms: make([]object, 0, prealloc(termCount))}
+	for i := 0; i < termCount; i++ {
+		term, err := sr.readTerm()
+		if err != nil {
+			return nil, fmt.Errorf("term %d: %s", i, err)
+		}
+		g.terms = append(g.terms, term)
+		g.ids[term.key()] = termID(i)
+	}
+
+	var sizes [6]int
+	for i := range sizes {
+		if sizes[i], err = sr.readCount(); err != nil {
+			return nil, fmt.Errorf("index sizes: %s", err)
+		}
+		sizes[i] = prealloc(sizes[i])
+	}
+	g.s, g.p, g.o = make(map[termID][]uint32, sizes[0]), make(map[termID][]uint32, sizes[1]), make(map[termID][]uint32, sizes[2])
+	g.sp, g.so, g.po = make(map[uint64][]uint32, sizes[3]), make(map[uint64][]uint32, sizes[4]), make(map[uint64][]uint32, sizes[5])
+
+	triCount, err := sr.readCount()
+	if err != nil {
+		return nil, fmt.Errorf("triples: %s", err)
+	}
+	g.tris = make([]idTriple, 0, prealloc(triCount))
+	g.triples = make([]Triple, 0, prealloc(triCount))
+	g.spo = make(map[idTriple]uint32, prealloc(triCount))
+	for i := 0; i < triCount; i++ {
+		var ids [3]termID
+		for j := range ids {
+			id, err := binary.ReadUvarint(sr.r)
+			if err != nil {
+				return nil, fmt.Errorf("triple %d: %s", i, err)
+			}
+			if id >= uint64(len(g.terms)) {
+				return nil, fmt.Errorf("triple %d: unknown term %d", i, id)
+			}
+			ids[j] = termID(id)
+		}
+		it := idTriple{s: ids[0], p: ids[1], o: ids[2]}
+		if _, ok := g.spo[it]; ok {
+			return nil, fmt.Errorf("triple %d: duplicate", i)
+		}
+		g.index(it, nil)
+	}
+
+	hasBloom, err := sr.r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("bloom filter: %s", err)
+	}
+	if hasBloom == 1 {
+		if g.bloom, err = sr.readBloom(); err != nil {
+			return nil, fmt.Errorf("bloom filter: %s", err)
+		}
+	}
+	return g, nil
+}
+
+// maxSnapshotPrealloc bounds what is allocated upfront from counts read in the
+// input, so that corrupted input does not exhaust memory before failing
+const maxSnapshotPrealloc = 1 << 24
+
+func (sr *snapshotReader) readCount() (int, error) {
+	n, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		return 0, err
+	}
+	if n > 1<<32 {
+		return 0, fmt.Errorf("invalid count %d", n)
+	}
+	return int(n), nil
+}
+
+func prealloc(n int) int {
+	if n > maxSnapshotPrealloc {
+		return maxSnapshotPrealloc
+	}
+	return n
+}
+
+func (sr *snapshotReader) readString() (string, error) {
+	n, err := sr.readCount()
+	if err != nil {
+		return "", err
+	}
+	if n <= sr.r.Size() {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(sr.r, b); err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, sr.r, int64(n)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (sr *snapshotReader) readTerm() (object, error) {
+	kind, err := sr.r.ReadByte()
+	if err != nil {
+		return object{}, err
+	}
+	switch kind {
+	case snapshotResource:
+		res, err := sr.readString()
+		return object{resource: res}, err
+	case snapshotBnode:
+		bnode, err := sr.readString()
+		return object{isBnode: true, bnode: bnode}, err
+	case snapshotLiteral:
+		var lit literal
+		var typ string
+		for _, s := range []*string{&lit.val, &typ, &lit.langtag} {
+			if *s, err = sr.readString(); err != nil {
+				return object{}, err
+			}
+		}
+		lit.typ = XsdType(typ)
+		return object{isLit: true, lit: lit}, nil
+	}
+	return object{}, fmt.Errorf("unknown kind %d", kind)
+}
+
+func (sr *snapshotReader) readBloom() (*bloomFilter, error) {
+	m, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		return nil, err
+	}
+	if m == 0 || m > 64*maxSnapshotPrealloc || hashes == 0 {
+		return nil, fmt.Errorf("invalid size %d with %d hashes", m, hashes)
+	}
+	b := &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, hashes: hashes}
+	word := make([]byte, 8)
+	for i := range b.bits {
+		if _, err := io.ReadFull(sr.r, word); err != nil {
+			return nil, err
+		}
+		b.bits[i] = binary.LittleEndian.Uint64(word)
+	}
+	return b, nil
+}
@@ -0,0 +1,134 @@
+package triplestore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotWriteToAndRead(t *testing.T) {
+	for _, opts := range [][]SourceOption{nil, {WithBloomFilter(0.01)}} {
+		src := NewSource(opts...)
+		src.Add(
+			SubjPred("one", "two").Resource("three"),
+			SubjPred("one", "two").StringLiteral("four"),
+			SubjPred("one", "five").StringLiteralWithLang("six", "en"),
+			SubjPred("seven", "two").IntegerLiteral(8),
+			BnodePred("nine", "ten").Bnode("eleven"),
+			SubjPred("three", "two").Bnode("nine"),
+		)
+		snap := src.Snapshot()
+
+		var buf bytes.Buffer
+		n, err := snap.(io.WriterTo).WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := n, int64(buf.Len()); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+
+		restored, err := ReadSnapshotFrom(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := Triples(restored.Triples()), Triples(snap.Triples()); !got.Equal(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		if got, want := StatsOf(restored), StatsOf(snap); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		if got, want := Triples(restored.WithSubjPred("one", "two")), Triples(snap.WithSubjPred("one", "two")); !got.Equal(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		if got, want := Triples(restored.WithObject(object{isBnode: true, bnode: "nine"})), Triples(snap.WithObject(object{isBnode: true, bnode: "nine"})); !got.Equal(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		if !restored.Contains(SubjPred("one", "five").StringLiteralWithLang("six", "en")) {
+			t.Fatal("expected restored snapshot to contain triple")
+		}
+		if restored.Contains(SubjPred("one", "five").StringLiteral("six")) {
+			t.Fatal("expected restored snapshot not to contain triple")
+		}
+		if got, want := restored.(*graph).bloom != nil, len(opts) > 0; got != want {
+			t.Fatalf("bloom filter: got %t, want %t", got, want)
+		}
+	}
+}
+
+func TestReadSnapshotErrors(t *testing.T) {
+	var buf bytes.Buffer
+	src := NewSource()
+	src.Add(SubjPred("one", "two").Resource("three"))
+	src.Snapshot().(io.WriterTo).WriteTo(&buf)
+	valid := buf.String()
+
+	tcases := []struct {
+		in, err string
+	}{
+		{in: "", err: "header"},
+		{in: "NOTSNAP", err: "not a snapshot"},
+		{in: snapshotMagic + "\x02", err: "unsupported version"},
+		{in: valid[:len(valid)-3], err: "triple 0"},
+		{in: snapshotMagic + "\x01\x01\x07", err: "unknown kind"},
+		{in: snapshotMagic + "\x01\x00" + "\x00\x00\x00\x00\x00\x00" + "\x01\x00\x00\x00", err: "unknown term"},
+	}
+	for i, tc := range tcases {
+		_, err := ReadSnapshotFrom(strings.NewReader(tc.in))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %v, want error containing %q", i, err, tc.err)
+		}
+	}
+}
+
+// BenchmarkLoadSnapshot/reindex_ntriples    1   1183588495 ns/op   280299200 B/op   2505149 allocs/op
+// BenchmarkLoadSnapshot/read_snapshot       3    448995004 ns/op    76525056 B/op   1003024 allocs/op
+func BenchmarkLoadSnapshot(b *testing.B) {
+	src := NewSource()
+	for i := 0; i < 100000; i++ {
+		src.Add(
+			SubjPred(fmt.Sprintf("http://example.org/%d", i), "http://example.org/digit").IntegerLiteral(i%10),
+			SubjPred(fmt.Sprintf("http://example.org/%d", i), "http://example.org/next").Resource(fmt.Sprintf("http://example.org/%d", i+1)),
+		)
+	}
+	var nt, snap bytes.Buffer
+	NewNTriplesEncoder(&nt).Encode(src.CopyTriples()...)
+	src.Snapshot().(io.WriterTo).WriteTo(&snap)
+
+	b.Run("reindex ntriples", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tris, err := NewNTriplesDecoder(bytes.NewReader(nt.Bytes())).Decode()
+			if err != nil {
+				b.Fatal(err)
+			}
+			s := NewSource()
+			AddBatch(s, tris)
+			s.Snapshot()
+		}
+	})
+	b.Run("read snapshot", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := ReadSnapshotFrom(bytes.NewReader(snap.Bytes())); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestSnapshotDuringBuildSeesPriorWrites(t *testing.T) {
+	s := NewSource().(*source)
+	tri := SubjPred("me", "name").StringLiteral("donald")
+	s.Add(tri)
+
+	// a concurrent build collected the triples but has not stored its snapshot yet
+	s.collect()
+
+	if !s.Snapshot().Contains(tri) {
+		t.Fatalf("snapshot should contain %v written before", tri)
+	}
+	if s.isUpdated() {
+		t.Fatal("source should be up to date once snapshotted")
+	}
+}
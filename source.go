@@ -1,15 +1,24 @@
 package triplestore
 
 import (
+	"context"
 	"fmt"
+	"math/big"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // A source is a persistent yet mutable source or container of triples.
+//
+// Triples are added to the default graph of a source, or to one of its named
+// graphs (see NamedGraphs). A source is the union of its graphs: its snapshots
+// and copied triples include the triples of all graphs and removed triples are
+// removed from all graphs.
 type Source interface {
 	Add(...Triple)
 	Remove(...Triple)
@@ -30,6 +39,25 @@ type RDFGraph interface {
 	WithPredObj(p string, o Object) []Triple
 }
 
+// Stats describes the content of a RDFGraph
+type Stats struct {
+	Triples                       int
+	Subjects, Predicates, Objects int
+	// MemoryBytes approximates the memory used by the graph terms and indexes
+	MemoryBytes int
+}
+
+// SourceStats counts the triples of a source, without scanning them.
+// Triples in several graphs of the source are counted once per graph.
+type SourceStats struct {
+	Triples int
+	// Predicates counts the triples by predicate
+	Predicates map[string]int
+	// Types counts the instances by class, i.e. the rdf:type triples by object
+	// (ex: "foaf:Person", "_:class" for a blank node class)
+	Types map[string]int
+}
+
 type Triples []Triple
 
 func (ts Triples) Equal(others Triples) bool {
@@ -61,6 +89,41 @@ func (ts Triples) Map(fn func(Triple) string) (out []string) {
 	return
 }
 
+// Dedup returns the triples without duplicates, i.e. triples with the same
+// TripleKey (as compared by Equal), keeping the first occurrences in order
+func (ts Triples) Dedup() (out Triples) {
+	seen := make(map[string]struct{}, len(ts))
+	for _, t := range ts {
+		k := TripleKey(t)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		out = append(out, t)
+	}
+	return
+}
+
+// Filter returns the triples for which fn returns true
+func (ts Triples) Filter(fn func(Triple) bool) (out Triples) {
+	for _, t := range ts {
+		if fn(t) {
+			out = append(out, t)
+		}
+	}
+	return
+}
+
+// GroupBySubject returns the triples indexed by subject, keeping their order.
+// Blank node and IRI subjects with the same identifier end up in the same group.
+func (ts Triples) GroupBySubject() map[string]Triples {
+	groups := make(map[string]Triples)
+	for _, t := range ts {
+		groups[t.Subject()] = append(groups[t.Subject()], t)
+	}
+	return groups
+}
+
 func (ts Triples) String() string {
 	joined := strings.Join(ts.Map(
 		func(t Triple) string { return fmt.Sprint(t) },
@@ -69,31 +132,67 @@ func (ts Triples) String() string {
 }
 
 type source struct {
-	latestSnap atomic.Value
-	updated    uint32 // atomic
-	mu         sync.RWMutex
-	triples    map[string]Triple
+	// version is incremented on each update and snapVersion is the version
+	// of the latest snapshot (atomics, first for 64-bit alignment)
+	version     uint64
+	snapVersion uint64
+	latestSnap  atomic.Value
+	snapMu      sync.Mutex
+	mu          sync.RWMutex
+	triples     map[string]Triple
+
+	// graphs holds the named graphs of a root source, parent being set for named graphs
+	graphs map[string]*source
+	parent *source
+	name   string
+
+	// history, when set, records the changes of the source and of its named graphs
+	history *history
+	// limits, when set, caps the size of the graph
+	limits *sourceLimits
+	counts tripleCounts
+
+	bloomFalsePositiveRate float64
+	// rangePreds are the predicates with a range index in snapshots
+	rangePreds []string
+}
+
+// A SourceOption configures a source
+type SourceOption func(*source)
+
+// WithBloomFilter builds a bloom filter of the triples of each snapshot
+// so that Contains answers most negative lookups without touching the indexes.
+// The false positive rate (ex: 0.01) trades memory for fewer index lookups.
+func WithBloomFilter(falsePositiveRate float64) SourceOption {
+	return func(s *source) {
+		if falsePositiveRate > 0 && falsePositiveRate < 1 {
+			s.bloomFalsePositiveRate = falsePositiveRate
+		}
+	}
 }
 
 // A source is a persistent yet mutable source or container of triples
-func NewSource() Source {
+func NewSource(opts ...SourceOption) Source {
 	s := &source{
 		triples: make(map[string]Triple),
+		counts:  newTripleCounts(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	s.latestSnap.Store(newGraph(0))
 	return s
 }
 
 func (s *source) isUpdated() bool {
-	return atomic.LoadUint32(&s.updated) > 0
+	return atomic.LoadUint64(&s.version) != atomic.LoadUint64(&s.snapVersion)
 }
 
 func (s *source) update() {
-	atomic.StoreUint32(&s.updated, uint32(1))
-}
-
-func (s *source) reset() {
-	atomic.StoreUint32(&s.updated, uint32(0))
+	atomic.AddUint64(&s.version, 1)
+	if s.parent != nil {
+		s.parent.update()
+	}
 }
 
 func (s *source) Add(ts ...Triple) {
@@ -101,10 +200,115 @@ func (s *source) Add(ts ...Triple) {
 	defer s.mu.Unlock()
 	defer s.update()
 
+	change := graphChange{graph: s.name}
 	for _, t := range ts {
+		s.put(t.(*triple), &change)
+	}
+	s.history.record(change)
+}
+
+// put stores the triple, making room for it when the source has limits.
+// It returns false when the triple is rejected. The lock must be held.
+func (s *source) put(t *triple, change *graphChange) bool {
+	if _, ok := s.triples[t.key()]; ok {
+		return true
+	}
+	if s.limits != nil && !s.limits.makeRoom(s, t, change) {
+		return false
+	}
+	s.triples[t.key()] = t
+	s.counts.count(t, 1)
+	s.limits.added(t)
+	if s.history != nil {
+		change.added = append(change.added, t)
+	}
+	return true
+}
+
+// AddBatch adds many triples at once. Terms shared by the triples
+// (subjects, predicates, resources, ...) are interned so that the stored
+// triples share their strings, which matters when triples come from a decoder.
+func (s *source) AddBatch(ts []Triple) {
+	s.addBatch(ts)
+}
+
+// addBatch returns the number of rejected triples
+func (s *source) addBatch(ts []Triple) (rejected int) {
+	interned := make(map[string]string)
+	intern := func(str string) string {
+		if i, ok := interned[str]; ok {
+			return i
+		}
+		interned[str] = str
+		return str
+	}
+
+	batch := make([]*triple, len(ts))
+	for i, t := range ts {
 		tr := t.(*triple)
-		s.triples[tr.key()] = t
+		obj := tr.obj
+		obj.resource, obj.bnode = intern(obj.resource), intern(obj.bnode)
+		obj.lit.typ, obj.lit.langtag = XsdType(intern(string(obj.lit.typ))), intern(obj.lit.langtag)
+		batch[i] = makeTriple(intern(tr.sub), tr.isSubBnode, intern(tr.pred), obj)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.update()
+
+	if len(s.triples) < len(batch) {
+		grown := make(map[string]Triple, len(s.triples)+len(batch))
+		for k, t := range s.triples {
+			grown[k] = t
+		}
+		s.triples = grown
+	}
+	change := graphChange{graph: s.name}
+	for _, t := range batch {
+		if !s.put(t, &change) {
+			rejected++
+		}
 	}
+	s.history.record(change)
+	return
+}
+
+// loadBatchSize is the number of decoded triples added at once by LoadFrom
+const loadBatchSize = 1024
+
+// LoadFrom adds the triples of the stream decoder as they are decoded, in batches,
+// without holding all of them in memory. It stops at the first decoding error,
+// triples decoded so far being kept. It stops with ErrLimitReached when triples
+// are rejected by the limits of the source.
+func (s *source) LoadFrom(ctx context.Context, dec StreamDecoder) error {
+	ctx, cancel := context.WithCancel(ctx)
+	results := dec.StreamDecode(ctx)
+	defer func() {
+		cancel()
+		for range results {
+		}
+	}()
+
+	batch := make([]Triple, 0, loadBatchSize)
+	for res := range results {
+		if res.Err != nil {
+			if s.addBatch(batch) > 0 {
+				return ErrLimitReached
+			}
+			return res.Err
+		}
+		batch = append(batch, res.Tri)
+		if len(batch) == loadBatchSize {
+			if s.addBatch(batch) > 0 {
+				return ErrLimitReached
+			}
+			batch = batch[:0]
+		}
+	}
+	if s.addBatch(batch) > 0 {
+		return ErrLimitReached
+	}
+	return ctx.Err()
 }
 
 func (s *source) Remove(ts ...Triple) {
@@ -112,10 +316,58 @@ func (s *source) Remove(ts ...Triple) {
 	defer s.mu.Unlock()
 	defer s.update()
 
+	changes := []graphChange{{graph: s.name, removed: s.remove(ts)}}
+	for _, g := range s.graphs {
+		g.mu.Lock()
+		changes = append(changes, graphChange{graph: g.name, removed: g.remove(ts)})
+		g.update()
+		g.mu.Unlock()
+	}
+	s.history.record(changes...)
+}
+
+// Replace replaces the triples of the source by the given ones at once: readers
+// see either the previous triples or the new ones. Unlike Remove, the triples of
+// the named graphs of a root source are kept, only its default graph being replaced.
+func (s *source) Replace(ts ...Triple) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.update()
+
+	kept := make(map[string]bool, len(ts))
+	for _, t := range ts {
+		kept[t.(*triple).key()] = true
+	}
+	var stale []Triple
+	for k, t := range s.triples {
+		if !kept[k] {
+			stale = append(stale, t)
+		}
+	}
+	change := graphChange{graph: s.name, removed: s.remove(stale)}
+	for _, t := range ts {
+		s.put(t.(*triple), &change)
+	}
+	s.history.record(change)
+}
+
+// remove deletes the triples, returning the removed ones when history is recorded.
+// The lock must be held.
+func (s *source) remove(ts []Triple) (removed []*triple) {
 	for _, t := range ts {
 		tr := t.(*triple)
+		stored, ok := s.triples[tr.key()]
+		if !ok {
+			continue
+		}
 		delete(s.triples, tr.key())
+		s.counts.count(stored.(*triple), -1)
+		s.limits.removed(stored.(*triple))
+		if s.history != nil {
+			removed = append(removed, stored.(*triple))
+		}
 	}
+	return
 }
 
 func (s *source) CopyTriples() (out []Triple) {
@@ -124,97 +376,715 @@ func (s *source) CopyTriples() (out []Triple) {
 	for _, t := range s.triples {
 		out = append(out, t.(*triple).clone())
 	}
+	if len(s.graphs) == 0 {
+		return
+	}
+
+	seen := make(map[string]bool, len(s.triples))
+	for k := range s.triples {
+		seen[k] = true
+	}
+	for _, g := range s.graphs {
+		g.mu.RLock()
+		for k, t := range g.triples {
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, t.(*triple).clone())
+			}
+		}
+		g.mu.RUnlock()
+	}
 	return
 }
 
+// Snapshot returns the latest snapshot without locking when the source has not
+// been updated since. Otherwise a single caller builds the new snapshot, concurrent
+// callers waiting for it. Writers are only blocked while triples are collected,
+// not while they are indexed.
 func (s *source) Snapshot() RDFGraph {
 	if !s.isUpdated() {
 		return s.latestSnap.Load().(RDFGraph)
 	}
 
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+	if !s.isUpdated() { // built by a concurrent call
+		return s.latestSnap.Load().(RDFGraph)
+	}
+
+	tris, version := s.collect()
+	gph := s.newGraph(tris)
+	s.latestSnap.Store(gph)
+	// the snapshot is stored before being marked up to date so that
+	// the lock-free path never returns a snapshot missing a prior update
+	atomic.StoreUint64(&s.snapVersion, version)
+
+	return gph
+}
+
+func (s *source) newGraph(tris []*triple) *graph {
+	gph := newGraph(len(tris))
+	if s.bloomFalsePositiveRate > 0 {
+		gph.bloom = newBloomFilter(len(tris), s.bloomFalsePositiveRate)
+	}
+	for _, t := range tris {
+		gph.add(t)
+	}
+	for _, p := range s.rangePreds {
+		gph.indexRange(p)
+	}
+	return gph
+}
+
+// collect returns the triples of all graphs with the version they reflect
+func (s *source) collect() ([]*triple, uint64) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	gph := newGraph(len(s.triples))
+	// read before the triples so that concurrent updates are not missed
+	version := atomic.LoadUint64(&s.version)
+	count := len(s.triples)
+	for _, g := range s.graphs {
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+		count += len(g.triples)
+	}
 
-	for k, t := range s.triples {
-		objKey := t.Object().(object).key()
-		sub, pred := t.Subject(), t.Predicate()
+	tris := make([]*triple, 0, count)
+	for _, t := range s.triples {
+		tris = append(tris, t.(*triple))
+	}
+	for _, g := range s.graphs {
+		for _, t := range g.triples {
+			tris = append(tris, t.(*triple))
+		}
+	}
+	return tris, version
+}
 
-		gph.s[sub] = append(gph.s[sub], t)
-		gph.p[pred] = append(gph.p[pred], t)
-		gph.o[objKey] = append(gph.o[objKey], t)
+func (s *source) Graph(name string) Source {
+	if name == "" {
+		return s
+	}
+	if s.parent != nil {
+		return s.parent.Graph(name)
+	}
 
-		sp := sub + pred
-		gph.sp[sp] = append(gph.sp[sp], t)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.graphs[name]; ok {
+		return g
+	}
+	g := &source{triples: make(map[string]Triple), counts: newTripleCounts(), parent: s, name: name, history: s.history, limits: s.limits.clone(), bloomFalsePositiveRate: s.bloomFalsePositiveRate, rangePreds: s.rangePreds}
+	g.latestSnap.Store(newGraph(0))
+	if s.graphs == nil {
+		s.graphs = make(map[string]*source)
+	}
+	s.graphs[name] = g
+	return g
+}
 
-		so := sub + objKey
-		gph.so[so] = append(gph.so[so], t)
+func (s *source) GraphNames() []string {
+	if s.parent != nil {
+		return s.parent.GraphNames()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.graphs))
+	for name := range s.graphs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-		po := pred + objKey
-		gph.po[po] = append(gph.po[po], t)
+// DropGraph removes the named graph. Views of the dropped graph are detached
+// from the source: triples added to them are not seen by the source anymore.
+func (s *source) DropGraph(name string) {
+	if s.parent != nil {
+		s.parent.DropGraph(name)
+		return
+	}
 
-		gph.spo[k] = t
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.graphs[name]; ok {
+		delete(s.graphs, name)
+		s.update()
+		if s.history != nil {
+			// changes of detached views are not recorded
+			g.mu.Lock()
+			g.history = nil
+			removed := make([]*triple, 0, len(g.triples))
+			for _, t := range g.triples {
+				removed = append(removed, t.(*triple))
+			}
+			g.mu.Unlock()
+			s.history.record(graphChange{graph: name, removed: removed, dropped: true})
+		}
 	}
+}
 
-	s.latestSnap.Store(gph)
-	s.reset()
+// termID identifies a term (resource, blank node or literal) of a graph
+type termID uint32
 
-	return gph
+// idTriple is a triple stored as the IDs of its terms
+type idTriple struct {
+	s, p, o termID
 }
 
+// graph stores its terms once in a dictionary and its triples as ID tuples.
+// Indexes reference triples by position, the triples being materialized once
+// per snapshot (shared with the source when built from it) and never on read.
 type graph struct {
-	once       sync.Once
-	unique     []Triple
-	s, p, o    map[string][]Triple
-	sp, so, po map[string][]Triple
-	spo        map[string]Triple
+	ids   map[string]termID
+	terms []object
+
+	tris       []idTriple
+	triples    []Triple
+	s, p, o    map[termID][]uint32
+	sp, so, po map[uint64][]uint32
+	spo        map[idTriple]uint32
+
+	// bloom, when set, holds the keys of the triples
+	bloom *bloomFilter
+	// ranges holds the range indexes by predicate
+	ranges map[termID]*rangeIndex
 }
 
 func newGraph(cap int) *graph {
 	return &graph{
-		s:   make(map[string][]Triple, cap),
-		p:   make(map[string][]Triple, cap),
-		o:   make(map[string][]Triple, cap),
-		sp:  make(map[string][]Triple, cap),
-		so:  make(map[string][]Triple, cap),
-		po:  make(map[string][]Triple, cap),
-		spo: make(map[string]Triple, cap),
+		ids: make(map[string]termID, cap),
+		s:   make(map[termID][]uint32, cap),
+		p:   make(map[termID][]uint32),
+		o:   make(map[termID][]uint32, cap),
+		sp:  make(map[uint64][]uint32, cap),
+		so:  make(map[uint64][]uint32, cap),
+		po:  make(map[uint64][]uint32, cap),
+		spo: make(map[idTriple]uint32, cap),
+	}
+}
+
+func (g *graph) add(t *triple) {
+	it := idTriple{s: g.intern(subjectObject(t)), p: g.intern(object{resource: t.pred}), o: g.intern(t.obj)}
+	if _, ok := g.spo[it]; ok {
+		return
+	}
+	if g.bloom != nil {
+		g.bloom.add(t.key())
+	}
+	g.index(it, t)
+}
+
+// index appends the triple of interned terms and indexes it,
+// the triple being materialized from its terms when nil
+func (g *graph) index(it idTriple, t *triple) {
+	if t == nil {
+		sub := g.terms[it.s]
+		t = makeTriple(nodeID(sub), sub.isBnode, g.terms[it.p].resource, g.terms[it.o])
+	}
+	i := uint32(len(g.tris))
+	g.tris = append(g.tris, it)
+	g.triples = append(g.triples, t)
+	g.spo[it] = i
+
+	g.s[it.s] = append(g.s[it.s], i)
+	g.p[it.p] = append(g.p[it.p], i)
+	g.o[it.o] = append(g.o[it.o], i)
+	g.sp[pairID(it.s, it.p)] = append(g.sp[pairID(it.s, it.p)], i)
+	g.so[pairID(it.s, it.o)] = append(g.so[pairID(it.s, it.o)], i)
+	g.po[pairID(it.p, it.o)] = append(g.po[pairID(it.p, it.o)], i)
+}
+
+func (g *graph) intern(o object) termID {
+	k := o.key()
+	if id, ok := g.ids[k]; ok {
+		return id
+	}
+	id := termID(len(g.terms))
+	g.terms = append(g.terms, o)
+	g.ids[k] = id
+	return id
+}
+
+func pairID(a, b termID) uint64 {
+	return uint64(a)<<32 | uint64(b)
+}
+
+func (g *graph) lookup(o object) (termID, bool) {
+	id, ok := g.ids[o.key()]
+	return id, ok
+}
+
+// subjectIDs returns the IDs of the resource and of the blank node named s
+func (g *graph) subjectIDs(s string) (out []termID) {
+	for _, o := range []object{{resource: s}, {isBnode: true, bnode: s}} {
+		if id, ok := g.lookup(o); ok {
+			out = append(out, id)
+		}
 	}
+	return
+}
+
+func (g *graph) materialize(indexes ...[]uint32) []Triple {
+	var n int
+	for _, idx := range indexes {
+		n += len(idx)
+	}
+	if n == 0 {
+		return nil
+	}
+	out := make([]Triple, 0, n)
+	for _, idx := range indexes {
+		for _, i := range idx {
+			out = append(out, g.triples[i])
+		}
+	}
+	return out
 }
 
 func (g *graph) Contains(t Triple) bool {
-	_, ok := g.spo[t.(*triple).key()]
+	tri := t.(*triple)
+	if g.bloom != nil && !g.bloom.mayContain(tri.key()) {
+		return false
+	}
+	s, sOk := g.lookup(subjectObject(tri))
+	p, pOk := g.lookup(object{resource: tri.pred})
+	o, oOk := g.lookup(tri.obj)
+	if !sOk || !pOk || !oOk {
+		return false
+	}
+	_, ok := g.spo[idTriple{s: s, p: p, o: o}]
 	return ok
 }
+
 func (g *graph) Triples() []Triple {
-	g.once.Do(func() {
-		for _, t := range g.spo {
-			g.unique = append(g.unique, t)
-		}
-	})
-	return g.unique
+	return g.triples
 }
 
 func (g *graph) Count() int {
-	return len(g.spo)
+	return len(g.tris)
 }
 
 func (g *graph) WithSubject(s string) []Triple {
-	return g.s[s]
+	var indexes [][]uint32
+	for _, id := range g.subjectIDs(s) {
+		indexes = append(indexes, g.s[id])
+	}
+	return g.materialize(indexes...)
 }
 func (g *graph) WithPredicate(p string) []Triple {
-	return g.p[p]
+	id, ok := g.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	return g.materialize(g.p[id])
 }
 func (g *graph) WithObject(o Object) []Triple {
-	return g.o[o.(object).key()]
+	id, ok := g.lookup(o.(object))
+	if !ok {
+		return nil
+	}
+	return g.materialize(g.o[id])
 }
 func (g *graph) WithSubjObj(s string, o Object) []Triple {
-	return g.so[s+o.(object).key()]
+	oid, ok := g.lookup(o.(object))
+	if !ok {
+		return nil
+	}
+	var indexes [][]uint32
+	for _, id := range g.subjectIDs(s) {
+		indexes = append(indexes, g.so[pairID(id, oid)])
+	}
+	return g.materialize(indexes...)
 }
 func (g *graph) WithSubjPred(s, p string) []Triple {
-	return g.sp[s+p]
+	pid, ok := g.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	var indexes [][]uint32
+	for _, id := range g.subjectIDs(s) {
+		indexes = append(indexes, g.sp[pairID(id, pid)])
+	}
+	return g.materialize(indexes...)
 }
 func (g *graph) WithPredObj(p string, o Object) []Triple {
-	return g.po[p+o.(object).key()]
+	pid, pOk := g.lookup(object{resource: p})
+	oid, oOk := g.lookup(o.(object))
+	if !pOk || !oOk {
+		return nil
+	}
+	return g.materialize(g.po[pairID(pid, oid)])
+}
+
+// WithPredObjMatching matches each distinct object of the predicate only once
+func (g *graph) WithPredObjMatching(p string, re *regexp.Regexp) []Triple {
+	pid, ok := g.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	var out []Triple
+	matches := make(map[termID]bool)
+	for _, i := range g.p[pid] {
+		oid := g.tris[i].o
+		match, ok := matches[oid]
+		if !ok {
+			obj := g.terms[oid]
+			match = obj.isLit && re.MatchString(obj.lit.val)
+			matches[oid] = match
+		}
+		if match {
+			out = append(out, g.triples[i])
+		}
+	}
+	return out
+}
+
+// indexRange builds the range index of the predicate, if present
+func (g *graph) indexRange(p string) {
+	pid, ok := g.lookup(object{resource: p})
+	if !ok {
+		return
+	}
+	if g.ranges == nil {
+		g.ranges = make(map[termID]*rangeIndex)
+	}
+	g.ranges[pid] = newRangeIndex(g, pid)
+}
+
+// WithPredRange uses the range index of the predicate if any, returning
+// triples sorted by value. Otherwise each distinct object is parsed only once.
+func (g *graph) WithPredRange(p string, min, max Literal) []Triple {
+	pid, ok := g.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	r, ok := newNumericRange(min, max)
+	if !ok {
+		return nil
+	}
+	if idx, ok := g.ranges[pid]; ok {
+		return g.materialize(idx.numbersIn(r))
+	}
+
+	var out []Triple
+	values := make(map[termID]*big.Rat)
+	for _, i := range g.p[pid] {
+		oid := g.tris[i].o
+		v, ok := values[oid]
+		if !ok {
+			v, _ = numericValue(g.terms[oid])
+			values[oid] = v
+		}
+		if v != nil && r.contains(v) {
+			out = append(out, g.triples[i])
+		}
+	}
+	return out
+}
+
+// WithPredTimeRange uses the range index of the predicate if any, returning
+// triples sorted by instant. Otherwise each distinct object is parsed only once.
+func (g *graph) WithPredTimeRange(p string, from, to time.Time) []Triple {
+	pid, ok := g.lookup(object{resource: p})
+	if !ok {
+		return nil
+	}
+	r := timeRange{from: from, to: to}
+	if idx, ok := g.ranges[pid]; ok {
+		return g.materialize(idx.timesIn(r))
+	}
+
+	var out []Triple
+	matches := make(map[termID]bool)
+	for _, i := range g.p[pid] {
+		oid := g.tris[i].o
+		match, ok := matches[oid]
+		if !ok {
+			t, isTime := timeValue(g.terms[oid])
+			match = isTime && r.contains(t)
+			matches[oid] = match
+		}
+		if match {
+			out = append(out, g.triples[i])
+		}
+	}
+	return out
+}
+
+// CountWith returns the number of triples matching the given subject, predicate
+// and object, nil meaning any. It uses the indexes without materializing triples.
+func (g *graph) CountWith(s, p *string, o Object) int {
+	var pid, oid termID
+	if p != nil {
+		id, ok := g.lookup(object{resource: *p})
+		if !ok {
+			return 0
+		}
+		pid = id
+	}
+	if o != nil {
+		id, ok := g.lookup(o.(object))
+		if !ok {
+			return 0
+		}
+		oid = id
+	}
+
+	if s == nil {
+		switch {
+		case p != nil && o != nil:
+			return len(g.po[pairID(pid, oid)])
+		case p != nil:
+			return len(g.p[pid])
+		case o != nil:
+			return len(g.o[oid])
+		default:
+			return len(g.tris)
+		}
+	}
+
+	var n int
+	for _, sub := range [2]object{{resource: *s}, {isBnode: true, bnode: *s}} {
+		sid, ok := g.lookup(sub)
+		if !ok {
+			continue
+		}
+		switch {
+		case p != nil && o != nil:
+			if _, ok := g.spo[idTriple{s: sid, p: pid, o: oid}]; ok {
+				n++
+			}
+		case p != nil:
+			n += len(g.sp[pairID(sid, pid)])
+		case o != nil:
+			n += len(g.so[pairID(sid, oid)])
+		default:
+			n += len(g.s[sid])
+		}
+	}
+	return n
+}
+
+// Exists returns true if a triple matches the given subject, predicate
+// and object, nil meaning any
+func (g *graph) Exists(s, p *string, o Object) bool {
+	return g.CountWith(s, p, o) > 0
+}
+
+// approximate sizes used to estimate memory usage
+var (
+	objectSize   = int(reflect.TypeOf(object{}).Size())
+	idTripleSize = int(reflect.TypeOf(idTriple{}).Size())
+	tripleSize   = int(reflect.TypeOf(triple{}).Size())
+	bigRatSize   = int(reflect.TypeOf(big.Rat{}).Size())
+	timeSize     = int(reflect.TypeOf(time.Time{}).Size())
+)
+
+const (
+	stringHeaderSize = 16
+	interfaceSize    = 16
+	sliceHeaderSize  = 24
+	mapEntryOverhead = 8
+)
+
+func (g *graph) Stats() Stats {
+	st := Stats{Triples: len(g.tris), Subjects: len(g.s), Predicates: len(g.p), Objects: len(g.o)}
+
+	mem := 0
+	for k := range g.ids {
+		mem += stringHeaderSize + len(k) + 4 + mapEntryOverhead
+	}
+	for _, t := range g.terms {
+		mem += objectSize + len(t.resource) + len(t.bnode) + len(t.lit.val) + len(t.lit.langtag)
+	}
+	mem += len(g.tris) * idTripleSize
+	// materialized triples, their terms being shared with the dictionary
+	mem += len(g.triples) * (interfaceSize + tripleSize)
+	mem += len(g.spo) * (idTripleSize + 4 + mapEntryOverhead)
+	for _, idx := range []map[termID][]uint32{g.s, g.p, g.o} {
+		mem += len(idx) * (4 + sliceHeaderSize + mapEntryOverhead)
+	}
+	for _, idx := range []map[uint64][]uint32{g.sp, g.so, g.po} {
+		mem += len(idx) * (8 + sliceHeaderSize + mapEntryOverhead)
+	}
+	// each triple is referenced once by each of the 6 indexes
+	mem += len(g.tris) * 6 * 4
+	if g.bloom != nil {
+		mem += len(g.bloom.bits) * 8
+	}
+	for _, idx := range g.ranges {
+		mem += len(idx.numbers) * (16 + bigRatSize)
+		mem += len(idx.times) * (timeSize + 8)
+	}
+	st.MemoryBytes = mem
+	return st
+}
+
+// Subgraph returns the triples describing the given root node, following
+// resource and bnode objects up to the given depth (i.e. a concise bounded description).
+// A depth of 0 returns only the triples having root as subject. A negative depth means no limit.
+func (g *graph) Subgraph(root string, depth int) Triples {
+	return subgraph(g, root, depth)
+}
+
+func subgraph(g RDFGraph, root string, depth int) (out Triples) {
+	visited := map[string]bool{root: true}
+	current := []string{root}
+	for level := 0; len(current) > 0 && (depth < 0 || level <= depth); level++ {
+		var next []string
+		for _, node := range current {
+			for _, t := range g.WithSubject(node) {
+				out = append(out, t)
+				obj := t.Object()
+				if _, isLit := obj.Literal(); isLit {
+					continue
+				}
+				id, _ := obj.Resource()
+				if bnode, isBnode := obj.Bnode(); isBnode {
+					id = bnode
+				}
+				if !visited[id] {
+					visited[id] = true
+					next = append(next, id)
+				}
+			}
+		}
+		current = next
+	}
+	return
+}
+
+// GC removes the triples of orphaned blank nodes, i.e. blank nodes which are not
+// objects of a triple with a resource subject, directly or through other blank nodes.
+// Reachability is computed across all graphs of the source, from which
+// orphaned triples are removed. Named graphs collect their source.
+func (s *source) GC() int {
+	if s.parent != nil {
+		return s.parent.GC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	graphs := []*source{s}
+	for _, g := range s.graphs {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		graphs = append(graphs, g)
+	}
+
+	var tris []*triple
+	for _, g := range graphs {
+		for _, t := range g.triples {
+			tris = append(tris, t.(*triple))
+		}
+	}
+	reachable := reachableBnodes(tris)
+
+	var removed int
+	var changes []graphChange
+	for _, g := range graphs {
+		var garbage []Triple
+		for _, t := range g.triples {
+			if tr := t.(*triple); tr.isSubBnode && !reachable[tr.sub] {
+				garbage = append(garbage, tr)
+			}
+		}
+		if len(garbage) == 0 {
+			continue
+		}
+		removed += len(garbage)
+		changes = append(changes, graphChange{graph: g.name, removed: g.remove(garbage)})
+		g.update()
+	}
+	s.history.record(changes...)
+	return removed
+}
+
+// reachableBnodes returns the blank nodes which are objects of a triple with
+// a resource subject, directly or through other blank nodes
+func reachableBnodes(tris []*triple) map[string]bool {
+	reachable := make(map[string]bool)
+	var queue []string
+	mark := func(bnode string) {
+		if !reachable[bnode] {
+			reachable[bnode] = true
+			queue = append(queue, bnode)
+		}
+	}
+	bySubject := make(map[string][]*triple)
+	for _, t := range tris {
+		if t.isSubBnode {
+			bySubject[t.sub] = append(bySubject[t.sub], t)
+		} else if t.obj.isBnode {
+			mark(t.obj.bnode)
+		}
+	}
+	for len(queue) > 0 {
+		bnode := queue[0]
+		queue = queue[1:]
+		for _, t := range bySubject[bnode] {
+			if t.obj.isBnode {
+				mark(t.obj.bnode)
+			}
+		}
+	}
+	return reachable
+}
+
+// tripleCounts counts triples by predicate and rdf:type triples by class
+type tripleCounts struct {
+	triples    int
+	predicates map[string]int
+	types      map[string]int
+}
+
+func newTripleCounts() tripleCounts {
+	return tripleCounts{predicates: make(map[string]int), types: make(map[string]int)}
+}
+
+func (c *tripleCounts) count(t *triple, delta int) {
+	c.triples += delta
+	countKey(c.predicates, t.pred, delta)
+	if (t.pred == rdfTypePreds[0] || t.pred == rdfTypePreds[1]) && !t.obj.isLit {
+		class := t.obj.resource
+		if t.obj.isBnode {
+			class = "_:" + t.obj.bnode
+		}
+		countKey(c.types, class, delta)
+	}
+}
+
+func countKey(counts map[string]int, k string, delta int) {
+	if n := counts[k] + delta; n > 0 {
+		counts[k] = n
+	} else {
+		delete(counts, k)
+	}
+}
+
+func (c *tripleCounts) addTo(st *SourceStats) {
+	st.Triples += c.triples
+	for k, n := range c.predicates {
+		st.Predicates[k] += n
+	}
+	for k, n := range c.types {
+		st.Types[k] += n
+	}
+}
+
+// Stats sums the counts of the graph and of the named graphs of a root source
+func (s *source) Stats() SourceStats {
+	st := SourceStats{Predicates: make(map[string]int), Types: make(map[string]int)}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.counts.addTo(&st)
+	for _, g := range s.graphs {
+		g.mu.RLock()
+		g.counts.addTo(&st)
+		g.mu.RUnlock()
+	}
+	return st
 }
@@ -1,9 +1,17 @@
 package triplestore_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	tstore "github.com/wallix/triplestore"
 )
@@ -93,6 +101,161 @@ func TestQueries(t *testing.T) {
 	}
 }
 
+func TestWithPredObjMatching(t *testing.T) {
+	s := tstore.NewSource()
+	s.Add(
+		tstore.SubjPred("one", "name").StringLiteral("Alice"),
+		tstore.SubjPred("two", "name").StringLiteral("alicia"),
+		tstore.SubjPred("three", "name").StringLiteral("Bob"),
+		tstore.SubjPred("four", "name").Resource("Alice"),
+		tstore.SubjPred("five", "label").StringLiteral("Alice"),
+		tstore.SubjPred("six", "name").StringLiteral("Alice"),
+	)
+	g := s.Snapshot()
+
+	exp := []tstore.Triple{
+		tstore.SubjPred("one", "name").StringLiteral("Alice"),
+		tstore.SubjPred("two", "name").StringLiteral("alicia"),
+		tstore.SubjPred("six", "name").StringLiteral("Alice"),
+	}
+	if got, want := tstore.Triples(tstore.WithPredObjMatching(g, "name", regexp.MustCompile("(?i)^ali"))), exp; !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := tstore.WithPredObjMatching(g, "unknown", regexp.MustCompile("")); len(got) != 0 {
+		t.Fatalf("expected no triples, got %v", got)
+	}
+}
+
+func TestWithPredRange(t *testing.T) {
+	decimal, _ := tstore.DecimalLiteral("9.5")
+	tris := []tstore.Triple{
+		tstore.SubjPred("one", "price").IntegerLiteral(10),
+		tstore.SubjPred("two", "price").Object(decimal),
+		tstore.SubjPred("three", "price").Float64Literal(1e3),
+		tstore.SubjPred("four", "price").IntegerLiteral(-3),
+		tstore.SubjPred("five", "price").StringLiteral("11"),
+		tstore.SubjPred("six", "price").IntegerLiteral(10),
+		tstore.SubjPred("seven", "weight").IntegerLiteral(10),
+	}
+	lit := func(o tstore.Object) tstore.Literal {
+		l, _ := o.Literal()
+		return l
+	}
+
+	tcases := []struct {
+		min, max tstore.Literal
+		exp      []string
+	}{
+		{nil, nil, []string{"four", "two", "one", "six", "three"}},
+		{lit(tstore.IntegerLiteral(10)), nil, []string{"one", "six", "three"}},
+		{nil, lit(tstore.Float64Literal(9.5)), []string{"four", "two"}},
+		{lit(tstore.IntegerLiteral(9)), lit(tstore.IntegerLiteral(10)), []string{"two", "one", "six"}},
+		{lit(tstore.IntegerLiteral(11)), lit(tstore.IntegerLiteral(999)), nil},
+		{lit(tstore.IntegerLiteral(11)), lit(tstore.IntegerLiteral(10)), nil},
+		{lit(tstore.StringLiteral("1")), nil, nil},
+	}
+	for _, indexed := range []bool{false, true} {
+		var opts []tstore.SourceOption
+		if indexed {
+			opts = append(opts, tstore.WithRangeIndex("price"))
+		}
+		s := tstore.NewSource(opts...)
+		s.Add(tris...)
+		g := s.Snapshot()
+		for i, tc := range tcases {
+			var got []string
+			for _, tri := range tstore.WithPredRange(g, "price", tc.min, tc.max) {
+				got = append(got, tri.Subject())
+			}
+			rank := map[string]int{"four": 0, "two": 1, "one": 2, "six": 2, "three": 3}
+			for j := 1; indexed && j < len(got); j++ {
+				if rank[got[j-1]] > rank[got[j]] {
+					t.Fatalf("indexed case %d: expected triples sorted by value, got %v", i, got)
+				}
+			}
+			want := append([]string(nil), tc.exp...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("indexed %t, case %d: got %v, want %v", indexed, i, got, want)
+			}
+		}
+		if got := tstore.WithPredRange(g, "weight", nil, nil); len(got) != 1 {
+			t.Fatalf("indexed %t: expected one weight, got %v", indexed, got)
+		}
+	}
+}
+
+func TestWithPredTimeRange(t *testing.T) {
+	paris := time.FixedZone("Paris", 2*3600)
+	tris := []tstore.Triple{
+		tstore.SubjPred("one", "at").DateTimeLiteral(time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)),
+		tstore.SubjPred("two", "at").DateTimeLiteral(time.Date(2020, 1, 1, 11, 30, 0, 0, paris)),
+		tstore.SubjPred("three", "at").DateLiteral(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)),
+		tstore.SubjPred("four", "at").StringLiteral("2020-03-01T00:00:00Z"),
+		tstore.SubjPred("five", "at").IntegerLiteral(2020),
+	}
+	tcases := []struct {
+		from, to time.Time
+		exp      []string
+	}{
+		{exp: []string{"one", "three", "two"}},
+		{from: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC), exp: []string{"one", "three"}},
+		{to: time.Date(2020, 1, 1, 11, 30, 0, 0, paris), exp: []string{"two"}},
+		{from: time.Date(2020, 1, 1, 9, 30, 0, 0, time.UTC), to: time.Date(2020, 1, 1, 12, 0, 0, 0, paris), exp: []string{"one", "two"}},
+		{from: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), exp: nil},
+	}
+	for _, indexed := range []bool{false, true} {
+		var opts []tstore.SourceOption
+		if indexed {
+			opts = append(opts, tstore.WithRangeIndex("at"))
+		}
+		s := tstore.NewSource(opts...)
+		s.Add(tris...)
+		for _, g := range []tstore.RDFGraph{s.Snapshot(), tstore.NewColumnarGraph(s.Snapshot())} {
+			for i, tc := range tcases {
+				var got []string
+				for _, tri := range tstore.WithPredTimeRange(g, "at", tc.from, tc.to) {
+					got = append(got, tri.Subject())
+				}
+				sort.Strings(got)
+				if want := tc.exp; !reflect.DeepEqual(got, want) {
+					t.Fatalf("indexed %t, case %d: got %v, want %v", indexed, i, got, want)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkWithPredRange queries 1% of 100000 prices, by scanning or with a range index:
+//
+// BenchmarkWithPredRange/scan         	      15	  93305775 ns/op
+// BenchmarkWithPredRange/indexed      	   10000	    184581 ns/op
+func BenchmarkWithPredRange(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		var opts []tstore.SourceOption
+		name := "scan"
+		if indexed {
+			opts, name = append(opts, tstore.WithRangeIndex("price")), "indexed"
+		}
+		s := tstore.NewSource(opts...)
+		for i := 0; i < 100000; i++ {
+			s.Add(tstore.SubjPred(fmt.Sprint(i), "price").IntegerLiteral(i))
+		}
+		g := s.Snapshot()
+		min, _ := tstore.IntegerLiteral(5000).Literal()
+		max, _ := tstore.IntegerLiteral(5999).Literal()
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if got := len(tstore.WithPredRange(g, "price", min, max)); got != 1000 {
+					b.Fatalf("got %d", got)
+				}
+			}
+		})
+	}
+}
+
 func TestSource(t *testing.T) {
 	s := tstore.NewSource()
 	s.Add(
@@ -179,6 +342,28 @@ func TestStoreConcurrentAccess(t *testing.T) {
 	wg.Wait()
 }
 
+func TestSnapshotLookupsShareTriples(t *testing.T) {
+	s := tstore.NewSource()
+	for i := 0; i < 1000; i++ {
+		s.Add(tstore.SubjPred(fmt.Sprint(i%10), "p").IntegerLiteral(i))
+	}
+	s.Add(tstore.SubjPred("single", "p").IntegerLiteral(0))
+	snap := s.Snapshot()
+
+	// lookups allocate their result slice but no triples
+	one := testing.AllocsPerRun(50, func() { snap.WithSubjPred("single", "p") })
+	many := testing.AllocsPerRun(50, func() { snap.WithSubjPred("1", "p") })
+	if one != many {
+		t.Fatalf("got %v allocs for 1 triple and %v for 100, want the same", one, many)
+	}
+	if got := testing.AllocsPerRun(50, func() { snap.Triples() }); got != 0 {
+		t.Fatalf("got %v allocs, want 0", got)
+	}
+	if got, want := snap.WithPredicate("p")[0], snap.WithPredicate("p")[0]; got != want {
+		t.Fatalf("got %p, want same triple %p", got, want)
+	}
+}
+
 // BenchmarkSnapshotSource-4   	       1	7462513791 ns/op
 func BenchmarkSnapshotSource(b *testing.B) {
 	s := tstore.NewSource()
@@ -205,3 +390,492 @@ func BenchmarkSnapshotSource(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkSnapshotMemory measures the heap retained by a snapshot of 100k triples
+// sharing their predicates and objects, besides the one of its source:
+//
+// indexes of triples:        BenchmarkSnapshotMemory  5  58959768 retained-B/op
+// term dictionary:           BenchmarkSnapshotMemory  5  50802099 retained-B/op
+// shared triples:            BenchmarkSnapshotMemory  5  52675110 retained-B/op
+func BenchmarkSnapshotMemory(b *testing.B) {
+	var retained uint64
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s := newSharedTermsSource()
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		b.StartTimer()
+
+		snap := s.Snapshot()
+
+		b.StopTimer()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		retained += after.HeapAlloc - before.HeapAlloc
+		runtime.KeepAlive(s)
+		runtime.KeepAlive(snap)
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+}
+
+// BenchmarkSnapshotLookups queries the 100k triples of a snapshot by predicate,
+// lookups allocating their result slices but no triples:
+//
+// indexes of triples:        BenchmarkSnapshotLookups  5      3932 ns/op       84 B/op       10 allocs/op
+// triples materialized:      BenchmarkSnapshotLookups  5  18958428 ns/op 16038515 B/op   100021 allocs/op
+// shared triples:            BenchmarkSnapshotLookups  5    671412 ns/op  1638484 B/op       20 allocs/op
+func BenchmarkSnapshotLookups(b *testing.B) {
+	snap := newSharedTermsSource().Snapshot()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for p := 0; p < 10; p++ {
+			snap.WithPredicate(fmt.Sprint("p", p))
+		}
+	}
+}
+
+func newSharedTermsSource() tstore.Source {
+	s := tstore.NewSource()
+	for i := 0; i < 100000; i++ {
+		s.Add(tstore.SubjPred(fmt.Sprint(i), fmt.Sprint("p", i%10)).IntegerLiteral(i % 5000))
+	}
+	return s
+}
+
+// BenchmarkWritesUnderReads measures writes while readers continuously snapshot the source.
+// Snapshots being indexed outside of the source lock, writers do not wait for them anymore:
+//
+// before: BenchmarkWritesUnderReads-8        13    88585220 ns/op
+// after:  BenchmarkWritesUnderReads-8   1000000        2960 ns/op
+func BenchmarkWritesUnderReads(b *testing.B) {
+	s := tstore.NewSource()
+	for i := 0; i < 10000; i++ {
+		s.Add(tstore.SubjPred(fmt.Sprint(i), "digit").IntegerLiteral(i % 10))
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					s.Snapshot().WithSubjPred("42", "digit")
+				}
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Add(tstore.SubjPred("writer", "count").IntegerLiteral(i))
+	}
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+}
+
+func TestSubgraph(t *testing.T) {
+	s := tstore.NewSource()
+	s.Add(
+		tstore.SubjPred("me", "name").StringLiteral("jsmith"),
+		tstore.SubjPred("me", "knows").Resource("you"),
+		tstore.SubjPred("me", "address").Bnode("addr"),
+		tstore.BnodePred("addr", "city").StringLiteral("Paris"),
+		tstore.SubjPred("you", "name").StringLiteral("fdupond"),
+		tstore.SubjPred("you", "knows").Resource("other"),
+		tstore.SubjPred("you", "knows").Resource("me"),
+		tstore.SubjPred("other", "name").StringLiteral("jdoe"),
+		tstore.SubjPred("unrelated", "name").StringLiteral("nobody"),
+	)
+	g := s.Snapshot()
+
+	tcases := []struct {
+		depth int
+		exp   int
+	}{
+		{depth: 0, exp: 3},
+		{depth: 1, exp: 7},
+		{depth: 2, exp: 8},
+		{depth: 10, exp: 8},
+		{depth: -1, exp: 8},
+	}
+
+	for _, tc := range tcases {
+		if got, want := len(tstore.Subgraph(g, "me", tc.depth)), tc.exp; got != want {
+			t.Fatalf("depth %d: got %d, want %d", tc.depth, got, want)
+		}
+	}
+
+	if got, want := len(tstore.Subgraph(g, "none", -1)), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := g.(tstore.Subgrapher).Subgraph("me", 1), tstore.Subgraph(g, "me", 1); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAddBatch(t *testing.T) {
+	s := tstore.NewSource()
+	s.Add(tstore.SubjPred("one", "two").Resource("three"))
+
+	var batch []tstore.Triple
+	for i := 0; i < 10; i++ {
+		batch = append(batch,
+			tstore.SubjPred("one", "digit").IntegerLiteral(i),
+			tstore.SubjPred("one", "digit").IntegerLiteral(i),
+		)
+	}
+	batch = append(batch, tstore.BnodePred("b", "two").Bnode("c"))
+	tstore.AddBatch(s, batch)
+
+	snap := s.Snapshot()
+	if got, want := snap.Count(), 12; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	for _, tri := range batch {
+		if !snap.Contains(tri) {
+			t.Fatalf("expected %v in snapshot", tri)
+		}
+	}
+	if got, want := len(snap.WithPredicate("digit")), 10; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if !snap.Contains(tstore.SubjPred("one", "two").Resource("three")) {
+		t.Fatal("expected previous triple to be kept")
+	}
+}
+
+func TestSnapshotTermDictionary(t *testing.T) {
+	s := tstore.NewSource()
+	s.Add(
+		tstore.SubjPred("one", "two").Resource("one"),
+		tstore.BnodePred("one", "two").Bnode("one"),
+		tstore.SubjPred("two", "two").StringLiteral("one"),
+	)
+	snap := s.Snapshot()
+
+	if got, want := snap.Count(), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := len(snap.WithSubject("one")), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := len(snap.WithSubjPred("one", "two")), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := len(snap.WithObject(tstore.Resource("one"))), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := len(snap.WithPredObj("two", tstore.Resource("two"))), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := len(snap.WithSubjObj("two", tstore.StringLiteral("one"))), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if !snap.Contains(tstore.BnodePred("one", "two").Bnode("one")) {
+		t.Fatal("expected bnode triple in snapshot")
+	}
+	if snap.Contains(tstore.BnodePred("one", "two").Resource("one")) {
+		t.Fatal("unexpected triple in snapshot")
+	}
+	if snap.Contains(tstore.SubjPred("unknown", "two").Resource("one")) {
+		t.Fatal("unexpected triple in snapshot")
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	var buff bytes.Buffer
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&buff, "<s%d> <p> \"%d\"^^<http://www.w3.org/2001/XMLSchema#integer> .\n", i, i)
+	}
+
+	s := tstore.NewSource()
+	if err := tstore.LoadFrom(context.Background(), s, tstore.NewLenientNTStreamDecoder(&buff)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Snapshot().Count(), 2500; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	s = tstore.NewSource()
+	invalid := strings.NewReader("<one> <two> <three> .\n<one> <two> .\n<four> <five> <six> .\n")
+	if err := tstore.LoadFrom(context.Background(), s, tstore.NewLenientNTStreamDecoder(invalid)); err == nil {
+		t.Fatal("expected error")
+	}
+	if !s.Snapshot().Contains(tstore.SubjPred("one", "two").Resource("three")) {
+		t.Fatal("expected triples decoded before the error to be loaded")
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := tstore.NewSource()
+	if got, want := tstore.StatsOf(s.Snapshot()), (tstore.Stats{}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	s.Add(
+		tstore.SubjPred("one", "two").Resource("three"),
+		tstore.SubjPred("one", "two").Resource("four"),
+		tstore.SubjPred("one", "five").IntegerLiteral(42),
+		tstore.SubjPred("three", "two").Resource("four"),
+	)
+	stats := tstore.StatsOf(s.Snapshot())
+	if got, want := fmt.Sprint(stats.Triples, stats.Subjects, stats.Predicates, stats.Objects), "4 2 2 3"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if stats.MemoryBytes <= 0 {
+		t.Fatalf("got %d, want positive memory usage", stats.MemoryBytes)
+	}
+
+	s.Add(tstore.SubjPred("six", "two").StringLiteral("a long literal value to make the graph bigger"))
+	if got := tstore.StatsOf(s.Snapshot()).MemoryBytes; got <= stats.MemoryBytes {
+		t.Fatalf("got %d, want more than %d", got, stats.MemoryBytes)
+	}
+	if got, want := s.Snapshot().(tstore.StatsReporter).Stats(), tstore.StatsOf(s.Snapshot()); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCountWithAndExists(t *testing.T) {
+	s := tstore.NewSource()
+	s.Add(
+		tstore.SubjPred("one", "two").Resource("three"),
+		tstore.SubjPred("one", "two").Resource("four"),
+		tstore.SubjPred("one", "five").Resource("three"),
+		tstore.BnodePred("one", "two").Resource("three"),
+		tstore.SubjPred("six", "two").IntegerLiteral(42),
+	)
+	snap := s.Snapshot()
+
+	str := func(s string) *string { return &s }
+	tcases := []struct {
+		s, p *string
+		o    tstore.Object
+		exp  int
+	}{
+		{exp: 5},
+		{s: str("one"), exp: 4},
+		{p: str("two"), exp: 4},
+		{o: tstore.Resource("three"), exp: 3},
+		{s: str("one"), p: str("two"), exp: 3},
+		{s: str("one"), o: tstore.Resource("three"), exp: 3},
+		{p: str("two"), o: tstore.Resource("three"), exp: 2},
+		{s: str("one"), p: str("two"), o: tstore.Resource("three"), exp: 2},
+		{s: str("six"), p: str("two"), o: tstore.IntegerLiteral(42), exp: 1},
+		{s: str("six"), p: str("two"), o: tstore.IntegerLiteral(43), exp: 0},
+		{s: str("unknown"), exp: 0},
+		{p: str("unknown"), exp: 0},
+	}
+	for i, tc := range tcases {
+		if got, want := tstore.CountWith(snap, tc.s, tc.p, tc.o), tc.exp; got != want {
+			t.Fatalf("case %d: got %d, want %d", i+1, got, want)
+		}
+		if got, want := tstore.Exists(snap, tc.s, tc.p, tc.o), tc.exp > 0; got != want {
+			t.Fatalf("case %d: got %t, want %t", i+1, got, want)
+		}
+	}
+
+	subj, pred, obj := str("one"), str("two"), tstore.Resource("three")
+	if allocs := testing.AllocsPerRun(100, func() { tstore.CountWith(snap, subj, pred, obj) }); allocs > 0 {
+		t.Fatalf("got %v allocations, want none", allocs)
+	}
+}
+
+func TestTriplesUtilities(t *testing.T) {
+	tris := tstore.Triples{
+		tstore.SubjPred("one", "two").StringLiteral("three"),
+		tstore.SubjPred("four", "two").IntegerLiteral(42),
+		tstore.SubjPred("one", "two").StringLiteral("three"),
+		tstore.SubjPred("one", "five").Resource("four"),
+	}
+
+	deduped := tris.Dedup()
+	if got, want := len(deduped), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := deduped, (tstore.Triples{tris[0], tris[1], tris[3]}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// duplicates up to the notation of their datatype or the case of their language tag
+	notations := tstore.Triples{
+		tstore.SubjPred("one", "two").IntegerLiteral(42),
+		tstore.SubjPred("one", "two").StringLiteralWithLang("trois", "FR"),
+		tstore.SubjPred("one", "two").Object(tstore.TypedLiteral("42", "http://www.w3.org/2001/XMLSchema#integer")),
+		tstore.SubjPred("one", "two").StringLiteralWithLang("trois", "fr"),
+	}
+	if got, want := notations.Dedup(), notations[:2]; !got.Equal(want) || len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	filtered := tris.Filter(func(tri tstore.Triple) bool { return tri.Predicate() == "two" })
+	if got, want := len(filtered), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got := tris.Filter(func(tstore.Triple) bool { return false }); len(got) != 0 {
+		t.Fatalf("got %v, want none", got)
+	}
+
+	groups := deduped.GroupBySubject()
+	if got, want := len(groups), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := groups["one"], (tstore.Triples{tris[0], tris[3]}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := groups["four"], (tstore.Triples{tris[1]}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestNamedGraphs(t *testing.T) {
+	s := tstore.NewSource()
+	s.Add(tstore.SubjPred("default", "p").Resource("o"))
+	people := s.(tstore.NamedGraphs).Graph("people")
+	people.Add(tstore.SubjPred("me", "knows").Resource("you"), tstore.SubjPred("default", "p").Resource("o"))
+	s.(tstore.NamedGraphs).Graph("places").Add(tstore.SubjPred("paris", "in").Resource("france"))
+
+	if got, want := s.(tstore.NamedGraphs).Graph("people"), people; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := s.(tstore.NamedGraphs).Graph(""), s; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := strings.Join(people.(tstore.NamedGraphs).GraphNames(), ","), "people,places"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if got, want := s.Snapshot().Count(), 3; got != want {
+		t.Fatalf("union: got %d, want %d", got, want)
+	}
+	if got, want := len(s.CopyTriples()), 3; got != want {
+		t.Fatalf("union: got %d, want %d", got, want)
+	}
+	if got, want := people.Snapshot().Count(), 2; got != want {
+		t.Fatalf("people: got %d, want %d", got, want)
+	}
+	if got, want := s.(tstore.NamedGraphs).Graph("places").Snapshot().Contains(tstore.SubjPred("me", "knows").Resource("you")), false; got != want {
+		t.Fatalf("places: got %t, want %t", got, want)
+	}
+
+	// dropping and reloading a graph leaves the others untouched
+	s.(tstore.NamedGraphs).DropGraph("people")
+	if got, want := strings.Join(s.(tstore.NamedGraphs).GraphNames(), ","), "places"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := s.Snapshot().Count(), 2; got != want {
+		t.Fatalf("after drop: got %d, want %d", got, want)
+	}
+	s.(tstore.NamedGraphs).Graph("people").Add(tstore.SubjPred("me", "knows").Resource("them"))
+	if got, want := s.Snapshot().Count(), 3; got != want {
+		t.Fatalf("after reload: got %d, want %d", got, want)
+	}
+	if got, want := s.(tstore.NamedGraphs).Graph("people").Snapshot().Count(), 1; got != want {
+		t.Fatalf("after reload: got %d, want %d", got, want)
+	}
+
+	// removing from the source removes from all graphs
+	s.Remove(tstore.SubjPred("paris", "in").Resource("france"))
+	if got, want := s.(tstore.NamedGraphs).Graph("places").Snapshot().Count(), 0; got != want {
+		t.Fatalf("after remove: got %d, want %d", got, want)
+	}
+	if got, want := s.Snapshot().Count(), 2; got != want {
+		t.Fatalf("after remove: got %d, want %d", got, want)
+	}
+
+	// replacing the source only replaces its default graph
+	tstore.Replace(s, tstore.SubjPred("default", "p").Resource("replaced"))
+	want := tstore.Triples{tstore.SubjPred("default", "p").Resource("replaced"), tstore.SubjPred("me", "knows").Resource("them")}
+	if got := tstore.Triples(s.CopyTriples()); !got.Equal(want) {
+		t.Fatalf("after replace: got %v, want %v", got, want)
+	}
+	tstore.Replace(s)
+	if got, want := s.Snapshot().Count(), 1; got != want {
+		t.Fatalf("after replace: got %d, want %d", got, want)
+	}
+}
+
+func TestSourceStats(t *testing.T) {
+	s := tstore.NewSource()
+	s.Add(
+		tstore.SubjPredRes("me", "rdf:type", "foaf:Person"),
+		tstore.SubjPredRes("you", "http://www.w3.org/1999/02/22-rdf-syntax-ns#type", "foaf:Person"),
+		tstore.SubjPredRes("it", "rdf:type", "foaf:Thing"),
+		tstore.SubjPred("me", "name").StringLiteral("me"),
+		tstore.SubjPred("me", "name").StringLiteral("me"),
+	)
+	tstore.AddBatch(s, []tstore.Triple{tstore.SubjPred("you", "name").StringLiteral("you")})
+	s.(tstore.NamedGraphs).Graph("other").Add(tstore.SubjPred("it", "name").StringLiteral("it"), tstore.BnodePred("b", "rdf:type").Bnode("class"))
+	s.Remove(tstore.SubjPredRes("it", "rdf:type", "foaf:Thing"), tstore.SubjPred("nobody", "name").StringLiteral("nobody"))
+
+	st := tstore.SourceStatsOf(s)
+	if got, want := st.Triples, 6; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	expPreds := map[string]int{"rdf:type": 2, "http://www.w3.org/1999/02/22-rdf-syntax-ns#type": 1, "name": 3}
+	if got, want := fmt.Sprint(st.Predicates), fmt.Sprint(expPreds); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	expTypes := map[string]int{"foaf:Person": 2, "_:class": 1}
+	if got, want := fmt.Sprint(st.Types), fmt.Sprint(expTypes); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	s.(tstore.NamedGraphs).DropGraph("other")
+	if got, want := fmt.Sprint(tstore.SourceStatsOf(s).Types), fmt.Sprint(map[string]int{"foaf:Person": 2}); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, want := tstore.SourceStatsOf(s).Triples, s.Snapshot().Count(); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func TestGC(t *testing.T) {
+	s := tstore.NewSource()
+	kept := []tstore.Triple{
+		tstore.SubjPred("me", "address").Bnode("addr"),
+		tstore.BnodePred("addr", "city").StringLiteral("Paris"),
+		tstore.BnodePred("addr", "geo").Bnode("geo"),
+		tstore.BnodePred("geo", "lat").StringLiteral("48.85"),
+		tstore.SubjPred("me", "name").StringLiteral("me"),
+	}
+	s.Add(kept...)
+	s.Add(
+		// replaced description
+		tstore.BnodePred("oldaddr", "city").StringLiteral("Lyon"),
+		tstore.BnodePred("oldaddr", "geo").Bnode("oldgeo"),
+		tstore.BnodePred("oldgeo", "lat").StringLiteral("45.76"),
+		// cycle between orphans
+		tstore.BnodePred("a", "next").Bnode("b"),
+		tstore.BnodePred("b", "next").Bnode("a"),
+	)
+	// referenced from another graph
+	s.(tstore.NamedGraphs).Graph("other").Add(tstore.SubjPred("you", "address").Bnode("youraddr"))
+	s.Add(tstore.BnodePred("youraddr", "city").StringLiteral("Nice"))
+	s.(tstore.NamedGraphs).Graph("other").Add(tstore.BnodePred("orphan", "city").StringLiteral("Brest"))
+
+	if got, want := tstore.GC(s.(tstore.NamedGraphs).Graph("other")), 6; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	expected := append(kept,
+		tstore.SubjPred("you", "address").Bnode("youraddr"),
+		tstore.BnodePred("youraddr", "city").StringLiteral("Nice"),
+	)
+	if got, want := tstore.Triples(s.CopyTriples()), tstore.Triples(expected); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := s.Snapshot().Count(), len(expected); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := tstore.GC(s), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
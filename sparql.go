@@ -0,0 +1,706 @@
+package triplestore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query is a parsed SPARQL query. Only a subset of SPARQL is supported:
+//
+//	PREFIX declarations
+//	SELECT [DISTINCT] (?var ... | *) [WHERE] { basic graph pattern }
+//	ASK [WHERE] { basic graph pattern }
+//	LIMIT and OFFSET modifiers
+//
+// Basic graph patterns are triple patterns separated by '.',
+// with ';' and ',' abbreviations, 'a' for rdf:type and
+// literal shorthands (numbers, booleans). They can be constrained by
+// FILTER regex(?var, "pattern"[, "flags"]) on the lexical value of literals,
+// and by bracketted comparisons of a variable with a literal joined by '&&'
+// (ex: FILTER (?d >= "2020-01-01T00:00:00Z"^^xsd:dateTime && ?d < "2021-01-01"^^xsd:date)),
+// in the value space of the datatypes.
+//
+// Prefixed names with a declared prefix are expanded, others are kept
+// as is (ex: "rdf:type") to match triples built with prefixed names.
+type Query struct {
+	prefixes map[string]string
+	ask      bool
+	vars     []string
+	distinct bool
+	patterns []triplePattern
+	filters  []filter
+	limit    int
+	offset   int
+}
+
+type triplePattern struct {
+	sub, pred, obj term
+}
+
+// term of a triple pattern: either a variable or a constant object
+// (resource, bnode or literal)
+type term struct {
+	variable string
+	value    object
+}
+
+func (t term) isVar() bool {
+	return t.variable != ""
+}
+
+func (t term) String() string {
+	if t.isVar() {
+		return "?" + t.variable
+	}
+	return t.value.key()
+}
+
+func (p triplePattern) String() string {
+	return p.sub.String() + " " + p.pred.String() + " " + p.obj.String()
+}
+
+// ParseQuery parses a SPARQL query
+func ParseQuery(q string) (*Query, error) {
+	toks, err := lexSPARQL(q)
+	if err != nil {
+		return nil, fmt.Errorf("sparql: %s", err)
+	}
+	p := &sparqlParser{toks: toks}
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("sparql: %s", err)
+	}
+	return query, nil
+}
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIRI
+	tokPName
+	tokVar
+	tokString
+	tokLang
+	tokNumber
+	tokIdent
+	tokPunct
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("'%s' at offset %d", t.val, t.pos)
+}
+
+func lexSPARQL(q string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(q) {
+		r, size := utf8.DecodeRuneInString(q[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '#':
+			for i < len(q) && q[i] != '\n' {
+				i++
+			}
+		case r == '<':
+			if end := strings.IndexByte(q[i:], '>'); end > 0 && !strings.ContainsAny(q[i+1:i+end], " \t\n\r<\"{}|^`\\") {
+				toks = append(toks, token{typ: tokIRI, val: q[i+1 : i+end], pos: i})
+				i += end + 1
+				continue
+			}
+			n := 1
+			if strings.HasPrefix(q[i:], "<=") {
+				n = 2
+			}
+			toks = append(toks, token{typ: tokPunct, val: q[i : i+n], pos: i})
+			i += n
+		case r == '?' || r == '$':
+			j := i + 1
+			for j < len(q) && isNameChar(q[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid variable at offset %d", i)
+			}
+			toks = append(toks, token{typ: tokVar, val: q[i+1 : j], pos: i})
+			i = j
+		case r == '"' || r == '\'':
+			val, n, err := lexString(q[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%s at offset %d", err, i)
+			}
+			toks = append(toks, token{typ: tokString, val: val, pos: i})
+			i += n
+		case r == '@':
+			j := i + 1
+			for j < len(q) && (isNameChar(q[j]) || q[j] == '-') {
+				j++
+			}
+			toks = append(toks, token{typ: tokLang, val: q[i+1 : j], pos: i})
+			i = j
+		case r >= '0' && r <= '9' || ((r == '+' || r == '-' || r == '.') && i+1 < len(q) && q[i+1] >= '0' && q[i+1] <= '9' && !lastIsOperand(toks)):
+			j := i + 1
+			for j < len(q) && (q[j] >= '0' && q[j] <= '9' || q[j] == '.' && j+1 < len(q) && q[j+1] >= '0' && q[j+1] <= '9') {
+				j++
+			}
+			if j < len(q) && (q[j] == 'e' || q[j] == 'E') {
+				j++
+				if j < len(q) && (q[j] == '+' || q[j] == '-') {
+					j++
+				}
+				for j < len(q) && q[j] >= '0' && q[j] <= '9' {
+					j++
+				}
+			}
+			toks = append(toks, token{typ: tokNumber, val: q[i:j], pos: i})
+			i = j
+		case r == '_' && strings.HasPrefix(q[i:], "_:"):
+			j := i + 2
+			for j < len(q) && (isNameChar(q[j]) || q[j] == '-' && j > i+2) {
+				j++
+				// dots allowed within labels, not at their end
+				k := j
+				for k < len(q) && q[k] == '.' {
+					k++
+				}
+				if k > j && k < len(q) && (isNameChar(q[k]) || q[k] == '-') {
+					j = k
+				}
+			}
+			toks = append(toks, token{typ: tokPName, val: q[i:j], pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == ':':
+			j := i
+			var isPName bool
+			for j < len(q) {
+				c := q[j]
+				if c == ':' {
+					isPName = true
+				} else if c == '.' && isPName && j+1 < len(q) && isNameChar(q[j+1]) {
+					// dots allowed within local names
+				} else if !isNameChar(c) && !(isPName && (c == '-' || c == '/' || c == '#' || c == '%')) {
+					break
+				}
+				j++
+			}
+			typ := tokIdent
+			if isPName {
+				typ = tokPName
+			}
+			toks = append(toks, token{typ: typ, val: q[i:j], pos: i})
+			i = j
+		default:
+			n := size
+			for _, op := range []string{"^^", "&&", "||", "!=", ">=", "=>"} {
+				if strings.HasPrefix(q[i:], op) {
+					n = len(op)
+					break
+				}
+			}
+			toks = append(toks, token{typ: tokPunct, val: q[i : i+n], pos: i})
+			i += n
+		}
+	}
+	return append(toks, token{typ: tokEOF, pos: len(q)}), nil
+}
+
+// lastIsOperand reports whether a sign would be an operator rather than a number sign
+func lastIsOperand(toks []token) bool {
+	if len(toks) == 0 {
+		return false
+	}
+	switch last := toks[len(toks)-1]; last.typ {
+	case tokVar, tokNumber, tokString, tokIRI, tokPName:
+		return true
+	case tokPunct:
+		return last.val == ")"
+	}
+	return false
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= utf8.RuneSelf
+}
+
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	if len(s) >= 3 && s[1] == quote && s[2] == quote {
+		delim := strings.Repeat(string(quote), 3)
+		for i := 3; i < len(s); i++ {
+			switch {
+			case s[i] == '\\':
+				i++
+			case strings.HasPrefix(s[i:], delim):
+				val, err := unescapeString(s[3:i])
+				return val, i + 3, err
+			}
+		}
+		return "", 0, errors.New("unterminated long string")
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\n':
+			return "", 0, errors.New("newline in string")
+		case quote:
+			val, err := unescapeString(s[1:i])
+			return val, i + 1, err
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+// unescapeString decodes the escape sequences of a string: \t, \b, \n, \r, \f,
+// \", \', \\ and the \uXXXX and \UXXXXXXXX code points
+func unescapeString(s string) (string, error) {
+	i := strings.IndexByte(s, '\\')
+	if i < 0 {
+		return s, nil
+	}
+	out := []byte(s[:i])
+	for i < len(s) {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			i++
+			continue
+		}
+		if i+1 == len(s) {
+			return "", errors.New("invalid escape at end of string")
+		}
+		switch e := s[i+1]; e {
+		case 't':
+			out = append(out, '\t')
+		case 'b':
+			out = append(out, '\b')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 'f':
+			out = append(out, '\f')
+		case '"', '\'', '\\':
+			out = append(out, e)
+		case 'u', 'U':
+			n := 4
+			if e == 'U' {
+				n = 8
+			}
+			if i+2+n > len(s) {
+				return "", fmt.Errorf("invalid escape %s", s[i:])
+			}
+			code, err := strconv.ParseUint(s[i+2:i+2+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid escape %s", s[i:i+2+n])
+			}
+			var buf [utf8.UTFMax]byte
+			out = append(out, buf[:utf8.EncodeRune(buf[:], rune(code))]...)
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape %s", s[i:i+2])
+		}
+		i += 2
+	}
+	return string(out), nil
+}
+
+type sparqlParser struct {
+	toks []token
+	pos  int
+	q    *Query
+}
+
+func (p *sparqlParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *sparqlParser) next() token {
+	t := p.toks[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sparqlParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.typ == tokIdent && strings.EqualFold(t.val, kw)
+}
+
+func (p *sparqlParser) acceptKeyword(kw string) bool {
+	if p.isKeyword(kw) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *sparqlParser) isPunct(punct string) bool {
+	t := p.peek()
+	return t.typ == tokPunct && t.val == punct
+}
+
+func (p *sparqlParser) acceptPunct(punct string) bool {
+	if p.isPunct(punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *sparqlParser) expectPunct(punct string) error {
+	if !p.acceptPunct(punct) {
+		return fmt.Errorf("expected '%s', got %s", punct, p.peek())
+	}
+	return nil
+}
+
+func (p *sparqlParser) parseQuery() (*Query, error) {
+	p.q = &Query{prefixes: make(map[string]string), limit: -1}
+
+	if err := p.parsePrologue(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.acceptKeyword("SELECT"):
+		if err := p.parseSelectClause(); err != nil {
+			return nil, err
+		}
+	case p.acceptKeyword("ASK"):
+		p.q.ask = true
+	default:
+		return nil, fmt.Errorf("expected SELECT or ASK, got %s", p.peek())
+	}
+	if err := p.parseWhereClause(); err != nil {
+		return nil, err
+	}
+	if err := p.parseModifiers(); err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.typ != tokEOF {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	return p.q, nil
+}
+
+func (p *sparqlParser) parsePrologue() error {
+	for {
+		switch {
+		case p.acceptKeyword("PREFIX"):
+			name := p.next()
+			if name.typ != tokPName || !strings.HasSuffix(name.val, ":") {
+				return fmt.Errorf("expected prefix name, got %s", name)
+			}
+			iri := p.next()
+			if iri.typ != tokIRI {
+				return fmt.Errorf("expected IRI, got %s", iri)
+			}
+			p.q.prefixes[strings.TrimSuffix(name.val, ":")] = iri.val
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *sparqlParser) parseSelectClause() error {
+	p.q.distinct = p.acceptKeyword("DISTINCT")
+	if p.acceptPunct("*") {
+		return nil
+	}
+	for p.peek().typ == tokVar {
+		p.q.vars = append(p.q.vars, p.next().val)
+	}
+	if len(p.q.vars) == 0 {
+		return fmt.Errorf("expected variables or '*', got %s", p.peek())
+	}
+	return nil
+}
+
+func (p *sparqlParser) parseWhereClause() error {
+	p.acceptKeyword("WHERE")
+	return p.parseGroupGraphPattern()
+}
+
+// parseGroupGraphPattern parses the triple patterns between braces
+func (p *sparqlParser) parseGroupGraphPattern() error {
+	if err := p.expectPunct("{"); err != nil {
+		return err
+	}
+	for !p.acceptPunct("}") {
+		if p.acceptKeyword("FILTER") {
+			if err := p.parseFilter(); err != nil {
+				return err
+			}
+			p.acceptPunct(".")
+			continue
+		}
+		if err := p.parseTriplesBlock(); err != nil {
+			return err
+		}
+		if !p.acceptPunct(".") && !p.isPunct("}") && !p.isKeyword("FILTER") {
+			return fmt.Errorf("expected '.' or '}', got %s", p.peek())
+		}
+	}
+	return nil
+}
+
+// parseFilter parses the constraints of a FILTER: either a regex call or
+// bracketted constraints (regex calls and comparisons) joined by '&&'
+func (p *sparqlParser) parseFilter() error {
+	if !p.acceptPunct("(") {
+		f, err := p.parseRegex()
+		if err != nil {
+			return err
+		}
+		p.q.filters = append(p.q.filters, f)
+		return nil
+	}
+	for {
+		var f filter
+		var err error
+		if t := p.peek(); t.typ == tokIdent && t.val != "true" && t.val != "false" {
+			f, err = p.parseRegex()
+		} else {
+			f, err = p.parseComparison()
+		}
+		if err != nil {
+			return err
+		}
+		p.q.filters = append(p.q.filters, f)
+		if p.acceptPunct(")") {
+			return nil
+		}
+		if !p.acceptPunct("&&") {
+			return fmt.Errorf("expected '&&' or ')', got %s", p.peek())
+		}
+	}
+}
+
+func (p *sparqlParser) parseRegex() (filter, error) {
+	if t := p.next(); t.typ != tokIdent || !strings.EqualFold(t.val, "regex") {
+		return nil, fmt.Errorf("unsupported filter %s", t)
+	}
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	v := p.next()
+	if v.typ != tokVar {
+		return nil, fmt.Errorf("regex: expected variable, got %s", v)
+	}
+	if err := p.expectPunct(","); err != nil {
+		return nil, err
+	}
+	f := regexFilter{variable: v.val}
+	pattern := p.next()
+	if pattern.typ != tokString {
+		return nil, fmt.Errorf("regex: expected pattern string, got %s", pattern)
+	}
+	f.pattern = pattern.val
+	if p.acceptPunct(",") {
+		flags := p.next()
+		if flags.typ != tokString {
+			return nil, fmt.Errorf("regex: expected flags string, got %s", flags)
+		}
+		f.flags = flags.val
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if f.re, err = compileSPARQLRegex(f.pattern, f.flags); err != nil {
+		return nil, fmt.Errorf("regex: %s", err)
+	}
+	return f, nil
+}
+
+// parseComparison parses the comparison of a variable with a literal
+func (p *sparqlParser) parseComparison() (filter, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	if _, ok := flippedOps[op.val]; !ok || op.typ != tokPunct {
+		return nil, fmt.Errorf("expected comparison operator, got %s", op)
+	}
+	right, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	f := compareFilter{op: op.val}
+	switch {
+	case left.isVar() && !right.isVar():
+		f.variable, f.value = left.variable, right.value
+	case right.isVar() && !left.isVar():
+		f.variable, f.value, f.op = right.variable, left.value, flippedOps[op.val]
+	default:
+		return nil, errors.New("comparison: expected a variable and a literal")
+	}
+	if !f.value.isLit {
+		return nil, fmt.Errorf("comparison: expected a literal, got %s", term{value: f.value})
+	}
+	return f, nil
+}
+
+func (p *sparqlParser) parseTriplesBlock() error {
+	sub, err := p.parseTerm()
+	if err != nil {
+		return err
+	}
+	if _, isLit := sub.value.Literal(); isLit && !sub.isVar() {
+		return errors.New("literal not allowed as subject")
+	}
+	for {
+		pred, err := p.parseVerb()
+		if err != nil {
+			return err
+		}
+		for {
+			obj, err := p.parseTerm()
+			if err != nil {
+				return err
+			}
+			p.q.patterns = append(p.q.patterns, triplePattern{sub: sub, pred: pred, obj: obj})
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if !p.acceptPunct(";") {
+			return nil
+		}
+		if p.isPunct(".") || p.isPunct("}") {
+			return nil
+		}
+	}
+}
+
+func (p *sparqlParser) parseVerb() (term, error) {
+	if p.acceptKeyword("a") {
+		return term{value: Resource(p.expandPName("rdf:type")).(object)}, nil
+	}
+	pred, err := p.parseTerm()
+	if err != nil {
+		return pred, err
+	}
+	if !pred.isVar() {
+		if _, isLit := pred.value.Literal(); isLit {
+			return pred, errors.New("literal not allowed as predicate")
+		}
+		if _, isBnode := pred.value.Bnode(); isBnode {
+			return pred, errors.New("blank node not allowed as predicate")
+		}
+	}
+	return pred, nil
+}
+
+func (p *sparqlParser) parseTerm() (term, error) {
+	t := p.next()
+	switch t.typ {
+	case tokVar:
+		return term{variable: t.val}, nil
+	case tokIRI:
+		return term{value: Resource(t.val).(object)}, nil
+	case tokPName:
+		if strings.HasPrefix(t.val, "_:") {
+			return term{value: object{bnode: t.val[2:], isBnode: true}}, nil
+		}
+		return term{value: Resource(p.expandPName(t.val)).(object)}, nil
+	case tokNumber:
+		return term{value: numberLiteral(t.val)}, nil
+	case tokIdent:
+		switch t.val {
+		case "true":
+			return term{value: BooleanLiteral(true).(object)}, nil
+		case "false":
+			return term{value: BooleanLiteral(false).(object)}, nil
+		}
+	case tokString:
+		lit := literal{typ: XsdString, val: t.val}
+		if p.peek().typ == tokLang {
+			lit.langtag = p.next().val
+		} else if p.acceptPunct("^^") {
+			dt := p.next()
+			switch dt.typ {
+			case tokIRI:
+				lit.typ = shortXsdType(XsdType(dt.val))
+			case tokPName:
+				lit.typ = shortXsdType(XsdType(p.expandPName(dt.val)))
+			default:
+				return term{}, fmt.Errorf("expected datatype, got %s", dt)
+			}
+		}
+		return term{value: object{isLit: true, lit: lit}}, nil
+	}
+	return term{}, fmt.Errorf("unexpected %s", t)
+}
+
+// numberLiteral builds the literal of a SPARQL numeric shorthand
+func numberLiteral(val string) object {
+	typ := XsdInteger
+	switch {
+	case strings.ContainsAny(val, "eE"):
+		typ = XsdDouble
+	case strings.Contains(val, "."):
+		typ = XsdDecimal
+	}
+	return object{isLit: true, lit: literal{typ: typ, val: strings.TrimPrefix(val, "+")}}
+}
+
+func (p *sparqlParser) expandPName(pname string) string {
+	splits := strings.SplitN(pname, ":", 2)
+	if ns, ok := p.q.prefixes[splits[0]]; ok {
+		return ns + splits[1]
+	}
+	return pname
+}
+
+func (p *sparqlParser) parseModifiers() error {
+	for {
+		switch {
+		case p.acceptKeyword("LIMIT"):
+			n, err := p.parseInt()
+			if err != nil {
+				return fmt.Errorf("limit: %s", err)
+			}
+			p.q.limit = n
+		case p.acceptKeyword("OFFSET"):
+			n, err := p.parseInt()
+			if err != nil {
+				return fmt.Errorf("offset: %s", err)
+			}
+			p.q.offset = n
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *sparqlParser) parseInt() (int, error) {
+	t := p.next()
+	if t.typ != tokNumber {
+		return 0, fmt.Errorf("expected integer, got %s", t)
+	}
+	n, err := strconv.Atoi(t.val)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected positive integer, got %s", t)
+	}
+	return n, nil
+}
@@ -0,0 +1,385 @@
+package triplestore
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sparqlTestGraph() RDFGraph {
+	s := NewSource()
+	s.Add(
+		SubjPred("alice", "rdf:type").Resource("Person"),
+		SubjPred("bob", "rdf:type").Resource("Person"),
+		SubjPred("fido", "rdf:type").Resource("Dog"),
+		SubjPred("alice", "name").StringLiteral("Alice"),
+		SubjPred("bob", "name").StringLiteral("Bob"),
+		SubjPred("alice", "age").IntegerLiteral(42),
+		SubjPred("bob", "age").IntegerLiteral(24),
+		SubjPred("alice", "knows").Resource("bob"),
+		SubjPred("bob", "knows").Resource("bob"),
+		SubjPred("alice", "owns").Resource("fido"),
+		SubjPred("alice", "label").Object(StringLiteralWithLang("Alice", "en")),
+		BnodePred("b1", "name").StringLiteral("anon"),
+		SubjPred("http://ex.org/x", "http://ex.org/p").Resource("http://ex.org/y"),
+	)
+	return s.Snapshot()
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tcases := []struct {
+		query, err string
+	}{
+		{"", "expected SELECT"},
+		{"SELECT WHERE { ?s ?p ?o }", "expected variables"},
+		{"SELECT * WHERE { ?s ?p ?o ", "got end of query"},
+		{"SELECT * WHERE { ?s ?p }", "unexpected '}'"},
+		{"SELECT * WHERE { \"lit\" ?p ?o }", "literal not allowed as subject"},
+		{"SELECT * WHERE { ?s 42 ?o }", "literal not allowed as predicate"},
+		{"SELECT * WHERE { ?s ?p ?o } LIMIT -1", "limit"},
+		{"SELECT * WHERE { ?s ?p \"unterminated }", "unterminated string"},
+		{"PREFIX ex <http://ex.org/> SELECT * { ?s ?p ?o }", "expected prefix name"},
+		{"SELECT * WHERE { ?s ?p ?o } extra", "unexpected 'extra'"},
+	}
+	for i, tc := range tcases {
+		_, err := ParseQuery(tc.query)
+		if err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %q, want it to contain %q", i, err, tc.err)
+		}
+	}
+}
+
+func TestQueryEval(t *testing.T) {
+	g := sparqlTestGraph()
+
+	tcases := []struct {
+		query string
+		vars  []string
+		rows  []string
+	}{
+		{
+			query: "SELECT ?s WHERE { ?s a <Person> }",
+			vars:  []string{"s"},
+			rows:  []string{"<alice>", "<bob>"},
+		},
+		{
+			query: "SELECT ?n ?a { ?s rdf:type <Person> ; <name> ?n ; <age> ?a }",
+			vars:  []string{"n", "a"},
+			rows:  []string{`"Alice" "42"^^<http://www.w3.org/2001/XMLSchema#integer>`, `"Bob" "24"^^<http://www.w3.org/2001/XMLSchema#integer>`},
+		},
+		{
+			query: "SELECT ?s WHERE { ?s <age> 42 }",
+			vars:  []string{"s"},
+			rows:  []string{"<alice>"},
+		},
+		{
+			query: "SELECT ?s WHERE { ?s <knows> ?s }",
+			vars:  []string{"s"},
+			rows:  []string{"<bob>"},
+		},
+		{
+			query: "SELECT ?dog WHERE { ?p <knows> <bob> ; <owns> ?dog . ?dog a <Dog> }",
+			vars:  []string{"dog"},
+			rows:  []string{"<fido>"},
+		},
+		{
+			query: "SELECT ?s WHERE { ?s <name> \"anon\" }",
+			vars:  []string{"s"},
+			rows:  []string{"_:b1"},
+		},
+		{
+			query: "SELECT ?s WHERE { ?s <label> \"Alice\"@en }",
+			vars:  []string{"s"},
+			rows:  []string{"<alice>"},
+		},
+		{
+			query: "SELECT ?s WHERE { ?s <label> \"Alice\" }",
+			vars:  []string{"s"},
+		},
+		{
+			query: "SELECT ?s WHERE { ?s <name> \"Alice\"^^xsd:string }",
+			vars:  []string{"s"},
+			rows:  []string{"<alice>"},
+		},
+		{
+			query: "PREFIX ex: <http://ex.org/> SELECT * WHERE { ex:x ex:p ?o }",
+			vars:  []string{"o"},
+			rows:  []string{"<http://ex.org/y>"},
+		},
+		{
+			query: "SELECT ?p WHERE { <http://ex.org/x> ?p <http://ex.org/y> }",
+			vars:  []string{"p"},
+			rows:  []string{"<http://ex.org/p>"},
+		},
+		{
+			query: "SELECT ?s ?o WHERE { ?s <knows> ?o , <bob> }",
+			vars:  []string{"s", "o"},
+			rows:  []string{"<alice> <bob>", "<bob> <bob>"},
+		},
+		{
+			query: "SELECT DISTINCT ?t WHERE { ?s a ?t }",
+			vars:  []string{"t"},
+			rows:  []string{"<Dog>", "<Person>"},
+		},
+		{
+			query: "SELECT ?s WHERE { ?s a <unknown> }",
+			vars:  []string{"s"},
+		},
+	}
+
+	for i, tc := range tcases {
+		q, err := ParseQuery(tc.query)
+		if err != nil {
+			t.Fatalf("case %d: %s", i, err)
+		}
+		res, err := q.Eval(g)
+		if err != nil {
+			t.Fatalf("case %d: %s", i, err)
+		}
+		if got, want := res.Vars, tc.vars; !reflect.DeepEqual(got, want) {
+			t.Fatalf("case %d: got %v, want %v", i, got, want)
+		}
+		if got, want := resultRows(res), tc.rows; !reflect.DeepEqual(got, want) {
+			t.Fatalf("case %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestQueryLimitOffset(t *testing.T) {
+	g := sparqlTestGraph()
+	tcases := []struct {
+		modifiers string
+		count     int
+	}{
+		{"", 3},
+		{"LIMIT 2", 2},
+		{"LIMIT 0", 0},
+		{"OFFSET 1", 2},
+		{"OFFSET 5", 0},
+		{"LIMIT 1 OFFSET 2", 1},
+	}
+	for i, tc := range tcases {
+		q, err := ParseQuery("SELECT ?s WHERE { ?s a ?t } " + tc.modifiers)
+		if err != nil {
+			t.Fatalf("case %d: %s", i, err)
+		}
+		res, err := q.Eval(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(res.Bindings), tc.count; got != want {
+			t.Fatalf("case %d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func resultRows(res *QueryResults) []string {
+	var rows []string
+	for _, b := range res.Bindings {
+		var cols []string
+		for _, v := range res.Vars {
+			cols = append(cols, b[v].(object).key())
+		}
+		rows = append(rows, strings.Join(cols, " "))
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+func TestQueryAsk(t *testing.T) {
+	g := sparqlTestGraph()
+	tcases := []struct {
+		query string
+		exp   bool
+	}{
+		{"ASK { <alice> <knows> <bob> }", true},
+		{"ASK WHERE { <bob> <knows> <alice> }", false},
+		{"ASK { ?p a <Person> ; <owns> ?d . ?d a <Dog> }", true},
+		{"ASK { ?p a <Dog> ; <name> ?n }", false},
+		{"PREFIX ex: <http://ex.org/> ASK { ex:x ex:p ?o }", true},
+		{"SELECT ?s WHERE { ?s <age> 42 }", true},
+		{"SELECT ?s WHERE { ?s <age> 43 }", false},
+	}
+	for i, tc := range tcases {
+		q, err := ParseQuery(tc.query)
+		if err != nil {
+			t.Fatalf("case %d: %s", i, err)
+		}
+		ok, err := q.Ask(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ok, tc.exp; got != want {
+			t.Fatalf("case %d: got %t, want %t", i, got, want)
+		}
+		if !q.IsAsk() {
+			continue
+		}
+		res, err := q.Eval(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(res.Bindings) == 1, tc.exp; got != want {
+			t.Fatalf("case %d: eval: got %t, want %t", i, got, want)
+		}
+		if got := res.Vars; len(got) != 0 {
+			t.Fatalf("case %d: expected no vars, got %v", i, got)
+		}
+	}
+}
+
+func TestQueryRegexFilter(t *testing.T) {
+	g := sparqlTestGraph()
+	tcases := []struct {
+		query string
+		exp   []string
+	}{
+		{`SELECT ?s WHERE { ?s <name> ?n FILTER regex(?n, "^A") }`, []string{"alice"}},
+		{`SELECT ?s WHERE { ?s <name> ?n . FILTER (regex(?n, "^a", "i")) }`, []string{"alice", "b1"}},
+		{`SELECT ?s WHERE { FILTER regex(?n, "o") ?s <name> ?n }`, []string{"b1", "bob"}},
+		{`SELECT ?s WHERE { ?s <name> ?n ; <age> ?a FILTER regex(?a, "^4") }`, []string{"alice"}},
+		{`SELECT ?s WHERE { ?s <knows> ?o FILTER regex(?o, "bob") }`, nil},
+		{`SELECT ?s WHERE { ?s <name> ?n FILTER regex(?n, "A.", "q") }`, nil},
+		{`SELECT ?s WHERE { ?s <name> ?n FILTER regex(?other, "A") }`, nil},
+		{`SELECT ?s WHERE { ?s <name> ?n FILTER regex(?n, "^A") FILTER regex(?n, "e$") }`, []string{"alice"}},
+	}
+	for i, tc := range tcases {
+		q, err := ParseQuery(tc.query)
+		if err != nil {
+			t.Fatalf("case %d: %s", i, err)
+		}
+		res, err := q.Eval(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, b := range res.Bindings {
+			got = append(got, nodeID(b["s"].(object)))
+		}
+		sort.Strings(got)
+		if want := tc.exp; !reflect.DeepEqual(got, want) {
+			t.Fatalf("case %d: got %v, want %v", i, got, want)
+		}
+		ok, err := q.Ask(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ok, len(tc.exp) > 0; got != want {
+			t.Fatalf("case %d: ask: got %t, want %t", i, got, want)
+		}
+	}
+
+	for i, tc := range []struct{ query, err string }{
+		{`SELECT * { ?s ?p ?o FILTER bound(?o) }`, "unsupported filter"},
+		{`SELECT * { ?s ?p ?o FILTER (?o > ?s) }`, "expected a variable and a literal"},
+		{`SELECT * { ?s ?p ?o FILTER (?o > <res>) }`, "expected a literal"},
+		{`SELECT * { ?s ?p ?o FILTER (?o + 3) }`, "expected comparison operator"},
+		{`SELECT * { ?s ?p ?o FILTER (?o > 3 || ?o < 1) }`, "expected '&&' or ')'"},
+		{`SELECT * { ?s ?p ?o FILTER regex("o", "a") }`, "expected variable"},
+		{`SELECT * { ?s ?p ?o FILTER regex(?o, "(") }`, "invalid regex"},
+		{`SELECT * { ?s ?p ?o FILTER regex(?o, "a", "x") }`, "unsupported regex flag"},
+	} {
+		_, err := ParseQuery(tc.query)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %v, want error containing %q", i, err, tc.err)
+		}
+	}
+}
+
+func TestQueryComparisonFilter(t *testing.T) {
+	s := NewSource()
+	s.Add(
+		SubjPred("one", "at").DateTimeLiteral(time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)),
+		SubjPred("two", "at").Object(object{isLit: true, lit: literal{typ: "http://www.w3.org/2001/XMLSchema#dateTime", val: "2020-01-01T11:30:00+02:00"}}),
+		SubjPred("three", "at").Object(object{isLit: true, lit: literal{typ: XsdDateTime, val: "2020-06-01T00:00:00"}}),
+		SubjPred("four", "at").DateLiteral(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+		SubjPred("five", "at").StringLiteral("2020-03-01T00:00:00Z"),
+		SubjPred("one", "price").IntegerLiteral(10),
+		SubjPred("two", "price").Float64Literal(9.5),
+		SubjPred("three", "price").IntegerLiteral(100),
+	)
+	for _, g := range []RDFGraph{s.Snapshot(), NewColumnarGraph(s.Snapshot())} {
+		tcases := []struct {
+			filter string
+			exp    []string
+		}{
+			{`?at > "2020-01-01T10:00:00Z"^^xsd:dateTime`, []string{"four", "three"}},
+			{`?at >= "2020-01-01T10:00:00Z"^^xsd:dateTime`, []string{"four", "one", "three"}},
+			{`?at < "2020-01-01T10:00:00Z"^^xsd:dateTime`, []string{"two"}},
+			{`?at = "2020-01-01T12:00:00+02:00"^^xsd:dateTime`, []string{"one"}},
+			{`?at >= "2020-01-01"^^xsd:date && ?at < "2021-01-01"^^xsd:date`, []string{"one", "three", "two"}},
+			{`"2020-06-01T00:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> <= ?at`, []string{"four", "three"}},
+			{`?price > 9.5`, []string{"one", "three"}},
+			{`?price <= 10 && ?price > 9`, []string{"one", "two"}},
+			{`?price != 10`, []string{"three", "two"}},
+			{`?price > "10"`, nil},
+		}
+		for i, tc := range tcases {
+			pred := "at"
+			if strings.Contains(tc.filter, "price") {
+				pred = "price"
+			}
+			q, err := ParseQuery(fmt.Sprintf("PREFIX xsd: <http://www.w3.org/2001/XMLSchema#> SELECT ?s WHERE { ?s <%s> ?%s FILTER (%s) }", pred, pred, tc.filter))
+			if err != nil {
+				t.Fatalf("case %d: %s", i, err)
+			}
+			res, err := q.Eval(g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, b := range res.Bindings {
+				got = append(got, nodeID(b["s"].(object)))
+			}
+			sort.Strings(got)
+			if want := tc.exp; !reflect.DeepEqual(got, want) {
+				t.Fatalf("case %d: got %v, want %v", i, got, want)
+			}
+		}
+	}
+}
+
+func TestQueryExplain(t *testing.T) {
+	q, err := ParseQuery(`SELECT * WHERE {
+		?p <name> ?n ; <age> ?a .
+		?p a <Person> .
+		?x ?y ?z
+		FILTER regex(?n, "^A")
+		FILTER (?a > 18)
+		FILTER (?unknown = 3)
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `1. ?p <rdf:type> <Person> (WithPredObj)
+2. ?p <name> ?n (WithSubjPred)
+   FILTER regex(?n, "^A")
+3. ?p <age> ?a (WithSubjPred)
+   FILTER (?a > "18"^^<http://www.w3.org/2001/XMLSchema#integer>)
+4. ?x ?y ?z (Triples, full scan)
+FILTER (?unknown = "3"^^<http://www.w3.org/2001/XMLSchema#integer>) (unbound variables, no solution)
+`
+	if got, want := q.Explain(), exp; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	q, err = ParseQuery(`SELECT * WHERE { ?p <name> ?n ; <age> ?a FILTER regex(?n, "^A") FILTER (?a > 18 && ?a <= 30) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = `1. ?p <name> ?n (WithPredObjMatching)
+   FILTER regex(?n, "^A")
+2. ?p <age> ?a (WithSubjPred)
+   FILTER (?a > "18"^^<http://www.w3.org/2001/XMLSchema#integer>)
+   FILTER (?a <= "30"^^<http://www.w3.org/2001/XMLSchema#integer>)
+`
+	if got, want := q.Explain(), exp; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
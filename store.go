@@ -0,0 +1,65 @@
+package triplestore
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A Store manages named datasets, each one being an isolated source.
+// It is safe for concurrent use.
+type Store struct {
+	opts []SourceOption
+
+	mu       sync.RWMutex
+	datasets map[string]Source
+}
+
+// NewStore returns an empty store. The options configure the sources of the created datasets.
+func NewStore(opts ...SourceOption) *Store {
+	return &Store{opts: opts, datasets: make(map[string]Source)}
+}
+
+// Create creates and returns an empty dataset. It fails if the dataset already exists.
+func (s *Store) Create(name string) (Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.datasets[name]; ok {
+		return nil, fmt.Errorf("store: dataset '%s' already exists", name)
+	}
+	src := NewSource(s.opts...)
+	s.datasets[name] = src
+	return src, nil
+}
+
+// Dataset returns the dataset of the given name, if any
+func (s *Store) Dataset(name string) (Source, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	src, ok := s.datasets[name]
+	return src, ok
+}
+
+// Drop removes the dataset from the store. It fails if the dataset does not exist.
+// The dropped source remains usable by the ones holding it.
+func (s *Store) Drop(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.datasets[name]; !ok {
+		return fmt.Errorf("store: dataset '%s' not found", name)
+	}
+	delete(s.datasets, name)
+	return nil
+}
+
+// List returns the sorted names of the datasets
+func (s *Store) List() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.datasets))
+	for name := range s.datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
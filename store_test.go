@@ -0,0 +1,90 @@
+package triplestore_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	tstore "github.com/wallix/triplestore"
+)
+
+func TestStoreDatasets(t *testing.T) {
+	store := tstore.NewStore()
+	acme, err := store.Create("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	initech, err := store.Create("initech")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create("acme"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("got %v, want already exists error", err)
+	}
+
+	acme.Add(tstore.SubjPred("me", "works").Resource("acme"))
+	if got, want := initech.Snapshot().Count(), 0; got != want {
+		t.Fatalf("datasets not isolated: got %d, want %d", got, want)
+	}
+	src, ok := store.Dataset("acme")
+	if !ok {
+		t.Fatal("expected dataset")
+	}
+	if got, want := src.Snapshot().Count(), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := strings.Join(store.List(), ","), "acme,initech"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if err := store.Drop("acme"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Drop("acme"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("got %v, want not found error", err)
+	}
+	if _, ok := store.Dataset("acme"); ok {
+		t.Fatal("expected no dataset")
+	}
+	if got, want := strings.Join(store.List(), ","), "initech"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	recreated, err := store.Create("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := recreated.Snapshot().Count(), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func TestStoreConcurrentDatasets(t *testing.T) {
+	store := tstore.NewStore()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("tenant%d", i)
+			src, err := store.Create(name)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			src.Add(tstore.SubjPred(name, "is").Resource("tenant"))
+			store.List()
+		}(i)
+	}
+	wg.Wait()
+	if got, want := len(store.List()), 10; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	for _, name := range store.List() {
+		src, _ := store.Dataset(name)
+		if got, want := src.Snapshot().WithSubject(name), 1; len(got) != want {
+			t.Fatalf("got %d, want %d", len(got), want)
+		}
+	}
+}
@@ -1,45 +1,305 @@
 package triplestore
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
+	"math/big"
 	"math/rand"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
-	predTag  = "predicate"
-	bnodeTag = "bnode"
+	predTag    = "predicate"
+	bnodeTag   = "bnode"
+	rdfTypeTag = "rdfType"
+	subjTplTag = "subjectTemplate"
+	namingTag  = "predicateNaming"
+
+	rdfTypePred = "rdf:type"
 )
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// fieldTag holds the predicate and options of a struct field's tag.
+// Ex: `predicate:"name,omitempty"`, `predicate:"weight,datatype=xsd:decimal"`,
+// `predicate:",inline"`, `predicate:"timeout,unit=ms"`, `predicate:"knows,resource"`,
+// `predicate:"id,required"`, `predicate:"label,lang=en|fr"`
+type fieldTag struct {
+	pred      string
+	hasPred   bool
+	omitEmpty bool
+	inline    bool
+	resource  bool
+	required  bool
+	langs     []string
+	datatype  XsdType
+	unit      time.Duration
+}
+
+func parseFieldTag(field reflect.StructField) fieldTag {
+	value, ok := field.Tag.Lookup(predTag)
+	splits := strings.Split(value, ",")
+	tag := fieldTag{pred: splits[0], hasPred: ok}
+	if tag.pred == "-" {
+		tag.pred = ""
+	}
+	for _, opt := range splits[1:] {
+		switch {
+		case opt == "omitempty":
+			tag.omitEmpty = true
+		case opt == "inline":
+			tag.inline = true
+		case opt == "resource":
+			tag.resource = true
+		case opt == "required":
+			tag.required = true
+		case strings.HasPrefix(opt, "datatype="):
+			tag.datatype = XsdType(strings.TrimPrefix(opt, "datatype="))
+		case strings.HasPrefix(opt, "lang="):
+			tag.langs = strings.Split(strings.TrimPrefix(opt, "lang="), "|")
+		case strings.HasPrefix(opt, "unit="):
+			if unit, err := time.ParseDuration("1" + strings.TrimPrefix(opt, "unit=")); err == nil && unit > 0 {
+				tag.unit = unit
+			}
+		}
+	}
+	return tag
+}
+
+// isInlineField reports whether the fields of a struct (or ptr to struct) field
+// are mapped under the parent subject: either the field has the "inline" tag option
+// or, as with encoding/json, it is an embedded struct without predicate nor bnode tag
+func isInlineField(field reflect.StructField, tag fieldTag) bool {
+	if !isStructOrPtrToStruct(field.Type) {
+		return false
+	}
+	if tag.inline {
+		return true
+	}
+	_, hasBnode := field.Tag.Lookup(bnodeTag)
+	return field.Anonymous && !tag.hasPred && !hasBnode
+}
+
+// structFieldTags returns the tags of all the fields of a struct type,
+// deriving the predicate of untagged fields when the struct declares
+// a naming strategy (see predicateNaming)
+func structFieldTags(st reflect.Type) []fieldTag {
+	naming, hasNaming := structPredicateNaming(st)
+	tags := make([]fieldTag, st.NumField())
+	for i := range tags {
+		field := st.Field(i)
+		tags[i] = parseFieldTag(field)
+		if hasNaming && !tags[i].hasPred && naming.applies(field) {
+			tags[i].pred, tags[i].hasPred = naming.predicate(field.Name), true
+		}
+	}
+	return tags
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+	}
+	return false
+}
+
+// TripleMarshaler is the interface implemented by types
+// that can marshal themselves into triples describing the given subject
+type TripleMarshaler interface {
+	MarshalTriples(sub string) ([]Triple, error)
+}
+
+// SubjectProvider is the interface implemented by structs
+// that give their own subject when converted into triples
+type SubjectProvider interface {
+	Subject() string
+}
+
+// TripleUnmarshaler is the interface implemented by types
+// that can unmarshal themselves from the triples of the given subject
+type TripleUnmarshaler interface {
+	UnmarshalTriples(g RDFGraph, sub string) error
+}
+
 // Convert a Struct or ptr to Struct into triples
 // using field tags.
 // For each struct's field a triple is created:
-// - Subject: function first argument
-// - Predicate: tag value
-// - Literal: actual field value according to field's type
-// Unsupported types are ignored
+// - Subject: function first argument, or if empty given by the
+// SubjectProvider implementation of the struct, or derived from
+// a subjectTemplate tag (ex: `_ struct{} subjectTemplate:"http://ex.com/people/{ID}"`)
+// - Predicate: tag value (with option "omitempty" to skip zero values),
+// or derived from the field's name when the struct declares a predicateNaming tag
+// - Literal: actual field value according to field's type ([]byte as xsd:base64Binary)
+// (or to the datatype given with the tag option "datatype=xsd:...").
+// Strings are tagged with the first language of the tag option "lang=en|fr".
+// time.Duration values are emitted as xsd:duration, or as an integer
+// number of the unit given with the tag option "unit=s" (or ms, us, m, h...).
+// url.URL values, and values of fields with the tag option "resource",
+// are emitted as resources instead of literals.
+// Types implementing encoding.TextMarshaler (and not natively supported)
+// are emitted as a string literal of their text.
+// Pointers to struct are emitted as a resource pointing to a derived
+// subject (subject template or see linkedSubject) from which the struct's triples are created.
+// A pointer back to a struct being converted (i.e. a references cycle)
+// is emitted as a link to the subject of that struct.
+// A rdfType tag on any field (ex: `_ struct{} rdfType:"foaf:Person"`)
+// emits a rdf:type triple for the subject.
+// Embedded structs without predicate nor bnode tag (as with encoding/json),
+// or struct fields with the tag option "inline", have their fields
+// emitted under the parent subject.
+// Slices of structs emit each element under its own random bnode
+// (or subject template) linked from the subject.
+// Maps with string keys emit a triple per entry, with the predicate being
+// the tag value (i.e. a prefix, possibly empty) followed by the key.
+// Types implementing TripleMarshaler (top level value or field's value)
+// are in charge of their own triples, fields being linked through
+// a resource pointing to a derived subject.
+// Unsupported types and marshaling errors are ignored
 func TriplesFromStruct(sub string, i interface{}, bnodes ...bool) (out []Triple) {
 	var isBnode bool
 	if len(bnodes) > 0 {
 		isBnode = bnodes[0]
 	}
-	val := reflect.ValueOf(i)
+	return newStructEncoder().triplesFromStruct(sub, reflect.ValueOf(i), isBnode)
+}
+
+// structEncoder converts structs into triples, keeping track of the structs
+// being converted so that references cycles (ex: A -> B -> A) are emitted
+// as links to the subject of the struct already being converted
+type structEncoder struct {
+	path map[structRef]subjectRef
+}
+
+// structRef identifies a struct through its address and type,
+// the address of a struct being also the one of its first field
+type structRef struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type subjectRef struct {
+	sub     string
+	isBnode bool
+}
+
+func newStructEncoder() *structEncoder {
+	return &structEncoder{path: make(map[structRef]subjectRef)}
+}
+
+func structRefOf(v reflect.Value) (structRef, bool) {
+	switch {
+	case v.Kind() == reflect.Ptr && !v.IsNil():
+		return structRef{ptr: v.Pointer(), typ: v.Type().Elem()}, true
+	case v.CanAddr():
+		return structRef{ptr: v.Addr().Pointer(), typ: v.Type()}, true
+	}
+	return structRef{}, false
+}
+
+// backRef returns the subject of the struct (or ptr to struct)
+// if it is already being converted, i.e. on a references cycle
+func (e *structEncoder) backRef(v reflect.Value) (subjectRef, bool) {
+	ref, ok := structRefOf(v)
+	if !ok {
+		return subjectRef{}, false
+	}
+	s, ok := e.path[ref]
+	return s, ok
+}
+
+func (r subjectRef) object() Object {
+	if r.isBnode {
+		return Bnode(r.sub)
+	}
+	return Resource(r.sub)
+}
+
+// linkTriple builds the triple of the given object with the subject of a
+// struct being converted, which is a bnode when isBnode is set
+func linkTriple(sub, pred string, isBnode bool, obj Object) Triple {
+	return makeTriple(sub, isBnode, pred, obj.(object))
+}
+
+func (e *structEncoder) triplesFromStruct(sub string, v reflect.Value, isBnode bool) (out []Triple) {
+	if !v.IsValid() || !v.CanInterface() {
+		return
+	}
+	if m, ok := v.Interface().(TripleMarshaler); ok {
+		tris, err := m.MarshalTriples(sub)
+		if err != nil {
+			return
+		}
+		return tris
+	}
 
-	var ok bool
-	val, ok = getStructOrPtrToStruct(val)
+	val, ok := getStructOrPtrToStruct(v)
 	if !ok {
 		return
 	}
 
+	if sub == "" {
+		if p, isProvider := v.Interface().(SubjectProvider); isProvider {
+			sub = p.Subject()
+		}
+	}
+	if sub == "" {
+		if sub, ok = structSubject(val); !ok {
+			return
+		}
+	}
+
+	return e.triplesFromStructValue(sub, val, isBnode)
+}
+
+func (e *structEncoder) triplesFromStructValue(sub string, val reflect.Value, isBnode bool) (out []Triple) {
+	if ref, ok := structRefOf(val); ok {
+		e.path[ref] = subjectRef{sub: sub, isBnode: isBnode}
+		defer delete(e.path, ref)
+	}
 	st := val.Type()
 
+	if typ, ok := structRDFType(st); ok {
+		out = append(out, linkTriple(sub, rdfTypePred, isBnode, Resource(typ)))
+	}
+
+	tags := structFieldTags(st)
 	for i := 0; i < st.NumField(); i++ {
 		field, fVal := st.Field(i), val.Field(i)
+		tag := tags[i]
+
+		if isInlineField(field, tag) {
+			if inlined, ok := getStructOrPtrToStruct(fVal); ok {
+				if _, cycle := e.backRef(inlined); !cycle {
+					out = append(out, e.triplesFromStructValue(sub, inlined, isBnode)...)
+				}
+			}
+			continue
+		}
+
 		if !fVal.CanInterface() {
 			continue
 		}
@@ -49,31 +309,87 @@ func TriplesFromStruct(sub string, i interface{}, bnodes ...bool) (out []Triple)
 			continue
 		}
 
-		pred := field.Tag.Get(predTag)
-		if tri, ok := buildTripleFromVal(sub, pred, fVal, isBnode); ok {
+		if tag.omitEmpty && isEmptyValue(fVal) {
+			continue
+		}
+
+		pred := tag.pred
+		if m, ok := asTripleMarshaler(fVal); ok {
+			if pred == "" {
+				continue
+			}
+			linked := linkedSubject(sub, pred)
+			tris, err := m.MarshalTriples(linked)
+			if err != nil {
+				continue
+			}
+			out = append(out, tris...)
+			out = append(out, linkTriple(sub, pred, isBnode, Resource(linked)))
+			continue
+		}
+
+		tri, isLit := buildTripleFromVal(sub, pred, fVal, isBnode, tag)
+		if isLit {
 			out = append(out, tri)
 		}
 
 		bnode, embedded := field.Tag.Lookup(bnodeTag)
 		fVal, ok := getStructOrPtrToStruct(fVal)
 		if embedded && ok {
+			if to, cycle := e.backRef(fVal); cycle {
+				if tag.hasPred {
+					out = append(out, linkTriple(sub, pred, isBnode, to.object()))
+				}
+				continue
+			}
 			if bnode == "" {
 				bnode = fmt.Sprintf("%x", rand.Uint32())
 			}
-			tris := TriplesFromStruct(bnode, fVal.Interface(), true)
+			tris := e.triplesFromStruct(bnode, fVal, true)
 			out = append(out, tris...)
-			if embedPred, hasPred := field.Tag.Lookup(predTag); hasPred {
-				out = append(out, SubjPred(sub, embedPred).Bnode(bnode))
+			if tag.hasPred {
+				out = append(out, linkTriple(sub, pred, isBnode, Bnode(bnode)))
 			}
 			continue
 		}
 
+		// native literal types not converted (ex: infinite *big.Float) are skipped, not linked
+		if !isLit && ok && pred != "" && intValue.Kind() == reflect.Ptr && !hasNativeLiteral(intValue.Type()) {
+			if to, cycle := e.backRef(fVal); cycle {
+				out = append(out, linkTriple(sub, pred, isBnode, to.object()))
+				continue
+			}
+			linked, hasTpl := structSubject(fVal)
+			if !hasTpl {
+				linked = linkedSubject(sub, pred)
+			}
+			out = append(out, e.triplesFromStruct(linked, fVal, false)...)
+			out = append(out, linkTriple(sub, pred, isBnode, Resource(linked)))
+			continue
+		}
+
 		switch fVal.Kind() {
 		case reflect.Slice:
+			if isLit {
+				continue
+			}
 			length := fVal.Len()
 			for i := 0; i < length; i++ {
 				sliceVal := fVal.Index(i)
-				if tri, ok := buildTripleFromVal(sub, pred, sliceVal, isBnode); ok {
+				if tri, ok := buildTripleFromVal(sub, pred, sliceVal, isBnode, tag); ok {
+					out = append(out, tri)
+				} else if pred != "" {
+					out = append(out, e.triplesFromSliceStruct(sub, pred, sliceVal, isBnode)...)
+				}
+			}
+		case reflect.Map:
+			if !tag.hasPred || fVal.Type().Key().Kind() != reflect.String {
+				continue
+			}
+			keys := fVal.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			for _, k := range keys {
+				if tri, ok := buildTripleFromVal(sub, pred+k.String(), fVal.MapIndex(k), isBnode, tag); ok {
 					out = append(out, tri)
 				}
 			}
@@ -84,22 +400,454 @@ func TriplesFromStruct(sub string, i interface{}, bnodes ...bool) (out []Triple)
 	return
 }
 
-func buildTripleFromVal(sub, pred string, v reflect.Value, bnode bool) (Triple, bool) {
+// TriplesFromStructs converts each element of a slice (or array) of structs
+// or ptrs to struct into triples (see TriplesFromStruct).
+// The subject of each element is given by subjectFn called with the element's
+// index and value; a nil subjectFn (or an empty subject) falls back
+// on the element's SubjectProvider implementation or subjectTemplate tag.
+// Non slice values and nil elements are ignored
+func TriplesFromStructs(subjectFn func(i int, v interface{}) string, slice interface{}) (out []Triple) {
+	val := reflect.ValueOf(slice)
+	if val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return
+	}
+
+	length := val.Len()
+	for i := 0; i < length; i++ {
+		elem := val.Index(i)
+		if (elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface) && elem.IsNil() {
+			continue
+		}
+		if !elem.CanInterface() {
+			continue
+		}
+		v := elem.Interface()
+		var sub string
+		if subjectFn != nil {
+			sub = subjectFn(i, v)
+		}
+		tris := TriplesFromStruct(sub, v)
+		if out == nil && len(tris) > 0 {
+			out = make([]Triple, 0, len(tris)*(length-i))
+		}
+		out = append(out, tris...)
+	}
+
+	return
+}
+
+// TriplesFromMap converts dynamic data (ex: decoded JSON) into triples
+// of the given subject, each key being a predicate:
+// - nil values are ignored
+// - slices (except []byte) emit a triple per element
+// - nested maps with string keys are emitted under their own random bnode
+// linked from the subject, as are structs (see TriplesFromStruct)
+// - other values are converted as struct fields values would be
+// Keys are processed in sorted order and unsupported values are ignored
+func TriplesFromMap(sub string, m map[string]interface{}) []Triple {
+	return newStructEncoder().triplesFromMap(sub, reflect.ValueOf(m), false)
+}
+
+func (e *structEncoder) triplesFromMap(sub string, m reflect.Value, isBnode bool) (out []Triple) {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, k := range keys {
+		out = append(out, e.triplesFromDynamicVal(sub, k.String(), m.MapIndex(k), isBnode)...)
+	}
+	return
+}
+
+func (e *structEncoder) triplesFromDynamicVal(sub, pred string, v reflect.Value, isBnode bool) (out []Triple) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Ptr && isStructOrPtrToStruct(v.Type()) {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || pred == "" {
+		return
+	}
+
+	if tri, ok := buildTripleFromVal(sub, pred, v, isBnode, fieldTag{}); ok {
+		return []Triple{tri}
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, e.triplesFromDynamicVal(sub, pred, v.Index(i), isBnode)...)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.IsNil() {
+			return
+		}
+		bnode := fmt.Sprintf("%x", rand.Uint32())
+		out = e.triplesFromMap(bnode, v, true)
+		out = append(out, linkTriple(sub, pred, isBnode, Bnode(bnode)))
+	case reflect.Struct, reflect.Ptr:
+		out = e.triplesFromSliceStruct(sub, pred, v, isBnode)
+	}
+	return
+}
+
+func buildTripleFromVal(sub, pred string, v reflect.Value, bnode bool, tag fieldTag) (Triple, bool) {
 	if !v.CanInterface() {
 		return nil, false
 	}
 	if pred == "" {
 		return nil, false
 	}
-	objLit, err := ObjectLiteral(v.Interface())
-	if err != nil {
+	if tag.resource || isURLType(v.Type()) {
+		res, ok := resourceFromVal(v)
+		if !ok {
+			return nil, false
+		}
+		return linkTriple(sub, pred, bnode, Resource(res)), true
+	}
+
+	var objLit Object
+	if tag.unit != 0 && v.Type() == durationType {
+		objLit = IntegerLiteral(int(time.Duration(v.Int()) / tag.unit))
+	} else if m, ok := asTextMarshaler(v); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return nil, false
+		}
+		objLit = StringLiteral(string(text))
+	} else if v.Type() == bigFloatType {
+		f := bigFloatFromVal(v)
+		if f.IsInf() {
+			return nil, false
+		}
+		objLit = BigDecimalLiteral(f)
+	} else {
+		var err error
+		if objLit, err = ObjectLiteral(v.Interface()); err != nil {
+			return nil, false
+		}
+	}
+	if tag.datatype != "" {
+		objLit = overrideDatatype(objLit, v, tag.datatype)
+	}
+	if lit, ok := objLit.Literal(); ok && len(tag.langs) > 0 && lit.Type() == XsdString {
+		objLit = StringLiteralWithLang(lit.Value(), tag.langs[0])
+	}
+
+	return linkTriple(sub, pred, bnode, objLit), true
+}
+
+// bigFloatFromVal returns the address of a big.Float value, or a deep copy when the value
+// is not addressable, as copied big.Float values share their mantissa
+func bigFloatFromVal(v reflect.Value) *big.Float {
+	if v.CanAddr() {
+		return v.Addr().Interface().(*big.Float)
+	}
+	f := v.Interface().(big.Float)
+	return new(big.Float).Copy(&f)
+}
+
+// resourceFromVal gives the IRI of a url.URL, a string or a fmt.Stringer value
+func resourceFromVal(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		if v.Type() != urlPtrType {
+			v = v.Elem()
+		}
+	}
+	switch {
+	case v.Type() == urlPtrType:
+		return v.Interface().(*url.URL).String(), true
+	case v.Type() == urlType:
+		u := v.Interface().(url.URL)
+		return u.String(), true
+	case v.Kind() == reflect.String:
+		return v.String(), v.Len() > 0
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return "", false
+}
+
+func isURLType(t reflect.Type) bool {
+	return t == urlType || t == urlPtrType
+}
+
+// overrideDatatype forces the datatype of a literal keeping its lexical form,
+// except for floats emitted as xsd:decimal which do not allow exponent notation
+func overrideDatatype(o Object, v reflect.Value, typ XsdType) Object {
+	obj := o.(object)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if typ == XsdDecimal {
+			obj.lit.val = strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+		}
+	}
+	obj.lit.typ = typ
+	return obj
+}
+
+// structSubject derives the subject of a struct from its SubjectProvider
+// implementation or else from its subjectTemplate tag
+func structSubject(val reflect.Value) (string, bool) {
+	if p, ok := asSubjectProvider(val); ok {
+		if sub := p.Subject(); sub != "" {
+			return sub, true
+		}
+	}
+	return subjectFromTemplate(val)
+}
+
+func asSubjectProvider(v reflect.Value) (SubjectProvider, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if p, ok := v.Interface().(SubjectProvider); ok {
+		return p, true
+	}
+	if v.CanAddr() {
+		if p, ok := v.Addr().Interface().(SubjectProvider); ok {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+var subjTplPlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// subjectFromTemplate derives the subject of a struct from a subjectTemplate tag
+// declared on any field (usually `_ struct{} subjectTemplate:"http://ex.com/people/{ID}"`)
+// where placeholders are replaced with the value of the named fields
+func subjectFromTemplate(val reflect.Value) (string, bool) {
+	st := val.Type()
+	for i := 0; i < st.NumField(); i++ {
+		tpl, ok := st.Field(i).Tag.Lookup(subjTplTag)
+		if !ok || tpl == "" {
+			continue
+		}
+		valid := true
+		sub := subjTplPlaceholder.ReplaceAllStringFunc(tpl, func(m string) string {
+			fVal := val.FieldByName(m[1 : len(m)-1])
+			if !fVal.IsValid() || !fVal.CanInterface() {
+				valid = false
+				return m
+			}
+			return fmt.Sprint(fVal.Interface())
+		})
+		return sub, valid
+	}
+	return "", false
+}
+
+// structRDFType returns the RDF type declared with a rdfType tag
+// on any field of the struct (usually `_ struct{} rdfType:"..."`)
+func structRDFType(st reflect.Type) (string, bool) {
+	for i := 0; i < st.NumField(); i++ {
+		if typ, ok := st.Field(i).Tag.Lookup(rdfTypeTag); ok && typ != "" {
+			return typ, true
+		}
+	}
+	return "", false
+}
+
+// predicateNaming derives the predicate of untagged fields from their name.
+// It is declared with a predicateNaming tag on any field of the struct
+// (usually `_ struct{} predicateNaming:"snake_case,prefix=ex:"`) giving the
+// strategy (camelCase, snake_case, or empty to keep the field name)
+// and an optional namespace prefix
+type predicateNaming struct {
+	strategy, prefix string
+}
+
+func structPredicateNaming(st reflect.Type) (predicateNaming, bool) {
+	for i := 0; i < st.NumField(); i++ {
+		value, ok := st.Field(i).Tag.Lookup(namingTag)
+		if !ok {
+			continue
+		}
+		splits := strings.Split(value, ",")
+		naming := predicateNaming{strategy: splits[0]}
+		for _, opt := range splits[1:] {
+			if strings.HasPrefix(opt, "prefix=") {
+				naming.prefix = strings.TrimPrefix(opt, "prefix=")
+			}
+		}
+		return naming, true
+	}
+	return predicateNaming{}, false
+}
+
+// applies reports whether the predicate of an untagged field is derived:
+// only exported fields are named, excluding embedded and map fields
+// as well as fields embedded under a bnode
+func (n predicateNaming) applies(field reflect.StructField) bool {
+	if field.PkgPath != "" || field.Anonymous || field.Name == "_" {
+		return false
+	}
+	if _, isBnode := field.Tag.Lookup(bnodeTag); isBnode {
+		return false
+	}
+	return field.Type.Kind() != reflect.Map
+}
+
+func (n predicateNaming) predicate(name string) string {
+	words := splitFieldName(name)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	switch n.strategy {
+	case "camelCase":
+		for i := 1; i < len(words); i++ {
+			r, size := utf8.DecodeRuneInString(words[i])
+			words[i] = string(unicode.ToUpper(r)) + words[i][size:]
+		}
+		name = strings.Join(words, "")
+	case "snake_case":
+		name = strings.Join(words, "_")
+	}
+	return n.prefix + name
+}
+
+// splitFieldName splits a Go identifier into words,
+// keeping acronyms together (ex: HTTPServerID gives HTTP, Server, ID)
+func splitFieldName(name string) (words []string) {
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		prev := runes[i-1]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if !unicode.IsUpper(prev) || nextIsLower {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+func hasRDFType(g RDFGraph, sub, typ string) bool {
+	for _, tri := range g.WithSubjPred(sub, rdfTypePred) {
+		if res, ok := tri.Object().Resource(); ok && res == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func asTripleMarshaler(v reflect.Value) (TripleMarshaler, bool) {
+	if m, ok := v.Interface().(TripleMarshaler); ok {
+		return m, true
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(TripleMarshaler); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// hasNativeLiteral reports whether the type is converted to a literal
+// without resorting to its encoding.TextMarshaler implementation
+func hasNativeLiteral(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || t == bigFloatType || t == bytesType || t == urlType {
+		return true
+	}
+	_, _, registered := lookupDatatypeByGoType(t)
+	return registered
+}
+
+// isTextType reports whether the type is mapped to a literal through
+// its encoding.TextMarshaler or TextUnmarshaler implementation
+func isTextType(t reflect.Type) bool {
+	if hasNativeLiteral(t) {
+		return false
+	}
+	return t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func asTextMarshaler(v reflect.Value) (encoding.TextMarshaler, bool) {
+	if hasNativeLiteral(v.Type()) || (v.Kind() == reflect.Ptr && v.IsNil()) {
 		return nil, false
 	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		return m, true
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
 
-	if bnode {
-		return BnodePred(sub, pred).Object(objLit), true
+func asTextUnmarshaler(v reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if hasNativeLiteral(v.Type()) || !v.CanAddr() {
+		return nil, false
 	}
-	return SubjPred(sub, pred).Object(objLit), true
+	u, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	return u, ok
+}
+
+func asTripleUnmarshaler(v reflect.Value) (TripleUnmarshaler, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		u, ok := v.Interface().(TripleUnmarshaler)
+		return u, ok
+	}
+	if v.CanAddr() {
+		u, ok := v.Addr().Interface().(TripleUnmarshaler)
+		return u, ok
+	}
+	return nil, false
+}
+
+// triplesFromSliceStruct creates the triples of a struct element of a slice,
+// linked from the parent subject either through the resource derived from
+// a subject template or through a random bnode
+func (e *structEncoder) triplesFromSliceStruct(sub, pred string, v reflect.Value, isBnode bool) (out []Triple) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return
+	}
+	v, ok := getStructOrPtrToStruct(v)
+	if !ok || !v.CanInterface() {
+		return
+	}
+	if to, cycle := e.backRef(v); cycle {
+		return []Triple{linkTriple(sub, pred, isBnode, to.object())}
+	}
+
+	var link Triple
+	if linked, hasTpl := structSubject(v); hasTpl {
+		out = e.triplesFromStruct(linked, v, false)
+		link = linkTriple(sub, pred, isBnode, Resource(linked))
+	} else {
+		bnode := fmt.Sprintf("%x", rand.Uint32())
+		out = e.triplesFromStruct(bnode, v, true)
+		link = linkTriple(sub, pred, isBnode, Bnode(bnode))
+	}
+
+	return append(out, link)
+}
+
+// linkedSubject derives the subject of a struct
+// referenced through a pointer field of a parent struct
+func linkedSubject(parent, pred string) string {
+	return parent + "/" + pred
 }
 
 func getStructOrPtrToStruct(v reflect.Value) (reflect.Value, bool) {
@@ -114,3 +862,542 @@ func getStructOrPtrToStruct(v reflect.Value) (reflect.Value, bool) {
 
 	return v, false
 }
+
+// Populate a ptr to Struct from the triples of the given subject
+// in a RDFGraph using field tags (i.e. the reverse of TriplesFromStruct).
+// For each struct's field with a predicate tag:
+// - the first triple object found for subject/predicate is converted to the field's type,
+// fields with the tag option "lang=en|fr" selecting the literals of the most preferred
+// language available (or else without language)
+// - slices are filled with all the triple objects found (in no particular order),
+// struct elements being populated from the referenced bnode or resource
+// - embedded structs with a bnode tag are populated from the referenced bnode
+// - inline structs (see TriplesFromStruct) are populated from the same subject
+// - pointers to struct are allocated and populated from the referenced resource
+// - types implementing TripleUnmarshaler are given the referenced resource
+// - types implementing encoding.TextUnmarshaler are given the literal value
+// - url.URL fields and fields with the "resource" tag option (strings, or
+// slices and maps of strings) are set from the resource IRI, literals being rejected
+// - maps are filled with the triples whose predicate starts with the tag value,
+// an empty tag value collecting all the predicates not mapped by other fields
+// References cycles in the graph are resolved as pointers to the struct
+// already being populated, or fail for non pointer destinations.
+// Fields without any matching triples are left untouched, unless tagged with
+// the option "required" in which case an error is returned.
+// A struct declaring a rdfType tag requires the subject to be of that type.
+// If the destination implements TripleUnmarshaler it is used instead.
+func StructFromTriples(g RDFGraph, sub string, dst interface{}) error {
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return errors.New("struct from triples: destination must be a non nil pointer to struct")
+	}
+	if u, ok := dst.(TripleUnmarshaler); ok {
+		return u.UnmarshalTriples(g, sub)
+	}
+	val = val.Elem()
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("struct from triples: destination must be a pointer to struct, got %T", dst)
+	}
+
+	return newStructDecoder(g).structFromTriples(sub, val)
+}
+
+// structDecoder populates structs from a graph, keeping track of the structs
+// being populated so that references cycles (ex: A -> B -> A) are resolved
+// as pointers to the struct already being populated
+type structDecoder struct {
+	g    RDFGraph
+	path map[decodeRef]reflect.Value
+}
+
+type decodeRef struct {
+	sub string
+	typ reflect.Type
+}
+
+func newStructDecoder(g RDFGraph) *structDecoder {
+	return &structDecoder{g: g, path: make(map[decodeRef]reflect.Value)}
+}
+
+// linkPtr sets the ptr to struct to the struct already being populated
+// from the subject, if any (i.e. on a references cycle)
+func (d *structDecoder) linkPtr(v reflect.Value, sub string) bool {
+	ptr, ok := d.path[decodeRef{sub: sub, typ: v.Type().Elem()}]
+	if !ok || !ptr.IsValid() {
+		return false
+	}
+	v.Set(ptr)
+	return true
+}
+
+func (d *structDecoder) structFromTriples(sub string, val reflect.Value) error {
+	g := d.g
+	st := val.Type()
+
+	ref := decodeRef{sub: sub, typ: st}
+	if _, cycle := d.path[ref]; cycle {
+		return fmt.Errorf("struct from triples: references cycle on %s", sub)
+	}
+	if val.CanAddr() {
+		d.path[ref] = val.Addr()
+	} else {
+		d.path[ref] = reflect.Value{}
+	}
+	defer delete(d.path, ref)
+
+	if typ, ok := structRDFType(st); ok {
+		if !hasRDFType(g, sub, typ) {
+			return fmt.Errorf("struct from triples: %s is not of type %s", sub, typ)
+		}
+	}
+
+	tags := structFieldTags(st)
+	for i := 0; i < st.NumField(); i++ {
+		field, fVal := st.Field(i), val.Field(i)
+		tag := tags[i]
+		if isInlineField(field, tag) {
+			if fVal.Kind() == reflect.Ptr {
+				if fVal.CanSet() && d.linkPtr(fVal, sub) {
+					continue
+				}
+				if !fVal.IsNil() {
+					fVal = fVal.Elem()
+				} else if fVal.CanSet() {
+					fVal.Set(reflect.New(fVal.Type().Elem()))
+					fVal = fVal.Elem()
+				} else {
+					continue
+				}
+			}
+			if err := d.structFromTriples(sub, fVal); err != nil {
+				return err
+			}
+			continue
+		}
+		if !fVal.CanSet() {
+			continue
+		}
+		if fVal.Kind() == reflect.Map {
+			if err := mapFromTriples(g, sub, field, fVal, val); err != nil {
+				return fmt.Errorf("struct from triples: field %s: %s", field.Name, err)
+			}
+			continue
+		}
+		pred := tag.pred
+		if pred == "" {
+			continue
+		}
+		tris := g.WithSubjPred(sub, pred)
+		if len(tag.langs) > 0 {
+			tris = selectByLang(tris, tag.langs)
+		}
+		if len(tris) == 0 {
+			if tag.required {
+				return fmt.Errorf("struct from triples: field %s: missing required predicate %s for %s", field.Name, pred, sub)
+			}
+			continue
+		}
+
+		if implementsTripleUnmarshaler(fVal.Type()) {
+			res, ok := tris[0].Object().Resource()
+			if _, isBnode := tris[0].Object().Bnode(); !ok || isBnode {
+				return fmt.Errorf("struct from triples: field %s: object is not a resource", field.Name)
+			}
+			u, _ := asTripleUnmarshaler(fVal)
+			if err := u.UnmarshalTriples(g, res); err != nil {
+				return fmt.Errorf("struct from triples: field %s: %s", field.Name, err)
+			}
+			continue
+		}
+
+		if isStructOrPtrToStruct(fVal.Type()) {
+			var linked string
+			if _, embedded := field.Tag.Lookup(bnodeTag); embedded {
+				bnode, ok := tris[0].Object().Bnode()
+				if !ok {
+					return fmt.Errorf("struct from triples: field %s: object is not a bnode", field.Name)
+				}
+				linked = bnode
+			} else if fVal.Kind() == reflect.Ptr {
+				res, ok := tris[0].Object().Resource()
+				if _, isBnode := tris[0].Object().Bnode(); !ok || isBnode {
+					return fmt.Errorf("struct from triples: field %s: object is not a resource", field.Name)
+				}
+				linked = res
+			}
+			if linked != "" {
+				if fVal.Kind() == reflect.Ptr {
+					if d.linkPtr(fVal, linked) {
+						continue
+					}
+					if fVal.IsNil() {
+						fVal.Set(reflect.New(fVal.Type().Elem()))
+					}
+					if err := d.structFromTriples(linked, fVal.Elem()); err != nil {
+						return err
+					}
+				} else if err := d.structFromTriples(linked, fVal); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if fVal.Kind() == reflect.Slice && fVal.Type() != bytesType && !isTextType(fVal.Type()) {
+			slice := reflect.MakeSlice(fVal.Type(), 0, len(tris))
+			for _, tri := range tris {
+				elem := reflect.New(fVal.Type().Elem()).Elem()
+				if isStructOrPtrToStruct(elem.Type()) {
+					if err := d.structFromLinkedObject(tri.Object(), elem); err != nil {
+						return fmt.Errorf("struct from triples: field %s: %s", field.Name, err)
+					}
+				} else if err := setFieldValueFromObject(elem, tri.Object(), tag); err != nil {
+					return fmt.Errorf("struct from triples: field %s: %s", field.Name, err)
+				}
+				slice = reflect.Append(slice, elem)
+			}
+			fVal.Set(slice)
+			continue
+		}
+
+		if err := setFieldValueFromObject(fVal, tris[0].Object(), tag); err != nil {
+			return fmt.Errorf("struct from triples: field %s: %s", field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func mapFromTriples(g RDFGraph, sub string, field reflect.StructField, fVal, parent reflect.Value) error {
+	tag := parseFieldTag(field)
+	prefix := tag.pred
+	if !tag.hasPred || fVal.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	mapped := make(map[string]bool)
+	var mappedPrefixes []string
+	if prefix == "" {
+		collectMappedPredicates(parent.Type(), mapped, &mappedPrefixes, make(map[reflect.Type]bool))
+	}
+
+	m := reflect.MakeMap(fVal.Type())
+	ranks := make(map[string]int)
+	for _, tri := range g.WithSubject(sub) {
+		pred := tri.Predicate()
+		if !strings.HasPrefix(pred, prefix) || mapped[pred] || hasAnyPrefix(pred, mappedPrefixes) {
+			continue
+		}
+		if len(tag.langs) > 0 {
+			rank := langRank(tri.Object(), tag.langs)
+			if best, seen := ranks[pred]; rank < 0 || (seen && best <= rank) {
+				continue
+			}
+			ranks[pred] = rank
+		}
+		elem := reflect.New(fVal.Type().Elem()).Elem()
+		if elem.Kind() == reflect.Interface {
+			parsed, err := ParseLiteral(tri.Object())
+			if err != nil {
+				return err
+			}
+			elem.Set(reflect.ValueOf(parsed))
+		} else if err := setFieldValueFromObject(elem, tri.Object(), tag); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(strings.TrimPrefix(pred, prefix)).Convert(fVal.Type().Key()), elem)
+	}
+
+	if m.Len() > 0 {
+		fVal.Set(m)
+	} else if tag.required {
+		return fmt.Errorf("missing required predicates with prefix '%s' for %s", prefix, sub)
+	}
+	return nil
+}
+
+// collectMappedPredicates gathers the predicates (and prefixes for maps)
+// of the fields of a struct type, including the ones of inline fields
+func collectMappedPredicates(st reflect.Type, mapped map[string]bool, prefixes *[]string, seen map[reflect.Type]bool) {
+	if seen[st] {
+		return
+	}
+	seen[st] = true
+	tags := structFieldTags(st)
+	for i := 0; i < st.NumField(); i++ {
+		field, tag := st.Field(i), tags[i]
+		if isInlineField(field, tag) {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			collectMappedPredicates(ft, mapped, prefixes, seen)
+			continue
+		}
+		if tag.pred == "" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Map {
+			*prefixes = append(*prefixes, tag.pred)
+		} else {
+			mapped[tag.pred] = true
+		}
+	}
+}
+
+// selectByLang keeps the triples whose object has the most preferred
+// language available, falling back on the objects without language
+func selectByLang(tris []Triple, langs []string) (out []Triple) {
+	best := -1
+	for _, tri := range tris {
+		rank := langRank(tri.Object(), langs)
+		switch {
+		case rank < 0:
+		case best < 0 || rank < best:
+			best, out = rank, []Triple{tri}
+		case rank == best:
+			out = append(out, tri)
+		}
+	}
+	return
+}
+
+// langRank gives the index of the object's language in the preferred languages,
+// len(langs) for objects without language and -1 for other languages
+func langRank(obj Object, langs []string) int {
+	lit, ok := obj.Literal()
+	if !ok || lit.Lang() == "" {
+		return len(langs)
+	}
+	for i, l := range langs {
+		if strings.EqualFold(l, lit.Lang()) {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// structFromLinkedObject populates a struct (or ptr to struct)
+// from the triples of the bnode or resource object
+func (d *structDecoder) structFromLinkedObject(obj Object, v reflect.Value) error {
+	linked, ok := obj.Bnode()
+	if !ok {
+		if _, isLit := obj.Literal(); isLit {
+			return errors.New("object is not a resource nor a bnode")
+		}
+		linked, _ = obj.Resource()
+	}
+	if v.Kind() == reflect.Ptr {
+		if d.linkPtr(v, linked) {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	return d.structFromTriples(linked, v)
+}
+
+var (
+	timeType              = reflect.TypeOf(time.Time{})
+	durationType          = reflect.TypeOf(time.Duration(0))
+	urlType               = reflect.TypeOf(url.URL{})
+	urlPtrType            = reflect.TypeOf(&url.URL{})
+	bytesType             = reflect.TypeOf([]byte(nil))
+	bigFloatType          = reflect.TypeOf(big.Float{})
+	tripleUnmarshalerType = reflect.TypeOf((*TripleUnmarshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func implementsTripleUnmarshaler(t reflect.Type) bool {
+	return t.Implements(tripleUnmarshalerType) || reflect.PtrTo(t).Implements(tripleUnmarshalerType)
+}
+
+// isStructOrPtrToStruct reports whether the type is a struct
+// (or pointer to struct) not mapped to a single literal
+func isStructOrPtrToStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isLiteralStructType(t)
+}
+
+// isLiteralStructType reports whether the struct type is mapped to a single
+// literal (or resource for url.URL)
+func isLiteralStructType(t reflect.Type) bool {
+	if t == timeType || t == bigFloatType || t == urlType {
+		return true
+	}
+	if isTextType(t) {
+		return true
+	}
+	_, _, registered := lookupDatatypeByGoType(t)
+	return registered
+}
+
+// setFieldValueFromObject sets the value according to the field's tag options,
+// durations with a unit being read from an integer literal
+// and url.URL or resource fields from a resource
+func setFieldValueFromObject(v reflect.Value, obj Object, tag fieldTag) error {
+	if tag.resource || isURLType(v.Type()) {
+		return setValueFromResource(v, obj)
+	}
+	if tag.unit == 0 || v.Type() != durationType {
+		return setValueFromObject(v, obj)
+	}
+	lit, ok := obj.Literal()
+	if !ok {
+		return errors.New("object is not a literal")
+	}
+	num, err := strconv.ParseInt(lit.Value(), 10, 64)
+	if err != nil {
+		return err
+	}
+	v.SetInt(num * int64(tag.unit))
+	return nil
+}
+
+func setValueFromResource(v reflect.Value, obj Object) error {
+	res, ok := obj.Resource()
+	if _, isBnode := obj.Bnode(); !ok || isBnode {
+		return errors.New("object is not a resource")
+	}
+
+	if v.Kind() == reflect.Ptr && v.Type() != urlPtrType {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValueFromResource(elem.Elem(), obj); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch {
+	case isURLType(v.Type()):
+		u, err := url.Parse(res)
+		if err != nil {
+			return err
+		}
+		if v.Type() == urlType {
+			v.Set(reflect.ValueOf(*u))
+		} else {
+			v.Set(reflect.ValueOf(u))
+		}
+	case v.Kind() == reflect.String:
+		v.SetString(res)
+	default:
+		return fmt.Errorf("cannot assign resource to %s", v.Type())
+	}
+	return nil
+}
+
+func setValueFromObject(v reflect.Value, obj Object) error {
+	lit, ok := obj.Literal()
+	if !ok {
+		return errors.New("object is not a literal")
+	}
+
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValueFromObject(elem.Elem(), obj); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if _, dt, ok := lookupDatatypeByGoType(v.Type()); ok {
+		parsed, err := dt.parse(lit.Type(), lit.Value())
+		if err != nil {
+			return err
+		}
+		pv := reflect.ValueOf(parsed)
+		if !pv.IsValid() || !pv.Type().ConvertibleTo(v.Type()) {
+			return fmt.Errorf("cannot assign %T to %s", parsed, v.Type())
+		}
+		v.Set(pv.Convert(v.Type()))
+		return nil
+	}
+
+	if v.Type() == bigFloatType {
+		f, err := ParseDecimal(obj)
+		if err != nil {
+			return err
+		}
+		v.Addr().Interface().(*big.Float).Set(f)
+		return nil
+	}
+
+	if v.Type() == bytesType {
+		b, err := ParseBinary(obj)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+		return nil
+	}
+
+	if u, ok := asTextUnmarshaler(v); ok {
+		return u.UnmarshalText([]byte(lit.Value()))
+	}
+
+	if v.Type() == durationType {
+		d, err := ParseDuration(obj)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	if v.Type() == timeType {
+		t, err := ParseDateTime(obj)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(lit.Value())
+	case reflect.Bool:
+		b, err := strconv.ParseBool(lit.Value())
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, err := strconv.ParseInt(lit.Value(), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(num)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, err := strconv.ParseUint(lit.Value(), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(num)
+	case reflect.Float32, reflect.Float64:
+		num, err := strconv.ParseFloat(lit.Value(), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(num)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
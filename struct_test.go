@@ -1,7 +1,14 @@
 package triplestore
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/big"
 	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -106,6 +113,71 @@ func TestEmbeddedStructToTriple(t *testing.T) {
 	})
 }
 
+type InlineBase struct {
+	ID      string `predicate:"id"`
+	Created int    `predicate:"created"`
+}
+
+type inlineAudit struct {
+	Author string `predicate:"author"`
+}
+
+func TestInlineEmbeddedStruct(t *testing.T) {
+	type geo struct {
+		Lat float64 `predicate:"lat"`
+	}
+	type doc struct {
+		InlineBase
+		*inlineAudit
+		Title string            `predicate:"title"`
+		Pos   geo               `predicate:",inline"`
+		Extra map[string]string `predicate:""`
+	}
+
+	d := doc{
+		InlineBase:  InlineBase{ID: "d1", Created: 2017},
+		inlineAudit: &inlineAudit{Author: "donald"},
+		Title:       "report",
+		Pos:         geo{Lat: 1.5},
+		Extra:       map[string]string{"lang": "en"},
+	}
+	tris := TriplesFromStruct("doc", d)
+	exp := []Triple{
+		SubjPred("doc", "id").StringLiteral("d1"),
+		SubjPred("doc", "created").IntegerLiteral(2017),
+		SubjPred("doc", "author").StringLiteral("donald"),
+		SubjPred("doc", "title").StringLiteral("report"),
+		SubjPred("doc", "lat").Float64Literal(1.5),
+		SubjPred("doc", "lang").StringLiteral("en"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	got := doc{inlineAudit: &inlineAudit{}} // unexported embedded pointers cannot be allocated
+	if err := StructFromTriples(src.Snapshot(), "doc", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, d) {
+		t.Fatalf("got %#v, want %#v", got, d)
+	}
+
+	type tagged struct {
+		InlineBase `predicate:"base" bnode:"b"`
+	}
+	tris = TriplesFromStruct("doc", tagged{InlineBase{ID: "d1"}})
+	exp = []Triple{
+		SubjPred("doc", "base").Bnode("b"),
+		BnodePred("b", "id").StringLiteral("d1"),
+		BnodePred("b", "created").IntegerLiteral(0),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+}
+
 func TestSimpleStructToTriple(t *testing.T) {
 	now := time.Now()
 	s := TestStruct{
@@ -178,3 +250,1137 @@ func TestReturnEmptyTriplesOnVoidPointers(t *testing.T) {
 		}
 	}
 }
+
+func TestStructFromTriples(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	s := TestStruct{
+		Name: "donald", Age: 32, Size: 186,
+		Male: true, Birth: now,
+		Surnames: []string{"one", "two", "three"},
+		Counts:   []int{1, 2, 3},
+	}
+
+	src := NewSource()
+	src.Add(TriplesFromStruct("me", s)...)
+
+	var got TestStruct
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != s.Name || got.Age != s.Age || got.Size != s.Size || got.Male != s.Male {
+		t.Fatalf("got %#v, want %#v", got, s)
+	}
+	if !got.Birth.Equal(now) {
+		t.Fatalf("got %s, want %s", got.Birth, now)
+	}
+	if got, want := len(got.Surnames), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := len(got.Counts), 3; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	t.Run("embedded bnode", func(t *testing.T) {
+		src := NewSource()
+		src.Add(TriplesFromStruct("me", OtherStruct{Name: "donald", Age: 32, E: Embedded{Size: 186, Male: true}})...)
+
+		var got OtherStruct
+		if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := got.E, (Embedded{Size: 186, Male: true}); got != want {
+			t.Fatalf("got %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("invalid destination", func(t *testing.T) {
+		var notPtr TestStruct
+		if err := StructFromTriples(src.Snapshot(), "me", notPtr); err == nil {
+			t.Fatal("expected error")
+		}
+		var str string
+		if err := StructFromTriples(src.Snapshot(), "me", &str); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("invalid literal", func(t *testing.T) {
+		src := NewSource()
+		src.Add(SubjPred("me", "age").StringLiteral("old"))
+		var got TestStruct
+		if err := StructFromTriples(src.Snapshot(), "me", &got); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+type Owner struct {
+	Name string `predicate:"name"`
+	Pet  *Pet   `predicate:"pet"`
+}
+
+type Pet struct {
+	Name string `predicate:"name"`
+	Age  int    `predicate:"age"`
+}
+
+func TestPointerToStructAsLinkedResource(t *testing.T) {
+	o := Owner{Name: "donald", Pet: &Pet{Name: "pluto", Age: 4}}
+
+	exp := []Triple{
+		SubjPred("me", "name").StringLiteral("donald"),
+		SubjPred("me", "pet").Resource("me/pet"),
+		SubjPred("me/pet", "name").StringLiteral("pluto"),
+		SubjPred("me/pet", "age").IntegerLiteral(4),
+	}
+
+	tris := TriplesFromStruct("me", o)
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+
+	var got Owner
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Pet == nil {
+		t.Fatal("expected pet to be set")
+	}
+	if got, want := *got.Pet, *o.Pet; got != want {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	tris = TriplesFromStruct("me", Owner{Name: "donald"})
+	if got, want := len(tris), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+type WithMaps struct {
+	Name  string                 `predicate:"name"`
+	Attrs map[string]string      `predicate:"attr:"`
+	Extra map[string]interface{} `predicate:""`
+}
+
+func TestMapFieldsToTriples(t *testing.T) {
+	s := WithMaps{
+		Name:  "donald",
+		Attrs: map[string]string{"color": "blue", "shape": "round"},
+		Extra: map[string]interface{}{"age": 32, "male": true},
+	}
+
+	exp := []Triple{
+		SubjPred("me", "name").StringLiteral("donald"),
+		SubjPred("me", "attr:color").StringLiteral("blue"),
+		SubjPred("me", "attr:shape").StringLiteral("round"),
+		SubjPred("me", "age").IntegerLiteral(32),
+		SubjPred("me", "male").BooleanLiteral(true),
+	}
+
+	tris := TriplesFromStruct("me", s)
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+
+	var got WithMaps
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.Attrs, s.Attrs; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := got.Extra, s.Extra; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+type GeoPoint struct {
+	Lat, Long float64
+}
+
+func (p GeoPoint) MarshalTriples(sub string) ([]Triple, error) {
+	return []Triple{
+		SubjPred(sub, "geo:lat").Float64Literal(p.Lat),
+		SubjPred(sub, "geo:long").Float64Literal(p.Long),
+	}, nil
+}
+
+func (p *GeoPoint) UnmarshalTriples(g RDFGraph, sub string) (err error) {
+	if tris := g.WithSubjPred(sub, "geo:lat"); len(tris) > 0 {
+		if p.Lat, err = ParseFloat64(tris[0].Object()); err != nil {
+			return
+		}
+	}
+	if tris := g.WithSubjPred(sub, "geo:long"); len(tris) > 0 {
+		if p.Long, err = ParseFloat64(tris[0].Object()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type Place struct {
+	Name     string    `predicate:"name"`
+	Location GeoPoint  `predicate:"location"`
+	Other    *GeoPoint `predicate:"other"`
+}
+
+func TestTripleMarshalerAndUnmarshaler(t *testing.T) {
+	p := Place{Name: "paris", Location: GeoPoint{48.85, 2.35}, Other: &GeoPoint{1, 2}}
+
+	exp := []Triple{
+		SubjPred("me", "name").StringLiteral("paris"),
+		SubjPred("me", "location").Resource("me/location"),
+		SubjPred("me/location", "geo:lat").Float64Literal(48.85),
+		SubjPred("me/location", "geo:long").Float64Literal(2.35),
+		SubjPred("me", "other").Resource("me/other"),
+		SubjPred("me/other", "geo:lat").Float64Literal(1),
+		SubjPred("me/other", "geo:long").Float64Literal(2),
+	}
+
+	tris := TriplesFromStruct("me", p)
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+
+	var got Place
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Location != p.Location || got.Other == nil || *got.Other != *p.Other {
+		t.Fatalf("got %#v, want %#v", got, p)
+	}
+
+	tris = TriplesFromStruct("here", GeoPoint{1, 2})
+	if got, want := len(tris), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	var point GeoPoint
+	src.Add(tris...)
+	if err := StructFromTriples(src.Snapshot(), "here", &point); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := point, (GeoPoint{1, 2}); got != want {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestOmitEmptyTagOption(t *testing.T) {
+	type optional struct {
+		Name     string    `predicate:"name,omitempty"`
+		Age      int       `predicate:"age,omitempty"`
+		Birth    time.Time `predicate:"birth,omitempty"`
+		Surnames []string  `predicate:"surnames,omitempty"`
+		Male     bool      `predicate:"male"`
+	}
+
+	tris := TriplesFromStruct("me", optional{})
+	exp := []Triple{SubjPred("me", "male").BooleanLiteral(false)}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	tris = TriplesFromStruct("me", optional{Name: "donald", Age: 32})
+	exp = []Triple{
+		SubjPred("me", "name").StringLiteral("donald"),
+		SubjPred("me", "age").IntegerLiteral(32),
+		SubjPred("me", "male").BooleanLiteral(false),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got optional
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "donald" || got.Age != 32 {
+		t.Fatalf("got %#v", got)
+	}
+}
+
+func TestDatatypeTagOption(t *testing.T) {
+	type measure struct {
+		Weight float64 `predicate:"weight,datatype=xsd:decimal"`
+		Big    float64 `predicate:"big,datatype=xsd:decimal"`
+		Code   int     `predicate:"code,datatype=xsd:string"`
+	}
+
+	tris := TriplesFromStruct("me", measure{Weight: 72.5, Big: 1e21, Code: 42})
+	exp := []Triple{
+		SubjPred("me", "weight").Object(object{isLit: true, lit: literal{typ: XsdDecimal, val: "72.5"}}),
+		SubjPred("me", "big").Object(object{isLit: true, lit: literal{typ: XsdDecimal, val: "1000000000000000000000"}}),
+		SubjPred("me", "code").StringLiteral("42"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got measure
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got, (measure{Weight: 72.5, Big: 1e21, Code: 42}); got != want {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
+
+type celsius float64
+
+type level uint8
+
+func TestNumericStructFields(t *testing.T) {
+	type numerics struct {
+		F32    float32   `predicate:"f32"`
+		F64    float64   `predicate:"f64"`
+		U      uint      `predicate:"u"`
+		U8     uint8     `predicate:"u8"`
+		U16    uint16    `predicate:"u16"`
+		U32    uint32    `predicate:"u32"`
+		U64    uint64    `predicate:"u64"`
+		I8     int8      `predicate:"i8"`
+		I16    int16     `predicate:"i16"`
+		I32    int32     `predicate:"i32"`
+		Temp   celsius   `predicate:"temp"`
+		Level  level     `predicate:"level"`
+		Floats []float64 `predicate:"floats"`
+		Uints  []uint16  `predicate:"uints"`
+	}
+
+	n := numerics{
+		F32: 1.5, F64: -2.25, U: 3, U8: 4, U16: 5, U32: 6, U64: 7,
+		I8: -8, I16: -9, I32: -10, Temp: 21.5, Level: 3,
+		Floats: []float64{0.5}, Uints: []uint16{12},
+	}
+
+	exp := []Triple{
+		SubjPred("me", "f32").Float32Literal(1.5),
+		SubjPred("me", "f64").Float64Literal(-2.25),
+		SubjPred("me", "u").UintegerLiteral(3),
+		SubjPred("me", "u8").Uint8(4),
+		SubjPred("me", "u16").Uint16(5),
+		SubjPred("me", "u32").UintegerLiteral(6),
+		SubjPred("me", "u64").UintegerLiteral(7),
+		SubjPred("me", "i8").Int8Literal(-8),
+		SubjPred("me", "i16").Int16Literal(-9),
+		SubjPred("me", "i32").IntegerLiteral(-10),
+		SubjPred("me", "temp").Float64Literal(21.5),
+		SubjPred("me", "level").Uint8(3),
+		SubjPred("me", "floats").Float64Literal(0.5),
+		SubjPred("me", "uints").Uint16(12),
+	}
+
+	tris := TriplesFromStruct("me", n)
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got numerics
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, n) {
+		t.Fatalf("got %#v, want %#v", got, n)
+	}
+}
+
+func TestRDFTypeTag(t *testing.T) {
+	type person struct {
+		_    struct{} `rdfType:"foaf:Person"`
+		Name string   `predicate:"name"`
+	}
+
+	tris := TriplesFromStruct("me", person{Name: "donald"})
+	exp := []Triple{
+		SubjPred("me", "rdf:type").Resource("foaf:Person"),
+		SubjPred("me", "name").StringLiteral("donald"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got person
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.Name, "donald"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	src.Remove(exp[0])
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err == nil {
+		t.Fatal("expected error on missing rdf:type")
+	}
+}
+
+func TestSubjectTemplateTag(t *testing.T) {
+	type dog struct {
+		_    struct{} `subjectTemplate:"http://ex.com/dogs/{Name}"`
+		Name string   `predicate:"name"`
+	}
+	type person struct {
+		_    struct{} `subjectTemplate:"http://ex.com/people/{ID}"`
+		ID   int      `predicate:"id"`
+		Name string   `predicate:"name"`
+		Dog  *dog     `predicate:"dog"`
+	}
+
+	tris := TriplesFromStruct("", person{ID: 12, Name: "donald", Dog: &dog{Name: "pluto"}})
+	exp := []Triple{
+		SubjPred("http://ex.com/people/12", "id").IntegerLiteral(12),
+		SubjPred("http://ex.com/people/12", "name").StringLiteral("donald"),
+		SubjPred("http://ex.com/people/12", "dog").Resource("http://ex.com/dogs/pluto"),
+		SubjPred("http://ex.com/dogs/pluto", "name").StringLiteral("pluto"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	tris = TriplesFromStruct("explicit", person{ID: 12})
+	if got, want := tris[0].Subject(), "explicit"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	type invalid struct {
+		_ struct{} `subjectTemplate:"http://ex.com/{Unknown}"`
+		A string   `predicate:"a"`
+	}
+	if got, want := len(TriplesFromStruct("", invalid{A: "a"})), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func TestSliceOfStructs(t *testing.T) {
+	type child struct {
+		Name string `predicate:"name"`
+		Age  int    `predicate:"age"`
+	}
+	type named struct {
+		_    struct{} `subjectTemplate:"http://ex.com/{Name}"`
+		Name string   `predicate:"name"`
+	}
+	type parent struct {
+		Name     string   `predicate:"name"`
+		Children []child  `predicate:"child"`
+		Friends  []*named `predicate:"friend"`
+	}
+
+	p := parent{
+		Name:     "donald",
+		Children: []child{{"riri", 5}, {"fifi", 6}},
+		Friends:  []*named{{Name: "mickey"}},
+	}
+
+	tris := TriplesFromStruct("me", p)
+	if got, want := len(tris), 9; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	snap := src.Snapshot()
+
+	if got, want := len(snap.WithSubjPred("me", "child")), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if tri := SubjPred("me", "friend").Resource("http://ex.com/mickey"); !snap.Contains(tri) {
+		t.Fatalf("snap should contains %v", tri)
+	}
+
+	var got parent
+	if err := StructFromTriples(snap, "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(got.Children), 2; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	names := map[string]int{}
+	for _, c := range got.Children {
+		names[c.Name] = c.Age
+	}
+	if got, want := names, map[string]int{"riri": 5, "fifi": 6}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(got.Friends) != 1 || got.Friends[0].Name != "mickey" {
+		t.Fatalf("got %#v", got.Friends)
+	}
+}
+
+func TestTriplesFromStructs(t *testing.T) {
+	type person struct {
+		_    struct{} `subjectTemplate:"http://ex.com/people/{Name}"`
+		Name string   `predicate:"name"`
+		Age  int      `predicate:"age"`
+	}
+
+	people := []person{{Name: "donald", Age: 32}, {Name: "mickey", Age: 28}}
+	tris := TriplesFromStructs(func(i int, v interface{}) string {
+		return fmt.Sprintf("p%d-%s", i, v.(person).Name)
+	}, people)
+	exp := []Triple{
+		SubjPred("p0-donald", "name").StringLiteral("donald"),
+		SubjPred("p0-donald", "age").IntegerLiteral(32),
+		SubjPred("p1-mickey", "name").StringLiteral("mickey"),
+		SubjPred("p1-mickey", "age").IntegerLiteral(28),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	tris = TriplesFromStructs(nil, []*person{{Name: "donald", Age: 32}, nil})
+	exp = []Triple{
+		SubjPred("http://ex.com/people/donald", "name").StringLiteral("donald"),
+		SubjPred("http://ex.com/people/donald", "age").IntegerLiteral(32),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	if got, want := len(TriplesFromStructs(nil, person{Name: "donald"})), 0; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+type providedAnimal struct {
+	ID   int    `predicate:"id"`
+	Name string `predicate:"name"`
+}
+
+func (a providedAnimal) Subject() string {
+	return fmt.Sprintf("http://ex.com/animals/%d", a.ID)
+}
+
+type providedOwner struct {
+	Name string          `predicate:"name"`
+	Pet  *providedAnimal `predicate:"pet"`
+}
+
+func (o *providedOwner) Subject() string {
+	return "http://ex.com/owners/" + o.Name
+}
+
+func TestSubjectProvider(t *testing.T) {
+	tris := TriplesFromStruct("", &providedOwner{Name: "donald", Pet: &providedAnimal{ID: 1, Name: "pluto"}})
+	exp := []Triple{
+		SubjPred("http://ex.com/owners/donald", "name").StringLiteral("donald"),
+		SubjPred("http://ex.com/owners/donald", "pet").Resource("http://ex.com/animals/1"),
+		SubjPred("http://ex.com/animals/1", "id").IntegerLiteral(1),
+		SubjPred("http://ex.com/animals/1", "name").StringLiteral("pluto"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	tris = TriplesFromStruct("explicit", providedAnimal{ID: 2})
+	if got, want := tris[0].Subject(), "explicit"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	tris = TriplesFromStructs(nil, []providedAnimal{{ID: 1, Name: "pluto"}, {ID: 2, Name: "rex"}})
+	exp = []Triple{
+		SubjPred("http://ex.com/animals/1", "id").IntegerLiteral(1),
+		SubjPred("http://ex.com/animals/1", "name").StringLiteral("pluto"),
+		SubjPred("http://ex.com/animals/2", "id").IntegerLiteral(2),
+		SubjPred("http://ex.com/animals/2", "name").StringLiteral("rex"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+}
+
+func TestDurationStructField(t *testing.T) {
+	type job struct {
+		Timeout  time.Duration            `predicate:"timeout"`
+		Delay    time.Duration            `predicate:"delay,unit=ms"`
+		Interval time.Duration            `predicate:"interval,unit=s"`
+		Steps    []time.Duration          `predicate:"step"`
+		Limits   map[string]time.Duration `predicate:"limit:,unit=m"`
+	}
+
+	j := job{
+		Timeout:  90 * time.Minute,
+		Delay:    1500 * time.Millisecond,
+		Interval: 30 * time.Second,
+		Steps:    []time.Duration{time.Second},
+		Limits:   map[string]time.Duration{"cpu": 2 * time.Hour},
+	}
+	tris := TriplesFromStruct("job", j)
+	exp := []Triple{
+		SubjPred("job", "timeout").DurationLiteral(90 * time.Minute),
+		SubjPred("job", "delay").IntegerLiteral(1500),
+		SubjPred("job", "interval").IntegerLiteral(30),
+		SubjPred("job", "step").DurationLiteral(time.Second),
+		SubjPred("job", "limit:cpu").IntegerLiteral(120),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got job
+	if err := StructFromTriples(src.Snapshot(), "job", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, j) {
+		t.Fatalf("got %#v, want %#v", got, j)
+	}
+
+	src = NewSource()
+	src.Add(SubjPred("job", "timeout").IntegerLiteral(90))
+	if err := StructFromTriples(src.Snapshot(), "job", &got); err == nil {
+		t.Fatal("expected error for integer literal without unit")
+	}
+}
+
+func TestResourceStructFields(t *testing.T) {
+	type link struct {
+		Homepage *url.URL  `predicate:"homepage"`
+		Source   url.URL   `predicate:"source"`
+		Knows    []string  `predicate:"knows,resource"`
+		Boss     string    `predicate:"boss,resource"`
+		Manager  *string   `predicate:"manager,resource"`
+		Name     string    `predicate:"name"`
+		Mirrors  []url.URL `predicate:"mirror"`
+	}
+
+	home, _ := url.Parse("http://ex.com/donald")
+	manager := "http://ex.com/scrooge"
+	l := link{
+		Homepage: home,
+		Source:   url.URL{Scheme: "http", Host: "ex.com", Path: "/src"},
+		Knows:    []string{"http://ex.com/mickey", "http://ex.com/goofy"},
+		Boss:     "http://ex.com/walt",
+		Manager:  &manager,
+		Name:     "donald",
+		Mirrors:  []url.URL{{Scheme: "ftp", Host: "mirror.ex.com"}},
+	}
+	tris := TriplesFromStruct("me", l)
+	exp := []Triple{
+		SubjPred("me", "homepage").Resource("http://ex.com/donald"),
+		SubjPred("me", "source").Resource("http://ex.com/src"),
+		SubjPred("me", "knows").Resource("http://ex.com/mickey"),
+		SubjPred("me", "knows").Resource("http://ex.com/goofy"),
+		SubjPred("me", "boss").Resource("http://ex.com/walt"),
+		SubjPred("me", "manager").Resource("http://ex.com/scrooge"),
+		SubjPred("me", "name").StringLiteral("donald"),
+		SubjPred("me", "mirror").Resource("ftp://mirror.ex.com"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got link
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got.Knows)
+	sort.Strings(l.Knows)
+	if !reflect.DeepEqual(got, l) {
+		t.Fatalf("got %#v, want %#v", got, l)
+	}
+
+	src = NewSource()
+	src.Add(SubjPred("me", "boss").StringLiteral("walt"))
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err == nil {
+		t.Fatal("expected error for literal object of resource field")
+	}
+}
+
+func TestResourceTagOption(t *testing.T) {
+	type friend struct {
+		Name string `predicate:"name"`
+		Page string `predicate:"page,resource"`
+	}
+	type person struct {
+		Links  map[string]string `predicate:"link:,resource"`
+		Friend friend            `predicate:"friend" bnode:"f"`
+		Empty  string            `predicate:"empty,resource"`
+		Age    int               `predicate:"age,resource"`
+	}
+
+	p := person{
+		Links:  map[string]string{"blog": "http://ex.com/blog"},
+		Friend: friend{Name: "mickey", Page: "http://ex.com/mickey"},
+		Age:    32,
+	}
+	tris := TriplesFromStruct("me", p)
+	exp := []Triple{
+		SubjPred("me", "link:blog").Resource("http://ex.com/blog"),
+		SubjPred("me", "friend").Bnode("f"),
+		BnodePred("f", "name").StringLiteral("mickey"),
+		BnodePred("f", "page").Resource("http://ex.com/mickey"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got person
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	p.Age = 0
+	if !reflect.DeepEqual(got, p) {
+		t.Fatalf("got %#v, want %#v", got, p)
+	}
+}
+
+type textPoint struct {
+	X, Y int
+}
+
+func (p textPoint) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d,%d", p.X, p.Y)), nil
+}
+
+func (p *textPoint) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d,%d", &p.X, &p.Y)
+	return err
+}
+
+func TestTextMarshalerStructFields(t *testing.T) {
+	type host struct {
+		IP     net.IP      `predicate:"ip"`
+		Pos    textPoint   `predicate:"pos"`
+		Origin *textPoint  `predicate:"origin"`
+		Path   []textPoint `predicate:"path"`
+		Birth  time.Time   `predicate:"birth"`
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	h := host{
+		IP:     net.ParseIP("10.0.0.1"),
+		Pos:    textPoint{1, 2},
+		Origin: &textPoint{0, 0},
+		Path:   []textPoint{{3, 4}},
+		Birth:  now,
+	}
+	tris := TriplesFromStruct("h", h)
+	exp := []Triple{
+		SubjPred("h", "ip").StringLiteral("10.0.0.1"),
+		SubjPred("h", "pos").StringLiteral("1,2"),
+		SubjPred("h", "origin").StringLiteral("0,0"),
+		SubjPred("h", "path").StringLiteral("3,4"),
+		SubjPred("h", "birth").DateTimeLiteral(now),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got host
+	if err := StructFromTriples(src.Snapshot(), "h", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, h) {
+		t.Fatalf("got %#v, want %#v", got, h)
+	}
+
+	src = NewSource()
+	src.Add(SubjPred("h", "pos").StringLiteral("invalid"))
+	if err := StructFromTriples(src.Snapshot(), "h", &got); err == nil {
+		t.Fatal("expected error for invalid text")
+	}
+}
+
+func TestTriplesFromMap(t *testing.T) {
+	var data map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"name": "donald",
+		"age": 32,
+		"male": true,
+		"nothing": null,
+		"surnames": ["duck", "dd"],
+		"address": {"city": "Duckburg", "zip": "12345"},
+		"pets": [{"name": "pluto"}]
+	}`), &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data["birth"] = time.Date(1934, 6, 9, 0, 0, 0, 0, time.UTC)
+	data["home"] = &url.URL{Scheme: "http", Host: "duckburg.com"}
+	data[""] = "ignored"
+
+	tris := TriplesFromMap("me", data)
+	src := NewSource()
+	src.Add(tris...)
+	snap := src.Snapshot()
+
+	for _, tri := range []Triple{
+		SubjPred("me", "name").StringLiteral("donald"),
+		SubjPred("me", "age").Float64Literal(32),
+		SubjPred("me", "male").BooleanLiteral(true),
+		SubjPred("me", "surnames").StringLiteral("duck"),
+		SubjPred("me", "surnames").StringLiteral("dd"),
+		SubjPred("me", "birth").DateTimeLiteral(time.Date(1934, 6, 9, 0, 0, 0, 0, time.UTC)),
+		SubjPred("me", "home").Resource("http://duckburg.com"),
+	} {
+		if !snap.Contains(tri) {
+			t.Fatalf("snap should contains %v", tri)
+		}
+	}
+	if got, want := snap.Count(), 12; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	addr := snap.WithSubjPred("me", "address")
+	if got, want := len(addr), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	bnode, ok := addr[0].Object().Bnode()
+	if !ok {
+		t.Fatalf("expected bnode object, got %v", addr[0])
+	}
+	if tri := BnodePred(bnode, "city").StringLiteral("Duckburg"); !snap.Contains(tri) {
+		t.Fatalf("snap should contains %v", tri)
+	}
+	pets := snap.WithSubjPred("me", "pets")
+	if got, want := len(pets), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	bnode, _ = pets[0].Object().Bnode()
+	if tri := BnodePred(bnode, "name").StringLiteral("pluto"); !snap.Contains(tri) {
+		t.Fatalf("snap should contains %v", tri)
+	}
+}
+
+func TestRequiredTagOption(t *testing.T) {
+	type address struct {
+		City string `predicate:"city,required"`
+	}
+	type person struct {
+		ID    string            `predicate:"id,required"`
+		Name  string            `predicate:"name"`
+		Addr  *address          `predicate:"address"`
+		Links map[string]string `predicate:"link:,required"`
+	}
+
+	tcases := []struct {
+		tris []Triple
+		err  string
+	}{
+		{
+			tris: []Triple{SubjPred("me", "id").StringLiteral("1"), SubjPred("me", "link:blog").StringLiteral("b")},
+		},
+		{
+			tris: []Triple{SubjPred("me", "name").StringLiteral("donald"), SubjPred("me", "link:blog").StringLiteral("b")},
+			err:  "struct from triples: field ID: missing required predicate id for me",
+		},
+		{
+			tris: []Triple{SubjPred("me", "id").StringLiteral("1")},
+			err:  "struct from triples: field Links: missing required predicates with prefix 'link:' for me",
+		},
+		{
+			tris: []Triple{
+				SubjPred("me", "id").StringLiteral("1"),
+				SubjPred("me", "link:blog").StringLiteral("b"),
+				SubjPred("me", "address").Resource("addr"),
+			},
+			err: "struct from triples: field City: missing required predicate city for addr",
+		},
+	}
+
+	for i, tc := range tcases {
+		src := NewSource()
+		src.Add(tc.tris...)
+		var p person
+		err := StructFromTriples(src.Snapshot(), "me", &p)
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if want := tc.err; got != want {
+			t.Fatalf("case %d: got error '%s', want '%s'", i+1, got, want)
+		}
+	}
+}
+
+func TestPredicateNamingTag(t *testing.T) {
+	tcases := []struct {
+		naming predicateNaming
+		name   string
+		exp    string
+	}{
+		{predicateNaming{strategy: "camelCase"}, "FirstName", "firstName"},
+		{predicateNaming{strategy: "camelCase"}, "HTTPServerID", "httpServerId"},
+		{predicateNaming{strategy: "camelCase"}, "ID", "id"},
+		{predicateNaming{strategy: "camelCase"}, "ÉtatÉlève", "étatÉlève"},
+		{predicateNaming{strategy: "snake_case"}, "FirstName", "first_name"},
+		{predicateNaming{strategy: "snake_case"}, "HTTPServerID", "http_server_id"},
+		{predicateNaming{strategy: "snake_case"}, "Address2", "address2"},
+		{predicateNaming{strategy: "snake_case", prefix: "ex:"}, "Age", "ex:age"},
+		{predicateNaming{prefix: "ex:"}, "FirstName", "ex:FirstName"},
+	}
+	for i, tc := range tcases {
+		if got, want := tc.naming.predicate(tc.name), tc.exp; got != want {
+			t.Fatalf("case %d: got %s, want %s", i+1, got, want)
+		}
+	}
+
+	type address struct {
+		_          struct{} `predicateNaming:"camelCase"`
+		StreetName string
+	}
+	type person struct {
+		_          struct{} `predicateNaming:"snake_case,prefix=ex:"`
+		FirstName  string
+		Age        int    `predicate:"age"`
+		Secret     string `predicate:"-"`
+		Home       *address
+		Extra      map[string]string
+		unexported string
+	}
+
+	p := person{FirstName: "donald", Age: 32, Secret: "s", Home: &address{StreetName: "5th avenue"}, unexported: "u"}
+	tris := TriplesFromStruct("me", p)
+	exp := []Triple{
+		SubjPred("me", "ex:first_name").StringLiteral("donald"),
+		SubjPred("me", "age").IntegerLiteral(32),
+		SubjPred("me", "ex:home").Resource("me/ex:home"),
+		SubjPred("me/ex:home", "streetName").StringLiteral("5th avenue"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got person
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	p.Secret, p.unexported = "", ""
+	if !reflect.DeepEqual(got, p) {
+		t.Fatalf("got %#v, want %#v", got, p)
+	}
+}
+
+func TestLangTagOption(t *testing.T) {
+	type movie struct {
+		Title  string            `predicate:"title,lang=fr|en"`
+		Tags   []string          `predicate:"tag,lang=en"`
+		Labels map[string]string `predicate:"label:,lang=fr|en"`
+		Year   int               `predicate:"year,lang=en"`
+	}
+
+	m := movie{Title: "Le Roi Lion", Tags: []string{"animation"}, Labels: map[string]string{"short": "Roi"}, Year: 1994}
+	tris := TriplesFromStruct("lk", m)
+	exp := []Triple{
+		SubjPred("lk", "title").StringLiteralWithLang("Le Roi Lion", "fr"),
+		SubjPred("lk", "tag").StringLiteralWithLang("animation", "en"),
+		SubjPred("lk", "label:short").StringLiteralWithLang("Roi", "fr"),
+		SubjPred("lk", "year").IntegerLiteral(1994),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(
+		SubjPred("lk", "title").StringLiteralWithLang("The Lion King", "en"),
+		SubjPred("lk", "title").StringLiteralWithLang("Der König der Löwen", "de"),
+		SubjPred("lk", "tag").StringLiteralWithLang("animation", "EN"),
+		SubjPred("lk", "tag").StringLiteralWithLang("Zeichentrick", "de"),
+		SubjPred("lk", "tag").StringLiteral("disney"),
+		SubjPred("lk", "label:short").StringLiteralWithLang("Lion", "en"),
+		SubjPred("lk", "label:short").StringLiteralWithLang("Lion", "fr"),
+		SubjPred("lk", "label:long").StringLiteralWithLang("Löwen", "de"),
+	)
+	var got movie
+	if err := StructFromTriples(src.Snapshot(), "lk", &got); err != nil {
+		t.Fatal(err)
+	}
+	want := movie{Title: "The Lion King", Tags: []string{"animation"}, Labels: map[string]string{"short": "Lion"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	tcases := []struct {
+		langs []string
+		exp   []string
+	}{
+		{[]string{"fr", "en"}, []string{"bonjour"}},
+		{[]string{"de"}, []string{"hi", "hey"}},
+		{[]string{"en"}, []string{"hello"}},
+	}
+	tris = []Triple{
+		SubjPred("s", "p").StringLiteralWithLang("hello", "en"),
+		SubjPred("s", "p").StringLiteral("hi"),
+		SubjPred("s", "p").StringLiteralWithLang("bonjour", "fr"),
+		SubjPred("s", "p").StringLiteral("hey"),
+	}
+	for i, tc := range tcases {
+		var got []string
+		for _, tri := range selectByLang(tris, tc.langs) {
+			lit, _ := tri.Object().Literal()
+			got = append(got, lit.Value())
+		}
+		if !reflect.DeepEqual(got, tc.exp) {
+			t.Fatalf("case %d: got %v, want %v", i+1, got, tc.exp)
+		}
+	}
+}
+
+type cycleNode struct {
+	Name    string       `predicate:"name"`
+	Next    *cycleNode   `predicate:"next"`
+	Friends []*cycleNode `predicate:"friend"`
+}
+
+type cycleValueNode struct {
+	Name     string           `predicate:"name"`
+	Children []cycleValueNode `predicate:"child"`
+}
+
+func TestStructReferencesCycle(t *testing.T) {
+	a := &cycleNode{Name: "a"}
+	b := &cycleNode{Name: "b", Next: a}
+	a.Next = b
+	a.Friends = []*cycleNode{a}
+
+	tris := TriplesFromStruct("a", a)
+	exp := []Triple{
+		SubjPred("a", "name").StringLiteral("a"),
+		SubjPred("a", "next").Resource("a/next"),
+		SubjPred("a/next", "name").StringLiteral("b"),
+		SubjPred("a/next", "next").Resource("a"),
+		SubjPred("a", "friend").Resource("a"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got cycleNode
+	if err := StructFromTriples(src.Snapshot(), "a", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Next == nil || got.Next.Name != "b" || got.Next.Next != &got {
+		t.Fatalf("got %#v, want a -> b -> a", got)
+	}
+	if len(got.Friends) != 1 || got.Friends[0] != &got {
+		t.Fatalf("got %#v, want a as its own friend", got.Friends)
+	}
+
+	src = NewSource()
+	src.Add(
+		SubjPred("x", "name").StringLiteral("x"),
+		SubjPred("x", "child").Resource("y"),
+		SubjPred("y", "name").StringLiteral("y"),
+		SubjPred("y", "child").Resource("x"),
+	)
+	var values cycleValueNode
+	err := StructFromTriples(src.Snapshot(), "x", &values)
+	if err == nil || !strings.Contains(err.Error(), "references cycle on x") {
+		t.Fatalf("got %v, want references cycle error", err)
+	}
+}
+
+type cycleHome struct {
+	Name  string      `predicate:"name"`
+	Owner *cycleOwner `predicate:"owner" bnode:"o"`
+}
+
+type cycleOwner struct {
+	Name string     `predicate:"name"`
+	Home *cycleHome `predicate:"home" bnode:"h"`
+}
+
+func TestEmbeddedStructReferencesCycle(t *testing.T) {
+	o := &cycleOwner{Name: "o"}
+	o.Home = &cycleHome{Name: "h", Owner: o}
+	a := &cycleHome{Name: "a", Owner: o}
+
+	tris := TriplesFromStruct("a", a)
+	exp := []Triple{
+		SubjPred("a", "name").StringLiteral("a"),
+		SubjPred("a", "owner").Bnode("o"),
+		BnodePred("o", "name").StringLiteral("o"),
+		BnodePred("o", "home").Bnode("h"),
+		BnodePred("h", "name").StringLiteral("h"),
+		BnodePred("h", "owner").Bnode("o"),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+}
+
+func TestBytesStructField(t *testing.T) {
+	type file struct {
+		Hash []byte `predicate:"hash"`
+	}
+
+	tris := TriplesFromStruct("me", file{Hash: []byte{0xca, 0xfe}})
+	exp := []Triple{SubjPred("me", "hash").Base64BinaryLiteral([]byte{0xca, 0xfe})}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got file
+	if err := StructFromTriples(src.Snapshot(), "me", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.Hash, []byte{0xca, 0xfe}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestBigDecimalStructField(t *testing.T) {
+	type invoice struct {
+		Amount *big.Float `predicate:"amount"`
+		Taxes  big.Float  `predicate:"taxes"`
+	}
+
+	in := invoice{Amount: big.NewFloat(1250.75), Taxes: *big.NewFloat(0.5)}
+	tris := TriplesFromStruct("inv", in)
+	exp := []Triple{
+		SubjPred("inv", "amount").BigDecimalLiteral(big.NewFloat(1250.75)),
+		SubjPred("inv", "taxes").BigDecimalLiteral(big.NewFloat(0.5)),
+	}
+	if got, want := Triples(tris), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+	if got, want := Triples(TriplesFromStruct("inv", &in)), Triples(exp); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+
+	src := NewSource()
+	src.Add(tris...)
+	var got invoice
+	if err := StructFromTriples(src.Snapshot(), "inv", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Amount == nil || got.Amount.Cmp(in.Amount) != 0 || got.Taxes.Cmp(&in.Taxes) != 0 {
+		t.Fatalf("got %v %v", got.Amount, &got.Taxes)
+	}
+
+	inf := invoice{Amount: new(big.Float).SetInf(false), Taxes: *big.NewFloat(0.5)}
+	if got, want := Triples(TriplesFromStruct("inv", inf)), Triples(exp[1:]); !got.Equal(want) {
+		t.Fatalf("got %s\n\n want %s", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package triplestore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Schema-less binary codecs (MessagePack, CBOR) write triples as maps
+// with the same shape as their JSON marshaling:
+//
+//	{"subject": {"type": "uri", "value": "http://ex.org/bob"}, "predicate": {...}, "object": {...}}
+
+func appendTermMapTriple(buf []byte, t *triple, mapHeader func([]byte, int) []byte, str func([]byte, string) []byte) []byte {
+	sub := jsonTerm{Type: "uri", Value: t.sub}
+	if t.isSubBnode {
+		sub.Type = "bnode"
+	}
+	buf = mapHeader(buf, 3)
+	buf = str(buf, "subject")
+	buf = appendTermMap(buf, sub, mapHeader, str)
+	buf = str(buf, "predicate")
+	buf = appendTermMap(buf, jsonTerm{Type: "uri", Value: t.pred}, mapHeader, str)
+	buf = str(buf, "object")
+	return appendTermMap(buf, toJSONTerm(t.obj), mapHeader, str)
+}
+
+func appendTermMap(buf []byte, t jsonTerm, mapHeader func([]byte, int) []byte, str func([]byte, string) []byte) []byte {
+	size := 2
+	if t.Lang != "" || t.Datatype != "" {
+		size++
+	}
+	buf = mapHeader(buf, size)
+	buf = str(buf, "type")
+	buf = str(buf, t.Type)
+	buf = str(buf, "value")
+	buf = str(buf, t.Value)
+	if t.Lang != "" {
+		buf = str(buf, "xml:lang")
+		buf = str(buf, t.Lang)
+	} else if t.Datatype != "" {
+		buf = str(buf, "datatype")
+		buf = str(buf, t.Datatype)
+	}
+	return buf
+}
+
+// termMapReader reads the items of schema-less binary formats
+type termMapReader interface {
+	readMapHeader() (int, error)
+	readString() (string, error)
+	// skip discards the next item whatever its type
+	skip(depth int) error
+}
+
+func readTermMapTriple(r termMapReader) (*triple, error) {
+	n, err := r.readMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	var sub, pred, obj *jsonTerm
+	for i := 0; i < n; i++ {
+		key, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		var term *jsonTerm
+		switch key {
+		case "subject":
+			sub = new(jsonTerm)
+			term = sub
+		case "predicate":
+			pred = new(jsonTerm)
+			term = pred
+		case "object":
+			obj = new(jsonTerm)
+			term = obj
+		default:
+			if err := r.skip(0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := readTermMap(r, term); err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+	}
+	if sub == nil || pred == nil || obj == nil {
+		return nil, errors.New("requires a subject, a predicate and an object")
+	}
+
+	subject, err := fromJSONTerm(*sub)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %s", err)
+	}
+	if subject.isLit {
+		return nil, errors.New("subject: literal not allowed")
+	}
+	if pred.Type != "uri" {
+		return nil, fmt.Errorf("predicate: expected uri, got %q", pred.Type)
+	}
+	object, err := fromJSONTerm(*obj)
+	if err != nil {
+		return nil, fmt.Errorf("object: %s", err)
+	}
+	return newTriple(subject, pred.Value, object), nil
+}
+
+func readTermMap(r termMapReader, t *jsonTerm) error {
+	n, err := r.readMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		key, err := r.readString()
+		if err != nil {
+			return err
+		}
+		var field *string
+		switch key {
+		case "type":
+			field = &t.Type
+		case "value":
+			field = &t.Value
+		case "xml:lang":
+			field = &t.Lang
+		case "datatype":
+			field = &t.Datatype
+		default:
+			if err := r.skip(0); err != nil {
+				return err
+			}
+			continue
+		}
+		if *field, err = r.readString(); err != nil {
+			return fmt.Errorf("%s: %s", key, err)
+		}
+	}
+	return nil
+}
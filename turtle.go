@@ -0,0 +1,472 @@
+package triplestore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TurtleDecoder decodes Turtle documents. Once decoded, the prefixes and
+// base declared in the document are available to re-encode triples with them.
+type TurtleDecoder interface {
+	Decoder
+	// Context returns the prefixes and base declared by the decoded document
+	Context() *Context
+}
+
+type turtleDecoder struct {
+	r    io.Reader
+	opts decoderOptions
+	ctx  *Context
+}
+
+// NewTurtleDecoder returns a Turtle decoder. Both Turtle (@prefix, @base) and
+// SPARQL (PREFIX, BASE) directives are supported. Relative IRIs are resolved
+// against the base. Abbreviations are supported: predicate (';') and object (',')
+// lists, 'a' for rdf:type, numbers, booleans and anonymous blank nodes ('[]'),
+// which are labelled "anon-1", "anon-2", etc., skipping the labels of the
+// document. As done by queries, prefixed names with an undeclared prefix are
+// kept as is (ex: "rdfs:label") to match triples built with prefixed names.
+func NewTurtleDecoder(r io.Reader, opts ...DecoderOption) TurtleDecoder {
+	return &turtleDecoder{r: r, opts: newDecoderOptions(opts), ctx: NewContext()}
+}
+
+func (d *turtleDecoder) Context() *Context {
+	return d.ctx
+}
+
+func (d *turtleDecoder) Decode() ([]Triple, error) {
+	tracker := newProgressTracker(d.opts.progress)
+	doc, err := ioutil.ReadAll(tracker.reader(d.r))
+	if err != nil {
+		return nil, err
+	}
+	toks, err := lexSPARQL(string(doc))
+	if err != nil {
+		return nil, fmt.Errorf("turtle: %s", err)
+	}
+	p := &turtleParser{sparqlParser: sparqlParser{toks: toks, q: &Query{prefixes: d.ctx.Prefixes}}, ctx: d.ctx}
+	if err := p.parseDocument(); err != nil {
+		return p.tris, fmt.Errorf("turtle: %s", err)
+	}
+	for i, t := range p.tris {
+		if err := d.opts.checkTermSize(t.(*triple)); err != nil {
+			return p.tris[:i], err
+		}
+		tracker.addTriple()
+	}
+	tracker.done()
+	return p.tris, nil
+}
+
+// turtleParser reuses the SPARQL lexer and parser helpers,
+// the query prefixes being the prefixes of the context
+type turtleParser struct {
+	sparqlParser
+	ctx       *Context
+	tris      []Triple
+	anonCount int
+	labels    map[string]bool
+}
+
+func (p *turtleParser) parseDocument() error {
+	for p.peek().typ != tokEOF {
+		if ok, err := p.parseDirective(); err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+		if err := p.parseTriples(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDirective parses @prefix, @base, PREFIX or BASE directives.
+// Turtle directives end with a '.', SPARQL ones do not.
+func (p *turtleParser) parseDirective() (bool, error) {
+	var turtleStyle bool
+	var directive string
+	switch t := p.peek(); {
+	case t.typ == tokLang && (t.val == "prefix" || t.val == "base"):
+		turtleStyle, directive = true, t.val
+	case p.isKeyword("PREFIX"):
+		directive = "prefix"
+	case p.isKeyword("BASE"):
+		directive = "base"
+	default:
+		return false, nil
+	}
+	p.next()
+
+	var name string
+	if directive == "prefix" {
+		t := p.next()
+		if t.typ != tokPName || !strings.HasSuffix(t.val, ":") {
+			return true, fmt.Errorf("expected prefix name, got %s", t)
+		}
+		name = strings.TrimSuffix(t.val, ":")
+	}
+	t := p.next()
+	if t.typ != tokIRI {
+		return true, fmt.Errorf("expected IRI, got %s", t)
+	}
+	iri, err := p.resolve(t.val)
+	if err != nil {
+		return true, fmt.Errorf("invalid IRI %s: %s", t, err)
+	}
+	if directive == "prefix" {
+		p.ctx.Prefixes[name] = iri
+	} else {
+		p.ctx.Base = iri
+	}
+
+	if turtleStyle {
+		return true, p.expectPunct(".")
+	}
+	return true, nil
+}
+
+// resolve resolves the IRI against the base
+func (p *turtleParser) resolve(iri string) (string, error) {
+	if p.ctx.Base == "" {
+		return iri, nil
+	}
+	base, err := url.Parse(p.ctx.Base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(iri)
+	if err != nil {
+		return "", err
+	}
+	resolved := base.ResolveReference(ref).String()
+	// url.URL drops empty fragments, which are common in namespace IRIs
+	if strings.HasSuffix(iri, "#") && !strings.HasSuffix(resolved, "#") {
+		resolved += "#"
+	}
+	return resolved, nil
+}
+
+// parseTriples parses a subject followed by its predicate object list,
+// or a blank node property list optionally followed by a predicate object list
+func (p *turtleParser) parseTriples() error {
+	if p.isPunct("[") {
+		sub, err := p.parseBlankNodePropertyList()
+		if err != nil {
+			return err
+		}
+		if p.isPunct(".") {
+			return nil
+		}
+		return p.parsePredicateObjectList(sub)
+	}
+	sub, err := p.parseTerm()
+	if err != nil {
+		return err
+	}
+	if sub.isLit {
+		return errors.New("literal not allowed as subject")
+	}
+	return p.parsePredicateObjectList(sub)
+}
+
+// parsePredicateObjectList parses predicates separated by ';',
+// each one followed by objects separated by ','
+func (p *turtleParser) parsePredicateObjectList(sub object) error {
+	for {
+		pred, err := p.parseVerb()
+		if err != nil {
+			return err
+		}
+		for {
+			obj, err := p.parseObject()
+			if err != nil {
+				return err
+			}
+			p.tris = append(p.tris, newTriple(sub, pred, obj))
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if !p.acceptPunct(";") {
+			return nil
+		}
+		for p.acceptPunct(";") {
+		}
+		if p.isPunct(".") || p.isPunct("]") {
+			return nil
+		}
+	}
+}
+
+// parseVerb parses a predicate, 'a' standing for rdf:type. Unlike in queries,
+// 'a' is always expanded to the full rdf:type IRI as required by Turtle.
+func (p *turtleParser) parseVerb() (string, error) {
+	if t := p.peek(); t.typ == tokIdent && t.val == "a" {
+		p.next()
+		return rdfNamespace + "type", nil
+	}
+	pred, err := p.parseTerm()
+	if err != nil {
+		return "", err
+	}
+	if pred.isLit || pred.isBnode {
+		return "", errors.New("predicate must be an IRI")
+	}
+	return pred.resource, nil
+}
+
+func (p *turtleParser) parseObject() (object, error) {
+	if p.isPunct("[") {
+		return p.parseBlankNodePropertyList()
+	}
+	return p.parseTerm()
+}
+
+// newAnonLabel returns the next "anon-N" label not used by the document
+func (p *turtleParser) newAnonLabel() string {
+	if p.labels == nil {
+		p.labels = make(map[string]bool)
+		for _, t := range p.toks {
+			if t.typ == tokPName && strings.HasPrefix(t.val, "_:") {
+				p.labels[t.val[2:]] = true
+			}
+		}
+	}
+	for {
+		p.anonCount++
+		if label := fmt.Sprintf("anon-%d", p.anonCount); !p.labels[label] {
+			return label
+		}
+	}
+}
+
+// parseBlankNodePropertyList parses '[ ... ]', emitting the inner triples
+// with a fresh blank node as subject
+func (p *turtleParser) parseBlankNodePropertyList() (object, error) {
+	if err := p.expectPunct("["); err != nil {
+		return object{}, err
+	}
+	bnode := object{isBnode: true, bnode: p.newAnonLabel()}
+	if p.acceptPunct("]") {
+		return bnode, nil
+	}
+	if err := p.parsePredicateObjectList(bnode); err != nil {
+		return object{}, err
+	}
+	return bnode, p.expectPunct("]")
+}
+
+func (p *turtleParser) parseTerm() (object, error) {
+	t := p.next()
+	switch t.typ {
+	case tokIRI:
+		iri, err := p.resolve(t.val)
+		if err != nil {
+			return object{}, fmt.Errorf("invalid IRI %s: %s", t, err)
+		}
+		return object{resource: iri}, nil
+	case tokPName:
+		if strings.HasPrefix(t.val, "_:") {
+			return object{isBnode: true, bnode: t.val[2:]}, nil
+		}
+		return object{resource: p.expandPName(t.val)}, nil
+	case tokNumber:
+		return numberLiteral(t.val), nil
+	case tokPunct:
+		// the lexer takes a sign following a term for an operator
+		if n := p.peek(); (t.val == "-" || t.val == "+") && n.typ == tokNumber && n.pos == t.pos+1 {
+			p.next()
+			return numberLiteral(t.val + n.val), nil
+		}
+	case tokIdent:
+		switch t.val {
+		case "true":
+			return BooleanLiteral(true).(object), nil
+		case "false":
+			return BooleanLiteral(false).(object), nil
+		}
+	case tokString:
+		lit := literal{typ: XsdString, val: t.val}
+		if p.peek().typ == tokLang {
+			lit.langtag = p.next().val
+		} else if p.acceptPunct("^^") {
+			dt, err := p.parseTerm()
+			if err != nil {
+				return object{}, fmt.Errorf("datatype: %s", err)
+			}
+			if dt.isLit || dt.isBnode {
+				return object{}, fmt.Errorf("datatype must be an IRI, got %s", dt.key())
+			}
+			lit.typ = shortXsdType(XsdType(dt.resource))
+		}
+		return object{isLit: true, lit: lit}, nil
+	}
+	return object{}, fmt.Errorf("unexpected %s", t)
+}
+
+type turtleEncoder struct {
+	w        io.Writer
+	c        *Context
+	sorted   bool
+	progress func(Progress)
+}
+
+// NewTurtleEncoder returns a Turtle encoder configured with the given options.
+// The triples of a subject are grouped in predicate (';') and object (',') lists,
+// rdf:type being written 'a'. The prefixes given with WithPrefixes are declared
+// and abbreviate the IRIs they are the namespace of. Relative IRIs are resolved
+// against the base given with WithBase.
+func NewTurtleEncoder(w io.Writer, opts ...EncoderOption) Encoder {
+	o := newEncoderOptions(opts)
+	return &turtleEncoder{w: w, c: o.context, sorted: o.sorted, progress: o.progress}
+}
+
+func (enc *turtleEncoder) Encode(tris ...Triple) error {
+	if enc.sorted {
+		tris = sortedTriples(tris)
+	}
+	tris = expandTriples(enc.c, tris)
+
+	var prefixes []string
+	if enc.c != nil {
+		for name := range enc.c.Prefixes {
+			prefixes = append(prefixes, name)
+		}
+		sort.Strings(prefixes)
+	}
+
+	buff := getBuffer()
+	defer putBuffer(buff)
+	tracker := newProgressTracker(enc.progress)
+
+	for _, name := range prefixes {
+		buff.WriteString("@prefix ")
+		buff.WriteString(name)
+		buff.WriteString(": ")
+		writeIRI(buff, enc.c.Prefixes[name])
+		buff.WriteString(" .\n")
+	}
+
+	// subjects and their predicates are written in order of first appearance
+	var subjects []object
+	bySubject := make(map[string][]*triple)
+	for _, t := range tris {
+		tri := t.(*triple)
+		sub := subjectObject(tri)
+		if _, ok := bySubject[sub.key()]; !ok {
+			subjects = append(subjects, sub)
+		}
+		bySubject[sub.key()] = append(bySubject[sub.key()], tri)
+	}
+
+	for _, sub := range subjects {
+		if buff.Len() > 0 {
+			buff.WriteByte('\n')
+		}
+		enc.writeTerm(buff, prefixes, sub)
+		var preds []string
+		byPred := make(map[string][]object)
+		for _, tri := range bySubject[sub.key()] {
+			if _, ok := byPred[tri.pred]; !ok {
+				preds = append(preds, tri.pred)
+			}
+			byPred[tri.pred] = append(byPred[tri.pred], tri.obj)
+			tracker.addTriple()
+		}
+		for i, pred := range preds {
+			if i > 0 {
+				buff.WriteString(" ;\n   ")
+			}
+			buff.WriteByte(' ')
+			if pred == rdfNamespace+"type" {
+				buff.WriteByte('a')
+			} else {
+				enc.writeIRI(buff, prefixes, pred)
+			}
+			for j, obj := range byPred[pred] {
+				if j > 0 {
+					buff.WriteByte(',')
+				}
+				buff.WriteByte(' ')
+				enc.writeTerm(buff, prefixes, obj)
+			}
+		}
+		buff.WriteString(" .\n")
+	}
+
+	if _, err := enc.w.Write(buff.Bytes()); err != nil {
+		return err
+	}
+	tracker.addBytes(buff.Len())
+	tracker.done()
+	return nil
+}
+
+func (enc *turtleEncoder) writeTerm(buff *bytes.Buffer, prefixes []string, o object) {
+	switch {
+	case o.isBnode:
+		buff.WriteString("_:")
+		buff.WriteString(o.bnode)
+	case !o.isLit:
+		enc.writeIRI(buff, prefixes, o.resource)
+	default:
+		buff.WriteByte('"')
+		buff.WriteString(escapeNQuadsLiteral(o.lit.val))
+		buff.WriteByte('"')
+		switch {
+		case o.lit.langtag != "":
+			buff.WriteByte('@')
+			buff.WriteString(o.lit.langtag)
+		case o.lit.typ != "" && o.lit.typ != XsdString:
+			buff.WriteString("^^")
+			enc.writeIRI(buff, prefixes, o.lit.typ.NTriplesNamespaced())
+		}
+	}
+}
+
+// writeIRI writes the IRI as a prefixed name when it starts with the namespace
+// of a prefix, the longest namespace being used, and as <IRI> otherwise
+func (enc *turtleEncoder) writeIRI(buff *bytes.Buffer, prefixes []string, iri string) {
+	var prefix, namespace string
+	for _, name := range prefixes {
+		ns := enc.c.Prefixes[name]
+		if len(ns) <= len(namespace) || !strings.HasPrefix(iri, ns) || !isTurtleLocalName(iri[len(ns):]) {
+			continue
+		}
+		prefix, namespace = name, ns
+	}
+	if namespace == "" {
+		writeIRI(buff, iri)
+		return
+	}
+	buff.WriteString(prefix)
+	buff.WriteByte(':')
+	buff.WriteString(iri[len(namespace):])
+}
+
+// isTurtleLocalName returns true if the name can be written after a prefix
+// without escaping: letters, digits, '_' and '-', with inner dots
+func isTurtleLocalName(name string) bool {
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '_':
+		case r == '-' && i > 0:
+		case r == '.' && i > 0 && i < len(name)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+package triplestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTurtleDirectives(t *testing.T) {
+	doc := `# a comment
+@prefix ex: <http://example.org/> .
+PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+@base <http://example.org/base/> .
+@prefix rel: <rel#> .
+
+ex:me foaf:name "me"@en .
+<me> rel:knows _:you .
+_:you foaf:age "42"^^xsd:integer .
+BASE <http://other.org/>
+<you> <#p> 'long'^^<http://www.w3.org/2001/XMLSchema#string> .
+`
+	dec := NewTurtleDecoder(strings.NewReader(doc))
+	tris, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Triple{
+		SubjPred("http://example.org/me", "http://xmlns.com/foaf/0.1/name").StringLiteralWithLang("me", "en"),
+		SubjPred("http://example.org/base/me", "http://example.org/base/rel#knows").Bnode("you"),
+		BnodePred("you", "http://xmlns.com/foaf/0.1/age").IntegerLiteral(42),
+		SubjPred("http://other.org/you", "http://other.org/#p").StringLiteral("long"),
+	}
+	if got, want := Triples(tris), Triples(expected); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	ctx := dec.Context()
+	if got, want := ctx.Base, "http://other.org/"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	prefixes := map[string]string{
+		"ex":   "http://example.org/",
+		"foaf": "http://xmlns.com/foaf/0.1/",
+		"rel":  "http://example.org/base/rel#",
+	}
+	if got, want := len(ctx.Prefixes), len(prefixes); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	for k, v := range prefixes {
+		if got, want := ctx.Prefixes[k], v; got != want {
+			t.Fatalf("prefix %s: got %s, want %s", k, got, want)
+		}
+	}
+}
+
+func TestTurtleErrors(t *testing.T) {
+	tcases := []struct {
+		doc, err string
+	}{
+		{doc: "@prefix ex <http://example.org/> .", err: "expected prefix name"},
+		{doc: "@prefix ex: <http://example.org/>", err: "expected '.'"},
+		{doc: "@base \"base\" .", err: "expected IRI"},
+		{doc: "<s> <p> <o>", err: "expected '.'"},
+		{doc: "\"lit\" <p> <o> .", err: "literal not allowed as subject"},
+		{doc: "<s> _:p <o> .", err: "predicate must be an IRI"},
+		{doc: "<s> <p> ?o .", err: "unexpected"},
+	}
+	for i, tc := range tcases {
+		_, err := NewTurtleDecoder(strings.NewReader(tc.doc)).Decode()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %v, want error containing %q", i, err, tc.err)
+		}
+		if !strings.HasPrefix(err.Error(), "turtle: ") {
+			t.Fatalf("case %d: got %v, want turtle prefix", i, err)
+		}
+	}
+}
+
+func TestTurtleEscapesAndBlankNodeLabels(t *testing.T) {
+	doc := `@prefix ex: <http://example.org/> .
+ex:me ex:name "caf\u00E9", "\U0001F600", "tab\there\b\f\"\'\\\n" ;
+	ex:bio """long "quoted\""" text\u0021""" .
+_:my-node ex:p "x" .
+_:a.b ex:p _:c..d.
+_:_1 ex:p _:2x .
+`
+	tris, err := NewTurtleDecoder(strings.NewReader(doc)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Triple{
+		SubjPred("http://example.org/me", "http://example.org/name").StringLiteral("café"),
+		SubjPred("http://example.org/me", "http://example.org/name").StringLiteral("\U0001F600"),
+		SubjPred("http://example.org/me", "http://example.org/name").StringLiteral("tab\there\b\f\"'\\\n"),
+		SubjPred("http://example.org/me", "http://example.org/bio").StringLiteral(`long "quoted""" text!`),
+		BnodePred("my-node", "http://example.org/p").StringLiteral("x"),
+		BnodePred("a.b", "http://example.org/p").Bnode("c..d"),
+		BnodePred("_1", "http://example.org/p").Bnode("2x"),
+	}
+	if got, want := Triples(tris), Triples(expected); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{`"\u00E"`, `"\uZZZZ"`, `"\UFFFFFFFF"`, `"\d"`, `"""a\q"""`} {
+		_, err := NewTurtleDecoder(strings.NewReader("<s> <p> " + invalid + " .")).Decode()
+		if err == nil || !strings.Contains(err.Error(), "invalid escape") {
+			t.Fatalf("%s: got %v, want invalid escape error", invalid, err)
+		}
+	}
+}
+
+func TestTurtleAbbreviations(t *testing.T) {
+	doc := `@prefix ex: <http://example.org/> .
+ex:me a ex:Person ;
+	ex:age 42, -1.5, +3e2 ;
+	ex:active true ;
+	ex:knows [ ex:name "you" ; ex:friend [] ] ;
+	;
+	.
+[ ex:name "anon" ] .
+[] ex:name "other" .
+_:anon-3 ex:name "labelled" .
+`
+	tris, err := NewTurtleDecoder(strings.NewReader(doc)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Triple{
+		SubjPredRes("http://example.org/me", rdfNamespace+"type", "http://example.org/Person"),
+		SubjPred("http://example.org/me", "http://example.org/age").IntegerLiteral(42),
+		newTriple(Resource("http://example.org/me").(object), "http://example.org/age", object{isLit: true, lit: literal{typ: XsdDecimal, val: "-1.5"}}),
+		newTriple(Resource("http://example.org/me").(object), "http://example.org/age", object{isLit: true, lit: literal{typ: XsdDouble, val: "3e2"}}),
+		SubjPred("http://example.org/me", "http://example.org/active").BooleanLiteral(true),
+		BnodePred("anon-1", "http://example.org/name").StringLiteral("you"),
+		BnodePred("anon-1", "http://example.org/friend").Bnode("anon-2"),
+		SubjPred("http://example.org/me", "http://example.org/knows").Bnode("anon-1"),
+		BnodePred("anon-4", "http://example.org/name").StringLiteral("anon"),
+		BnodePred("anon-5", "http://example.org/name").StringLiteral("other"),
+		BnodePred("anon-3", "http://example.org/name").StringLiteral("labelled"),
+	}
+	if got, want := Triples(tris), Triples(expected); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := NewLenientNTEncoder(&buf).Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewLenientNTDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), Triples(tris); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTurtleEncoder(t *testing.T) {
+	tris := []Triple{
+		SubjPredRes("http://example.org/me", rdfNamespace+"type", "http://example.org/Person"),
+		SubjPred("http://example.org/me", "http://example.org/name").StringLiteral(`me "quoted"`),
+		SubjPred("http://example.org/me", "http://example.org/knows").Resource("http://example.org/you"),
+		SubjPred("http://example.org/me", "http://example.org/knows").Bnode("b1"),
+		BnodePred("b1", "http://example.org/name").StringLiteralWithLang("moi", "fr"),
+		SubjPred("http://example.org/you", "http://example.org/age").IntegerLiteral(42),
+		SubjPred("http://example.org/you", "http://example.org/sub/code").Object(TypedLiteral("x1", "http://example.org/dt")),
+		SubjPred("http://other.org/a/b", "http://example.org/p").Resource("http://example.org/not/local"),
+	}
+
+	var buf bytes.Buffer
+	enc := NewTurtleEncoder(&buf, WithPrefixes(map[string]string{
+		"ex":  "http://example.org/",
+		"sub": "http://example.org/sub/",
+		"xsd": "http://www.w3.org/2001/XMLSchema#",
+	}))
+	if err := enc.Encode(tris...); err != nil {
+		t.Fatal(err)
+	}
+	expected := `@prefix ex: <http://example.org/> .
+@prefix sub: <http://example.org/sub/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+ex:me a ex:Person ;
+    ex:name "me \"quoted\"" ;
+    ex:knows ex:you, _:b1 .
+
+_:b1 ex:name "moi"@fr .
+
+ex:you ex:age "42"^^xsd:integer ;
+    sub:code "x1"^^ex:dt .
+
+<http://other.org/a/b> ex:p <http://example.org/not/local> .
+`
+	if got, want := buf.String(), expected; got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	decoded, err := NewTurtleDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Triples(decoded), Triples(tris); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	buf.Reset()
+	if err := NewTurtleEncoder(&buf).Encode(SubjPred("me", "rdf:type").Resource("Person")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "<me> <rdf:type> <Person> .\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
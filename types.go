@@ -11,6 +11,23 @@ var (
 	XsdString   = XsdType("xsd:string")
 	XsdBoolean  = XsdType("xsd:boolean")
 	XsdDateTime = XsdType("xsd:dateTime")
+	XsdDate     = XsdType("xsd:date")
+	XsdTime     = XsdType("xsd:time")
+	XsdDuration = XsdType("xsd:duration")
+
+	// partial gregorian dates
+	XsdGYear      = XsdType("xsd:gYear")
+	XsdGYearMonth = XsdType("xsd:gYearMonth")
+	XsdGMonthDay  = XsdType("xsd:gMonthDay")
+	XsdGMonth     = XsdType("xsd:gMonth")
+	XsdGDay       = XsdType("xsd:gDay")
+
+	// binary data
+	XsdBase64Binary = XsdType("xsd:base64Binary")
+	XsdHexBinary    = XsdType("xsd:hexBinary")
+
+	// arbitrary precision decimal numbers
+	XsdDecimal = XsdType("xsd:decimal")
 
 	// 64-bit floating point numbers
 	XsdDouble = XsdType("xsd:double")
@@ -34,11 +51,12 @@ var (
 
 const XMLSchemaNamespace = "http://www.w3.org/2001/XMLSchema"
 
+// NTriplesNamespaced returns the full IRI of the datatype, expanding the xsd: prefix.
+// Other datatypes (ex: "http://example.org/dt") are returned unchanged.
 func (x XsdType) NTriplesNamespaced() string {
-	splits := strings.Split(string(x), ":")
-	if len(splits) != 2 {
+	if !strings.HasPrefix(string(x), "xsd:") {
 		return string(x)
 	}
 
-	return fmt.Sprintf("%s#%s", XMLSchemaNamespace, splits[1])
+	return fmt.Sprintf("%s#%s", XMLSchemaNamespace, strings.TrimPrefix(string(x), "xsd:"))
 }
@@ -0,0 +1,166 @@
+package triplestore
+
+import (
+	"regexp"
+	"time"
+)
+
+// NewUnionGraph returns a virtual graph, union of the given graphs (ex: snapshots
+// of several sources), so that they can be queried together, with the RDFGraph
+// methods or SPARQL, without copying their triples into a single source.
+//
+// Each lookup is run against every graph in turn, a triple being returned only
+// by the first graph containing it.
+func NewUnionGraph(graphs ...RDFGraph) RDFGraph {
+	return &unionGraph{graphs: graphs}
+}
+
+type unionGraph struct {
+	graphs []RDFGraph
+}
+
+// merge concatenates the triples found by the lookup in each graph,
+// skipping the ones of a graph contained in previous graphs
+func (u *unionGraph) merge(lookup func(RDFGraph) []Triple) []Triple {
+	var out []Triple
+	for i, g := range u.graphs {
+		for _, t := range lookup(g) {
+			if !u.containedBefore(i, t) {
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
+
+func (u *unionGraph) containedBefore(i int, t Triple) bool {
+	for _, prev := range u.graphs[:i] {
+		if prev.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *unionGraph) Contains(t Triple) bool {
+	for _, g := range u.graphs {
+		if g.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *unionGraph) Triples() []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return g.Triples() })
+}
+
+func (u *unionGraph) Count() int {
+	return u.CountWith(nil, nil, nil)
+}
+
+func (u *unionGraph) WithSubject(s string) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return g.WithSubject(s) })
+}
+
+func (u *unionGraph) WithPredicate(p string) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return g.WithPredicate(p) })
+}
+
+func (u *unionGraph) WithObject(o Object) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return g.WithObject(o) })
+}
+
+func (u *unionGraph) WithSubjObj(s string, o Object) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return g.WithSubjObj(s, o) })
+}
+
+func (u *unionGraph) WithSubjPred(s, p string) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return g.WithSubjPred(s, p) })
+}
+
+func (u *unionGraph) WithPredObj(p string, o Object) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return g.WithPredObj(p, o) })
+}
+
+func (u *unionGraph) WithPredObjMatching(p string, re *regexp.Regexp) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return WithPredObjMatching(g, p, re) })
+}
+
+func (u *unionGraph) WithPredRange(p string, min, max Literal) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return WithPredRange(g, p, min, max) })
+}
+
+func (u *unionGraph) WithPredTimeRange(p string, from, to time.Time) []Triple {
+	return u.merge(func(g RDFGraph) []Triple { return WithPredTimeRange(g, p, from, to) })
+}
+
+func (u *unionGraph) Subgraph(root string, depth int) Triples {
+	return subgraph(u, root, depth)
+}
+
+// CountWith counts the matching triples of the first graph through its indexes,
+// the ones of the following graphs being checked against previous graphs
+func (u *unionGraph) CountWith(s, p *string, o Object) int {
+	if len(u.graphs) == 0 {
+		return 0
+	}
+	count := CountWith(u.graphs[0], s, p, o)
+	for i, g := range u.graphs[1:] {
+		if !Exists(g, s, p, o) {
+			continue
+		}
+		for _, t := range lookupWith(g, s, p, o) {
+			if matches(t, s, p, o) && !u.containedBefore(i+1, t) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (u *unionGraph) Exists(s, p *string, o Object) bool {
+	for _, g := range u.graphs {
+		if Exists(g, s, p, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats counts the distinct terms of the union. Its memory is the one of the graphs.
+func (u *unionGraph) Stats() Stats {
+	st := termStats(u.Triples())
+	for _, g := range u.graphs {
+		st.MemoryBytes += StatsOf(g).MemoryBytes
+	}
+	return st
+}
+
+// lookupWith returns candidate triples for the given subject, predicate and
+// object, nil meaning any, through the most selective lookup
+func lookupWith(g RDFGraph, s, p *string, o Object) []Triple {
+	switch {
+	case s != nil && p != nil:
+		return g.WithSubjPred(*s, *p)
+	case s != nil && o != nil:
+		return g.WithSubjObj(*s, o)
+	case p != nil && o != nil:
+		return g.WithPredObj(*p, o)
+	case s != nil:
+		return g.WithSubject(*s)
+	case p != nil:
+		return g.WithPredicate(*p)
+	case o != nil:
+		return g.WithObject(o)
+	default:
+		return g.Triples()
+	}
+}
+
+// matches returns true if the triple has the given subject, predicate
+// and object, nil meaning any
+func matches(t Triple, s, p *string, o Object) bool {
+	tri := t.(*triple)
+	return (s == nil || tri.sub == *s) && (p == nil || tri.pred == *p) && (o == nil || tri.obj.key() == o.(object).key())
+}
@@ -0,0 +1,124 @@
+package triplestore
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestUnionGraph(t *testing.T) {
+	first, second := NewSource(), NewSource()
+	first.Add(
+		SubjPred("alice", "knows").Resource("bob"),
+		SubjPred("alice", "name").StringLiteral("Alice"),
+		SubjPred("alice", "age").IntegerLiteral(42),
+	)
+	second.Add(
+		SubjPred("alice", "knows").Resource("bob"),
+		SubjPred("bob", "name").StringLiteral("Bob"),
+		SubjPred("bob", "age").IntegerLiteral(24),
+		SubjPred("bob", "knows").Bnode("b1"),
+		BnodePred("b1", "name").StringLiteral("Anon"),
+	)
+	union := NewUnionGraph(first.Snapshot(), second.Snapshot())
+
+	all := NewSource()
+	all.Add(first.CopyTriples()...)
+	all.Add(second.CopyTriples()...)
+	merged := all.Snapshot()
+
+	if got, want := Triples(union.Triples()), Triples(merged.Triples()); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := union.Count(), 7; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := StatsOf(union), StatsOf(merged); got.Triples != want.Triples || got.Subjects != want.Subjects || got.Predicates != want.Predicates || got.Objects != want.Objects {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if !union.Contains(SubjPred("bob", "age").IntegerLiteral(24)) || union.Contains(SubjPred("bob", "age").IntegerLiteral(42)) {
+		t.Fatal("unexpected Contains")
+	}
+
+	subjects := []string{"alice", "bob", "b1", "unknown"}
+	preds := []string{"knows", "name", "age", "unknown"}
+	objs := []Object{Resource("bob"), StringLiteral("Bob"), IntegerLiteral(24), object{isBnode: true, bnode: "b1"}, Resource("unknown")}
+	for _, s := range subjects {
+		if got, want := Triples(union.WithSubject(s)), Triples(merged.WithSubject(s)); !got.Equal(want) {
+			t.Fatalf("subject %s: got %v, want %v", s, got, want)
+		}
+		if got, want := Subgraph(union, s, -1), Subgraph(merged, s, -1); !got.Equal(want) {
+			t.Fatalf("subgraph %s: got %v, want %v", s, got, want)
+		}
+		for _, p := range preds {
+			if got, want := Triples(union.WithSubjPred(s, p)), Triples(merged.WithSubjPred(s, p)); !got.Equal(want) {
+				t.Fatalf("subject %s, predicate %s: got %v, want %v", s, p, got, want)
+			}
+		}
+		for _, o := range objs {
+			if got, want := Triples(union.WithSubjObj(s, o)), Triples(merged.WithSubjObj(s, o)); !got.Equal(want) {
+				t.Fatalf("subject %s, object %v: got %v, want %v", s, o, got, want)
+			}
+		}
+	}
+	for _, p := range preds {
+		if got, want := Triples(union.WithPredicate(p)), Triples(merged.WithPredicate(p)); !got.Equal(want) {
+			t.Fatalf("predicate %s: got %v, want %v", p, got, want)
+		}
+		for _, o := range objs {
+			if got, want := Triples(union.WithPredObj(p, o)), Triples(merged.WithPredObj(p, o)); !got.Equal(want) {
+				t.Fatalf("predicate %s, object %v: got %v, want %v", p, o, got, want)
+			}
+		}
+		re := regexp.MustCompile("^[AB]")
+		if got, want := Triples(WithPredObjMatching(union, p, re)), Triples(WithPredObjMatching(merged, p, re)); !got.Equal(want) {
+			t.Fatalf("predicate %s: got %v, want %v", p, got, want)
+		}
+		if got, want := Triples(WithPredRange(union, p, nil, nil)), Triples(WithPredRange(merged, p, nil, nil)); !got.Equal(want) {
+			t.Fatalf("predicate %s: got %v, want %v", p, got, want)
+		}
+		if got := WithPredTimeRange(union, p, time.Time{}, time.Time{}); len(got) != 0 {
+			t.Fatalf("predicate %s: expected no triples, got %v", p, got)
+		}
+	}
+	for _, o := range objs {
+		if got, want := Triples(union.WithObject(o)), Triples(merged.WithObject(o)); !got.Equal(want) {
+			t.Fatalf("object %v: got %v, want %v", o, got, want)
+		}
+	}
+
+	for _, s := range append(subjects, "") {
+		for _, p := range append(preds, "") {
+			for _, o := range append(objs, nil) {
+				sp, pp := &s, &p
+				if s == "" {
+					sp = nil
+				}
+				if p == "" {
+					pp = nil
+				}
+				if got, want := CountWith(union, sp, pp, o), CountWith(merged, sp, pp, o); got != want {
+					t.Fatalf("count %q %q %v: got %d, want %d", s, p, o, got, want)
+				}
+				if got, want := Exists(union, sp, pp, o), Exists(merged, sp, pp, o); got != want {
+					t.Fatalf("exists %q %q %v: got %t, want %t", s, p, o, got, want)
+				}
+			}
+		}
+	}
+
+	q, err := ParseQuery("SELECT ?name WHERE { <alice> <knows> ?p . ?p <name> ?name }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := q.Eval(union)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(res.Bindings), 1; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := res.Bindings[0]["name"], StringLiteral("Bob"); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
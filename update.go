@@ -0,0 +1,146 @@
+package triplestore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Update is a parsed SPARQL Update request, i.e. a sequence of operations
+// separated by ';'. Only a subset of SPARQL Update is supported:
+//
+//	PREFIX declarations
+//	INSERT DATA { triples }
+//	DELETE DATA { triples }
+//	DELETE WHERE { basic graph pattern }
+//
+// Triples of INSERT DATA and DELETE DATA cannot contain variables nor filters.
+type Update struct {
+	ops []updateOperation
+}
+
+type updateKind int
+
+const (
+	insertData updateKind = iota
+	deleteData
+	deleteWhere
+)
+
+type updateOperation struct {
+	kind     updateKind
+	patterns []triplePattern
+	filters  []filter
+}
+
+// ParseUpdate parses a SPARQL Update request
+func ParseUpdate(u string) (*Update, error) {
+	toks, err := lexSPARQL(u)
+	if err != nil {
+		return nil, fmt.Errorf("sparql: %s", err)
+	}
+	p := &sparqlParser{toks: toks}
+	update, err := p.parseUpdate()
+	if err != nil {
+		return nil, fmt.Errorf("sparql: %s", err)
+	}
+	return update, nil
+}
+
+// Exec applies the operations of the update to the source, in order.
+// The patterns of a DELETE WHERE are matched against a snapshot of the
+// source taken when the operation is applied.
+func (u *Update) Exec(s Source) error {
+	for _, op := range u.ops {
+		switch op.kind {
+		case insertData:
+			s.Add(op.triples(Binding{})...)
+		case deleteData:
+			s.Remove(op.triples(Binding{})...)
+		case deleteWhere:
+			var ts []Triple
+			for _, b := range solvePatterns(s.Snapshot(), planPatterns(op.patterns), op.filters...) {
+				ts = append(ts, op.triples(b)...)
+			}
+			s.Remove(ts...)
+		}
+	}
+	return nil
+}
+
+// triples instantiates the patterns of the operation with the binding
+func (op updateOperation) triples(b Binding) []Triple {
+	ts := make([]Triple, 0, len(op.patterns))
+	for _, p := range op.patterns {
+		if t, ok := instantiate(p, b); ok {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+func (p *sparqlParser) parseUpdate() (*Update, error) {
+	p.q = &Query{prefixes: make(map[string]string)}
+	u := &Update{}
+	for {
+		if err := p.parsePrologue(); err != nil {
+			return nil, err
+		}
+		if p.peek().typ == tokEOF && len(u.ops) > 0 {
+			return u, nil
+		}
+		op, err := p.parseUpdateOperation()
+		if err != nil {
+			return nil, err
+		}
+		u.ops = append(u.ops, op)
+
+		if !p.acceptPunct(";") {
+			if t := p.peek(); t.typ != tokEOF {
+				return nil, fmt.Errorf("unexpected %s", t)
+			}
+			return u, nil
+		}
+	}
+}
+
+func (p *sparqlParser) parseUpdateOperation() (updateOperation, error) {
+	var op updateOperation
+	switch {
+	case p.acceptKeyword("INSERT"):
+		if !p.acceptKeyword("DATA") {
+			return op, fmt.Errorf("expected DATA, got %s", p.peek())
+		}
+		op.kind = insertData
+	case p.acceptKeyword("DELETE"):
+		switch {
+		case p.acceptKeyword("DATA"):
+			op.kind = deleteData
+		case p.acceptKeyword("WHERE"):
+			op.kind = deleteWhere
+		default:
+			return op, fmt.Errorf("expected DATA or WHERE, got %s", p.peek())
+		}
+	default:
+		return op, fmt.Errorf("expected INSERT or DELETE, got %s", p.peek())
+	}
+
+	p.q.patterns, p.q.filters = nil, nil
+	if err := p.parseGroupGraphPattern(); err != nil {
+		return op, err
+	}
+	op.patterns, op.filters = p.q.patterns, p.q.filters
+
+	if op.kind != deleteWhere {
+		if len(op.filters) > 0 {
+			return op, errors.New("filters not allowed in data")
+		}
+		for _, pattern := range op.patterns {
+			for _, t := range []term{pattern.sub, pattern.pred, pattern.obj} {
+				if t.isVar() {
+					return op, errors.New("variables not allowed in data")
+				}
+			}
+		}
+	}
+	return op, nil
+}
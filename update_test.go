@@ -0,0 +1,122 @@
+package triplestore
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestUpdateExec(t *testing.T) {
+	tcases := []struct {
+		update string
+		exp    []string
+	}{
+		{
+			update: `PREFIX ex: <http://ex.org/>
+			INSERT DATA { ex:carol a <Person> ; <name> "Carol" ; <age> 33 . _:b2 <name> "anon2" }`,
+			exp: []string{
+				"<_:b1> <name> anon", "<_:b2> <name> anon2", "<alice> <knows> bob", "<alice> <name> Alice",
+				"<bob> <name> Bob", "<http://ex.org/carol> <age> 33", "<http://ex.org/carol> <name> Carol",
+				"<http://ex.org/carol> <rdf:type> Person", "<x> <rdf:type> Person",
+			},
+		},
+		{
+			update: `DELETE DATA { <alice> <name> "Alice" . <alice> <name> "Unknown" }`,
+			exp: []string{
+				"<_:b1> <name> anon", "<alice> <knows> bob", "<bob> <name> Bob", "<x> <rdf:type> Person",
+			},
+		},
+		{
+			update: `DELETE WHERE { ?s <name> ?n }`,
+			exp:    []string{"<alice> <knows> bob", "<x> <rdf:type> Person"},
+		},
+		{
+			update: `DELETE WHERE { ?s <knows> ?o . ?o <name> "Bob" }`,
+			exp:    []string{"<_:b1> <name> anon", "<alice> <name> Alice", "<x> <rdf:type> Person"},
+		},
+		{
+			update: `DELETE WHERE { ?s <name> ?n FILTER regex(?n, "^[AB]") }`,
+			exp:    []string{"<_:b1> <name> anon", "<alice> <knows> bob", "<x> <rdf:type> Person"},
+		},
+		{
+			update: `DELETE WHERE { ?s <knows> ?o . ?o <name> "Unknown" }`,
+			exp: []string{
+				"<_:b1> <name> anon", "<alice> <knows> bob", "<alice> <name> Alice", "<bob> <name> Bob",
+				"<x> <rdf:type> Person",
+			},
+		},
+		{
+			update: `DELETE DATA { <alice> <knows> <bob> } ; INSERT DATA { <bob> <knows> <alice> } ;`,
+			exp: []string{
+				"<_:b1> <name> anon", "<alice> <name> Alice", "<bob> <knows> alice", "<bob> <name> Bob",
+				"<x> <rdf:type> Person",
+			},
+		},
+	}
+	for i, tc := range tcases {
+		s := NewSource()
+		s.Add(
+			SubjPred("alice", "name").StringLiteral("Alice"),
+			SubjPred("bob", "name").StringLiteral("Bob"),
+			SubjPred("alice", "knows").Resource("bob"),
+			SubjPred("x", "rdf:type").Resource("Person"),
+			BnodePred("b1", "name").StringLiteral("anon"),
+		)
+		u, err := ParseUpdate(tc.update)
+		if err != nil {
+			t.Fatalf("case %d: %s", i, err)
+		}
+		if err := u.Exec(s); err != nil {
+			t.Fatalf("case %d: %s", i, err)
+		}
+		var got []string
+		for _, tri := range s.CopyTriples() {
+			got = append(got, tripleString(tri))
+		}
+		sort.Strings(got)
+		if want := tc.exp; !reflect.DeepEqual(got, want) {
+			t.Fatalf("case %d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestParseUpdateErrors(t *testing.T) {
+	tcases := []struct {
+		update, err string
+	}{
+		{"", "expected INSERT or DELETE"},
+		{"INSERT { <s> <p> <o> }", "expected DATA"},
+		{"DELETE { <s> <p> <o> }", "expected DATA or WHERE"},
+		{"INSERT DATA { ?s <p> <o> }", "variables not allowed"},
+		{"DELETE DATA { <s> <p> ?o }", "variables not allowed"},
+		{"INSERT DATA { <s> <p> \"o\" FILTER regex(?o, \"o\") }", "filters not allowed"},
+		{"INSERT DATA { <s> <p> <o> ", "got end of query"},
+		{"INSERT DATA { <s> <p> <o> } DELETE DATA { <s> <p> <o> }", "unexpected 'DELETE'"},
+		{"SELECT * { ?s ?p ?o }", "expected INSERT or DELETE"},
+	}
+	for i, tc := range tcases {
+		_, err := ParseUpdate(tc.update)
+		if err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: got %q, want it to contain %q", i, err, tc.err)
+		}
+	}
+}
+
+func tripleString(t Triple) string {
+	sub := t.Subject()
+	if t.(*triple).isSubBnode {
+		sub = "_:" + sub
+	}
+	obj := t.Object().(object)
+	val := obj.resource
+	if obj.isLit {
+		val = obj.lit.val
+	} else if obj.isBnode {
+		val = "_:" + obj.bnode
+	}
+	return "<" + sub + "> <" + t.Predicate() + "> " + val
+}
@@ -0,0 +1,54 @@
+package triplestore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type unknownLiteralTypeError XsdType
+
+func (e unknownLiteralTypeError) Error() string {
+	return fmt.Sprintf("unknown literal type: %s", string(e))
+}
+
+var langtagRegexp = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+
+// ValidateTriples checks that literal values are valid lexical forms of their
+// datatype (ex: "abc"^^xsd:integer is invalid) and that language tags are well formed.
+// Datatypes are recognized as prefixed names (ex: "xsd:integer") or full IRIs;
+// literals of unknown datatypes are not checked.
+func ValidateTriples(tris ...Triple) []error {
+	var errs []error
+	for i, t := range tris {
+		tri := t.(*triple)
+		if !tri.obj.isLit {
+			continue
+		}
+		if err := validateLiteral(tri.obj); err != nil {
+			errs = append(errs, fmt.Errorf("triple %d: invalid literal %s: %s", i, tri.obj.key(), err))
+		}
+	}
+	return errs
+}
+
+// validateLiteral checks the lexical form of XSD and registered datatypes
+func validateLiteral(obj object) error {
+	if lang := obj.lit.langtag; lang != "" && !langtagRegexp.MatchString(lang) {
+		return fmt.Errorf("malformed language tag '%s'", lang)
+	}
+	obj.lit.typ = shortXsdType(obj.lit.typ)
+	_, err := ParseLiteral(obj)
+	if _, unknown := err.(unknownLiteralTypeError); unknown {
+		return nil
+	}
+	return err
+}
+
+// shortXsdType returns the prefixed name of a XML schema datatype given as a full IRI
+func shortXsdType(typ XsdType) XsdType {
+	if ns := XMLSchemaNamespace + "#"; strings.HasPrefix(string(typ), ns) {
+		return XsdType("xsd:" + strings.TrimPrefix(string(typ), ns))
+	}
+	return typ
+}
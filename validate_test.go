@@ -0,0 +1,33 @@
+package triplestore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTriples(t *testing.T) {
+	tris := []Triple{
+		SubjPred("s", "p").IntegerLiteral(42),
+		SubjPred("s", "p").Object(object{isLit: true, lit: literal{typ: XsdInteger, val: "abc"}}),
+		SubjPred("s", "p").Object(object{isLit: true, lit: literal{typ: XsdType(XMLSchemaNamespace + "#boolean"), val: "yes"}}),
+		SubjPred("s", "p").Object(object{isLit: true, lit: literal{typ: XsdType(XMLSchemaNamespace + "#boolean"), val: "true"}}),
+		SubjPred("s", "p").Object(object{isLit: true, lit: literal{typ: XsdDateTime, val: "yesterday"}}),
+		SubjPred("s", "p").Object(object{isLit: true, lit: literal{typ: XsdType("xsd:anyURI"), val: "whatever"}}),
+		SubjPred("s", "p").Object(object{isLit: true, lit: literal{typ: XsdType("http://ex.org/custom"), val: "whatever"}}),
+		SubjPred("s", "p").StringLiteralWithLang("hello", "en-US"),
+		SubjPred("s", "p").StringLiteralWithLang("hello", "not a tag"),
+		SubjPred("s", "p").Resource("abc"),
+	}
+
+	errs := ValidateTriples(tris...)
+	var got []string
+	for _, err := range errs {
+		got = append(got, strings.SplitN(err.Error(), ":", 2)[0])
+	}
+	if got, want := strings.Join(got, ","), "triple 1,triple 2,triple 4,triple 8"; got != want {
+		t.Fatalf("got %s, want %s (%v)", got, want, errs)
+	}
+	if !strings.Contains(errs[0].Error(), `invalid literal "abc"^^<http://www.w3.org/2001/XMLSchema#integer>`) {
+		t.Fatalf("unexpected error message %s", errs[0])
+	}
+}
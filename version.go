@@ -0,0 +1,154 @@
+package triplestore
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A VersionedSource is a source recording the history of its changes so that
+// it can be snapshotted as it was at any version. Each write changing the source
+// or one of its named graphs (Add, AddBatch, Remove, DropGraph) is a commit
+// numbered with the next version, starting at 1. LoadFrom commits one version per batch.
+//
+// The history holds every triple ever added or removed.
+type VersionedSource interface {
+	Source
+	// Version returns the current version, 0 for a new source
+	Version() uint64
+	// History returns the commits, oldest first
+	History() []Version
+	// VersionAt returns the version of the source at the given time
+	VersionAt(t time.Time) uint64
+	// SnapshotAt returns a snapshot of the source, including all of its graphs, at the given version
+	SnapshotAt(version uint64) (RDFGraph, error)
+}
+
+// Version describes a commit of a versioned source
+type Version struct {
+	Number         uint64
+	Time           time.Time
+	Added, Removed int
+}
+
+type versionedSource struct {
+	*source
+}
+
+// NewVersionedSource returns an empty source recording its history
+func NewVersionedSource(opts ...SourceOption) VersionedSource {
+	s := NewSource(opts...).(*source)
+	s.history = &history{}
+	return &versionedSource{s}
+}
+
+func (s *versionedSource) Version() uint64 {
+	return s.history.version()
+}
+
+func (s *versionedSource) History() []Version {
+	s.history.mu.RLock()
+	defer s.history.mu.RUnlock()
+	out := make([]Version, len(s.history.commits))
+	for i, c := range s.history.commits {
+		out[i] = c.Version
+	}
+	return out
+}
+
+func (s *versionedSource) VersionAt(t time.Time) uint64 {
+	s.history.mu.RLock()
+	defer s.history.mu.RUnlock()
+	commits := s.history.commits
+	i := sort.Search(len(commits), func(i int) bool { return commits[i].Time.After(t) })
+	if i == 0 {
+		return 0
+	}
+	return commits[i-1].Number
+}
+
+func (s *versionedSource) SnapshotAt(version uint64) (RDFGraph, error) {
+	s.history.mu.RLock()
+	commits := s.history.commits
+	s.history.mu.RUnlock()
+	if version > uint64(len(commits)) {
+		return nil, fmt.Errorf("version %d not found, latest is %d", version, len(commits))
+	}
+
+	graphs := make(map[string]map[string]*triple)
+	for _, c := range commits[:version] {
+		for _, change := range c.changes {
+			g, ok := graphs[change.graph]
+			if !ok {
+				g = make(map[string]*triple)
+				graphs[change.graph] = g
+			}
+			for _, t := range change.added {
+				g[t.key()] = t
+			}
+			for _, t := range change.removed {
+				delete(g, t.key())
+			}
+			if change.dropped {
+				delete(graphs, change.graph)
+			}
+		}
+	}
+
+	var tris []*triple
+	for _, g := range graphs {
+		for _, t := range g {
+			tris = append(tris, t)
+		}
+	}
+	return s.newGraph(tris), nil
+}
+
+// history is the log of the commits of a source
+type history struct {
+	mu      sync.RWMutex
+	commits []commit
+}
+
+type commit struct {
+	Version
+	changes []graphChange
+}
+
+// graphChange holds the triples actually added to or removed from a graph
+type graphChange struct {
+	graph          string
+	added, removed []*triple
+	dropped        bool
+}
+
+func (h *history) version() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return uint64(len(h.commits))
+}
+
+// record commits the changes, if any, with the next version
+func (h *history) record(changes ...graphChange) {
+	if h == nil {
+		return
+	}
+	var c commit
+	for _, change := range changes {
+		if len(change.added) == 0 && len(change.removed) == 0 && !change.dropped {
+			continue
+		}
+		c.Added += len(change.added)
+		c.Removed += len(change.removed)
+		c.changes = append(c.changes, change)
+	}
+	if len(c.changes) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.Number, c.Time = uint64(len(h.commits))+1, time.Now()
+	h.commits = append(h.commits, c)
+}
@@ -0,0 +1,88 @@
+package triplestore_test
+
+import (
+	"testing"
+	"time"
+
+	tstore "github.com/wallix/triplestore"
+)
+
+func TestVersionedSource(t *testing.T) {
+	s := tstore.NewVersionedSource()
+	if got, want := s.Version(), uint64(0); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	one := tstore.SubjPred("one", "is").Resource("first")
+	two := tstore.SubjPred("two", "is").Resource("second")
+	three := tstore.SubjPred("three", "is").Resource("third")
+
+	s.Add(one, two)                                    // 1
+	s.Add(one)                                         // no change, no version
+	s.Remove(tstore.SubjPred("no", "p").Resource("o")) // no change, no version
+	before := time.Now()
+	s.Remove(one)                                         // 2
+	s.(tstore.NamedGraphs).Graph("other").Add(three, two) // 3
+	tstore.AddBatch(s, []tstore.Triple{one})              // 4
+	s.Remove(two)                                         // 5, removed from both graphs
+	s.(tstore.NamedGraphs).DropGraph("other")             // 6
+
+	if got, want := s.Version(), uint64(6); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	history := s.History()
+	if got, want := len(history), 6; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	for i, exp := range []struct{ added, removed int }{{2, 0}, {0, 1}, {2, 0}, {1, 0}, {0, 2}, {0, 1}} {
+		if got, want := history[i].Number, uint64(i+1); got != want {
+			t.Fatalf("version %d: got %d, want %d", i+1, got, want)
+		}
+		if got, want := history[i].Added, exp.added; got != want {
+			t.Fatalf("version %d: added: got %d, want %d", i+1, got, want)
+		}
+		if got, want := history[i].Removed, exp.removed; got != want {
+			t.Fatalf("version %d: removed: got %d, want %d", i+1, got, want)
+		}
+		if i > 0 && history[i].Time.Before(history[i-1].Time) {
+			t.Fatalf("version %d: time not monotonic", i+1)
+		}
+	}
+
+	tcases := []struct {
+		version  uint64
+		expected []tstore.Triple
+	}{
+		{0, nil},
+		{1, []tstore.Triple{one, two}},
+		{2, []tstore.Triple{two}},
+		{3, []tstore.Triple{two, three}},
+		{4, []tstore.Triple{one, two, three}},
+		{5, []tstore.Triple{one, three}},
+		{6, []tstore.Triple{one}},
+	}
+	for _, tc := range tcases {
+		snap, err := s.SnapshotAt(tc.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := tstore.Triples(snap.Triples()), tstore.Triples(tc.expected); !got.Equal(want) {
+			t.Fatalf("version %d: got %v, want %v", tc.version, got, want)
+		}
+	}
+	if got, want := tstore.Triples(s.Snapshot().Triples()), tstore.Triples([]tstore.Triple{one}); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, err := s.SnapshotAt(7); err == nil {
+		t.Fatal("expected error for unknown version")
+	}
+
+	if got, want := s.VersionAt(before), uint64(1); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := s.VersionAt(before.Add(-time.Hour)), uint64(0); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := s.VersionAt(time.Now()), uint64(6); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}